@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Defaults for the device fields of exported CEF and OCSF records.
+const (
+	defaultExportVendor  = "Stacklok"
+	defaultExportProduct = "ToolHive"
+)
+
+// CEFConfig configures a CEFExporter.
+type CEFConfig struct {
+	// Vendor, Product and Version fill the CEF header's device fields.
+	// Empty uses "Stacklok", "ToolHive" and "unknown".
+	Vendor  string
+	Product string
+	Version string
+}
+
+// CEFExporter writes events as ArcSight Common Event Format lines:
+//
+//	CEF:0|Stacklok|ToolHive|1.2.0|mcp_tool_call|mcp_tool_call|3|rt=... outcome=success ...
+//
+// The event type is the signature ID and the name. Severity follows the
+// outcome: 3 for success, 5 for failure, 6 for error and 7 for denied, so
+// that refusals stand out. The extension carries rt, externalId, outcome,
+// suser and suid (the user and user_id subjects), src or shost,
+// requestClientApplication (the User-Agent) and act (the target method),
+// with the component, target, subjects and delegation chain in cs1 to cs4,
+// maps as JSON.
+type CEFExporter struct {
+	w      *bufio.Writer
+	header string
+}
+
+// Compile-time check that CEFExporter is an Exporter.
+var _ Exporter = (*CEFExporter)(nil)
+
+// NewCEFExporter returns a CEFExporter writing to w.
+func NewCEFExporter(w io.Writer, cfg CEFConfig) *CEFExporter {
+	header := "CEF:0|" + strings.Join([]string{
+		cefHeaderEscape(cmp.Or(cfg.Vendor, defaultExportVendor)),
+		cefHeaderEscape(cmp.Or(cfg.Product, defaultExportProduct)),
+		cefHeaderEscape(cmp.Or(cfg.Version, "unknown")),
+	}, "|")
+	return &CEFExporter{w: bufio.NewWriter(w), header: header}
+}
+
+// Export writes e as one line.
+func (x *CEFExporter) Export(e *AuditEvent) error {
+	var b strings.Builder
+	b.WriteString(x.header)
+	for _, field := range []string{e.Type, e.Type, strconv.Itoa(cefSeverity(e.Outcome))} {
+		b.WriteString("|" + cefHeaderEscape(field))
+	}
+	b.WriteString("|")
+
+	var ext []string
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+cefExtensionEscape(value))
+		}
+	}
+	add("rt", strconv.FormatInt(e.LoggedAt.UnixMilli(), 10))
+	add("externalId", e.Metadata.AuditID)
+	add("outcome", e.Outcome)
+	add("suser", e.Subjects[SubjectKeyUser])
+	add("suid", e.Subjects[SubjectKeyUserID])
+	if e.Source.Type == SourceTypeNetwork && net.ParseIP(e.Source.Value) != nil {
+		add("src", e.Source.Value)
+	} else {
+		add("shost", e.Source.Value)
+	}
+	if ua, ok := e.Source.Extra[SourceExtraKeyUserAgent].(string); ok {
+		add("requestClientApplication", ua)
+	}
+	add("act", e.Target[TargetKeyMethod])
+	for i, custom := range []struct{ label, value string }{
+		{"component", e.Component},
+		{"target", csvJSON(e.Target)},
+		{"subjects", csvJSON(e.Subjects)},
+		{"delegation", csvJSON(nonZeroChain(e.DelegationChain))},
+	} {
+		if custom.value != "" {
+			n := strconv.Itoa(i + 1)
+			add("cs"+n+"Label", custom.label)
+			add("cs"+n, custom.value)
+		}
+	}
+	b.WriteString(strings.Join(ext, " "))
+	b.WriteString("\n")
+
+	if _, err := x.w.WriteString(b.String()); err != nil {
+		return fmt.Errorf("audit: failed to write CEF: %w", err)
+	}
+	return nil
+}
+
+// Flush writes out buffered lines.
+func (x *CEFExporter) Flush() error {
+	if err := x.w.Flush(); err != nil {
+		return fmt.Errorf("audit: failed to write CEF: %w", err)
+	}
+	return nil
+}
+
+// cefSeverity maps an outcome to a CEF severity (0-10).
+func cefSeverity(outcome string) int {
+	switch outcome {
+	case OutcomeSuccess:
+		return 3
+	case OutcomeFailure:
+		return 5
+	case OutcomeError:
+		return 6
+	case OutcomeDenied:
+		return 7
+	default:
+		return 5
+	}
+}
+
+// nonZeroChain returns c, or nil when it carries nothing.
+func nonZeroChain(c *DelegationChain) *DelegationChain {
+	if c.IsZero() {
+		return nil
+	}
+	return c
+}
+
+// cefHeaderEscaper escapes a CEF header field: backslash and pipe. Line
+// breaks, which would end the record, become spaces.
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+
+// cefExtensionEscaper escapes a CEF extension value: backslash, equals and
+// line breaks.
+var cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+func cefHeaderEscape(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func cefExtensionEscape(s string) string {
+	return cefExtensionEscaper.Replace(s)
+}
@@ -0,0 +1,373 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventTypeAuditCheckpoint is the type of the signed checkpoint events a
+// ChainSink inserts into its chain. Data holds a Checkpoint.
+const EventTypeAuditCheckpoint = "audit_checkpoint"
+
+const (
+	// chainHashPrefix names the hash algorithm in PrevHash and
+	// Checkpoint.Hash, so a later change of algorithm is detectable rather
+	// than a silent mismatch.
+	chainHashPrefix = "sha256:"
+	// checkpointDomain prefixes every signed checkpoint payload, so a
+	// checkpoint signature cannot be passed off as a signature over anything
+	// else made with the same key, an artifact included.
+	checkpointDomain = "toolhive-audit-checkpoint/v1\n"
+	// defaultCheckpointEvery is ChainConfig.CheckpointEvery when zero.
+	defaultCheckpointEvery = 1000
+	// defaultChainComponent is ChainConfig.Component when empty.
+	defaultChainComponent = "audit"
+)
+
+// ChainHead identifies the last event of a chain: its sequence number and
+// hash. It is all a ChainSink needs to continue a chain after a restart.
+type ChainHead struct {
+	Sequence uint64
+	Hash     string
+}
+
+// Checkpoint is the Data of an EventTypeAuditCheckpoint event: a signature
+// over the head of the chain at the event before it. A verifier holding the
+// public key can then tell that every event up to Sequence is as it was
+// written — the hash chain alone shows only that the events are consistent
+// with each other, which an attacker able to rewrite the whole file can
+// also arrange.
+type Checkpoint struct {
+	// Sequence and Hash are the head being signed.
+	Sequence uint64 `json:"sequence"`
+	Hash     string `json:"hash"`
+	// KeyID is "sha256:<hex>" of the signing key's PKIX public key, to tell
+	// which key to verify with after a rotation.
+	KeyID string `json:"keyId"`
+	// Signature signs the checkpoint payload (see checkpointPayload): ASN.1
+	// ECDSA or PKCS #1 v1.5 RSA over its SHA-256, or Ed25519 over the
+	// payload itself.
+	Signature []byte `json:"signature"`
+}
+
+// ChainConfig configures a ChainSink.
+type ChainConfig struct {
+	// Signer signs checkpoints. ECDSA, RSA and Ed25519 keys are supported;
+	// container/signer.LoadPrivateKey loads the cosign key used for artifact
+	// signing. Nil disables checkpoints, leaving the hash chain alone.
+	Signer crypto.Signer
+
+	// CheckpointEvery is how many events are written between checkpoints.
+	// Zero uses 1000; negative is an error. Events after the last checkpoint
+	// can be cut from the end of the log undetected, so this bounds what
+	// truncation can hide.
+	CheckpointEvery int
+
+	// CheckpointInterval, when positive, also checkpoints once this long has
+	// passed with events written since the last checkpoint, so a quiet
+	// period does not leave recent events unsigned indefinitely. Negative is
+	// an error.
+	CheckpointInterval time.Duration
+
+	// Component is the Component and source of checkpoint events. Empty
+	// uses "audit".
+	Component string
+
+	// Resume continues an existing chain from its head, as reported by
+	// VerifyChain or ChainSink.Head before a restart. The zero value starts
+	// a new chain at sequence 1.
+	Resume ChainHead
+
+	// Logger receives checkpoint failures that no caller is waiting on: those
+	// of automatic checkpoints, which are retried with the next event. Nil
+	// uses slog.Default.
+	Logger *slog.Logger
+}
+
+// ChainSink makes the events passing through it a tamper-evident hash chain
+// (NIST SP 800-53 AU-9): it numbers each event in Metadata.Sequence, records
+// the previous event's hash in Metadata.PrevHash, and periodically inserts a
+// signed checkpoint event, before handing the event to the next sink.
+// VerifyChain checks the result.
+//
+// An event's hash is SHA-256 over its JSON encoding — the exact line FileSink
+// writes — so verification needs the log byte for byte: a pipeline that
+// re-serializes events breaks every link. Chain in front of the fan-out, not
+// behind it, so that every sink carries the same chain:
+//
+//	chain, err := audit.NewChainSink(dispatcher, audit.ChainConfig{Signer: key})
+//
+// Writes are serialized: the chain is a single sequence. A Write the next
+// sink fails does not advance the chain, so a retried event takes the same
+// sequence number. It is safe for concurrent use.
+type ChainSink struct {
+	next      Sink
+	signer    crypto.Signer
+	keyID     string
+	every     int
+	component string
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	head    ChainHead
+	pending int // events written since the last checkpoint
+	closed  bool
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Compile-time check that ChainSink is a Sink.
+var _ Sink = (*ChainSink)(nil)
+
+// NewChainSink returns a ChainSink writing to next. next belongs to the
+// ChainSink from then on: Close closes it.
+func NewChainSink(next Sink, cfg ChainConfig) (*ChainSink, error) {
+	switch {
+	case next == nil:
+		return nil, errors.New("audit: chain sink requires a next sink")
+	case cfg.CheckpointEvery < 0:
+		return nil, fmt.Errorf("audit: checkpoint count must not be negative: %d", cfg.CheckpointEvery)
+	case cfg.CheckpointInterval < 0:
+		return nil, fmt.Errorf("audit: checkpoint interval must not be negative: %s", cfg.CheckpointInterval)
+	case cfg.Signer == nil && (cfg.CheckpointEvery != 0 || cfg.CheckpointInterval != 0):
+		return nil, errors.New("audit: checkpoints require a signer")
+	case cfg.Resume.Sequence == 0 && cfg.Resume.Hash != "",
+		cfg.Resume.Sequence != 0 && cfg.Resume.Hash == "":
+		return nil, errors.New("audit: resume head needs both a sequence and a hash")
+	}
+	if cfg.CheckpointEvery == 0 {
+		cfg.CheckpointEvery = defaultCheckpointEvery
+	}
+	if cfg.Component == "" {
+		cfg.Component = defaultChainComponent
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	s := &ChainSink{
+		next:      next,
+		signer:    cfg.Signer,
+		every:     cfg.CheckpointEvery,
+		component: cfg.Component,
+		logger:    cfg.Logger,
+		head:      cfg.Resume,
+	}
+	if cfg.Signer != nil {
+		keyID, err := checkpointKeyID(cfg.Signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		s.keyID = keyID
+	}
+	if cfg.Signer != nil && cfg.CheckpointInterval > 0 {
+		s.stop, s.done = make(chan struct{}), make(chan struct{})
+		go s.tick(cfg.CheckpointInterval)
+	}
+	return s, nil
+}
+
+// Head returns the head of the chain: the last event written.
+func (s *ChainSink) Head() ChainHead {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.head
+}
+
+// Write links a copy of event into the chain and writes it to the next sink,
+// followed by a checkpoint if one is due.
+func (s *ChainSink) Write(ctx context.Context, event *AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("audit: chain sink is closed")
+	}
+	if err := s.append(ctx, event.clone()); err != nil {
+		return err
+	}
+	s.pending++
+	if s.signer != nil && s.pending >= s.every {
+		// The event is written whatever happens here; a failed checkpoint
+		// stays due and is retried after the next one.
+		if err := s.checkpoint(ctx); err != nil {
+			s.logger.ErrorContext(ctx, "failed to write audit checkpoint", "error", err)
+		}
+	}
+	return nil
+}
+
+// Checkpoint writes a signed checkpoint now, if any event has been written
+// since the last one. It fails without a Signer.
+func (s *ChainSink) Checkpoint(ctx context.Context) error {
+	if s.signer == nil {
+		return errors.New("audit: checkpoints require a signer")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("audit: chain sink is closed")
+	}
+	return s.checkpoint(ctx)
+}
+
+// append numbers and links e, writes it, and advances the head. The caller
+// holds mu.
+func (s *ChainSink) append(ctx context.Context, e *AuditEvent) error {
+	e.Metadata.Sequence = s.head.Sequence + 1
+	e.Metadata.PrevHash = s.head.Hash
+	data, err := marshalEvent(e)
+	if err != nil {
+		return err
+	}
+	if err := s.next.Write(ctx, e); err != nil {
+		return err
+	}
+	s.head = ChainHead{Sequence: e.Metadata.Sequence, Hash: chainHash(data)}
+	return nil
+}
+
+// checkpoint signs the head and appends it as a checkpoint event. The
+// caller holds mu.
+func (s *ChainSink) checkpoint(ctx context.Context) error {
+	if s.pending == 0 {
+		return nil
+	}
+	cp := Checkpoint{Sequence: s.head.Sequence, Hash: s.head.Hash, KeyID: s.keyID}
+	sig, err := signCheckpoint(s.signer, cp)
+	if err != nil {
+		return err
+	}
+	cp.Signature = sig
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("audit: failed to encode checkpoint: %w", err)
+	}
+	raw := json.RawMessage(data)
+	event := NewAuditEvent(EventTypeAuditCheckpoint,
+		EventSource{Type: SourceTypeLocal, Value: s.component},
+		OutcomeSuccess, map[string]string{}, s.component).WithData(&raw)
+	if err := s.append(ctx, event); err != nil {
+		return err
+	}
+	s.pending = 0
+	return nil
+}
+
+// tick checkpoints every interval until Close.
+func (s *ChainSink) tick(interval time.Duration) {
+	defer close(s.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.Checkpoint(context.Background()); err != nil {
+				s.logger.Error("failed to write audit checkpoint", "error", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close writes a final checkpoint covering any unsigned events, then closes
+// the next sink.
+func (s *ChainSink) Close() error {
+	if s.stop != nil {
+		s.stopOnce.Do(func() {
+			close(s.stop)
+			<-s.done
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	var errs []error
+	if s.signer != nil {
+		errs = append(errs, s.checkpoint(context.Background()))
+	}
+	return errors.Join(append(errs, s.next.Close())...)
+}
+
+// chainHash is the hash of an event's JSON encoding as PrevHash records it.
+func chainHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return chainHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// checkpointPayload is the byte string a checkpoint signature covers.
+func checkpointPayload(cp Checkpoint) []byte {
+	return []byte(checkpointDomain + strconv.FormatUint(cp.Sequence, 10) + "\n" + cp.Hash + "\n" + cp.KeyID + "\n")
+}
+
+// signCheckpoint signs cp's payload with key.
+func signCheckpoint(key crypto.Signer, cp Checkpoint) ([]byte, error) {
+	payload := checkpointPayload(cp)
+	var (
+		sig []byte
+		err error
+	)
+	if _, ok := key.Public().(ed25519.PublicKey); ok {
+		sig, err = key.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(payload)
+		sig, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to sign checkpoint: %w", err)
+	}
+	return sig, nil
+}
+
+// verifyCheckpoint reports whether cp's signature is valid under pub.
+func verifyCheckpoint(pub crypto.PublicKey, cp Checkpoint) bool {
+	payload := checkpointPayload(cp)
+	digest := sha256.Sum256(payload)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], cp.Signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], cp.Signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, cp.Signature)
+	default:
+		return false
+	}
+}
+
+// checkpointKeyID names pub in Checkpoint.KeyID, rejecting key types
+// verifyCheckpoint cannot check.
+func checkpointKeyID(pub crypto.PublicKey) (string, error) {
+	switch pub.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return "", fmt.Errorf("audit: unsupported checkpoint key type %T", pub)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("audit: failed to encode checkpoint key: %w", err)
+	}
+	return chainHash(der), nil
+}
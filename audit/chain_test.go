@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineSink keeps the JSON line of every event written to it, as FileSink
+// would write it. It fails the first failures Writes.
+type lineSink struct {
+	mu       sync.Mutex
+	lines    [][]byte
+	failures int
+}
+
+func (s *lineSink) Write(_ context.Context, event *AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	data, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	s.lines = append(s.lines, data)
+	return nil
+}
+
+func (*lineSink) Close() error { return nil }
+
+// log returns the lines as a JSON-lines document.
+func (s *lineSink) log() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append(bytes.Join(s.lines, []byte("\n")), '\n')
+}
+
+// events decodes the lines.
+func (s *lineSink) events(t *testing.T) []AuditEvent {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]AuditEvent, len(s.lines))
+	for i, l := range s.lines {
+		require.NoError(t, json.Unmarshal(l, &out[i]))
+	}
+	return out
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// writeChain writes ids through a new ChainSink over next and closes it.
+func writeChain(t *testing.T, next Sink, cfg ChainConfig, ids ...string) ChainHead {
+	t.Helper()
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	s, err := NewChainSink(next, cfg)
+	require.NoError(t, err)
+	for _, id := range ids {
+		require.NoError(t, s.Write(context.Background(), newTestEvent(id)))
+	}
+	require.NoError(t, s.Close())
+	return s.Head()
+}
+
+func TestNewChainSinkConfig(t *testing.T) {
+	t.Parallel()
+
+	key := newECKey(t)
+	tests := []struct {
+		name    string
+		next    Sink
+		cfg     ChainConfig
+		wantErr string
+	}{
+		{name: "hash chain only", next: &lineSink{}},
+		{name: "with checkpoints", next: &lineSink{}, cfg: ChainConfig{Signer: key, CheckpointEvery: 10}},
+		{name: "no next sink", cfg: ChainConfig{}, wantErr: "requires a next sink"},
+		{name: "negative count", next: &lineSink{}, cfg: ChainConfig{Signer: key, CheckpointEvery: -1}, wantErr: "count"},
+		{
+			name:    "negative interval",
+			next:    &lineSink{},
+			cfg:     ChainConfig{Signer: key, CheckpointInterval: -time.Second},
+			wantErr: "interval",
+		},
+		{name: "checkpoints without signer", next: &lineSink{}, cfg: ChainConfig{CheckpointEvery: 10}, wantErr: "require a signer"},
+		{name: "resume without hash", next: &lineSink{}, cfg: ChainConfig{Resume: ChainHead{Sequence: 3}}, wantErr: "resume head"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewChainSink(tt.next, tt.cfg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, s)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestChainSinkLinksEvents(t *testing.T) {
+	t.Parallel()
+
+	key := newECKey(t)
+	next := &lineSink{}
+	s, err := NewChainSink(next, ChainConfig{Signer: key, CheckpointEvery: 2, Component: "thv-proxy"})
+	require.NoError(t, err)
+
+	event := newTestEvent("e1")
+	require.NoError(t, s.Write(context.Background(), event))
+	assert.Zero(t, event.Metadata.Sequence, "the caller's event is not modified")
+	for _, id := range []string{"e2", "e3"} {
+		require.NoError(t, s.Write(context.Background(), newTestEvent(id)))
+	}
+	require.NoError(t, s.Close())
+
+	// e1 e2 checkpoint e3 checkpoint: the last written by Close.
+	events := next.events(t)
+	require.Len(t, events, 5)
+	var types []string
+	for i, e := range events {
+		assert.Equal(t, uint64(i+1), e.Metadata.Sequence)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{
+		EventTypeMCPToolCall, EventTypeMCPToolCall, EventTypeAuditCheckpoint, EventTypeMCPToolCall, EventTypeAuditCheckpoint,
+	}, types)
+	assert.Empty(t, events[0].Metadata.PrevHash)
+	assert.Equal(t, chainHash(next.lines[0]), events[1].Metadata.PrevHash)
+
+	cp := events[2]
+	assert.Equal(t, "thv-proxy", cp.Component)
+	var data Checkpoint
+	require.NoError(t, json.Unmarshal(*cp.Data, &data))
+	assert.Equal(t, uint64(2), data.Sequence)
+	assert.Equal(t, cp.Metadata.PrevHash, data.Hash)
+	assert.True(t, verifyCheckpoint(key.Public(), data))
+
+	assert.Equal(t, ChainHead{Sequence: 5, Hash: chainHash(next.lines[4])}, s.Head())
+	assert.ErrorContains(t, s.Write(context.Background(), newTestEvent("e4")), "closed")
+	assert.NoError(t, s.Close(), "second Close is a no-op")
+}
+
+func TestChainSinkKeyTypes(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, key := range map[string]crypto.Signer{"ecdsa": newECKey(t), "rsa": rsaKey, "ed25519": edKey} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			next := &lineSink{}
+			writeChain(t, next, ChainConfig{Signer: key}, "e1", "e2")
+
+			report, err := VerifyChain(bytes.NewReader(next.log()), key.Public())
+			require.NoError(t, err)
+			assert.True(t, report.OK(), report.Problems)
+			assert.Equal(t, 1, report.Checkpoints)
+			assert.Equal(t, uint64(2), report.SignedThrough)
+		})
+	}
+}
+
+func TestChainSinkResume(t *testing.T) {
+	t.Parallel()
+
+	key := newECKey(t)
+	next := &lineSink{}
+	head := writeChain(t, next, ChainConfig{Signer: key}, "e1", "e2")
+	writeChain(t, next, ChainConfig{Signer: key, Resume: head}, "e3")
+
+	report, err := VerifyChain(bytes.NewReader(next.log()), key.Public())
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Problems)
+	assert.Equal(t, uint64(5), report.Head.Sequence, "e1 e2 checkpoint e3 checkpoint")
+	assert.Equal(t, uint64(4), report.SignedThrough)
+}
+
+func TestChainSinkFailedWriteDoesNotAdvance(t *testing.T) {
+	t.Parallel()
+
+	next := &lineSink{failures: 1}
+	s, err := NewChainSink(next, ChainConfig{})
+	require.NoError(t, err)
+	assert.Error(t, s.Write(context.Background(), newTestEvent("e1")))
+	assert.Zero(t, s.Head().Sequence)
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e1")))
+	require.NoError(t, s.Close())
+
+	events := next.events(t)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(1), events[0].Metadata.Sequence, "the retried event takes the same sequence number")
+}
+
+func TestChainSinkCheckpointInterval(t *testing.T) {
+	t.Parallel()
+
+	key := newECKey(t)
+	next := &lineSink{}
+	s, err := NewChainSink(next, ChainConfig{Signer: key, CheckpointInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e1")))
+	require.Eventually(t, func() bool { return s.Head().Sequence == 2 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, EventTypeAuditCheckpoint, next.events(t)[1].Type)
+
+	// With nothing new to cover, the ticker writes no further checkpoints.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, uint64(2), s.Head().Sequence)
+}
+
+func TestChainSinkWithoutSigner(t *testing.T) {
+	t.Parallel()
+
+	next := &lineSink{}
+	writeChain(t, next, ChainConfig{}, "e1", "e2")
+	assert.Len(t, next.lines, 2, "no checkpoints without a signer")
+
+	s, err := NewChainSink(next, ChainConfig{})
+	require.NoError(t, err)
+	assert.ErrorContains(t, s.Checkpoint(context.Background()), "require a signer")
+}
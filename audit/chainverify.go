@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChainProblemKind classifies what VerifyChain found wrong at a line.
+type ChainProblemKind string
+
+const (
+	// ChainMalformed marks a line that is not a chained event: not JSON, or
+	// without a sequence number.
+	ChainMalformed ChainProblemKind = "malformed"
+
+	// ChainGap marks a sequence number more than one past the previous: events
+	// are missing before this line.
+	ChainGap ChainProblemKind = "gap"
+
+	// ChainOutOfOrder marks a sequence number not greater than the previous:
+	// events were reordered or duplicated.
+	ChainOutOfOrder ChainProblemKind = "out_of_order"
+
+	// ChainBrokenLink marks an event whose PrevHash is not the hash of the
+	// event before it: that event was modified, or replaced.
+	ChainBrokenLink ChainProblemKind = "broken_link"
+
+	// ChainBadCheckpoint marks a checkpoint that does not cover the event
+	// before it, names a different key, or carries an invalid signature.
+	ChainBadCheckpoint ChainProblemKind = "bad_checkpoint"
+)
+
+// ChainProblem is one inconsistency VerifyChain found.
+type ChainProblem struct {
+	// Line is the 1-based line number in the input.
+	Line int
+	// Sequence is the line's sequence number, zero if it has none.
+	Sequence uint64
+	Kind     ChainProblemKind
+	Detail   string
+}
+
+// String formats the problem for a report.
+func (p ChainProblem) String() string {
+	return fmt.Sprintf("line %d (sequence %d): %s: %s", p.Line, p.Sequence, p.Kind, p.Detail)
+}
+
+// ChainReport is the outcome of VerifyChain.
+type ChainReport struct {
+	// Events counts the chained events read, checkpoints included.
+	Events int
+
+	// First is the first event read and Head the highest-numbered. A log that starts
+	// mid-chain — the live file after a rotation — has First.Sequence above
+	// 1, and its first link cannot be checked; verify the rotated files
+	// with it, oldest first, to cover the whole chain.
+	First ChainHead
+	Head  ChainHead
+
+	// Checkpoints counts checkpoints with a valid signature, and
+	// SignedThrough is the sequence number the last of them covers. Both
+	// are zero when VerifyChain was given no public key. Events after
+	// SignedThrough are linked but unsigned: removing them from the end of
+	// the log is not detectable.
+	Checkpoints   int
+	SignedThrough uint64
+
+	// Problems lists every inconsistency found, in line order.
+	Problems []ChainProblem
+}
+
+// OK reports whether the chain verified without problems.
+func (r *ChainReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// VerifyChain checks a JSON-lines audit log written through a ChainSink,
+// such as a FileSink's file, for gaps, reordering and modification. When pub
+// is non-nil it also verifies checkpoint signatures with it; nil checks the
+// hash chain only.
+//
+// It reads the whole log and reports every problem rather than stopping at
+// the first. The error is for failing to read r, not for what was read.
+func VerifyChain(r io.Reader, pub crypto.PublicKey) (*ChainReport, error) {
+	var keyID string
+	if pub != nil {
+		var err error
+		if keyID, err = checkpointKeyID(pub); err != nil {
+			return nil, err
+		}
+	}
+
+	v := chainVerifier{pub: pub, keyID: keyID, report: &ChainReport{}}
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		raw, err := br.ReadBytes('\n')
+		if len(raw) > 0 {
+			v.line(line, bytes.TrimSuffix(raw, []byte("\n")))
+		}
+		if errors.Is(err, io.EOF) {
+			return v.report, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("audit: failed to read chain: %w", err)
+		}
+	}
+}
+
+// chainVerifier carries VerifyChain's state from line to line.
+type chainVerifier struct {
+	pub    crypto.PublicKey
+	keyID  string
+	report *ChainReport
+}
+
+// line checks one line of the log.
+func (v *chainVerifier) line(n int, raw []byte) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return
+	}
+	var e AuditEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		v.problem(n, 0, ChainMalformed, "not a JSON audit event: "+err.Error())
+		return
+	}
+	seq := e.Metadata.Sequence
+	if seq == 0 {
+		v.problem(n, 0, ChainMalformed, "event has no sequence number")
+		return
+	}
+	head := ChainHead{Sequence: seq, Hash: chainHash(raw)}
+
+	// Each event links to the highest-numbered one before it, so a
+	// displaced event is reported where it sits and not again at the events
+	// around it.
+	linked := false
+	switch {
+	case v.report.Events == 0:
+		v.report.First = head
+		if seq == 1 && e.Metadata.PrevHash != "" {
+			v.problem(n, seq, ChainBrokenLink, "the first event of a chain has a previous hash")
+		}
+	case seq <= v.report.Head.Sequence:
+		v.problem(n, seq, ChainOutOfOrder,
+			fmt.Sprintf("follows sequence %d", v.report.Head.Sequence))
+	case seq > v.report.Head.Sequence+1:
+		v.problem(n, seq, ChainGap,
+			fmt.Sprintf("%d events missing after sequence %d", seq-v.report.Head.Sequence-1, v.report.Head.Sequence))
+	case e.Metadata.PrevHash != v.report.Head.Hash:
+		v.problem(n, seq, ChainBrokenLink, "previous hash does not match the event before it")
+	default:
+		linked = true
+	}
+
+	if e.Type == EventTypeAuditCheckpoint {
+		v.checkpoint(n, &e, linked)
+	}
+
+	if v.report.Events == 0 || seq > v.report.Head.Sequence {
+		v.report.Head = head
+	}
+	v.report.Events++
+}
+
+// checkpoint checks a checkpoint event. linked reports whether the event's
+// own PrevHash checked out against the line before it.
+func (v *chainVerifier) checkpoint(n int, e *AuditEvent, linked bool) {
+	seq := e.Metadata.Sequence
+	var cp Checkpoint
+	if e.Data == nil || json.Unmarshal(*e.Data, &cp) != nil {
+		v.problem(n, seq, ChainBadCheckpoint, "checkpoint data is missing or malformed")
+		return
+	}
+	if cp.Sequence != seq-1 || cp.Hash != e.Metadata.PrevHash {
+		v.problem(n, seq, ChainBadCheckpoint, "checkpoint does not cover the event before it")
+		return
+	}
+	if v.pub == nil {
+		return
+	}
+	switch {
+	case cp.KeyID != v.keyID:
+		v.problem(n, seq, ChainBadCheckpoint, "checkpoint is signed by key "+cp.KeyID)
+	case !verifyCheckpoint(v.pub, cp):
+		v.problem(n, seq, ChainBadCheckpoint, "checkpoint signature is invalid")
+	case linked:
+		v.report.Checkpoints++
+		v.report.SignedThrough = cp.Sequence
+	}
+}
+
+// problem records that the event at line, with sequence number seq (zero if
+// unknown), is inconsistent in the way kind and detail describe.
+func (v *chainVerifier) problem(line int, seq uint64, kind ChainProblemKind, detail string) {
+	v.report.Problems = append(v.report.Problems, ChainProblem{Line: line, Sequence: seq, Kind: kind, Detail: detail})
+}
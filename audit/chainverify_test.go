@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problemKinds returns the kinds of report's problems, in order.
+func problemKinds(report *ChainReport) []ChainProblemKind {
+	var out []ChainProblemKind
+	for _, p := range report.Problems {
+		out = append(out, p.Kind)
+	}
+	return out
+}
+
+func TestVerifyChain(t *testing.T) {
+	t.Parallel()
+
+	key := newECKey(t)
+	// e1 e2 e3 checkpoint(3) e4 e5 e6 checkpoint(7), one line each.
+	next := &lineSink{}
+	writeChain(t, next, ChainConfig{Signer: key, CheckpointEvery: 3}, "e1", "e2", "e3", "e4", "e5", "e6")
+	require.Len(t, next.lines, 8)
+
+	// forged is a checkpoint over the real head at sequence 3, signed with
+	// another key.
+	forged := func() []byte {
+		other := newECKey(t)
+		keyID, err := checkpointKeyID(other.Public())
+		require.NoError(t, err)
+		var e AuditEvent
+		require.NoError(t, json.Unmarshal(next.lines[3], &e))
+		var cp Checkpoint
+		require.NoError(t, json.Unmarshal(*e.Data, &cp))
+		cp.KeyID = keyID
+		cp.Signature, err = signCheckpoint(other, cp)
+		require.NoError(t, err)
+		data, err := json.Marshal(cp)
+		require.NoError(t, err)
+		raw := json.RawMessage(data)
+		e.Data = &raw
+		line, err := marshalEvent(&e)
+		require.NoError(t, err)
+		return line
+	}()
+
+	tests := []struct {
+		name      string
+		edit      func(lines [][]byte) [][]byte
+		wantKinds []ChainProblemKind
+		wantLines []int
+	}{
+		{name: "untouched", edit: func(l [][]byte) [][]byte { return l }},
+		{
+			name: "modified event",
+			edit: func(l [][]byte) [][]byte {
+				l[1] = bytes.Replace(l[1], []byte("alice"), []byte("mallory"), 1)
+				return l
+			},
+			wantKinds: []ChainProblemKind{ChainBrokenLink},
+			wantLines: []int{3},
+		},
+		{
+			name:      "deleted event",
+			edit:      func(l [][]byte) [][]byte { return append(l[:1:1], l[2:]...) },
+			wantKinds: []ChainProblemKind{ChainGap},
+			wantLines: []int{2},
+		},
+		{
+			name: "swapped events",
+			edit: func(l [][]byte) [][]byte {
+				l[1], l[2] = l[2], l[1]
+				return l
+			},
+			wantKinds: []ChainProblemKind{ChainGap, ChainOutOfOrder},
+			wantLines: []int{2, 3},
+		},
+		{
+			name:      "duplicated event",
+			edit:      func(l [][]byte) [][]byte { return append(l[:2:2], append([][]byte{l[1]}, l[2:]...)...) },
+			wantKinds: []ChainProblemKind{ChainOutOfOrder},
+			wantLines: []int{3},
+		},
+		{
+			name:      "inserted garbage",
+			edit:      func(l [][]byte) [][]byte { return append(l[:2:2], append([][]byte{[]byte("not json")}, l[2:]...)...) },
+			wantKinds: []ChainProblemKind{ChainMalformed},
+			wantLines: []int{3},
+		},
+		{
+			name: "forged checkpoint",
+			edit: func(l [][]byte) [][]byte {
+				l[3] = forged
+				return l
+			},
+			// The forged line also changes the hash the next event links to.
+			wantKinds: []ChainProblemKind{ChainBadCheckpoint, ChainBrokenLink},
+			wantLines: []int{4, 5},
+		},
+		{
+			name:      "start mid-chain",
+			edit:      func(l [][]byte) [][]byte { return l[4:] },
+			wantKinds: nil,
+		},
+		{
+			name:      "truncated tail",
+			edit:      func(l [][]byte) [][]byte { return l[:6] },
+			wantKinds: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lines := make([][]byte, len(next.lines))
+			copy(lines, next.lines)
+			log := append(bytes.Join(tt.edit(lines), []byte("\n")), '\n')
+
+			report, err := VerifyChain(bytes.NewReader(log), key.Public())
+			require.NoError(t, err)
+			require.Equal(t, tt.wantKinds, problemKinds(report), report.Problems)
+			for i, line := range tt.wantLines {
+				assert.Equal(t, line, report.Problems[i].Line)
+			}
+		})
+	}
+
+	t.Run("report", func(t *testing.T) {
+		t.Parallel()
+		report, err := VerifyChain(bytes.NewReader(next.log()), key.Public())
+		require.NoError(t, err)
+		assert.Equal(t, 8, report.Events)
+		assert.Equal(t, ChainHead{Sequence: 1, Hash: chainHash(next.lines[0])}, report.First)
+		assert.Equal(t, ChainHead{Sequence: 8, Hash: chainHash(next.lines[7])}, report.Head)
+		assert.Equal(t, 2, report.Checkpoints)
+		assert.Equal(t, uint64(7), report.SignedThrough)
+	})
+
+	t.Run("truncation is visible as unsigned events", func(t *testing.T) {
+		t.Parallel()
+		report, err := VerifyChain(bytes.NewReader(bytes.Join(next.lines[:6], []byte("\n"))), key.Public())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(6), report.Head.Sequence)
+		assert.Equal(t, uint64(3), report.SignedThrough)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		t.Parallel()
+		report, err := VerifyChain(bytes.NewReader(next.log()), newECKey(t).Public())
+		require.NoError(t, err)
+		assert.Equal(t, []ChainProblemKind{ChainBadCheckpoint, ChainBadCheckpoint}, problemKinds(report))
+		assert.Zero(t, report.SignedThrough)
+	})
+
+	t.Run("without a key", func(t *testing.T) {
+		t.Parallel()
+		report, err := VerifyChain(bytes.NewReader(next.log()), nil)
+		require.NoError(t, err)
+		assert.True(t, report.OK())
+		assert.Zero(t, report.Checkpoints)
+	})
+
+	t.Run("unsupported key", func(t *testing.T) {
+		t.Parallel()
+		_, err := VerifyChain(bytes.NewReader(next.log()), &struct{}{})
+		assert.ErrorContains(t, err, "unsupported checkpoint key type")
+	})
+}
+
+func TestVerifyChainFileSink(t *testing.T) {
+	t.Parallel()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := NewFileSink(FileSinkConfig{Path: path})
+	require.NoError(t, err)
+	chain, err := NewChainSink(file, ChainConfig{Signer: priv})
+	require.NoError(t, err)
+	for _, id := range []string{"e1", "e2", "e3"} {
+		require.NoError(t, chain.Write(context.Background(), newTestEvent(id)))
+	}
+	require.NoError(t, chain.Close())
+
+	f, err := os.Open(path) // #nosec G304 - test temp file
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	report, err := VerifyChain(f, pub)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Problems)
+	assert.Equal(t, uint64(3), report.SignedThrough)
+}
@@ -0,0 +1,423 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/stacklok/toolhive-core/telemetry/metrics"
+)
+
+const (
+	// defaultBufferSize is DispatcherConfig.BufferSize when zero.
+	defaultBufferSize = 1024
+	// defaultMaxAttempts is DispatcherConfig.MaxAttempts when zero.
+	defaultMaxAttempts = 3
+	// defaultRetryBackoff is DispatcherConfig.RetryBackoff when zero.
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+// Metric names recorded on DispatcherConfig.Meter, each labelled with the
+// sink's name under LabelSink.
+const (
+	// MetricDeliveries counts events a sink finished with, labelled with
+	// metrics.LabelOutcome: success, or error once every attempt failed.
+	MetricDeliveries = "stacklok.audit.deliveries"
+
+	// MetricDropped counts events discarded from a full buffer under
+	// OverflowDropOldest.
+	MetricDropped = "stacklok.audit.dropped"
+
+	// MetricRejected counts Writes refused under OverflowFailClosed. It is
+	// not per sink: the event is refused for all of them.
+	MetricRejected = "stacklok.audit.rejected"
+
+	// MetricQueueDepth reports the events buffered for a sink.
+	MetricQueueDepth = "stacklok.audit.queue.depth"
+)
+
+// LabelSink carries the sink name, the key of DispatcherConfig.Sinks.
+const LabelSink = "sink"
+
+// OverflowPolicy decides what Dispatcher.Write does when a sink's buffer is
+// full: the sink has fallen behind by BufferSize events.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Write wait for room, until its context is done or
+	// the Dispatcher closes. Nothing is lost, at the price of the caller's
+	// latency: a stalled sink eventually stalls every request that audits.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest buffered event to make room, and
+	// counts it in MetricDropped. Write never waits; the record that is lost
+	// is the one that has waited longest.
+	OverflowDropOldest
+
+	// OverflowFailClosed makes Write return ErrBufferFull at once, so a
+	// caller that must not act unaudited can refuse the action instead
+	// (NIST SP 800-53 AU-5(4)). The event is then enqueued for no sink.
+	OverflowFailClosed
+)
+
+// String returns the policy's name.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowFailClosed:
+		return "fail-closed"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+var (
+	// ErrBufferFull is returned by Dispatcher.Write under OverflowFailClosed
+	// when a sink's buffer has no room.
+	ErrBufferFull = errors.New("audit: dispatcher buffer is full")
+
+	// ErrDispatcherClosed is returned by Dispatcher.Write once Close has
+	// been called.
+	ErrDispatcherClosed = errors.New("audit: dispatcher is closed")
+)
+
+// DispatcherConfig configures a Dispatcher.
+type DispatcherConfig struct {
+	// Sinks are the destinations, by name. The name labels the sink's
+	// metrics and log lines, so keep it short and fixed: "file", "siem".
+	// At least one is required.
+	Sinks map[string]Sink
+
+	// BufferSize is how many events each sink may fall behind by before
+	// Overflow applies. Zero uses 1024; negative is an error.
+	BufferSize int
+
+	// Overflow is what Write does when a buffer is full. The zero value is
+	// OverflowBlock.
+	Overflow OverflowPolicy
+
+	// MaxAttempts is how many times a sink is given an event before it is
+	// counted as failed and logged. Zero uses 3; negative is an error.
+	MaxAttempts int
+
+	// RetryBackoff is the wait before the second attempt, doubling for each
+	// attempt after. Zero uses 100ms; negative is an error.
+	RetryBackoff time.Duration
+
+	// Meter records the delivery metrics. Nil records nothing.
+	Meter metric.Meter
+
+	// Logger receives events a sink failed to deliver. Nil uses
+	// slog.Default.
+	Logger *slog.Logger
+}
+
+// Dispatcher fans audit events out to several sinks asynchronously. Each sink
+// has its own buffer and goroutine, so a slow or failing sink delays only
+// itself, and each receives events in the order they were written. It is safe
+// for concurrent use.
+//
+// Dispatcher is itself a Sink: Write enqueues and returns, and its error
+// reports only whether the event was accepted (see OverflowPolicy) — delivery
+// failures surface in the metrics and the log. Close delivers what is
+// buffered, then closes the sinks.
+type Dispatcher struct {
+	logger      *slog.Logger
+	overflow    OverflowPolicy
+	maxAttempts int
+	backoff     time.Duration
+	queues      []*sinkQueue
+
+	// mu serialises enqueueing so an event lands in every queue or none, and
+	// in the same order in each. It is held while OverflowBlock waits, which
+	// is what keeps that order.
+	mu        sync.Mutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	deliveries metric.Int64Counter
+	dropped    metric.Int64Counter
+	rejected   metric.Int64Counter
+	depth      metric.Registration
+}
+
+// sinkQueue is one sink's buffer.
+type sinkQueue struct {
+	name  string
+	sink  Sink
+	ch    chan queuedEvent
+	attrs metric.MeasurementOption
+}
+
+// queuedEvent is an event awaiting delivery, with the context it was written
+// under, stripped of its cancellation so a finished request does not abort
+// its own audit record.
+type queuedEvent struct {
+	ctx   context.Context
+	event *AuditEvent
+}
+
+// Compile-time check that Dispatcher is a Sink.
+var _ Sink = (*Dispatcher)(nil)
+
+// NewDispatcher validates cfg and starts a goroutine per sink. The sinks
+// belong to the Dispatcher from then on: Close closes them.
+func NewDispatcher(cfg DispatcherConfig) (*Dispatcher, error) {
+	switch {
+	case len(cfg.Sinks) == 0:
+		return nil, errors.New("audit: dispatcher requires at least one sink")
+	case cfg.BufferSize < 0:
+		return nil, fmt.Errorf("audit: dispatcher buffer size must not be negative: %d", cfg.BufferSize)
+	case cfg.Overflow < OverflowBlock || cfg.Overflow > OverflowFailClosed:
+		return nil, fmt.Errorf("audit: unknown overflow policy: %s", cfg.Overflow)
+	case cfg.MaxAttempts < 0:
+		return nil, fmt.Errorf("audit: dispatcher max attempts must not be negative: %d", cfg.MaxAttempts)
+	case cfg.RetryBackoff < 0:
+		return nil, fmt.Errorf("audit: dispatcher retry backoff must not be negative: %s", cfg.RetryBackoff)
+	}
+	for name, sink := range cfg.Sinks {
+		if sink == nil {
+			return nil, fmt.Errorf("audit: sink %q is nil", name)
+		}
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	d := &Dispatcher{
+		logger:      cfg.Logger,
+		overflow:    cfg.Overflow,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.RetryBackoff,
+		closing:     make(chan struct{}),
+	}
+	// Sorted, so enqueue order and log output do not vary run to run.
+	for _, name := range slices.Sorted(maps.Keys(cfg.Sinks)) {
+		d.queues = append(d.queues, &sinkQueue{
+			name:  name,
+			sink:  cfg.Sinks[name],
+			ch:    make(chan queuedEvent, cfg.BufferSize),
+			attrs: metric.WithAttributes(attribute.String(LabelSink, name)),
+		})
+	}
+	if err := d.initMetrics(cfg.Meter); err != nil {
+		return nil, err
+	}
+	for _, q := range d.queues {
+		d.wg.Add(1)
+		go d.run(q)
+	}
+	return d, nil
+}
+
+// initMetrics creates the instruments on meter, or on a no-op meter when
+// meter is nil.
+func (d *Dispatcher) initMetrics(meter metric.Meter) error {
+	if meter == nil {
+		meter = noop.NewMeterProvider().Meter("")
+	}
+	var err error
+	if d.deliveries, err = meter.Int64Counter(MetricDeliveries,
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Audit events delivered or abandoned by a sink, by outcome.")); err != nil {
+		return fmt.Errorf("audit: failed to create %s counter: %w", MetricDeliveries, err)
+	}
+	if d.dropped, err = meter.Int64Counter(MetricDropped,
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Audit events discarded from a full sink buffer.")); err != nil {
+		return fmt.Errorf("audit: failed to create %s counter: %w", MetricDropped, err)
+	}
+	if d.rejected, err = meter.Int64Counter(MetricRejected,
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Audit events refused because a sink buffer was full.")); err != nil {
+		return fmt.Errorf("audit: failed to create %s counter: %w", MetricRejected, err)
+	}
+	depth, err := meter.Int64ObservableGauge(MetricQueueDepth,
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Audit events buffered for a sink."))
+	if err != nil {
+		return fmt.Errorf("audit: failed to create %s gauge: %w", MetricQueueDepth, err)
+	}
+	d.depth, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, q := range d.queues {
+			o.ObserveInt64(depth, int64(len(q.ch)), metric.WithAttributes(attribute.String(LabelSink, q.name)))
+		}
+		return nil
+	}, depth)
+	if err != nil {
+		return fmt.Errorf("audit: failed to register %s callback: %w", MetricQueueDepth, err)
+	}
+	return nil
+}
+
+// Write enqueues a copy of event for every sink, applying the overflow
+// policy to any that is full. A nil error means the event was accepted, not
+// that it was delivered.
+func (d *Dispatcher) Write(ctx context.Context, event *AuditEvent) error {
+	item := queuedEvent{ctx: context.WithoutCancel(ctx), event: event.clone()}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+	if d.overflow == OverflowFailClosed {
+		// Only Write adds to the queues, and it holds mu, so room seen here
+		// is still there below.
+		for _, q := range d.queues {
+			if len(q.ch) == cap(q.ch) {
+				d.rejected.Add(ctx, 1)
+				return ErrBufferFull
+			}
+		}
+	}
+	for _, q := range d.queues {
+		switch d.overflow {
+		case OverflowBlock:
+			select {
+			case q.ch <- item:
+			case <-ctx.Done():
+				// Sinks before q already have the event; it cannot be
+				// withdrawn, so the caller learns it was accepted only in
+				// part.
+				return fmt.Errorf("audit: gave up waiting for sink %q: %w", q.name, ctx.Err())
+			case <-d.closing:
+				return ErrDispatcherClosed
+			}
+		case OverflowDropOldest:
+			d.pushDroppingOldest(ctx, q, item)
+		default:
+			q.ch <- item
+		}
+	}
+	return nil
+}
+
+// pushDroppingOldest enqueues item, discarding from the head of the queue
+// until it fits. The caller holds mu.
+func (d *Dispatcher) pushDroppingOldest(ctx context.Context, q *sinkQueue, item queuedEvent) {
+	for {
+		select {
+		case q.ch <- item:
+			return
+		default:
+		}
+		select {
+		case <-q.ch:
+			d.dropped.Add(ctx, 1, q.attrs)
+		default:
+			// The sink's goroutine took one first; there is room now.
+		}
+	}
+}
+
+// run delivers q's events until its channel is closed and drained.
+func (d *Dispatcher) run(q *sinkQueue) {
+	defer d.wg.Done()
+	for item := range q.ch {
+		err := d.deliver(q, item)
+		outcome := metrics.OutcomeSuccess
+		if err != nil {
+			outcome = metrics.OutcomeError
+			d.logger.ErrorContext(item.ctx, "audit sink failed to deliver event",
+				"sink", q.name, "audit_id", item.event.Metadata.AuditID, "error", err)
+		}
+		d.deliveries.Add(item.ctx, 1, q.attrs,
+			metric.WithAttributes(attribute.String(metrics.LabelOutcome, outcome)))
+	}
+}
+
+// deliver writes item to q's sink, retrying with exponential backoff. Once
+// the Dispatcher is closing it stops waiting between attempts, so Close is
+// not held up by a dead sink's backoff schedule.
+func (d *Dispatcher) deliver(q *sinkQueue, item queuedEvent) error {
+	var err error
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		if err = q.sink.Write(item.ctx, item.event); err == nil || attempt == d.maxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-d.closing:
+			return err
+		}
+	}
+}
+
+// Close stops accepting events, delivers those already buffered, then closes
+// every sink. Each buffered event gets one more attempt but no further
+// retries, so a dead sink costs shutdown one failed Write per event rather
+// than its whole backoff schedule. It returns the sinks' Close errors joined;
+// it is safe to call more than once, and later calls return nil.
+func (d *Dispatcher) Close() error {
+	first := false
+	d.closeOnce.Do(func() {
+		first = true
+		// Wake any Write blocked on a full queue so it releases mu.
+		close(d.closing)
+	})
+	if !first {
+		return nil
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	for _, q := range d.queues {
+		close(q.ch)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+
+	errs := []error{d.depth.Unregister()}
+	for _, q := range d.queues {
+		if err := q.sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("audit: failed to close sink %q: %w", q.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// clone copies e deeply enough that the caller may mutate or reuse it while
+// the copy waits in a queue. DelegationChain is shared: nothing modifies a
+// chain once it is attached.
+func (e *AuditEvent) clone() *AuditEvent {
+	c := *e
+	c.Metadata.Extra = maps.Clone(e.Metadata.Extra)
+	c.Source.Extra = maps.Clone(e.Source.Extra)
+	c.Subjects = maps.Clone(e.Subjects)
+	c.Target = maps.Clone(e.Target)
+	if e.Data != nil {
+		data := json.RawMessage(slices.Clone(*e.Data))
+		c.Data = &data
+	}
+	return &c
+}
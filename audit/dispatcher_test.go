@@ -0,0 +1,332 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/stacklok/toolhive-core/telemetry/metrics"
+)
+
+// memSink records the audit IDs it is given. It fails the first failures
+// Writes and, when gate is set, holds every Write until gate is closed,
+// announcing each on entered.
+type memSink struct {
+	gate    chan struct{}
+	entered chan struct{}
+
+	mu       sync.Mutex
+	ids      []string
+	attempts int
+	failures int
+	closed   bool
+}
+
+func newMemSink() *memSink {
+	return &memSink{entered: make(chan struct{}, 64)}
+}
+
+func newGatedSink() *memSink {
+	s := newMemSink()
+	s.gate = make(chan struct{})
+	return s
+}
+
+func (s *memSink) Write(_ context.Context, event *AuditEvent) error {
+	s.entered <- struct{}{}
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.ids = append(s.ids, event.Metadata.AuditID)
+	return nil
+}
+
+func (s *memSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *memSink) snapshot() (ids []string, attempts int, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ids...), s.attempts, s.closed
+}
+
+// waitEntered waits for the sink's goroutine to take an event.
+func (s *memSink) waitEntered(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink was never written to")
+	}
+}
+
+// sinkCounters returns the data points of the named int64 sum keyed by sink
+// and outcome.
+func sinkCounters(t *testing.T, reader *sdkmetric.ManualReader, name string) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	out := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "%s must be an int64 counter", name)
+			for _, dp := range sum.DataPoints {
+				sink, _ := dp.Attributes.Value(LabelSink)
+				outcome, _ := dp.Attributes.Value(attribute.Key(metrics.LabelOutcome))
+				out[sink.AsString()+"/"+outcome.AsString()] = dp.Value
+			}
+		}
+	}
+	return out
+}
+
+func newTestDispatcher(t *testing.T, cfg DispatcherConfig) (*Dispatcher, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	cfg.Meter = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("audit_test")
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Millisecond
+	}
+	d, err := NewDispatcher(cfg)
+	require.NoError(t, err)
+	return d, reader
+}
+
+func TestNewDispatcherConfig(t *testing.T) {
+	t.Parallel()
+
+	sinks := map[string]Sink{"mem": newMemSink()}
+	tests := []struct {
+		name    string
+		cfg     DispatcherConfig
+		wantErr string
+	}{
+		{name: "no sinks", cfg: DispatcherConfig{}, wantErr: "at least one sink"},
+		{name: "nil sink", cfg: DispatcherConfig{Sinks: map[string]Sink{"mem": nil}}, wantErr: `sink "mem" is nil`},
+		{name: "negative buffer", cfg: DispatcherConfig{Sinks: sinks, BufferSize: -1}, wantErr: "buffer size"},
+		{name: "unknown policy", cfg: DispatcherConfig{Sinks: sinks, Overflow: 7}, wantErr: "OverflowPolicy(7)"},
+		{name: "negative attempts", cfg: DispatcherConfig{Sinks: sinks, MaxAttempts: -1}, wantErr: "max attempts"},
+		{name: "negative backoff", cfg: DispatcherConfig{Sinks: sinks, RetryBackoff: -time.Second}, wantErr: "backoff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d, err := NewDispatcher(tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Nil(t, d)
+		})
+	}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	t.Parallel()
+
+	a, b := newMemSink(), newMemSink()
+	d, reader := newTestDispatcher(t, DispatcherConfig{Sinks: map[string]Sink{"a": a, "b": b}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, id := range []string{"e1", "e2", "e3"} {
+		require.NoError(t, d.Write(ctx, newTestEvent(id)))
+	}
+	// A cancelled request context does not abort delivery.
+	cancel()
+	require.NoError(t, d.Close())
+
+	for _, s := range []*memSink{a, b} {
+		ids, _, closed := s.snapshot()
+		assert.Equal(t, []string{"e1", "e2", "e3"}, ids, "every sink gets every event, in order")
+		assert.True(t, closed, "Close closes the sinks")
+	}
+	assert.Equal(t, map[string]int64{"a/success": 3, "b/success": 3}, sinkCounters(t, reader, MetricDeliveries))
+
+	assert.ErrorIs(t, d.Write(context.Background(), newTestEvent("e4")), ErrDispatcherClosed)
+	assert.NoError(t, d.Close(), "second Close is a no-op")
+}
+
+func TestDispatcherCopiesEvent(t *testing.T) {
+	t.Parallel()
+
+	s := newGatedSink()
+	d, _ := newTestDispatcher(t, DispatcherConfig{Sinks: map[string]Sink{"mem": s}})
+
+	var got *AuditEvent
+	d.queues[0].sink = sinkFunc(func(_ context.Context, e *AuditEvent) error {
+		got = e
+		return s.Write(context.Background(), e)
+	})
+	event := newTestEvent("e1")
+	require.NoError(t, d.Write(context.Background(), event))
+	event.Subjects[subjKeyUser] = "mallory"
+	*event.Data = append((*event.Data)[:0], "{}"...)
+	close(s.gate)
+	require.NoError(t, d.Close())
+
+	assert.Equal(t, "alice", got.Subjects[subjKeyUser])
+	assert.JSONEq(t, `{"arguments":{"a":1}}`, string(*got.Data))
+}
+
+// sinkFunc adapts a function to Sink.
+type sinkFunc func(context.Context, *AuditEvent) error
+
+func (f sinkFunc) Write(ctx context.Context, e *AuditEvent) error { return f(ctx, e) }
+func (sinkFunc) Close() error                                     { return nil }
+
+func TestDispatcherRetries(t *testing.T) {
+	t.Parallel()
+
+	flaky, dead := newMemSink(), newMemSink()
+	flaky.failures = 2
+	dead.failures = 100
+	d, reader := newTestDispatcher(t, DispatcherConfig{
+		Sinks:       map[string]Sink{"flaky": flaky, "dead": dead},
+		MaxAttempts: 3,
+	})
+	require.NoError(t, d.Write(context.Background(), newTestEvent("e1")))
+	// Close stops retrying, so let both sinks finish first.
+	require.Eventually(t, func() bool {
+		return len(sinkCounters(t, reader, MetricDeliveries)) == 2
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, d.Close())
+
+	ids, attempts, _ := flaky.snapshot()
+	assert.Equal(t, []string{"e1"}, ids)
+	assert.Equal(t, 3, attempts)
+	ids, attempts, _ = dead.snapshot()
+	assert.Empty(t, ids)
+	assert.Equal(t, 3, attempts, "a failing sink is given MaxAttempts tries")
+
+	assert.Equal(t, map[string]int64{"flaky/success": 1, "dead/error": 1}, sinkCounters(t, reader, MetricDeliveries))
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	t.Parallel()
+
+	// Each case stalls the sink on e1 and fills the one-slot buffer with e2,
+	// so e3 meets a full buffer.
+	setup := func(t *testing.T, policy OverflowPolicy) (*Dispatcher, *memSink, *sdkmetric.ManualReader) {
+		t.Helper()
+		s := newGatedSink()
+		d, reader := newTestDispatcher(t, DispatcherConfig{
+			Sinks:      map[string]Sink{"mem": s},
+			BufferSize: 1,
+			Overflow:   policy,
+		})
+		require.NoError(t, d.Write(context.Background(), newTestEvent("e1")))
+		s.waitEntered(t)
+		require.NoError(t, d.Write(context.Background(), newTestEvent("e2")))
+		return d, s, reader
+	}
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+		d, s, _ := setup(t, OverflowBlock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, d.Write(ctx, newTestEvent("e3")), context.DeadlineExceeded)
+
+		done := make(chan error, 1)
+		go func() { done <- d.Write(context.Background(), newTestEvent("e4")) }()
+		close(s.gate)
+		require.NoError(t, <-done, "Write proceeds once the sink catches up")
+		require.NoError(t, d.Close())
+		ids, _, _ := s.snapshot()
+		assert.Equal(t, []string{"e1", "e2", "e4"}, ids)
+	})
+
+	t.Run("block is released by Close", func(t *testing.T) {
+		t.Parallel()
+		d, s, _ := setup(t, OverflowBlock)
+
+		blocked := make(chan error, 1)
+		go func() { blocked <- d.Write(context.Background(), newTestEvent("e3")) }()
+		closed := make(chan error, 1)
+		go func() { closed <- d.Close() }()
+		assert.ErrorIs(t, <-blocked, ErrDispatcherClosed)
+		close(s.gate)
+		require.NoError(t, <-closed)
+		ids, _, _ := s.snapshot()
+		assert.Equal(t, []string{"e1", "e2"}, ids, "Close delivers what was buffered")
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		t.Parallel()
+		d, s, reader := setup(t, OverflowDropOldest)
+
+		require.NoError(t, d.Write(context.Background(), newTestEvent("e3")))
+		require.NoError(t, d.Write(context.Background(), newTestEvent("e4")))
+		close(s.gate)
+		require.NoError(t, d.Close())
+		ids, _, _ := s.snapshot()
+		assert.Equal(t, []string{"e1", "e4"}, ids)
+		assert.Equal(t, map[string]int64{"mem/": 2}, sinkCounters(t, reader, MetricDropped))
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		t.Parallel()
+		d, s, reader := setup(t, OverflowFailClosed)
+
+		assert.ErrorIs(t, d.Write(context.Background(), newTestEvent("e3")), ErrBufferFull)
+		close(s.gate)
+		require.NoError(t, d.Close())
+		ids, _, _ := s.snapshot()
+		assert.Equal(t, []string{"e1", "e2"}, ids)
+		assert.Equal(t, map[string]int64{"/": 1}, sinkCounters(t, reader, MetricRejected))
+	})
+
+	t.Run("fail closed is all or nothing", func(t *testing.T) {
+		t.Parallel()
+		stalled, free := newGatedSink(), newMemSink()
+		d, _ := newTestDispatcher(t, DispatcherConfig{
+			Sinks:      map[string]Sink{"stalled": stalled, "free": free},
+			BufferSize: 1,
+			Overflow:   OverflowFailClosed,
+		})
+		require.NoError(t, d.Write(context.Background(), newTestEvent("e1")))
+		stalled.waitEntered(t)
+		free.waitEntered(t)
+		require.NoError(t, d.Write(context.Background(), newTestEvent("e2")))
+		assert.ErrorIs(t, d.Write(context.Background(), newTestEvent("e3")), ErrBufferFull)
+		close(stalled.gate)
+		require.NoError(t, d.Close())
+
+		ids, _, _ := free.snapshot()
+		assert.Equal(t, []string{"e1", "e2"}, ids, "a refused event reaches no sink")
+	})
+}
+
+func TestOverflowPolicyString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "block", OverflowBlock.String())
+	assert.Equal(t, "drop-oldest", OverflowDropOldest.String())
+	assert.Equal(t, "fail-closed", OverflowFailClosed.String())
+}
@@ -77,6 +77,102 @@ Use [AuditEvent.LogTo] to emit the event to a [log/slog.Logger] at a
 specified level. This produces structured JSON output suitable for audit log
 collection.
 
+# Sinks
+
+A [Sink] delivers events to a destination. [FileSink] appends JSON lines to
+a local file and rotates it by size; [SyslogSink] sends RFC 5424 messages to
+a collector over TCP or TLS; [WebhookSink] POSTs each event to an HTTP
+endpoint; [OTelSink] emits OpenTelemetry log records. Every sink writes the
+same JSON encoding of [AuditEvent].
+
+A [Dispatcher] puts sinks behind per-sink buffers so that auditing does not
+wait on the network. Its [OverflowPolicy] chooses what happens when a sink
+falls behind: block the caller, drop the oldest buffered event, or fail the
+Write with [ErrBufferFull] so the caller can refuse to act unaudited. Failed
+deliveries are retried with backoff, and delivery, drop and queue-depth
+metrics are recorded on an OpenTelemetry meter:
+
+	d, err := audit.NewDispatcher(audit.DispatcherConfig{
+		Sinks:    map[string]audit.Sink{"file": fileSink, "siem": syslogSink},
+		Overflow: audit.OverflowFailClosed,
+		Meter:    meter,
+	})
+	...
+	if err := d.Write(ctx, event); errors.Is(err, audit.ErrBufferFull) {
+		// refuse the request
+	}
+
+# Redaction
+
+Data, Target and Subjects are recorded as given, so tool-call arguments can
+carry secrets into the audit trail (NIST SP 800-53 AU-3(3)). A [Redactor]
+removes them: by JSON path ("$.data.arguments.password"), by key name
+pattern at any depth, and by detectors that recognise token formats inside
+strings. [DefaultRedactionConfig] covers common credential names and tokens.
+The redacted fields are listed under [MetadataExtraKeyRedacted].
+
+Redact before anything else sees the event: wrap the sinks with a
+[RedactingSink], ahead of any [ChainSink], and log with
+redactor.Redact(event).LogTo(...).
+
+# Tamper Evidence
+
+A [ChainSink] in front of the sinks makes the audit trail tamper evident
+(NIST SP 800-53 AU-9). Each event is numbered in [EventMetadata.Sequence] and
+carries the hash of the event before it in [EventMetadata.PrevHash]. A
+signed [Checkpoint] event is inserted periodically. The signing key can be
+the cosign key used for artifact signing, loaded with
+container/signer.LoadPrivateKey. [VerifyChain] reads an exported JSON-lines
+log and reports gaps, reordering, modified events and bad checkpoints.
+
+# Reading and Exporting
+
+A [Reader] parses events back from the JSON lines written by a logger from
+[NewAuditLogger] or by a [FileSink], skipping other log records. A [Filter]
+narrows them by time range, type, outcome, component, subjects and target:
+
+	r := audit.NewReader(f, audit.Filter{
+		Since:    yesterday,
+		Until:    today,
+		Subjects: map[string]string{audit.SubjectKeyUserID: "alice"},
+	})
+
+[Export] copies what a Reader yields to an [Exporter]: [CSVExporter] for
+spreadsheets, [OCSFExporter] (API Activity class) and [CEFExporter] for
+SIEM ingestion.
+
+# Workflows
+
+A [WorkflowAuditor] records one execution of a vMCP composite workflow: a
+started event, started and outcome events for each step, and a terminal
+completed, failed or timed out event carrying step counts, retries and the
+duration. Every event's Target carries the workflow ID, so one execution's
+events can be correlated. Deferring [WorkflowAuditor.Finish] guarantees the
+terminal event on every return path, panics included; a cancelled or expired
+workflow context emits it at once:
+
+	wa, err := audit.NewWorkflowAuditor(ctx, audit.WorkflowConfig{Sink: d, WorkflowName: "triage"})
+	if err != nil {
+		return err
+	}
+	defer wa.Finish(ctx, &err)
+
+	step, err := wa.StartStep(ctx, audit.WorkflowStep{ID: "fetch", ToolName: "github.get_issue"})
+	...
+	step.Complete(ctx)
+
+A step still running when the workflow ends keeps its own events, emitted after
+the terminal one and marked with [MetadataExtraKeyAfterWorkflowEnd]; they are
+not counted in the totals the terminal event already reported.
+
+# Schema
+
+The JSON encoding of [AuditEvent] is specified by a JSON Schema, returned by
+[Schema] and published at [SchemaID]. Events record the schema version they
+conform to in [EventMetadata.SchemaVersion]; the major version changes only
+when a consumer of the old version could misread the new one. Use
+[AuditEvent.Validate] or [ValidateEventBytes] to check an event against it.
+
 # Well-Known Constants
 
 The package defines well-known constants for event types, outcomes, source
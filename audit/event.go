@@ -69,6 +69,17 @@ type AuditEvent struct {
 type EventMetadata struct {
 	// AuditID: is a unique identifier for the audit event.
 	AuditID string `json:"auditId"`
+	// SchemaVersion is the version of the audit event JSON Schema the event
+	// conforms to (see SchemaVersion). Empty, and omitted, for events
+	// created without NewAuditEvent or NewAuditEventWithID.
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+	// Sequence is the event's position in a hash chain (see ChainSink),
+	// starting at 1. Zero, and omitted, outside a chain.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// PrevHash is the "sha256:<hex>" hash of the previous event in the
+	// chain, empty for the first. It is what makes the chain tamper evident:
+	// altering, removing or reordering an event breaks the link after it.
+	PrevHash string `json:"prevHash,omitempty"`
 	// Extra allows for including additional information about the event
 	// that aids in tracking, parsing or auditing
 	Extra map[string]any `json:"extra,omitempty"`
@@ -97,7 +108,8 @@ func NewAuditEvent(
 ) *AuditEvent {
 	return &AuditEvent{
 		Metadata: EventMetadata{
-			AuditID: uuid.New().String(),
+			AuditID:       uuid.New().String(),
+			SchemaVersion: SchemaVersion,
 		},
 		Type:      eventType,
 		LoggedAt:  time.Now().UTC(),
@@ -119,7 +131,8 @@ func NewAuditEventWithID(
 ) *AuditEvent {
 	return &AuditEvent{
 		Metadata: EventMetadata{
-			AuditID: auditID,
+			AuditID:       auditID,
+			SchemaVersion: SchemaVersion,
 		},
 		Type:      eventType,
 		LoggedAt:  time.Now().UTC(),
@@ -191,6 +204,11 @@ func (e *AuditEvent) LogTo(ctx context.Context, logger *slog.Logger, level slog.
 		slog.Any("subjects", e.Subjects),
 	}
 
+	// Add schema version if present
+	if e.Metadata.SchemaVersion != "" {
+		attrs = append(attrs, slog.String("schemaVersion", e.Metadata.SchemaVersion))
+	}
+
 	// Add target if present
 	if e.Target != nil {
 		attrs = append(attrs, slog.Any("target", e.Target))
@@ -221,6 +239,14 @@ func (e *AuditEvent) LogTo(ctx context.Context, logger *slog.Logger, level slog.
 	logger.LogAttrs(ctx, level, "audit_event", attrs...)
 }
 
+// Common event types
+const (
+	// EventTypeAuthentication represents an inbound authentication decision:
+	// a credential verified, rejected, or absent on a route that allows
+	// anonymous access
+	EventTypeAuthentication = "authentication"
+)
+
 // Common event outcomes
 const (
 	// OutcomeSuccess indicates the event was successful
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exporter writes events in a format another system ingests: CSV for a
+// spreadsheet, OCSF or CEF for a SIEM.
+type Exporter interface {
+	// Export writes one event.
+	Export(e *AuditEvent) error
+	// Flush writes out anything buffered. It does not close the underlying
+	// writer, which stays the caller's.
+	Flush() error
+}
+
+// Export copies every event r yields to x and flushes it, returning the
+// number of events exported. It stops at the first error, r's included.
+func Export(x Exporter, r *Reader) (int, error) {
+	n := 0
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return n, x.Flush()
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := x.Export(e); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// csvHeader names the columns CSVExporter writes. Maps and the delegation
+// chain are written as JSON, so that no value is lost or ambiguous.
+var csvHeader = []string{
+	"logged_at", "audit_id", "type", "outcome", "component",
+	"source_type", "source_value", "subjects", "target", "delegation", "metadata", "data",
+}
+
+// CSVExporter writes events as CSV, one row each under a header row.
+//
+// Cells that a spreadsheet would evaluate as a formula (starting with =, +,
+// -, @, tab or carriage return) are prefixed with a single quote: event
+// values such as tool names are chosen by callers, and exporting them must
+// not hand those callers a formula (CWE-1236).
+type CSVExporter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// Compile-time check that CSVExporter is an Exporter.
+var _ Exporter = (*CSVExporter)(nil)
+
+// NewCSVExporter returns a CSVExporter writing to w.
+func NewCSVExporter(w io.Writer) *CSVExporter {
+	return &CSVExporter{w: csv.NewWriter(w)}
+}
+
+// Export writes e as a row, after the header row for the first.
+func (x *CSVExporter) Export(e *AuditEvent) error {
+	if !x.wroteHeader {
+		if err := x.w.Write(csvHeader); err != nil {
+			return fmt.Errorf("audit: failed to write CSV: %w", err)
+		}
+		x.wroteHeader = true
+	}
+	metadata := map[string]any{}
+	if e.Metadata.SchemaVersion != "" {
+		metadata["schemaVersion"] = e.Metadata.SchemaVersion
+	}
+	if e.Metadata.Sequence != 0 {
+		metadata["sequence"] = e.Metadata.Sequence
+	}
+	if e.Metadata.PrevHash != "" {
+		metadata["prevHash"] = e.Metadata.PrevHash
+	}
+	if len(e.Metadata.Extra) > 0 {
+		metadata["extra"] = e.Metadata.Extra
+	}
+	var data string
+	if e.Data != nil {
+		data = string(*e.Data)
+	}
+
+	row := []string{
+		e.LoggedAt.UTC().Format(time.RFC3339Nano),
+		e.Metadata.AuditID,
+		e.Type,
+		e.Outcome,
+		e.Component,
+		e.Source.Type,
+		e.Source.Value,
+		csvJSON(e.Subjects),
+		csvJSON(e.Target),
+		csvJSON(nonZeroChain(e.DelegationChain)),
+		csvJSON(metadata),
+		data,
+	}
+	for i, cell := range row {
+		row[i] = csvSafe(cell)
+	}
+	if err := x.w.Write(row); err != nil {
+		return fmt.Errorf("audit: failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// Flush writes out buffered rows.
+func (x *CSVExporter) Flush() error {
+	x.w.Flush()
+	if err := x.w.Error(); err != nil {
+		return fmt.Errorf("audit: failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// csvJSON encodes v as a cell: empty for nil or empty maps.
+func csvJSON(v any) string {
+	switch v := v.(type) {
+	case map[string]string:
+		if len(v) == 0 {
+			return ""
+		}
+	case map[string]any:
+		if len(v) == 0 {
+			return ""
+		}
+	case *DelegationChain:
+		if v == nil {
+			return ""
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(v))
+	}
+	return string(data)
+}
+
+// csvSafe neutralises a cell a spreadsheet would evaluate as a formula.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
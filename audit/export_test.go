@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportAll runs the events through a Reader into x.
+func exportAll(t *testing.T, x Exporter, events []*AuditEvent) {
+	t.Helper()
+	next := &lineSink{}
+	for _, e := range events {
+		require.NoError(t, next.Write(t.Context(), e))
+	}
+	n, err := Export(x, NewReader(bytes.NewReader(next.log()), Filter{}))
+	require.NoError(t, err)
+	assert.Equal(t, len(events), n)
+}
+
+// testPrevHash is a well-formed, arbitrary hash chain link.
+var testPrevHash = "sha256:" + strings.Repeat("ab", 32)
+
+func TestCSVExporter(t *testing.T) {
+	t.Parallel()
+
+	events := readerTestEvents()
+	events[0].Target[TargetKeyName] = "=HYPERLINK(\"http://evil\")"
+	events[0].Metadata.Sequence = 2
+	events[0].Metadata.PrevHash = testPrevHash
+
+	var buf bytes.Buffer
+	exportAll(t, NewCSVExporter(&buf), events)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, csvHeader, rows[0])
+
+	row := map[string]string{}
+	for i, col := range csvHeader {
+		row[col] = rows[1][i]
+	}
+	assert.Equal(t, "2026-01-02T03:04:05Z", row["logged_at"])
+	assert.Equal(t, "e1", row["audit_id"])
+	assert.Equal(t, OutcomeSuccess, row["outcome"])
+	assert.Equal(t, "10.0.0.1", row["source_value"])
+	assert.JSONEq(t, `{"user":"alice"}`, row["subjects"])
+	assert.JSONEq(t, `{"extra":{"transport":"stdio"},"schemaVersion":"`+SchemaVersion+
+		`","sequence":2,"prevHash":"`+testPrevHash+`"}`, row["metadata"])
+	assert.JSONEq(t, `{"arguments":{"a":1}}`, row["data"])
+	assert.Empty(t, row["delegation"])
+	assert.True(t, strings.HasPrefix(row["target"], "{"), "JSON cells start with a brace")
+
+	// The delegated event carries its chain; the third has no data.
+	assert.Contains(t, rows[2][9], "gateway")
+	assert.Empty(t, rows[3][11])
+
+	assert.Equal(t, "'=x", csvSafe("=x"))
+	assert.Equal(t, "'-1", csvSafe("-1"))
+	assert.Equal(t, "x=1", csvSafe("x=1"))
+}
+
+func TestOCSFExporter(t *testing.T) {
+	t.Parallel()
+
+	events := readerTestEvents()
+	events[0].Source.Extra = map[string]any{SourceExtraKeyUserAgent: "curl/8", SourceExtraKeySessionID: "s-1"}
+	events[0].Metadata.Sequence = 2
+	events[0].Metadata.PrevHash = testPrevHash
+
+	var buf bytes.Buffer
+	exportAll(t, NewOCSFExporter(&buf, OCSFConfig{Version: "1.2.0"}), events)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	records := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &records[i]))
+	}
+
+	first := records[0]
+	assert.EqualValues(t, ocsfClassUID, first["class_uid"])
+	assert.EqualValues(t, ocsfClassUID*100+ocsfActivityOther, first["type_uid"])
+	assert.EqualValues(t, ocsfStatusSuccess, first["status_id"])
+	assert.EqualValues(t, events[0].LoggedAt.UnixMilli(), first["time"])
+	assert.Equal(t, map[string]any{
+		"version": ocsfVersion, "uid": "e1", "event_code": EventTypeMCPToolCall, "log_name": "test-component",
+		"product": map[string]any{"name": "ToolHive", "vendor_name": "Stacklok", "version": "1.2.0"},
+	}, first["metadata"])
+	assert.Equal(t, map[string]any{
+		"user": map[string]any{"name": "alice"}, "session": map[string]any{"uid": "s-1"},
+	}, first["actor"])
+	assert.Equal(t, map[string]any{"ip": "10.0.0.1"}, first["src_endpoint"])
+	assert.Equal(t, map[string]any{"user_agent": "curl/8"}, first["http_request"])
+	assert.Equal(t, []any{map[string]any{"type": "tool", "name": "calculator"}}, first["resources"])
+	unmapped, ok := first["unmapped"].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, unmapped, "data")
+	assert.EqualValues(t, 2, unmapped["sequence"])
+	assert.Equal(t, testPrevHash, unmapped["prev_hash"])
+	assert.Equal(t, SchemaVersion, unmapped["schema_version"])
+
+	denied := records[1]
+	assert.EqualValues(t, ocsfStatusFailure, denied["status_id"])
+	assert.EqualValues(t, ocsfSeverityMedium, denied["severity_id"])
+	assert.Contains(t, denied["unmapped"], "delegation")
+
+	list := records[2]
+	assert.EqualValues(t, ocsfActivityRead, list["activity_id"])
+	assert.Equal(t, "tools/list", list["api"].(map[string]any)["operation"])
+}
+
+func TestCEFExporter(t *testing.T) {
+	t.Parallel()
+
+	events := readerTestEvents()
+	events[0].Subjects[SubjectKeyUser] = "a=b\\c\nd"
+
+	var buf bytes.Buffer
+	exportAll(t, NewCEFExporter(&buf, CEFConfig{Product: "Tool|Hive"}), events)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	assert.True(t, strings.HasPrefix(lines[0], `CEF:0|Stacklok|Tool\|Hive|unknown|mcp_tool_call|mcp_tool_call|3|`), lines[0])
+	assert.Contains(t, lines[0], "externalId=e1 outcome=success")
+	assert.Contains(t, lines[0], `suser=a\=b\\c\nd`)
+	assert.Contains(t, lines[0], "src=10.0.0.1")
+	assert.Contains(t, lines[0], "cs1Label=component cs1=test-component")
+	assert.NotContains(t, lines[0], "cs4Label")
+
+	assert.Contains(t, lines[1], "|7|")
+	assert.Contains(t, lines[1], "suid=u-bob")
+	assert.Contains(t, lines[1], "cs4Label=delegation")
+
+	assert.Contains(t, lines[2], "act=tools/list")
+}
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// defaultFileMaxSize is FileSinkConfig.MaxSize when zero.
+	defaultFileMaxSize = 100 << 20
+	// defaultFileMaxBackups is FileSinkConfig.MaxBackups when zero.
+	defaultFileMaxBackups = 5
+	// fileMode keeps audit files private to the writing user: they record who
+	// did what, which is not for every local account to read.
+	fileMode = 0o600
+)
+
+// FileSinkConfig configures a FileSink.
+type FileSinkConfig struct {
+	// Path is the file events are appended to, one JSON object per line. It
+	// is created with mode 0600 if missing. Required.
+	Path string
+
+	// MaxSize is the size in bytes at which the file is rotated. Zero uses
+	// 100 MiB; negative is an error. A single event larger than MaxSize is
+	// still written, to a file of its own.
+	MaxSize int64
+
+	// MaxBackups is how many rotated files are kept, as Path.1 (newest)
+	// through Path.N. Zero uses 5; negative is an error. The oldest is
+	// deleted on rotation: keeping more than MaxSize*(MaxBackups+1) bytes of
+	// history is the log shipper's job, not this sink's.
+	MaxBackups int
+
+	// Sync fsyncs the file after every event, so an acknowledged Write
+	// survives a machine crash and not only a process crash. It costs a disk
+	// flush per event.
+	Sync bool
+}
+
+// FileSink appends events to a local file as JSON lines, rotating it by size.
+// It is safe for concurrent use.
+type FileSink struct {
+	cfg FileSinkConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+}
+
+// Compile-time check that FileSink is a Sink.
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink validates cfg and opens the file for appending.
+func NewFileSink(cfg FileSinkConfig) (*FileSink, error) {
+	switch {
+	case cfg.Path == "":
+		return nil, errors.New("audit: file sink requires a path")
+	case cfg.MaxSize < 0:
+		return nil, fmt.Errorf("audit: file sink max size must not be negative: %d", cfg.MaxSize)
+	case cfg.MaxBackups < 0:
+		return nil, fmt.Errorf("audit: file sink max backups must not be negative: %d", cfg.MaxBackups)
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = defaultFileMaxSize
+	}
+	if cfg.MaxBackups == 0 {
+		cfg.MaxBackups = defaultFileMaxBackups
+	}
+	s := &FileSink{cfg: cfg}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens cfg.Path for appending and records its current size.
+func (s *FileSink) open() error {
+	// #nosec G304 - path is operator configuration
+	f, err := os.OpenFile(s.cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, fileMode)
+	if err != nil {
+		return fmt.Errorf("audit: failed to open %s: %w", s.cfg.Path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("audit: failed to stat %s: %w", s.cfg.Path, err)
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// Write appends event as one line, rotating first if it would take the file
+// past MaxSize.
+func (s *FileSink) Write(_ context.Context, event *AuditEvent) error {
+	data, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("audit: file sink is closed")
+	}
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(data)) > s.cfg.MaxSize {
+		// A failed rotation is retried on the next Write. Meanwhile the event
+		// goes to whatever file rotate left open: an oversized file is a
+		// lesser harm than a lost audit record.
+		if err := s.rotate(); err != nil && s.file == nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit: failed to write %s: %w", s.cfg.Path, err)
+	}
+	if s.cfg.Sync {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("audit: failed to sync %s: %w", s.cfg.Path, err)
+		}
+	}
+	return nil
+}
+
+// rotate shifts Path.N-1 to Path.N down to Path to Path.1, dropping the
+// oldest, and reopens an empty Path. The caller holds mu.
+//
+// Whatever fails, rotate reopens Path, so a rotation problem (a read-only
+// backup, say) costs the rotation and not the events after it. s.file is nil
+// on return only if even that failed.
+func (s *FileSink) rotate() error {
+	f := s.file
+	s.file = nil
+	if err := f.Close(); err != nil {
+		return errors.Join(fmt.Errorf("audit: failed to close %s for rotation: %w", s.cfg.Path, err), s.open())
+	}
+	for i := s.cfg.MaxBackups - 1; i >= 1; i-- {
+		err := os.Rename(backupPath(s.cfg.Path, i), backupPath(s.cfg.Path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Join(fmt.Errorf("audit: failed to rotate %s: %w", s.cfg.Path, err), s.open())
+		}
+	}
+	if err := os.Rename(s.cfg.Path, backupPath(s.cfg.Path, 1)); err != nil {
+		return errors.Join(fmt.Errorf("audit: failed to rotate %s: %w", s.cfg.Path, err), s.open())
+	}
+	return s.open()
+}
+
+// backupPath names the n-th rotated file.
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// Close syncs and closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	return errors.Join(s.file.Sync(), s.file.Close())
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestEvent returns a fully populated event for sink tests. LoggedAt is
+// fixed so every event encodes to the same length.
+func newTestEvent(id string) *AuditEvent {
+	e := NewAuditEventWithID(id, EventTypeMCPToolCall,
+		EventSource{Type: SourceTypeNetwork, Value: "10.0.0.1"},
+		OutcomeSuccess, map[string]string{subjKeyUser: "alice"}, "test-component",
+	).WithTarget(map[string]string{targetKeyType: targetTypeTool, targetKeyName: "calculator"}).
+		WithDataFromString(`{"arguments":{"a":1}}`)
+	e.LoggedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return e
+}
+
+// readEvents decodes the JSON-lines file at path.
+func readEvents(t *testing.T, path string) []AuditEvent {
+	t.Helper()
+	f, err := os.Open(path) // #nosec G304 - test temp file
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	var events []AuditEvent
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e AuditEvent
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		events = append(events, e)
+	}
+	require.NoError(t, sc.Err())
+	return events
+}
+
+func TestNewFileSinkConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     FileSinkConfig
+		wantErr string
+	}{
+		{name: "no path", cfg: FileSinkConfig{}, wantErr: "requires a path"},
+		{name: "negative max size", cfg: FileSinkConfig{Path: "x", MaxSize: -1}, wantErr: "max size"},
+		{name: "negative max backups", cfg: FileSinkConfig{Path: "x", MaxBackups: -1}, wantErr: "max backups"},
+		{name: "missing directory", cfg: FileSinkConfig{Path: "/nonexistent/dir/audit.log"}, wantErr: "failed to open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewFileSink(tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Nil(t, s)
+		})
+	}
+}
+
+func TestFileSinkWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := NewFileSink(FileSinkConfig{Path: path, Sync: true})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e1")))
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e2")))
+	require.NoError(t, s.Close())
+
+	events := readEvents(t, path)
+	require.Len(t, events, 2)
+	assert.Equal(t, "e1", events[0].Metadata.AuditID)
+	assert.Equal(t, "e2", events[1].Metadata.AuditID)
+	assert.Equal(t, "alice", events[0].Subjects[subjKeyUser])
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(fileMode), info.Mode().Perm())
+
+	assert.ErrorContains(t, s.Write(context.Background(), newTestEvent("e3")), "closed")
+	assert.NoError(t, s.Close(), "second Close is a no-op")
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for _, id := range []string{"e1", "e2"} {
+		s, err := NewFileSink(FileSinkConfig{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, s.Write(context.Background(), newTestEvent(id)))
+		require.NoError(t, s.Close())
+	}
+	assert.Len(t, readEvents(t, path), 2, "reopening appends rather than truncating")
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	line, err := marshalEvent(newTestEvent("e0"))
+	require.NoError(t, err)
+
+	// Room for two events per file, three files in all.
+	s, err := NewFileSink(FileSinkConfig{Path: path, MaxSize: int64(2*(len(line)+1) + 1), MaxBackups: 2})
+	require.NoError(t, err)
+	for _, id := range []string{"e0", "e1", "e2", "e3", "e4", "e5", "e6", "e7"} {
+		require.NoError(t, s.Write(context.Background(), newTestEvent(id)))
+	}
+	require.NoError(t, s.Close())
+
+	ids := func(p string) []string {
+		var out []string
+		for _, e := range readEvents(t, p) {
+			out = append(out, e.Metadata.AuditID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"e6", "e7"}, ids(path))
+	assert.Equal(t, []string{"e4", "e5"}, ids(path+".1"))
+	assert.Equal(t, []string{"e2", "e3"}, ids(path+".2"))
+	assert.NoFileExists(t, path+".3", "the oldest backup is dropped")
+}
+
+func TestFileSinkOversizedEvent(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := NewFileSink(FileSinkConfig{Path: path, MaxSize: 10})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e1")))
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e2")))
+	require.NoError(t, s.Close())
+
+	assert.Len(t, readEvents(t, path), 1, "an event larger than MaxSize gets a file of its own")
+	assert.Len(t, readEvents(t, path+".1"), 1)
+}
@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// OCSF schema values for the API Activity class the OCSFExporter writes.
+const (
+	ocsfVersion         = "1.3.0"
+	ocsfCategoryUID     = 6 // Application Activity
+	ocsfClassUID        = 6003
+	ocsfActivityRead    = 2
+	ocsfActivityOther   = 99
+	ocsfStatusSuccess   = 1
+	ocsfStatusFailure   = 2
+	ocsfStatusOther     = 99
+	ocsfSeverityInfo    = 1
+	ocsfSeverityLow     = 2
+	ocsfSeverityMedium  = 3
+	ocsfSeverityUnknown = 0
+)
+
+// OCSFConfig configures an OCSFExporter.
+type OCSFConfig struct {
+	// Vendor, Product and Version describe the product in each record's
+	// metadata. Empty uses "Stacklok" and "ToolHive", and omits the version.
+	Vendor  string
+	Product string
+	Version string
+}
+
+// OCSFExporter writes events as JSON lines in the Open Cybersecurity Schema
+// Framework's API Activity class (6003, schema 1.3.0):
+//
+//   - activity_id is Read for list, read and get events, Other otherwise;
+//     status_id is Success for a success outcome and Failure otherwise.
+//   - actor.user carries the user_id and user subjects, and actor.session the
+//     session ID; src_endpoint the source address and http_request the
+//     User-Agent.
+//   - api.operation is the target method (the event type when there is
+//     none), and resources the target.
+//   - Everything without an OCSF field — the full subjects and target, the
+//     delegation chain, metadata (including the hash chain's sequence and
+//     prev_hash, and the schema_version) and data — is kept under unmapped,
+//     so the export loses nothing.
+type OCSFExporter struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	product ocsfProduct
+}
+
+// Compile-time check that OCSFExporter is an Exporter.
+var _ Exporter = (*OCSFExporter)(nil)
+
+// NewOCSFExporter returns an OCSFExporter writing to w.
+func NewOCSFExporter(w io.Writer, cfg OCSFConfig) *OCSFExporter {
+	bw := bufio.NewWriter(w)
+	return &OCSFExporter{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+		product: ocsfProduct{
+			Name:       cmp.Or(cfg.Product, defaultExportProduct),
+			VendorName: cmp.Or(cfg.Vendor, defaultExportVendor),
+			Version:    cfg.Version,
+		},
+	}
+}
+
+// ocsfRecord is an API Activity record, limited to the attributes an
+// AuditEvent fills.
+type ocsfRecord struct {
+	ActivityID   int              `json:"activity_id"`
+	ActivityName string           `json:"activity_name"`
+	CategoryUID  int              `json:"category_uid"`
+	ClassUID     int              `json:"class_uid"`
+	TypeUID      int              `json:"type_uid"`
+	Time         int64            `json:"time"`
+	SeverityID   int              `json:"severity_id"`
+	StatusID     int              `json:"status_id"`
+	Status       string           `json:"status,omitempty"`
+	Message      string           `json:"message,omitempty"`
+	Metadata     ocsfMetadata     `json:"metadata"`
+	Actor        ocsfActor        `json:"actor"`
+	API          ocsfAPI          `json:"api"`
+	SrcEndpoint  ocsfEndpoint     `json:"src_endpoint"`
+	HTTPRequest  *ocsfHTTPRequest `json:"http_request,omitempty"`
+	Resources    []ocsfResource   `json:"resources,omitempty"`
+	Unmapped     map[string]any   `json:"unmapped,omitempty"`
+}
+
+type ocsfMetadata struct {
+	Version   string      `json:"version"`
+	UID       string      `json:"uid,omitempty"`
+	EventCode string      `json:"event_code,omitempty"`
+	LogName   string      `json:"log_name,omitempty"`
+	Product   ocsfProduct `json:"product"`
+}
+
+type ocsfProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version,omitempty"`
+}
+
+type ocsfActor struct {
+	User    *ocsfUser    `json:"user,omitempty"`
+	Session *ocsfSession `json:"session,omitempty"`
+}
+
+type ocsfUser struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type ocsfSession struct {
+	UID string `json:"uid"`
+}
+
+type ocsfAPI struct {
+	Operation string      `json:"operation"`
+	Service   ocsfService `json:"service"`
+}
+
+type ocsfService struct {
+	Name string `json:"name"`
+}
+
+type ocsfEndpoint struct {
+	IP       string `json:"ip,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+type ocsfHTTPRequest struct {
+	UserAgent string `json:"user_agent"`
+}
+
+type ocsfResource struct {
+	Type string `json:"type,omitempty"`
+	Name string `json:"name,omitempty"`
+	UID  string `json:"uid,omitempty"`
+}
+
+// Export writes e as one JSON line.
+func (x *OCSFExporter) Export(e *AuditEvent) error {
+	if err := x.enc.Encode(x.record(e)); err != nil {
+		return fmt.Errorf("audit: failed to write OCSF: %w", err)
+	}
+	return nil
+}
+
+// Flush writes out buffered lines.
+func (x *OCSFExporter) Flush() error {
+	if err := x.w.Flush(); err != nil {
+		return fmt.Errorf("audit: failed to write OCSF: %w", err)
+	}
+	return nil
+}
+
+// record maps e to an API Activity record.
+func (x *OCSFExporter) record(e *AuditEvent) ocsfRecord {
+	activity, activityName := ocsfActivityOther, "Other"
+	if strings.HasSuffix(e.Type, "_list") || strings.HasSuffix(e.Type, "_read") || strings.HasSuffix(e.Type, "_get") {
+		activity, activityName = ocsfActivityRead, "Read"
+	}
+	status, severity := ocsfStatusFailure, ocsfSeverityLow
+	switch e.Outcome {
+	case OutcomeSuccess:
+		status, severity = ocsfStatusSuccess, ocsfSeverityInfo
+	case OutcomeDenied:
+		severity = ocsfSeverityMedium
+	case OutcomeFailure, OutcomeError:
+	default:
+		status, severity = ocsfStatusOther, ocsfSeverityUnknown
+	}
+
+	r := ocsfRecord{
+		ActivityID:   activity,
+		ActivityName: activityName,
+		CategoryUID:  ocsfCategoryUID,
+		ClassUID:     ocsfClassUID,
+		TypeUID:      ocsfClassUID*100 + activity,
+		Time:         e.LoggedAt.UnixMilli(),
+		SeverityID:   severity,
+		StatusID:     status,
+		Status:       e.Outcome,
+		Message:      e.Type,
+		Metadata: ocsfMetadata{
+			Version:   ocsfVersion,
+			UID:       e.Metadata.AuditID,
+			EventCode: e.Type,
+			LogName:   e.Component,
+			Product:   x.product,
+		},
+		API: ocsfAPI{
+			Operation: cmp.Or(e.Target[TargetKeyMethod], e.Type),
+			Service:   ocsfService{Name: e.Component},
+		},
+	}
+
+	if uid, name := e.Subjects[SubjectKeyUserID], e.Subjects[SubjectKeyUser]; uid != "" || name != "" {
+		r.Actor.User = &ocsfUser{UID: uid, Name: name}
+	}
+	if sid, ok := e.Source.Extra[SourceExtraKeySessionID].(string); ok && sid != "" {
+		r.Actor.Session = &ocsfSession{UID: sid}
+	}
+	if e.Source.Type == SourceTypeNetwork {
+		if net.ParseIP(e.Source.Value) != nil {
+			r.SrcEndpoint.IP = e.Source.Value
+		} else {
+			r.SrcEndpoint.Hostname = e.Source.Value
+		}
+	}
+	if ua, ok := e.Source.Extra[SourceExtraKeyUserAgent].(string); ok && ua != "" {
+		r.HTTPRequest = &ocsfHTTPRequest{UserAgent: ua}
+	}
+	if len(e.Target) > 0 {
+		r.Resources = []ocsfResource{{
+			Type: e.Target[TargetKeyType],
+			Name: e.Target[TargetKeyName],
+			UID:  e.Target[TargetKeyURI],
+		}}
+	}
+
+	unmapped := map[string]any{}
+	if len(e.Subjects) > 0 {
+		unmapped["subjects"] = e.Subjects
+	}
+	if len(e.Target) > 0 {
+		unmapped["target"] = e.Target
+	}
+	if c := nonZeroChain(e.DelegationChain); c != nil {
+		unmapped["delegation"] = c
+	}
+	if e.Source.Type != SourceTypeNetwork || len(e.Source.Extra) > 0 {
+		unmapped["source"] = e.Source
+	}
+	if len(e.Metadata.Extra) > 0 {
+		unmapped["metadata_extra"] = e.Metadata.Extra
+	}
+	if e.Metadata.Sequence != 0 {
+		unmapped["sequence"] = e.Metadata.Sequence
+	}
+	if e.Metadata.PrevHash != "" {
+		unmapped["prev_hash"] = e.Metadata.PrevHash
+	}
+	if e.Metadata.SchemaVersion != "" {
+		unmapped["schema_version"] = e.Metadata.SchemaVersion
+	}
+	if e.Data != nil {
+		unmapped["data"] = e.Data
+	}
+	if len(unmapped) > 0 {
+		r.Unmapped = unmapped
+	}
+	return r
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+)
+
+const (
+	// otelScope is the instrumentation scope audit records are emitted under.
+	otelScope = "github.com/stacklok/toolhive-core/audit"
+	// otelEventName is the event name of every audit record.
+	otelEventName = "audit_event"
+	// otelSeverity is LevelAudit on the OpenTelemetry scale, which sits slog's
+	// levels nine above their own: INFO3, between INFO and WARN as LevelAudit
+	// is.
+	otelSeverity = log.SeverityInfo3
+	// otelSeverityText names the severity, so a backend that shows only text
+	// shows "AUDIT" rather than "INFO3".
+	otelSeverityText = "AUDIT"
+)
+
+// Attribute keys set on every record, for filtering without parsing the body.
+const (
+	otelAttrAuditID = "audit.id"
+	otelAttrType    = "audit.type"
+	otelAttrOutcome = "audit.outcome"
+)
+
+// OTelSink emits events as OpenTelemetry log records, for deployments that
+// already ship logs through an OTLP pipeline. The body is the event's JSON
+// structure as a map value — the same shape the other sinks write — and the
+// timestamp is LoggedAt.
+//
+// Emitting hands the record to the LoggerProvider's processors, which export
+// it asynchronously: Write therefore succeeds once the record is emitted, and
+// an export failure is the provider's to report, not the Dispatcher's to
+// retry. Use a file or syslog sink alongside it where delivery must be known.
+type OTelSink struct {
+	logger log.Logger
+	closed atomic.Bool
+}
+
+// Compile-time check that OTelSink is a Sink.
+var _ Sink = (*OTelSink)(nil)
+
+// NewOTelSink returns a sink emitting through provider. The provider is the
+// caller's: Close does not shut it down.
+func NewOTelSink(provider log.LoggerProvider) (*OTelSink, error) {
+	if provider == nil {
+		return nil, errors.New("audit: OTel sink requires a logger provider")
+	}
+	return &OTelSink{logger: provider.Logger(otelScope)}, nil
+}
+
+// Write emits event as one log record.
+func (s *OTelSink) Write(ctx context.Context, event *AuditEvent) error {
+	if s.closed.Load() {
+		return errors.New("audit: OTel sink is closed")
+	}
+	body, err := eventValue(event)
+	if err != nil {
+		return err
+	}
+	var rec log.Record
+	rec.SetEventName(otelEventName)
+	rec.SetTimestamp(event.LoggedAt)
+	rec.SetSeverity(otelSeverity)
+	rec.SetSeverityText(otelSeverityText)
+	rec.SetBody(body)
+	rec.AddAttributes(
+		attribute.String(otelAttrAuditID, event.Metadata.AuditID),
+		attribute.String(otelAttrType, event.Type),
+		attribute.String(otelAttrOutcome, event.Outcome),
+	)
+	s.logger.Emit(ctx, rec)
+	return nil
+}
+
+// Close stops further Writes.
+func (s *OTelSink) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+// eventValue converts event to a log body by way of its JSON form, so custom
+// marshalling (DelegationChain's, json.RawMessage Data) applies exactly as it
+// does for the byte-oriented sinks.
+func eventValue(event *AuditEvent) (attribute.Value, error) {
+	data, err := marshalEvent(event)
+	if err != nil {
+		return attribute.Value{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return attribute.Value{}, fmt.Errorf("audit: failed to decode event %s: %w", event.Metadata.AuditID, err)
+	}
+	return jsonValue(v), nil
+}
+
+// jsonValue converts a decoded JSON value. Object keys are sorted so the
+// record is deterministic; null becomes the empty value.
+func jsonValue(v any) attribute.Value {
+	switch v := v.(type) {
+	case string:
+		return attribute.StringValue(v)
+	case bool:
+		return attribute.BoolValue(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return attribute.Int64Value(i)
+		}
+		f, _ := v.Float64()
+		return attribute.Float64Value(f)
+	case []any:
+		vals := make([]attribute.Value, len(v))
+		for i, e := range v {
+			vals[i] = jsonValue(e)
+		}
+		return attribute.SliceValue(vals...)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		kvs := make([]attribute.KeyValue, len(keys))
+		for i, k := range keys {
+			kvs[i] = attribute.KeyValue{Key: attribute.Key(k), Value: jsonValue(v[k])}
+		}
+		return attribute.MapValue(kvs...)
+	default:
+		return attribute.Value{}
+	}
+}
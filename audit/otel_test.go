@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// capturingProcessor keeps every record emitted through it.
+type capturingProcessor struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (p *capturingProcessor) OnEmit(_ context.Context, r *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, r.Clone())
+	return nil
+}
+
+func (*capturingProcessor) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+func (*capturingProcessor) Shutdown(context.Context) error                         { return nil }
+func (*capturingProcessor) ForceFlush(context.Context) error                       { return nil }
+
+// mapLookup returns the value under key in a MAP value.
+func mapLookup(t *testing.T, v attribute.Value, key string) attribute.Value {
+	t.Helper()
+	require.Equal(t, attribute.MAP, v.Type())
+	for _, kv := range v.AsMap() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	t.Fatalf("key %q not in map", key)
+	return attribute.Value{}
+}
+
+func TestNewOTelSink(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewOTelSink(nil)
+	assert.ErrorContains(t, err, "requires a logger provider")
+	assert.Nil(t, s)
+}
+
+func TestOTelSinkWrite(t *testing.T) {
+	t.Parallel()
+
+	proc := &capturingProcessor{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(proc))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	s, err := NewOTelSink(provider)
+	require.NoError(t, err)
+	event := newTestEvent("e1")
+	require.NoError(t, s.Write(context.Background(), event))
+
+	require.Len(t, proc.records, 1)
+	rec := proc.records[0]
+	assert.Equal(t, otelScope, rec.InstrumentationScope().Name)
+	assert.Equal(t, otelEventName, rec.EventName())
+	assert.Equal(t, event.LoggedAt, rec.Timestamp())
+	assert.Equal(t, log.SeverityInfo3, rec.Severity())
+	assert.Equal(t, "AUDIT", rec.SeverityText())
+
+	attrs := map[string]string{}
+	rec.WalkAttributes(func(kv attribute.KeyValue) bool {
+		attrs[string(kv.Key)] = kv.Value.AsString()
+		return true
+	})
+	assert.Equal(t, map[string]string{
+		otelAttrAuditID: "e1",
+		otelAttrType:    EventTypeMCPToolCall,
+		otelAttrOutcome: OutcomeSuccess,
+	}, attrs)
+
+	body := rec.Body()
+	assert.Equal(t, "e1", mapLookup(t, mapLookup(t, body, "metadata"), "auditId").AsString())
+	assert.Equal(t, "alice", mapLookup(t, mapLookup(t, body, "subjects"), subjKeyUser).AsString())
+	args := mapLookup(t, mapLookup(t, body, "data"), "arguments")
+	assert.Equal(t, int64(1), mapLookup(t, args, "a").AsInt64(), "integral numbers stay integers")
+
+	require.NoError(t, s.Close())
+	assert.ErrorContains(t, s.Write(context.Background(), event), "closed")
+}
+
+func TestJSONValue(t *testing.T) {
+	t.Parallel()
+
+	v := jsonValue([]any{nil, true, "s"})
+	require.Equal(t, attribute.SLICE, v.Type())
+	got := v.AsSlice()
+	require.Len(t, got, 3)
+	assert.Equal(t, attribute.EMPTY, got[0].Type())
+	assert.True(t, got[1].AsBool())
+	assert.Equal(t, "s", got[2].AsString())
+}
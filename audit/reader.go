@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// auditLogMessage is the slog message LogTo logs events under.
+const auditLogMessage = "audit_event"
+
+// maxLineSize bounds the length of one line the Reader accepts. Events carry
+// tool arguments in Data, so lines can be long, but not unboundedly.
+const maxLineSize = 16 << 20
+
+// Filter selects events. Every set field must match; the zero Filter matches
+// every event.
+type Filter struct {
+	// Since and Until bound LoggedAt: Since inclusive, Until exclusive. A
+	// zero time leaves that end open.
+	Since time.Time
+	Until time.Time
+
+	// Types, Outcomes and Components, when non-empty, list the values
+	// accepted for the event's Type, Outcome and Component.
+	Types      []string
+	Outcomes   []string
+	Components []string
+
+	// Subjects and Target, when non-empty, must be contained in the event's
+	// Subjects and Target: {"user_id": "alice"} matches every event with that
+	// user ID, whatever else identifies it.
+	Subjects map[string]string
+	Target   map[string]string
+}
+
+// Match reports whether e passes the filter.
+func (f *Filter) Match(e *AuditEvent) bool {
+	switch {
+	case !f.Since.IsZero() && e.LoggedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.LoggedAt.Before(f.Until):
+		return false
+	case len(f.Types) > 0 && !slices.Contains(f.Types, e.Type):
+		return false
+	case len(f.Outcomes) > 0 && !slices.Contains(f.Outcomes, e.Outcome):
+		return false
+	case len(f.Components) > 0 && !slices.Contains(f.Components, e.Component):
+		return false
+	}
+	return containsAll(e.Subjects, f.Subjects) && containsAll(e.Target, f.Target)
+}
+
+// containsAll reports whether m holds every entry of want.
+func containsAll(m, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := m[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseError reports a line the Reader could not parse as an audit event.
+type ParseError struct {
+	// Line is the 1-based line number in the input.
+	Line int
+	Err  error
+}
+
+// Error implements error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("audit: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads audit events back from JSON lines: the output of a logger
+// from NewAuditLogger (or any slog JSON handler LogTo wrote to), or of a
+// FileSink. The two can be mixed. Lines that are neither — other log
+// records sharing the stream, blank lines — are skipped.
+//
+// A line that looks like an event but does not parse is reported as a
+// *ParseError; reading can continue past it with the next call to Next.
+type Reader struct {
+	scanner *bufio.Scanner
+	filter  Filter
+	line    int
+}
+
+// NewReader returns a Reader of the events in r that match filter.
+func NewReader(r io.Reader, filter Filter) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	return &Reader{scanner: scanner, filter: filter}
+}
+
+// Next returns the next matching event, or io.EOF after the last.
+func (r *Reader) Next() (*AuditEvent, error) {
+	for r.scanner.Scan() {
+		r.line++
+		e, err := parseEventLine(r.scanner.Bytes())
+		if err != nil {
+			return nil, &ParseError{Line: r.line, Err: err}
+		}
+		if e != nil && r.filter.Match(e) {
+			return e, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("audit: failed to read events: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// ReadAll returns the events in r that match filter. It stops at the first
+// error; use a Reader to skip over unparseable lines instead.
+func ReadAll(r io.Reader, filter Filter) ([]*AuditEvent, error) {
+	reader := NewReader(r, filter)
+	var events []*AuditEvent
+	for {
+		e, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
+
+// lineKind holds the fields that tell the two line formats apart.
+type lineKind struct {
+	Msg      string `json:"msg"`
+	Metadata *struct {
+		AuditID string `json:"auditId"`
+	} `json:"metadata"`
+}
+
+// logLine is an event as LogTo logs it through a slog JSON handler.
+type logLine struct {
+	AuditID       string            `json:"audit_id"`
+	SchemaVersion string            `json:"schemaVersion"`
+	Type          string            `json:"type"`
+	LoggedAt      time.Time         `json:"logged_at"`
+	Outcome       string            `json:"outcome"`
+	Component     string            `json:"component"`
+	Source        EventSource       `json:"source"`
+	Subjects      map[string]string `json:"subjects"`
+	Target        map[string]string `json:"target"`
+	Metadata      struct {
+		Extra map[string]any `json:"extra"`
+	} `json:"metadata"`
+	Data       *json.RawMessage `json:"data"`
+	Delegation *DelegationChain `json:"delegation"`
+}
+
+// parseEventLine parses one line, returning nil without error for a line
+// that is not an audit event.
+func parseEventLine(line []byte) (*AuditEvent, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return nil, nil
+	}
+	var kind lineKind
+	if err := json.Unmarshal(line, &kind); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case kind.Msg == auditLogMessage:
+		var l logLine
+		if err := json.Unmarshal(line, &l); err != nil {
+			return nil, err
+		}
+		e := &AuditEvent{
+			Metadata:  EventMetadata{AuditID: l.AuditID, SchemaVersion: l.SchemaVersion, Extra: l.Metadata.Extra},
+			Type:      l.Type,
+			LoggedAt:  l.LoggedAt,
+			Source:    l.Source,
+			Outcome:   l.Outcome,
+			Subjects:  l.Subjects,
+			Component: l.Component,
+			Target:    l.Target,
+			Data:      l.Data,
+		}
+		return e.WithDelegationChain(l.Delegation), nil
+	case kind.Metadata != nil && kind.Metadata.AuditID != "":
+		var e AuditEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	default:
+		return nil, nil
+	}
+}
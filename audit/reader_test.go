@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readerTestEvents returns three events an hour apart, the second denied and
+// delegated.
+func readerTestEvents() []*AuditEvent {
+	e1 := newTestEvent("e1")
+	e1.Metadata.Extra = map[string]any{MetadataExtraKeyTransport: "stdio"}
+
+	e2 := newTestEvent("e2")
+	e2.Outcome = OutcomeDenied
+	e2.LoggedAt = e2.LoggedAt.Add(time.Hour)
+	e2.Subjects = map[string]string{SubjectKeyUser: "bob", SubjectKeyUserID: "u-bob"}
+	e2.WithDelegationChain(ParseDelegationChain(map[string]any{"iss": "https://idp.example.com", "sub": "gateway"}, 0))
+
+	e3 := newTestEvent("e3")
+	e3.Type = EventTypeMCPToolsList
+	e3.LoggedAt = e3.LoggedAt.Add(2 * time.Hour)
+	e3.Target = map[string]string{TargetKeyMethod: "tools/list"}
+	e3.Data = nil
+	return []*AuditEvent{e1, e2, e3}
+}
+
+func TestReaderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	want := readerTestEvents()
+
+	t.Run("audit logger", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		logger := NewAuditLogger(&buf)
+		logger.Info("not an audit event", "type", "noise")
+		for _, e := range want {
+			e.LogTo(context.Background(), logger, LevelAudit)
+		}
+
+		got, err := ReadAll(&buf, Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("file sink", func(t *testing.T) {
+		t.Parallel()
+		next := &lineSink{}
+		for _, e := range want {
+			require.NoError(t, next.Write(context.Background(), e))
+		}
+
+		got, err := ReadAll(bytes.NewReader(next.log()), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	events := readerTestEvents()
+	start := events[0].LoggedAt
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "zero", want: []string{"e1", "e2", "e3"}},
+		{name: "since inclusive", filter: Filter{Since: start.Add(time.Hour)}, want: []string{"e2", "e3"}},
+		{name: "until exclusive", filter: Filter{Until: start.Add(time.Hour)}, want: []string{"e1"}},
+		{name: "types", filter: Filter{Types: []string{EventTypeMCPToolsList}}, want: []string{"e3"}},
+		{name: "outcomes", filter: Filter{Outcomes: []string{OutcomeDenied, OutcomeError}}, want: []string{"e2"}},
+		{name: "components", filter: Filter{Components: []string{"other"}}},
+		{name: "subjects", filter: Filter{Subjects: map[string]string{SubjectKeyUserID: "u-bob"}}, want: []string{"e2"}},
+		{name: "subject mismatch", filter: Filter{Subjects: map[string]string{SubjectKeyUser: "bob", SubjectKeyUserID: "x"}}},
+		{name: "target", filter: Filter{Target: map[string]string{TargetKeyName: "calculator"}}, want: []string{"e1", "e2"}},
+		{
+			name:   "combined",
+			filter: Filter{Since: start, Until: start.Add(3 * time.Hour), Outcomes: []string{OutcomeSuccess}, Types: []string{EventTypeMCPToolCall}},
+			want:   []string{"e1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got []string
+			for _, e := range events {
+				if tt.filter.Match(e) {
+					got = append(got, e.Metadata.AuditID)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReaderParseErrors(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	newTestEvent("e1").LogTo(context.Background(), NewAuditLogger(&buf), LevelAudit)
+	buf.WriteString("\nplain text\n{\"msg\":\"audit_event\",\"logged_at\":\"yesterday\"}\n{truncated\n")
+	newTestEvent("e2").LogTo(context.Background(), NewAuditLogger(&buf), LevelAudit)
+
+	r := NewReader(&buf, Filter{})
+	var ids []string
+	var lines []int
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			lines = append(lines, perr.Line)
+			continue
+		}
+		require.NoError(t, err)
+		ids = append(ids, e.Metadata.AuditID)
+	}
+	assert.Equal(t, []string{"e1", "e2"}, ids)
+	assert.Equal(t, []int{4, 5}, lines)
+
+	_, err := ReadAll(strings.NewReader("{truncated\n"), Filter{})
+	assert.ErrorContains(t, err, "line 1")
+}
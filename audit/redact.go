@@ -0,0 +1,492 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MetadataExtraKeyRedacted is the key in EventMetadata.Extra under which a
+// Redactor lists, as sorted JSON paths, the fields it redacted. Its presence
+// tells a reader that the record is not verbatim, and where.
+const MetadataExtraKeyRedacted = "redacted"
+
+// defaultReplacement is RedactionConfig.Replacement when empty.
+const defaultReplacement = "[REDACTED]"
+
+// Path roots a redaction path may start from: the event fields a Redactor
+// covers.
+const (
+	redactRootData     = "data"
+	redactRootTarget   = "target"
+	redactRootSubjects = "subjects"
+)
+
+// Detector finds secrets inside string values by their format.
+type Detector struct {
+	// Name identifies the detector in replacements: a match is replaced with
+	// "[REDACTED:<Name>]".
+	Name string
+
+	// Pattern is an RE2 regular expression. The whole match is replaced,
+	// unless the pattern has a group named "secret", in which case only that
+	// group is: `://[^:/@\s]+:(?P<secret>[^@/\s]+)@` redacts a URL's password
+	// and keeps the user and host.
+	Pattern string
+}
+
+// RedactionConfig configures a Redactor. The zero value redacts nothing but
+// unparseable Data (see Redactor); DefaultRedactionConfig is a starting point
+// that covers common credential names and token formats.
+type RedactionConfig struct {
+	// Paths are fields to redact wherever they hold, as JSON paths rooted at
+	// the event: "$.data.arguments.password", "$.subjects.email",
+	// "$.data.headers['x-api-key']". A segment may be a name (.name or
+	// ['name']), an array index ([0]) or a wildcard (.* or [*]). The first
+	// segment must be data, target or subjects.
+	Paths []string
+
+	// KeyPatterns are regular expressions matched, case-insensitively,
+	// against the whole of each key in Data, Target and Subjects, at any
+	// depth. The value of a matching key is redacted entirely, whatever its
+	// type.
+	KeyPatterns []string
+
+	// Detectors are applied to every string value not already redacted, and
+	// redact the parts that match.
+	Detectors []Detector
+
+	// Replacement replaces a redacted value. Empty uses "[REDACTED]".
+	Replacement string
+}
+
+// DefaultRedactionConfig returns key patterns for common credential field
+// names and detectors for common token formats: JWTs, bearer credentials,
+// AWS access key IDs, GitHub, Slack, Google and "sk-" API keys, PEM private
+// keys and passwords in URLs. Extend it rather than replacing it.
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		KeyPatterns: []string{
+			`.*pass(word|wd|phrase)?`,
+			`.*secret.*`,
+			`(.*[_-])?token`,
+			`(access|refresh|id|auth|bearer|session|api)token`,
+			`.*api[_-]?key`,
+			`.*private[_-]?key`,
+			`.*credentials?`,
+			`(proxy[_-])?authorization`,
+			`(set[_-])?cookie`,
+		},
+		Detectors: []Detector{
+			{Name: "jwt", Pattern: `eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`},
+			{Name: "bearer", Pattern: `(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`},
+			{Name: "aws_access_key", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+			{Name: "github_token", Pattern: `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`},
+			{Name: "slack_token", Pattern: `\bxox[abposr]-[A-Za-z0-9-]{10,}`},
+			{Name: "google_api_key", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+			{Name: "api_key", Pattern: `\bsk-[A-Za-z0-9_-]{20,}`},
+			{
+				Name:    "private_key",
+				Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+			},
+			{Name: "url_password", Pattern: `://[^:/@\s]+:(?P<secret>[^@/\s]+)@`},
+		},
+	}
+}
+
+// Redactor removes secrets from an event's Data, Target and Subjects before
+// it is logged or handed to a sink. It is immutable and safe for concurrent
+// use.
+//
+// Data that is not valid JSON cannot be inspected, and would fail every sink
+// anyway, so it is replaced wholesale.
+type Redactor struct {
+	paths       [][]pathSegment
+	keys        []*regexp.Regexp
+	detectors   []detector
+	replacement string
+}
+
+// detector is a compiled Detector.
+type detector struct {
+	name   string
+	re     *regexp.Regexp
+	secret int // index of the "secret" group, or 0 for the whole match
+}
+
+// pathSegment is one step of a redaction path. wildcard matches any key or
+// index; otherwise key is set for an object member and index (>= 0) for an
+// array element.
+type pathSegment struct {
+	key      string
+	index    int
+	wildcard bool
+}
+
+// NewRedactor compiles cfg.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	r := &Redactor{replacement: cfg.Replacement}
+	if r.replacement == "" {
+		r.replacement = defaultReplacement
+	}
+	for _, p := range cfg.Paths {
+		segs, err := parseRedactionPath(p)
+		if err != nil {
+			return nil, err
+		}
+		r.paths = append(r.paths, segs)
+	}
+	for _, p := range cfg.KeyPatterns {
+		re, err := regexp.Compile(`(?i)^(?:` + p + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("audit: invalid redaction key pattern %q: %w", p, err)
+		}
+		r.keys = append(r.keys, re)
+	}
+	for _, d := range cfg.Detectors {
+		if d.Name == "" {
+			return nil, fmt.Errorf("audit: redaction detector %q has no name", d.Pattern)
+		}
+		re, err := regexp.Compile(d.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("audit: invalid pattern for redaction detector %s: %w", d.Name, err)
+		}
+		r.detectors = append(r.detectors, detector{name: d.Name, re: re, secret: max(re.SubexpIndex("secret"), 0)})
+	}
+	return r, nil
+}
+
+// parseRedactionPath parses a JSON path such as $.data.items[*]['api-key'].
+func parseRedactionPath(path string) ([]pathSegment, error) {
+	fail := func(reason string) ([]pathSegment, error) {
+		return nil, fmt.Errorf("audit: invalid redaction path %q: %s", path, reason)
+	}
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return fail(`must start with "$"`)
+	}
+	var segs []pathSegment
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".*"):
+			segs, rest = append(segs, pathSegment{wildcard: true}), rest[2:]
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return fail("empty name")
+			}
+			segs, rest = append(segs, pathSegment{key: rest[1 : end+1]}), rest[end+1:]
+		case strings.HasPrefix(rest, "[*]"):
+			segs, rest = append(segs, pathSegment{wildcard: true}), rest[3:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return fail("unterminated ['")
+			}
+			segs, rest = append(segs, pathSegment{key: rest[2:end]}), rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return fail("unterminated [")
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return fail("bad array index " + rest[1:end])
+			}
+			segs, rest = append(segs, pathSegment{index: i}), rest[end+1:]
+		default:
+			return fail("unexpected " + strconv.Quote(rest[:1]))
+		}
+	}
+	switch {
+	case len(segs) == 1 && segs[0] == pathSegment{key: redactRootData}:
+		return segs, nil // $.data: all of Data
+	case len(segs) < 2 || segs[0].wildcard:
+		return fail("must select a field under data, target or subjects")
+	case segs[0].key == redactRootData:
+		return segs, nil
+	case segs[0].key == redactRootTarget, segs[0].key == redactRootSubjects:
+		if len(segs) != 2 || segs[1].key == "" && !segs[1].wildcard {
+			return fail(segs[0].key + " is a flat map of names")
+		}
+		return segs, nil
+	default:
+		return fail("must select a field under data, target or subjects")
+	}
+}
+
+// Redact returns event with its secrets redacted and the redacted fields
+// listed under MetadataExtraKeyRedacted. It never modifies event: the result
+// is a copy when anything was redacted, and event itself when nothing was.
+func (r *Redactor) Redact(event *AuditEvent) *AuditEvent {
+	var (
+		redacted []string
+		out      = event
+	)
+	copyOnce := func() *AuditEvent {
+		if out == event {
+			out = event.clone()
+		}
+		return out
+	}
+
+	for _, root := range []struct {
+		name string
+		m    map[string]string
+		set  func(*AuditEvent, map[string]string)
+	}{
+		{redactRootTarget, event.Target, func(e *AuditEvent, m map[string]string) { e.Target = m }},
+		{redactRootSubjects, event.Subjects, func(e *AuditEvent, m map[string]string) { e.Subjects = m }},
+	} {
+		if m, paths := r.redactStringMap(root.name, root.m); len(paths) > 0 {
+			root.set(copyOnce(), m)
+			redacted = append(redacted, paths...)
+		}
+	}
+
+	if event.Data != nil {
+		if data, paths := r.redactData(*event.Data); len(paths) > 0 {
+			copyOnce().Data = &data
+			redacted = append(redacted, paths...)
+		}
+	}
+
+	if len(redacted) == 0 {
+		return event
+	}
+	slices.Sort(redacted)
+	if out.Metadata.Extra == nil {
+		out.Metadata.Extra = map[string]any{}
+	}
+	out.Metadata.Extra[MetadataExtraKeyRedacted] = slices.Compact(redacted)
+	return out
+}
+
+// redactStringMap redacts a Target or Subjects map, returning the new map
+// and the paths redacted; the map is unchanged, and not copied, when none
+// were.
+func (r *Redactor) redactStringMap(root string, m map[string]string) (map[string]string, []string) {
+	var (
+		out   map[string]string
+		paths []string
+	)
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		path := []pathSegment{{key: root}, {key: k}}
+		v := m[k]
+		nv := v
+		if r.matchesPath(path) || r.matchesKey(k) {
+			nv = r.replacement
+		} else {
+			nv = r.detect(v)
+		}
+		if nv == v {
+			continue
+		}
+		if out == nil {
+			out = maps.Clone(m)
+		}
+		out[k] = nv
+		paths = append(paths, formatRedactionPath(path))
+	}
+	if out == nil {
+		return m, nil
+	}
+	return out, paths
+}
+
+// redactData redacts the JSON document data.
+func (r *Redactor) redactData(data json.RawMessage) (json.RawMessage, []string) {
+	root := []pathSegment{{key: redactRootData}}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return r.marshalReplacement(), []string{formatRedactionPath(root)}
+	}
+	if r.matchesPath(root) {
+		return r.marshalReplacement(), []string{formatRedactionPath(root)}
+	}
+	var paths []string
+	v = r.walk(v, root, &paths)
+	if len(paths) == 0 {
+		return data, nil
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		// Every value came from decoding JSON or is a string; this cannot
+		// happen. Fail safe regardless.
+		return r.marshalReplacement(), []string{formatRedactionPath(root)}
+	}
+	return out, paths
+}
+
+// walk redacts v, found at path, in place where it can, and returns its
+// replacement.
+func (r *Redactor) walk(v any, path []pathSegment, paths *[]string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			child := appendSegment(path, pathSegment{key: k})
+			if r.matchesPath(child) || r.matchesKey(k) {
+				v[k] = r.replacement
+				*paths = append(*paths, formatRedactionPath(child))
+				continue
+			}
+			v[k] = r.walk(v[k], child, paths)
+		}
+		return v
+	case []any:
+		for i := range v {
+			child := appendSegment(path, pathSegment{index: i})
+			if r.matchesPath(child) {
+				v[i] = r.replacement
+				*paths = append(*paths, formatRedactionPath(child))
+				continue
+			}
+			v[i] = r.walk(v[i], child, paths)
+		}
+		return v
+	case string:
+		if nv := r.detect(v); nv != v {
+			*paths = append(*paths, formatRedactionPath(path))
+			return nv
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// appendSegment returns path extended by seg without sharing path's array,
+// which siblings reuse.
+func appendSegment(path []pathSegment, seg pathSegment) []pathSegment {
+	return append(path[:len(path):len(path)], seg)
+}
+
+// matchesPath reports whether a configured path selects path.
+func (r *Redactor) matchesPath(path []pathSegment) bool {
+	for _, p := range r.paths {
+		if len(p) != len(path) {
+			continue
+		}
+		match := true
+		for i, seg := range p {
+			if !seg.wildcard && seg != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesKey reports whether a key pattern matches key.
+func (r *Redactor) matchesKey(key string) bool {
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// detect replaces what the detectors find in s.
+func (r *Redactor) detect(s string) string {
+	for _, d := range r.detectors {
+		marker := "[REDACTED:" + d.name + "]"
+		if d.secret == 0 {
+			s = d.re.ReplaceAllLiteralString(s, marker)
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range d.re.FindAllStringSubmatchIndex(s, -1) {
+			start, end := m[2*d.secret], m[2*d.secret+1]
+			if start < 0 {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(marker)
+			last = end
+		}
+		if last > 0 {
+			b.WriteString(s[last:])
+			s = b.String()
+		}
+	}
+	return s
+}
+
+// marshalReplacement is the replacement as a JSON string.
+func (r *Redactor) marshalReplacement() json.RawMessage {
+	data, _ := json.Marshal(r.replacement)
+	return data
+}
+
+// identPattern matches names that need no brackets in a path.
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// formatRedactionPath renders path as the marker lists it.
+func formatRedactionPath(path []pathSegment) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range path {
+		switch {
+		case seg.wildcard:
+			b.WriteString("[*]")
+		case seg.key != "" && identPattern.MatchString(seg.key):
+			b.WriteString("." + seg.key)
+		case seg.key != "":
+			b.WriteString("['" + seg.key + "']")
+		default:
+			b.WriteString("[" + strconv.Itoa(seg.index) + "]")
+		}
+	}
+	return b.String()
+}
+
+// RedactingSink redacts every event with a Redactor before writing it to
+// the next sink. Put it first, ahead of any ChainSink, so that what is
+// chained and signed is what is stored.
+type RedactingSink struct {
+	next     Sink
+	redactor *Redactor
+}
+
+// Compile-time check that RedactingSink is a Sink.
+var _ Sink = (*RedactingSink)(nil)
+
+// NewRedactingSink returns a sink redacting with redactor into next. next
+// belongs to it from then on: Close closes it.
+func NewRedactingSink(next Sink, redactor *Redactor) (*RedactingSink, error) {
+	if next == nil || redactor == nil {
+		return nil, errors.New("audit: redacting sink requires a next sink and a redactor")
+	}
+	return &RedactingSink{next: next, redactor: redactor}, nil
+}
+
+// Write redacts event and writes the result.
+func (s *RedactingSink) Write(ctx context.Context, event *AuditEvent) error {
+	return s.next.Write(ctx, s.redactor.Redact(event))
+}
+
+// Close closes the next sink.
+func (s *RedactingSink) Close() error {
+	return s.next.Close()
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaVersion is the version of the audit event JSON Schema that events
+// created by NewAuditEvent and NewAuditEventWithID conform to. It is recorded
+// in [EventMetadata.SchemaVersion].
+//
+// The major version changes when an event valid under the old schema may not
+// be under the new one: a field removed, renamed or retyped, or a new
+// required field. Adding an optional field is a minor change.
+const SchemaVersion = "1.0.0"
+
+// SchemaID is the $id of the audit event JSON Schema, the URL it is
+// published at.
+const SchemaID = "https://raw.githubusercontent.com/stacklok/toolhive-core/main/audit/data/audit-event.schema.json"
+
+//go:embed data/audit-event.schema.json
+var eventSchema []byte
+
+// compiled event schema, built once by loadEventSchema.
+var (
+	eventSchemaOnce     sync.Once
+	eventSchemaCompiled *gojsonschema.Schema
+	eventSchemaErr      error
+)
+
+// Schema returns the audit event JSON Schema, for publication alongside a
+// SIEM integration. The returned slice is a copy.
+func Schema() []byte {
+	return append([]byte(nil), eventSchema...)
+}
+
+// Validate validates the event's JSON encoding against the audit event
+// schema.
+func (e *AuditEvent) Validate() error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit event: %w", err)
+	}
+	return ValidateEventBytes(data)
+}
+
+// ValidateEventBytes validates one JSON-encoded audit event, such as a line
+// of a FileSink log, against the audit event schema.
+func ValidateEventBytes(data []byte) error {
+	const errPrefix = "audit event schema validation failed"
+
+	schema, err := loadEventSchema()
+	if err != nil {
+		return fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(result.Errors()))
+	for _, desc := range result.Errors() {
+		msgs = append(msgs, desc.String())
+	}
+	if len(msgs) == 1 {
+		return fmt.Errorf("%s: %s", errPrefix, msgs[0])
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s with %d errors:\n", errPrefix, len(msgs))
+	for i, msg := range msgs {
+		fmt.Fprintf(&b, "  %d. %s\n", i+1, msg)
+	}
+	return errors.New(strings.TrimSuffix(b.String(), "\n"))
+}
+
+// loadEventSchema compiles the embedded schema once.
+func loadEventSchema() (*gojsonschema.Schema, error) {
+	eventSchemaOnce.Do(func() {
+		eventSchemaCompiled, eventSchemaErr = gojsonschema.NewSchema(gojsonschema.NewBytesLoader(eventSchema))
+	})
+	return eventSchemaCompiled, eventSchemaErr
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// goldenEvents returns the events pinned by the files in testdata, keyed by
+// file name.
+func goldenEvents() map[string]*AuditEvent {
+	loggedAt := time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+
+	minimal := &AuditEvent{
+		Metadata:  EventMetadata{AuditID: "a1"},
+		Type:      EventTypeAuthentication,
+		LoggedAt:  loggedAt,
+		Source:    EventSource{Type: SourceTypeLocal, Value: "localhost"},
+		Outcome:   OutcomeFailure,
+		Component: ComponentToolHive,
+	}
+
+	full := NewAuditEventWithID("a2", EventTypeMCPToolCall,
+		EventSource{
+			Type:  SourceTypeNetwork,
+			Value: "10.0.0.1",
+			Extra: map[string]any{SourceExtraKeyUserAgent: "curl/8", SourceExtraKeySessionID: "s-1"},
+		},
+		OutcomeSuccess,
+		map[string]string{SubjectKeyUser: "alice", SubjectKeyUserID: "u-alice"},
+		"github",
+	).WithTarget(map[string]string{
+		TargetKeyType:   TargetTypeTool,
+		TargetKeyName:   "create_issue",
+		TargetKeyMethod: "tools/call",
+	}).WithDataFromString(`{"arguments":{"title":"bug"}}`)
+	full.LoggedAt = loggedAt
+	full.Metadata.Sequence = 7
+	full.Metadata.PrevHash = "sha256:" + string(bytes.Repeat([]byte("ab"), 32))
+	full.Metadata.Extra = map[string]any{MetadataExtraKeyTransport: "streamable-http", MetadataExtraKeyDuration: 12}
+	full.WithDelegationChain(&DelegationChain{
+		Chain: []DelegatedActor{
+			{Issuer: "https://idp.example.com", Subject: "gateway"},
+			{Issuer: "https://idp.example.com", Subject: "agent"},
+		},
+		Truncated: true,
+		Omitted:   1,
+	})
+
+	malformed := NewAuditEventWithID("a3", EventTypeMCPToolsList,
+		EventSource{Type: SourceTypeNetwork, Value: "10.0.0.2"},
+		OutcomeDenied, map[string]string{SubjectKeyUser: "bob"}, "github",
+	).WithDelegationChain(ParseDelegationChain("not an object", 0))
+	malformed.LoggedAt = loggedAt
+
+	return map[string]*AuditEvent{
+		"event_minimal.json":   minimal,
+		"event_full.json":      full,
+		"event_malformed.json": malformed,
+	}
+}
+
+// TestEventWireFormat_Golden pins the JSON encoding of AuditEvent, the
+// contract with SIEMs. A failure here means the wire format changed: if that
+// was intended, update the golden file and the schema in data, and bump
+// SchemaVersion.
+func TestEventWireFormat_Golden(t *testing.T) {
+	t.Parallel()
+
+	for name, event := range goldenEvents() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			want, err := os.ReadFile(filepath.Join("testdata", name)) // #nosec G304 - test fixture
+			require.NoError(t, err)
+
+			got, err := json.Marshal(event)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+
+			require.NoError(t, ValidateEventBytes(want), "golden file must conform to the schema")
+
+			var decoded AuditEvent
+			require.NoError(t, json.Unmarshal(want, &decoded))
+			again, err := json.Marshal(&decoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(again), "decoding must not lose fields")
+		})
+	}
+}
+
+func TestEventValidate(t *testing.T) {
+	t.Parallel()
+
+	event := NewAuditEvent(EventTypeMCPToolCall, EventSource{Type: SourceTypeNetwork, Value: "10.0.0.1"},
+		OutcomeSuccess, nil, "github")
+	assert.Equal(t, SchemaVersion, event.Metadata.SchemaVersion)
+	require.NoError(t, event.Validate())
+
+	event.Type = ""
+	assert.ErrorContains(t, event.Validate(), "type")
+}
+
+func TestValidateEventBytes(t *testing.T) {
+	t.Parallel()
+
+	valid := `{"metadata":{"auditId":"a1"},"type":"t","loggedAt":"2026-01-02T03:04:05Z",` +
+		`"source":{"type":"local","value":"x"},"outcome":"success","subjects":{},"component":"c"`
+
+	tests := []struct {
+		name          string
+		data          string
+		errorContains string
+	}{
+		{name: "valid", data: valid + `}`},
+		{name: "unknown field", data: valid + `,"severity":"high"}`, errorContains: "severity"},
+		{name: "non-string subject", data: valid[:len(valid)-len(`"subjects":{},"component":"c"`)] + `"subjects":{"n":1},"component":"c"}`, errorContains: "subjects"},
+		{name: "future major version", data: `{"metadata":{"auditId":"a1","schemaVersion":"2.0.0"}` + valid[len(`{"metadata":{"auditId":"a1"}`):] + `}`, errorContains: "schemaVersion"},
+		{name: "bad prev hash", data: `{"metadata":{"auditId":"a1","prevHash":"md5:x"}` + valid[len(`{"metadata":{"auditId":"a1"}`):] + `}`, errorContains: "prevHash"},
+		{name: "missing outcome", data: `{"metadata":{"auditId":"a1"},"type":"t","loggedAt":"2026-01-02T03:04:05Z","source":{"type":"local","value":"x"},"subjects":{},"component":"c"}`, errorContains: "outcome"},
+		{
+			name:          "unknown malformed reason",
+			data:          valid + `,"delegation":{"chain":[],"truncated":false,"omitted":0,"malformed":true,"malformedReason":"bad"}}`,
+			errorContains: "malformedReason",
+		},
+		{
+			name:          "hop extra claims",
+			data:          valid + `,"delegation":{"chain":[{"sub":"a","email":"a@example.com"}],"truncated":false,"omitted":0,"malformed":false}}`,
+			errorContains: "email",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateEventBytes([]byte(tt.data))
+			if tt.errorContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errorContains)
+		})
+	}
+}
+
+// TestSchemaVersionLogged checks that the schema version survives LogTo and
+// the Reader, as it does the JSON encoding.
+func TestSchemaVersionLogged(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	event := goldenEvents()["event_full.json"]
+	event.LogTo(context.Background(), NewAuditLogger(&buf), LevelAudit)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, SchemaVersion, line["schemaVersion"], "the log line uses the JSON encoding's key")
+
+	got, err := ReadAll(&buf, Filter{})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, SchemaVersion, got[0].Metadata.SchemaVersion)
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(Schema(), &doc))
+	assert.Equal(t, SchemaID, doc["$id"])
+	assert.Contains(t, doc["description"], SchemaVersion)
+}
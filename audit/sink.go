@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Sink is a destination for audit events: a file, a syslog collector, a
+// webhook, an OpenTelemetry logs pipeline.
+//
+// Write delivers one event synchronously and reports whether it was
+// delivered; it must not retain event after returning, since the caller may
+// reuse or mutate it. A Sink need not be safe for concurrent Writes — the
+// Dispatcher, which is how sinks are normally driven, calls each from a single
+// goroutine — but the built-in sinks are. Close flushes and releases the
+// sink's resources; Write after Close fails.
+type Sink interface {
+	Write(ctx context.Context, event *AuditEvent) error
+	Close() error
+}
+
+// marshalEvent encodes event as the single-line JSON every byte-oriented sink
+// writes. The encoding is AuditEvent's JSON form, so a record has the same
+// shape whichever sink carried it.
+func marshalEvent(event *AuditEvent) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to encode event %s: %w", event.Metadata.AuditID, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syslogCollector accepts TCP connections and reports each octet-counted
+// frame it receives.
+type syslogCollector struct {
+	ln     net.Listener
+	frames chan string
+}
+
+func newSyslogCollector(t *testing.T) *syslogCollector {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	c := &syslogCollector{ln: ln, frames: make(chan string, 16)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go c.serve(conn)
+		}
+	}()
+	return c
+}
+
+func (c *syslogCollector) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	for {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil {
+			return
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		c.frames <- string(msg)
+	}
+}
+
+func (c *syslogCollector) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case f := <-c.frames:
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("no syslog frame received")
+		return ""
+	}
+}
+
+func TestNewSyslogSinkConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     SyslogSinkConfig
+		wantErr string
+	}{
+		{name: "no address", cfg: SyslogSinkConfig{}, wantErr: "invalid syslog address"},
+		{name: "no port", cfg: SyslogSinkConfig{Address: "collector"}, wantErr: "invalid syslog address"},
+		{name: "facility too large", cfg: SyslogSinkConfig{Address: "collector:514", Facility: 24}, wantErr: "facility"},
+		{name: "negative timeout", cfg: SyslogSinkConfig{Address: "collector:514", Timeout: -time.Second}, wantErr: "timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewSyslogSink(tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Nil(t, s)
+		})
+	}
+}
+
+func TestSyslogSinkWrite(t *testing.T) {
+	t.Parallel()
+
+	c := newSyslogCollector(t)
+	s, err := NewSyslogSink(SyslogSinkConfig{Address: c.ln.Addr().String(), AppName: "thv proxy", Hostname: "host-1"})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	event := newTestEvent("e1")
+	require.NoError(t, s.Write(context.Background(), event))
+
+	frame := c.next(t)
+	header, msg, ok := strings.Cut(frame, " - ")
+	require.True(t, ok, frame)
+	fields := strings.Fields(header)
+	require.Len(t, fields, 6)
+	assert.Equal(t, "<109>1", fields[0], "facility 13 (log audit), severity 5 (notice)")
+	assert.Equal(t, "2026-01-02T03:04:05Z", fields[1])
+	assert.Equal(t, "host-1", fields[2])
+	assert.Equal(t, "thv_proxy", fields[3], "spaces are not allowed in header fields")
+	assert.Equal(t, strconv.Itoa(os.Getpid()), fields[4])
+	assert.Equal(t, EventTypeMCPToolCall, fields[5])
+
+	var got AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(msg), &got))
+	assert.Equal(t, "e1", got.Metadata.AuditID)
+}
+
+func TestSyslogSinkReconnects(t *testing.T) {
+	t.Parallel()
+
+	c := newSyslogCollector(t)
+	s, err := NewSyslogSink(SyslogSinkConfig{Address: c.ln.Addr().String()})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e1")))
+	c.next(t)
+
+	// Simulate the collector dropping the connection.
+	s.mu.Lock()
+	_ = s.conn.Close()
+	s.mu.Unlock()
+
+	// The write on the dead connection fails and discards it; the next one
+	// connects afresh.
+	assert.Error(t, s.Write(context.Background(), newTestEvent("e2")))
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e3")))
+	assert.Contains(t, c.next(t), `"auditId":"e3"`)
+}
+
+func TestSyslogSinkUnreachable(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	s, err := NewSyslogSink(SyslogSinkConfig{Address: addr, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.ErrorContains(t, s.Write(context.Background(), newTestEvent("e1")), "failed to connect")
+	require.NoError(t, s.Close())
+	assert.ErrorContains(t, s.Write(context.Background(), newTestEvent("e1")), "closed")
+}
+
+func TestSyslogField(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "-", syslogField("", 10))
+	assert.Equal(t, "a_b_c", syslogField("a b\tc", 10))
+	assert.Equal(t, "abc", syslogField("abcdef", 3))
+	assert.Equal(t, "__", syslogField("é", 10), "non-ASCII bytes are replaced")
+}
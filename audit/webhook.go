@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/stacklok/toolhive-core/networking"
+)
+
+// defaultWebhookTimeout is WebhookSinkConfig.Timeout when zero.
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookSinkConfig configures a WebhookSink.
+type WebhookSinkConfig struct {
+	// URL receives one POST per event. It must be https unless
+	// InsecureAllowHTTP is set. Required.
+	URL string
+
+	// Headers are added to every request: typically an Authorization header
+	// the receiver checks. Content-Type is always application/json.
+	Headers http.Header
+
+	// HTTPClient sends the requests. Leave it nil unless you need a custom
+	// transport: the default client refuses private addresses (see
+	// AllowPrivateIP), and a caller-supplied client brings its own policy.
+	HTTPClient *http.Client
+
+	// AllowPrivateIP lets the default client reach private, loopback and
+	// link-local addresses, for a receiver inside the cluster. Loopback is
+	// always allowed.
+	AllowPrivateIP bool
+
+	// InsecureAllowHTTP permits an http:// URL. Audit records then cross the
+	// network in the clear; it exists for development only.
+	InsecureAllowHTTP bool
+
+	// Timeout bounds each request with the default client. Zero uses ten
+	// seconds; negative is an error.
+	Timeout time.Duration
+}
+
+// WebhookSink POSTs each event as a JSON body to an HTTP endpoint. Any 2xx
+// response is delivery; anything else, including a redirect, is an error for
+// the Dispatcher to retry. It is safe for concurrent use.
+type WebhookSink struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+	closed  atomic.Bool
+}
+
+// Compile-time check that WebhookSink is a Sink.
+var _ Sink = (*WebhookSink)(nil)
+
+// NewWebhookSink validates cfg and builds the HTTP client.
+func NewWebhookSink(cfg WebhookSinkConfig) (*WebhookSink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("audit: invalid webhook URL %q", cfg.URL)
+	}
+	switch {
+	case u.Scheme == networking.HttpScheme && !cfg.InsecureAllowHTTP:
+		return nil, fmt.Errorf("audit: webhook URL must use https (set InsecureAllowHTTP for dev only): %s", cfg.URL)
+	case u.Scheme != networking.HttpsScheme && u.Scheme != networking.HttpScheme:
+		return nil, fmt.Errorf("audit: webhook URL must use https: %s", cfg.URL)
+	case cfg.Timeout < 0:
+		return nil, fmt.Errorf("audit: webhook timeout must not be negative: %s", cfg.Timeout)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client, err = networking.NewHostScopedClientBuilder(u.Hostname(), cfg.AllowPrivateIP, cfg.InsecureAllowHTTP).
+			WithTimeout(cfg.Timeout).
+			Build()
+		if err != nil {
+			return nil, fmt.Errorf("audit: failed to build webhook HTTP client: %w", err)
+		}
+		// A redirect would re-send the record, and its Authorization
+		// header, somewhere the operator did not configure.
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	return &WebhookSink{url: cfg.URL, headers: cfg.Headers.Clone(), client: client}, nil
+}
+
+// Write POSTs event.
+func (s *WebhookSink) Write(ctx context.Context, event *AuditEvent) error {
+	if s.closed.Load() {
+		return errors.New("audit: webhook sink is closed")
+	}
+	data, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("audit: failed to build webhook request: %w", err)
+	}
+	for k, v := range s.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audit: webhook request to %s failed: %w", s.url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Drain a little so the connection can be reused; the body itself is of
+	// no interest.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit: webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// Close stops further Writes. Requests in flight are not interrupted.
+func (s *WebhookSink) Close() error {
+	s.closed.Store(true)
+	return nil
+}
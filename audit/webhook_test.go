@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhookSinkConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     WebhookSinkConfig
+		wantErr string
+	}{
+		{name: "https", cfg: WebhookSinkConfig{URL: "https://siem.example.com/ingest"}},
+		{name: "http allowed", cfg: WebhookSinkConfig{URL: "http://siem.example.com/ingest", InsecureAllowHTTP: true}},
+		{name: "no URL", cfg: WebhookSinkConfig{}, wantErr: "invalid webhook URL"},
+		{name: "http", cfg: WebhookSinkConfig{URL: "http://siem.example.com/ingest"}, wantErr: "must use https"},
+		{name: "other scheme", cfg: WebhookSinkConfig{URL: "ftp://siem.example.com/ingest"}, wantErr: "must use https"},
+		{
+			name:    "negative timeout",
+			cfg:     WebhookSinkConfig{URL: "https://siem.example.com/ingest", Timeout: -time.Second},
+			wantErr: "timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewWebhookSink(tt.cfg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, s)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWebhookSinkWrite(t *testing.T) {
+	t.Parallel()
+
+	var (
+		got    AuditEvent
+		header http.Header
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		if assert.Equal(t, http.MethodPost, r.Method) {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s, err := NewWebhookSink(WebhookSinkConfig{
+		URL:        srv.URL,
+		Headers:    http.Header{"Authorization": {"Bearer secret"}},
+		HTTPClient: srv.Client(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(context.Background(), newTestEvent("e1")))
+
+	assert.Equal(t, "e1", got.Metadata.AuditID)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+
+	require.NoError(t, s.Close())
+	assert.ErrorContains(t, s.Write(context.Background(), newTestEvent("e2")), "closed")
+}
+
+func TestWebhookSinkFailures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name:    "server error",
+			handler: func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) },
+			wantErr: "503",
+		},
+		{
+			name: "redirect is not followed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://elsewhere.example.com/", http.StatusTemporaryRedirect)
+			},
+			wantErr: "307",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			// The default client, which allows loopback without AllowPrivateIP.
+			s, err := NewWebhookSink(WebhookSinkConfig{URL: srv.URL, InsecureAllowHTTP: true})
+			require.NoError(t, err)
+			assert.ErrorContains(t, s.Write(context.Background(), newTestEvent("e1")), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Workflow metadata field keys for EventMetadata.Extra, recorded on the
+// terminal workflow event alongside MetadataExtraKeyStepCount.
+const (
+	// MetadataExtraKeyStepsCompleted is the key for the number of steps that completed
+	MetadataExtraKeyStepsCompleted = "steps_completed"
+	// MetadataExtraKeyStepsFailed is the key for the number of steps that failed
+	MetadataExtraKeyStepsFailed = "steps_failed"
+	// MetadataExtraKeyStepsSkipped is the key for the number of steps that were skipped
+	MetadataExtraKeyStepsSkipped = "steps_skipped"
+	// MetadataExtraKeyAfterWorkflowEnd is the key, set to true, on a step
+	// event emitted after the workflow's terminal event
+	MetadataExtraKeyAfterWorkflowEnd = "after_workflow_end"
+)
+
+// WorkflowConfig configures a WorkflowAuditor.
+type WorkflowConfig struct {
+	// Sink receives the events, typically a Dispatcher. Required.
+	Sink Sink
+
+	// WorkflowID identifies the execution; every event carries it under
+	// TargetKeyWorkflowID. Empty generates a UUID.
+	WorkflowID string
+	// WorkflowName is the workflow definition's name.
+	WorkflowName string
+
+	// Component, Source, Subjects and DelegationChain are recorded on every
+	// event, as in NewAuditEvent and WithDelegationChain.
+	Component       string
+	Source          EventSource
+	Subjects        map[string]string
+	DelegationChain *DelegationChain
+
+	// StepCount is the number of steps in the workflow definition, recorded
+	// under MetadataExtraKeyStepCount. Zero records the number of steps
+	// started instead, on the terminal event only.
+	StepCount int
+
+	// Timeout is the workflow's time limit, recorded under
+	// MetadataExtraKeyTimeout. Zero records none. It is informational: the
+	// caller enforces it, typically with context.WithTimeout.
+	Timeout time.Duration
+}
+
+// WorkflowAuditor audits one execution of a vMCP composite workflow. It
+// emits EventTypeWorkflowStarted when created, a started event and an outcome
+// event for each step, and exactly one terminal event: completed, failed or
+// timed out. Every event's Target carries the workflow ID and name, and step
+// events the step ID, type and tool, so the events of one execution can be
+// correlated.
+//
+// The terminal event is guaranteed by Finish, deferred straight after
+// NewWorkflowAuditor, and by the workflow context: when it is done before
+// the workflow ends, a timed out event (for context.DeadlineExceeded) or a
+// failed one is emitted at once, without waiting for the steps still
+// running. Only the first terminal event is emitted; later ones are no-ops.
+//
+// Steps still running when the workflow ends are not silenced: their events
+// are emitted, since they record work that happened, but marked with
+// MetadataExtraKeyAfterWorkflowEnd and left out of the counts, which the
+// terminal event has already reported.
+//
+// A WorkflowAuditor is safe for concurrent use, so parallel steps may share
+// it.
+type WorkflowAuditor struct {
+	cfg       WorkflowConfig
+	target    map[string]string
+	started   time.Time
+	stopWatch func() bool
+
+	mu        sync.Mutex
+	ended     bool
+	steps     int
+	completed int
+	failed    int
+	skipped   int
+	retries   int
+}
+
+// NewWorkflowAuditor emits EventTypeWorkflowStarted and returns the auditor
+// for the rest of the execution. ctx is the workflow's context: its
+// cancellation ends the workflow, as described on WorkflowAuditor.
+//
+//	wa, err := audit.NewWorkflowAuditor(ctx, cfg)
+//	if err != nil {
+//		return err
+//	}
+//	defer wa.Finish(ctx, &err)
+func NewWorkflowAuditor(ctx context.Context, cfg WorkflowConfig) (*WorkflowAuditor, error) {
+	if cfg.Sink == nil {
+		return nil, errors.New("audit: workflow auditor requires a sink")
+	}
+	if cfg.WorkflowID == "" {
+		cfg.WorkflowID = uuid.New().String()
+	}
+	w := &WorkflowAuditor{
+		cfg: cfg,
+		target: map[string]string{
+			TargetKeyType:         TargetTypeWorkflow,
+			TargetKeyWorkflowID:   cfg.WorkflowID,
+			TargetKeyWorkflowName: cfg.WorkflowName,
+		},
+		started: time.Now(),
+	}
+
+	extra := map[string]any{}
+	if cfg.StepCount > 0 {
+		extra[MetadataExtraKeyStepCount] = cfg.StepCount
+	}
+	if cfg.Timeout > 0 {
+		extra[MetadataExtraKeyTimeout] = cfg.Timeout.Milliseconds()
+	}
+	if err := w.emit(ctx, EventTypeWorkflowStarted, OutcomeSuccess, w.target, extra, nil); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	w.stopWatch = context.AfterFunc(ctx, func() {
+		_ = w.end(ctx, nil)
+	})
+	w.mu.Unlock()
+	return w, nil
+}
+
+// WorkflowID returns the execution's ID.
+func (w *WorkflowAuditor) WorkflowID() string {
+	return w.cfg.WorkflowID
+}
+
+// Complete emits EventTypeWorkflowCompleted.
+func (w *WorkflowAuditor) Complete(ctx context.Context) error {
+	return w.end(ctx, nil)
+}
+
+// Fail emits EventTypeWorkflowFailed, or EventTypeWorkflowTimedOut when err
+// is or wraps context.DeadlineExceeded. The error message is recorded in
+// Data.
+func (w *WorkflowAuditor) Fail(ctx context.Context, err error) error {
+	if err == nil {
+		err = errors.New("workflow failed")
+	}
+	return w.end(ctx, err)
+}
+
+// Finish ends the workflow from a deferred call, with a pointer to the
+// function's named error result. It emits the terminal event for the way the
+// function returned: failed when it panicked, which Finish re-panics, or
+// when *errp is non-nil; timed out or failed when ctx is done; completed
+// otherwise. It emits nothing when the workflow has already ended.
+func (w *WorkflowAuditor) Finish(ctx context.Context, errp *error) {
+	if r := recover(); r != nil {
+		_ = w.end(ctx, fmt.Errorf("workflow panicked: %v", r))
+		panic(r)
+	}
+	var err error
+	if errp != nil {
+		err = *errp
+	}
+	_ = w.end(ctx, err)
+}
+
+// end emits the terminal event once. A nil err ends the workflow by ctx:
+// completed while ctx is live, timed out or failed once it is done.
+func (w *WorkflowAuditor) end(ctx context.Context, err error) error {
+	if err == nil {
+		err = context.Cause(ctx)
+	}
+
+	w.mu.Lock()
+	if w.ended {
+		w.mu.Unlock()
+		return nil
+	}
+	w.ended = true
+	stepCount := w.cfg.StepCount
+	if stepCount == 0 {
+		stepCount = w.steps
+	}
+	extra := map[string]any{
+		MetadataExtraKeyDuration:       time.Since(w.started).Milliseconds(),
+		MetadataExtraKeyStepCount:      stepCount,
+		MetadataExtraKeyStepsCompleted: w.completed,
+		MetadataExtraKeyStepsFailed:    w.failed,
+		MetadataExtraKeyStepsSkipped:   w.skipped,
+		MetadataExtraKeyRetryCount:     w.retries,
+	}
+	stopWatch := w.stopWatch
+	w.mu.Unlock()
+	if stopWatch != nil {
+		stopWatch()
+	}
+
+	eventType, outcome := EventTypeWorkflowCompleted, OutcomeSuccess
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		eventType, outcome = EventTypeWorkflowTimedOut, OutcomeFailure
+		if w.cfg.Timeout > 0 {
+			extra[MetadataExtraKeyTimeout] = w.cfg.Timeout.Milliseconds()
+		}
+	case err != nil:
+		eventType, outcome = EventTypeWorkflowFailed, OutcomeFailure
+	}
+	return w.emit(ctx, eventType, outcome, w.target, extra, err)
+}
+
+// WorkflowStep identifies a step of a workflow.
+type WorkflowStep struct {
+	// ID is the step's ID in the workflow definition. Required.
+	ID string
+	// Type is the step's kind, such as "tool" or "elicitation".
+	Type string
+	// ToolName is the tool the step calls, if any.
+	ToolName string
+}
+
+// StepAuditor audits one execution of a workflow step. Its methods are not
+// safe for concurrent use; use a StepAuditor per step.
+type StepAuditor struct {
+	w       *WorkflowAuditor
+	target  map[string]string
+	started time.Time
+	retries int
+	ended   bool
+}
+
+// StartStep emits EventTypeWorkflowStepStarted and returns the auditor for
+// the step. The step's outcome event is emitted by Complete or Fail on the
+// returned StepAuditor.
+func (w *WorkflowAuditor) StartStep(ctx context.Context, step WorkflowStep) (*StepAuditor, error) {
+	w.mu.Lock()
+	late := w.ended
+	if !late {
+		w.steps++
+	}
+	w.mu.Unlock()
+
+	s := &StepAuditor{w: w, target: w.stepTarget(step), started: time.Now()}
+	extra := lateExtra(nil, late)
+	if err := w.emit(ctx, EventTypeWorkflowStepStarted, OutcomeSuccess, s.target, extra, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SkipStep emits EventTypeWorkflowStepSkipped for a step whose condition
+// was not met, with the reason in Data.
+func (w *WorkflowAuditor) SkipStep(ctx context.Context, step WorkflowStep, reason string) error {
+	w.mu.Lock()
+	late := w.ended
+	if !late {
+		w.skipped++
+	}
+	w.mu.Unlock()
+
+	data, err := json.Marshal(map[string]string{"reason": reason})
+	if err != nil {
+		return fmt.Errorf("audit: failed to encode skip reason: %w", err)
+	}
+	event := w.newEvent(EventTypeWorkflowStepSkipped, OutcomeSuccess, w.stepTarget(step), lateExtra(nil, late))
+	raw := json.RawMessage(data)
+	return w.write(ctx, event.WithData(&raw))
+}
+
+// stepTarget returns the Target of the events for step.
+func (w *WorkflowAuditor) stepTarget(step WorkflowStep) map[string]string {
+	target := maps.Clone(w.target)
+	target[TargetKeyType] = TargetTypeWorkflowStep
+	target[TargetKeyStepID] = step.ID
+	if step.Type != "" {
+		target[TargetKeyStepType] = step.Type
+	}
+	if step.ToolName != "" {
+		target[TargetKeyToolName] = step.ToolName
+	}
+	return target
+}
+
+// Retry records that the step is being attempted again. The count is
+// reported on the step's outcome event and summed on the workflow's.
+func (s *StepAuditor) Retry() {
+	s.retries++
+	s.w.mu.Lock()
+	if !s.w.ended {
+		s.w.retries++
+	}
+	s.w.mu.Unlock()
+}
+
+// Complete emits EventTypeWorkflowStepCompleted.
+func (s *StepAuditor) Complete(ctx context.Context) error {
+	return s.end(ctx, nil)
+}
+
+// Fail emits EventTypeWorkflowStepFailed, with the error message in Data.
+func (s *StepAuditor) Fail(ctx context.Context, err error) error {
+	if err == nil {
+		err = errors.New("step failed")
+	}
+	return s.end(ctx, err)
+}
+
+// end emits the step's outcome event once.
+func (s *StepAuditor) end(ctx context.Context, err error) error {
+	if s.ended {
+		return nil
+	}
+	s.ended = true
+
+	eventType, outcome := EventTypeWorkflowStepCompleted, OutcomeSuccess
+	if err != nil {
+		eventType, outcome = EventTypeWorkflowStepFailed, OutcomeFailure
+	}
+	s.w.mu.Lock()
+	late := s.w.ended
+	switch {
+	case late:
+	case err != nil:
+		s.w.failed++
+	default:
+		s.w.completed++
+	}
+	s.w.mu.Unlock()
+
+	extra := lateExtra(map[string]any{
+		MetadataExtraKeyDuration:   time.Since(s.started).Milliseconds(),
+		MetadataExtraKeyRetryCount: s.retries,
+	}, late)
+	return s.w.emit(ctx, eventType, outcome, s.target, extra, err)
+}
+
+// lateExtra marks extra with MetadataExtraKeyAfterWorkflowEnd when the event
+// comes after the workflow's terminal event, allocating it if need be.
+func lateExtra(extra map[string]any, late bool) map[string]any {
+	if !late {
+		return extra
+	}
+	if extra == nil {
+		extra = map[string]any{}
+	}
+	extra[MetadataExtraKeyAfterWorkflowEnd] = true
+	return extra
+}
+
+// emit writes an event with err's message, if any, as its Data.
+func (w *WorkflowAuditor) emit(
+	ctx context.Context, eventType, outcome string, target map[string]string, extra map[string]any, err error,
+) error {
+	event := w.newEvent(eventType, outcome, target, extra)
+	if err != nil {
+		data, merr := json.Marshal(map[string]string{"error": err.Error()})
+		if merr != nil {
+			return fmt.Errorf("audit: failed to encode workflow error: %w", merr)
+		}
+		raw := json.RawMessage(data)
+		event.WithData(&raw)
+	}
+	return w.write(ctx, event)
+}
+
+// newEvent returns an event of the workflow.
+func (w *WorkflowAuditor) newEvent(eventType, outcome string, target map[string]string, extra map[string]any) *AuditEvent {
+	event := NewAuditEvent(eventType, w.cfg.Source, outcome, maps.Clone(w.cfg.Subjects), w.cfg.Component).
+		WithTarget(target).
+		WithDelegationChain(w.cfg.DelegationChain)
+	if len(extra) > 0 {
+		event.Metadata.Extra = extra
+	}
+	return event
+}
+
+// write writes event to the sink. The context's cancellation is stripped:
+// the events that record a cancelled workflow must still be delivered.
+func (w *WorkflowAuditor) write(ctx context.Context, event *AuditEvent) error {
+	if err := w.cfg.Sink.Write(context.WithoutCancel(ctx), event); err != nil {
+		return fmt.Errorf("audit: failed to write %s event: %w", event.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWorkflow(ctx context.Context, t *testing.T, sink Sink) *WorkflowAuditor {
+	t.Helper()
+	w, err := NewWorkflowAuditor(ctx, WorkflowConfig{
+		Sink:         sink,
+		WorkflowID:   "wf-1",
+		WorkflowName: "triage",
+		Component:    "vmcp",
+		Source:       EventSource{Type: SourceTypeNetwork, Value: "10.0.0.1"},
+		Subjects:     map[string]string{SubjectKeyUser: "alice"},
+		StepCount:    3,
+		Timeout:      time.Minute,
+	})
+	require.NoError(t, err)
+	return w
+}
+
+// eventTypes returns the type of each event.
+func eventTypes(events []AuditEvent) []string {
+	types := make([]string, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestWorkflowAuditor(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sink := &lineSink{}
+	w := newTestWorkflow(ctx, t, sink)
+
+	fetch, err := w.StartStep(ctx, WorkflowStep{ID: "fetch", Type: "tool", ToolName: "github.get_issue"})
+	require.NoError(t, err)
+	fetch.Retry()
+	fetch.Retry()
+	require.NoError(t, fetch.Complete(ctx))
+	require.NoError(t, fetch.Complete(ctx), "a second outcome is a no-op")
+
+	label, err := w.StartStep(ctx, WorkflowStep{ID: "label", Type: "tool"})
+	require.NoError(t, err)
+	require.NoError(t, label.Fail(ctx, errors.New("rate limited")))
+
+	require.NoError(t, w.SkipStep(ctx, WorkflowStep{ID: "notify"}, "no assignee"))
+	require.NoError(t, w.Complete(ctx))
+	require.NoError(t, w.Fail(ctx, errors.New("late")), "a second terminal event is a no-op")
+
+	events := sink.events(t)
+	require.Equal(t, []string{
+		EventTypeWorkflowStarted,
+		EventTypeWorkflowStepStarted,
+		EventTypeWorkflowStepCompleted,
+		EventTypeWorkflowStepStarted,
+		EventTypeWorkflowStepFailed,
+		EventTypeWorkflowStepSkipped,
+		EventTypeWorkflowCompleted,
+	}, eventTypes(events))
+
+	for _, e := range events {
+		assert.Equal(t, "wf-1", e.Target[TargetKeyWorkflowID])
+		assert.Equal(t, "triage", e.Target[TargetKeyWorkflowName])
+		assert.Equal(t, "vmcp", e.Component)
+		assert.Equal(t, "alice", e.Subjects[SubjectKeyUser])
+	}
+
+	started := events[0]
+	assert.Equal(t, TargetTypeWorkflow, started.Target[TargetKeyType])
+	assert.EqualValues(t, 3, started.Metadata.Extra[MetadataExtraKeyStepCount])
+	assert.EqualValues(t, 60000, started.Metadata.Extra[MetadataExtraKeyTimeout])
+
+	stepDone := events[2]
+	assert.Equal(t, map[string]string{
+		TargetKeyType:         TargetTypeWorkflowStep,
+		TargetKeyWorkflowID:   "wf-1",
+		TargetKeyWorkflowName: "triage",
+		TargetKeyStepID:       "fetch",
+		TargetKeyStepType:     "tool",
+		TargetKeyToolName:     "github.get_issue",
+	}, stepDone.Target)
+	assert.EqualValues(t, 2, stepDone.Metadata.Extra[MetadataExtraKeyRetryCount])
+	assert.Contains(t, stepDone.Metadata.Extra, MetadataExtraKeyDuration)
+
+	stepFailed := events[4]
+	assert.Equal(t, OutcomeFailure, stepFailed.Outcome)
+	assert.JSONEq(t, `{"error":"rate limited"}`, string(*stepFailed.Data))
+
+	assert.JSONEq(t, `{"reason":"no assignee"}`, string(*events[5].Data))
+
+	done := events[6]
+	assert.Equal(t, OutcomeSuccess, done.Outcome)
+	assert.Nil(t, done.Data)
+	for key, want := range map[string]int{
+		MetadataExtraKeyStepCount:      3,
+		MetadataExtraKeyStepsCompleted: 1,
+		MetadataExtraKeyStepsFailed:    1,
+		MetadataExtraKeyStepsSkipped:   1,
+		MetadataExtraKeyRetryCount:     2,
+	} {
+		assert.EqualValues(t, want, done.Metadata.Extra[key], key)
+	}
+}
+
+func TestWorkflowAuditorFinish(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		run      func(ctx context.Context, w *WorkflowAuditor) error
+		cancel   bool
+		wantType string
+		wantData string
+	}{
+		{
+			name:     "success",
+			run:      func(context.Context, *WorkflowAuditor) error { return nil },
+			wantType: EventTypeWorkflowCompleted,
+		},
+		{
+			name:     "error",
+			run:      func(context.Context, *WorkflowAuditor) error { return errors.New("boom") },
+			wantType: EventTypeWorkflowFailed,
+			wantData: `{"error":"boom"}`,
+		},
+		{
+			name:     "deadline error",
+			run:      func(context.Context, *WorkflowAuditor) error { return context.DeadlineExceeded },
+			wantType: EventTypeWorkflowTimedOut,
+			wantData: `{"error":"context deadline exceeded"}`,
+		},
+		{
+			name: "already ended",
+			run: func(ctx context.Context, w *WorkflowAuditor) error {
+				_ = w.Fail(ctx, errors.New("first"))
+				return nil
+			},
+			wantType: EventTypeWorkflowFailed,
+			wantData: `{"error":"first"}`,
+		},
+		{
+			name:     "cancelled",
+			run:      func(context.Context, *WorkflowAuditor) error { return nil },
+			cancel:   true,
+			wantType: EventTypeWorkflowFailed,
+			wantData: `{"error":"context canceled"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sink := &lineSink{}
+			w := newTestWorkflow(ctx, t, sink)
+
+			_ = func() (err error) {
+				defer w.Finish(ctx, &err)
+				if tt.cancel {
+					cancel()
+				}
+				return tt.run(ctx, w)
+			}()
+
+			events := sink.events(t)
+			require.Len(t, events, 2)
+			last := events[1]
+			assert.Equal(t, tt.wantType, last.Type)
+			if tt.wantData == "" {
+				assert.Nil(t, last.Data)
+			} else {
+				assert.JSONEq(t, tt.wantData, string(*last.Data))
+			}
+		})
+	}
+}
+
+func TestWorkflowAuditorPanic(t *testing.T) {
+	t.Parallel()
+
+	sink := &lineSink{}
+	w := newTestWorkflow(context.Background(), t, sink)
+
+	assert.PanicsWithValue(t, "step exploded", func() {
+		defer w.Finish(context.Background(), nil)
+		panic("step exploded")
+	})
+
+	events := sink.events(t)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventTypeWorkflowFailed, events[1].Type)
+	assert.JSONEq(t, `{"error":"workflow panicked: step exploded"}`, string(*events[1].Data))
+}
+
+// TestWorkflowAuditorTimeout checks that the deadline alone emits the
+// terminal event, while a step is still running.
+func TestWorkflowAuditorTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	sink := &lineSink{}
+	w := newTestWorkflow(ctx, t, sink)
+	step, err := w.StartStep(ctx, WorkflowStep{ID: "slow"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(sink.events(t)) == 3 }, time.Second, 5*time.Millisecond)
+	timedOut := sink.events(t)[2]
+	assert.Equal(t, EventTypeWorkflowTimedOut, timedOut.Type)
+	assert.Equal(t, OutcomeFailure, timedOut.Outcome)
+	assert.EqualValues(t, 60000, timedOut.Metadata.Extra[MetadataExtraKeyTimeout])
+
+	// The step's own outcome is still recorded; the workflow's is not again.
+	require.NoError(t, step.Fail(ctx, ctx.Err()))
+	_ = func() (err error) {
+		defer w.Finish(ctx, &err)
+		return ctx.Err()
+	}()
+	late := sink.events(t)[3]
+	assert.Equal(t, EventTypeWorkflowStepFailed, late.Type)
+	assert.Equal(t, true, late.Metadata.Extra[MetadataExtraKeyAfterWorkflowEnd])
+	assert.Len(t, sink.events(t), 4)
+}
+
+// TestWorkflowAuditorLateSteps checks that step events after the terminal
+// event are marked, and not counted in totals that were already reported.
+func TestWorkflowAuditorLateSteps(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &lineSink{}
+	w := newTestWorkflow(ctx, t, sink)
+	running, err := w.StartStep(ctx, WorkflowStep{ID: "running"})
+	require.NoError(t, err)
+
+	cancel()
+	require.Eventually(t, func() bool { return len(sink.events(t)) == 3 }, time.Second, 5*time.Millisecond)
+	failed := sink.events(t)[2]
+	require.Equal(t, EventTypeWorkflowFailed, failed.Type)
+	assert.NotContains(t, failed.Metadata.Extra, MetadataExtraKeyAfterWorkflowEnd)
+
+	running.Retry()
+	require.NoError(t, running.Complete(ctx))
+	late, err := w.StartStep(ctx, WorkflowStep{ID: "late"})
+	require.NoError(t, err)
+	require.NoError(t, late.Fail(ctx, errors.New("too late")))
+	require.NoError(t, w.SkipStep(ctx, WorkflowStep{ID: "skipped"}, "cancelled"))
+
+	events := sink.events(t)
+	assert.Equal(t, []string{
+		EventTypeWorkflowStarted, EventTypeWorkflowStepStarted, EventTypeWorkflowFailed,
+		EventTypeWorkflowStepCompleted, EventTypeWorkflowStepStarted, EventTypeWorkflowStepFailed,
+		EventTypeWorkflowStepSkipped,
+	}, eventTypes(events))
+	for _, e := range events[3:] {
+		assert.Equal(t, true, e.Metadata.Extra[MetadataExtraKeyAfterWorkflowEnd], e.Type)
+	}
+	for _, e := range events[:3] {
+		assert.NotContains(t, e.Metadata.Extra, MetadataExtraKeyAfterWorkflowEnd, e.Type)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	assert.Equal(t, 1, w.steps)
+	assert.Zero(t, w.completed+w.failed+w.skipped+w.retries, "late steps leave the reported totals alone")
+}
+
+func TestNewWorkflowAuditorErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWorkflowAuditor(context.Background(), WorkflowConfig{})
+	assert.ErrorContains(t, err, "requires a sink")
+
+	_, err = NewWorkflowAuditor(context.Background(), WorkflowConfig{Sink: &lineSink{failures: 1}})
+	assert.ErrorContains(t, err, EventTypeWorkflowStarted)
+
+	w, err := NewWorkflowAuditor(context.Background(), WorkflowConfig{Sink: &lineSink{}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, w.WorkflowID())
+}
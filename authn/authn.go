@@ -17,6 +17,7 @@ import (
 
 	"github.com/lestrrat-go/httprc/v3"
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.opentelemetry.io/otel/metric"
 
 	"github.com/stacklok/toolhive-core/networking"
 	httpvalidation "github.com/stacklok/toolhive-core/validation/http"
@@ -64,6 +65,14 @@ const (
 	schemeHTTP  = "http"
 )
 
+// schemeBearer and schemeDPoP are the Authorization header auth-schemes of RFC
+// 6750 and RFC 9449, in their canonical spelling. Matching is case-insensitive
+// (RFC 7235 §2.1).
+const (
+	schemeBearer = "Bearer"
+	schemeDPoP   = "DPoP"
+)
+
 // Config holds the trusted issuer/audience policy and fetch behavior for a
 // Validator. A Config is validated eagerly by NewValidator so that a typo is
 // a startup failure, not a 401 on every request.
@@ -171,6 +180,19 @@ type Config struct {
 	// the two kinds of token apart.
 	AcceptedTokenTypes []string
 
+	// AllowedAlgs is the JWS signature-algorithm allow-list. A token whose alg
+	// is not listed is rejected before any key material is consulted (RFC 8725
+	// §3.1). Empty (the default) allows RS256/384/512, PS256/384/512 and
+	// ES256/384/512.
+	//
+	// Entries must be drawn from those algorithms plus EdDSA, which is accepted
+	// with Ed25519 keys only (RFC 8037) and must be listed explicitly. `none`
+	// and the HMAC algorithms can never be allowed: with a public JWKS, HMAC is
+	// the algorithm-confusion attack. Listing a subset is how a deployment pins
+	// the algorithms its issuer actually uses, so a key published for one
+	// family cannot be exercised through another.
+	AllowedAlgs []string
+
 	// MaxTokenLifetime rejects tokens whose exp-iat span exceeds it, when both
 	// claims are present. Negative is an error.
 	//
@@ -281,6 +303,34 @@ type Config struct {
 	// With no KeyProvider, construction stays fail-closed: discovery and the
 	// first JWKS fetch must both succeed or NewValidator returns an error.
 	KeyProvider KeyProvider
+
+	// KeyProviderOnly runs the validator on KeyProvider keys alone, for an
+	// air-gapped deployment that cannot reach the issuer at all: no discovery
+	// document is fetched, no JWKS cache is started, and NewValidator performs
+	// no network I/O. A token whose kid the provider does not offer is rejected
+	// with ReasonUnknownKID.
+	//
+	// It requires KeyProvider (typically a FileKeyProvider) and Issuer, which
+	// is still verified against the iss claim but never contacted. JWKSURL and
+	// MaxJWKSStaleness must be unset, since there is no JWKS for either to
+	// apply to.
+	KeyProviderOnly bool
+
+	// RevocationChecker, when set, is consulted for every token that passes
+	// all other checks, so individual tokens, sessions or subjects can be
+	// revoked before they expire (see RevocationChecker). Nil checks nothing.
+	//
+	// It complements MaxJWKSStaleness, which bounds how long a revoked KEY is
+	// honoured; revoking one token has no other mechanism short of
+	// introspection. Checker errors fail closed with CodeUnavailable.
+	RevocationChecker RevocationChecker
+
+	// Meter, when set, receives the validator's metrics: JWKS refresh
+	// outcomes and validation outcomes by Reason (see validatorMetrics). Nil
+	// records nothing. Several Validators may share one Meter — a
+	// MultiValidator's configs typically do — and then report into the same
+	// instruments.
+	Meter metric.Meter
 }
 
 // Validator verifies inbound JWT bearer tokens against the configured issuer
@@ -324,11 +374,21 @@ type Validator struct {
 	// keys off this. Guarded by refreshMu.
 	lastSuccess time.Time
 
+	// lastAttempt is when a JWKS fetch this package initiated was last
+	// attempted, including the construction fetch, which deliberately does
+	// not set lastRefresh (that would hold off unknown-kid recovery for
+	// refreshFloor after startup). It is reported by Status only. Guarded by
+	// refreshMu.
+	lastAttempt time.Time
+
 	// negativeMu guards negativeKids, the bounded negative cache of kids that
 	// resolved to no key after a refresh (suppresses repeat fetches for a kid
 	// already failed within negativeCacheTTL).
 	negativeMu   sync.Mutex
 	negativeKids map[string]time.Time
+
+	// metrics records refresh and validation outcomes on Config.Meter.
+	metrics validatorMetrics
 }
 
 // ValidateConfig validates cfg and returns a normalized copy with defaults
@@ -364,6 +424,7 @@ func ValidateConfig(cfg Config) (Config, error) {
 	// cloning first is equivalent to cloning after, and safer to keep that way.
 	cfg.Audiences = slices.Clone(cfg.Audiences)
 	cfg.AcceptedTokenTypes = slices.Clone(cfg.AcceptedTokenTypes)
+	cfg.AllowedAlgs = slices.Clone(cfg.AllowedAlgs)
 	if err := cfg.validate(); err != nil {
 		return Config{}, err
 	}
@@ -399,9 +460,15 @@ func NewValidator(ctx context.Context, cfg Config) (*Validator, error) {
 		return nil, err
 	}
 
+	metrics, err := newValidatorMetrics(cfg.Meter)
+	if err != nil {
+		return nil, err
+	}
+
 	v := &Validator{
 		cfg:        cfg,
 		httpClient: httpClient,
+		metrics:    metrics,
 	}
 	// Deriving the lifetime context before any construction I/O guarantees a
 	// validator that can fail construction never leaks refresh goroutines:
@@ -465,7 +532,11 @@ func (c *Config) validate() error {
 	// resolves the JWKS URL from, so with neither there is no key material to
 	// reach. An Issuer-less config is permitted (and skips iss verification) so
 	// a static-JWKS deployment can be expressed; see the Config.Issuer docs.
-	if c.Issuer == "" && c.JWKSURL == "" {
+	if c.KeyProviderOnly {
+		if err := c.validateKeyProviderOnly(); err != nil {
+			return err
+		}
+	} else if c.Issuer == "" && c.JWKSURL == "" {
 		return fmt.Errorf("authn: at least one of issuer or jwks_url is required")
 	}
 	if c.Issuer != "" {
@@ -505,6 +576,40 @@ func (c *Config) validate() error {
 		}
 	}
 
+	return c.validateAllowedAlgs()
+}
+
+// validateKeyProviderOnly checks the fields KeyProviderOnly constrains. The
+// issuer stays mandatory: with no JWKS endpoint to act as the trust boundary,
+// iss verification is the only thing tying a token to the intended issuer
+// rather than to anyone holding one of the provider's keys.
+func (c *Config) validateKeyProviderOnly() error {
+	switch {
+	case c.KeyProvider == nil:
+		return fmt.Errorf("authn: key_provider_only requires a key provider")
+	case c.Issuer == "":
+		return fmt.Errorf("authn: key_provider_only requires an issuer")
+	case c.JWKSURL != "":
+		return fmt.Errorf("authn: jwks_url cannot be combined with key_provider_only")
+	case c.MaxJWKSStaleness != 0:
+		return fmt.Errorf("authn: max JWKS staleness cannot be combined with key_provider_only")
+	}
+	return nil
+}
+
+// validateAllowedAlgs checks Config.AllowedAlgs against supportedAlgs and fills
+// in the default when it is empty. Entries are compared exactly: JWA names are
+// case-sensitive (RFC 7515 §4.1.1), so "rs256" is a typo, not RS256.
+func (c *Config) validateAllowedAlgs() error {
+	if len(c.AllowedAlgs) == 0 {
+		c.AllowedAlgs = slices.Clone(defaultAllowedAlgs)
+		return nil
+	}
+	for i, alg := range c.AllowedAlgs {
+		if !slices.Contains(supportedAlgs, alg) {
+			return fmt.Errorf("authn: allowed_algs[%d] %q is not a supported signature algorithm", i, alg)
+		}
+	}
 	return nil
 }
 
@@ -536,13 +641,20 @@ func (c *Config) validateLeeway() error {
 // gated behind AllowAnyAudience rather than being the accidental result of
 // leaving a field unset.
 func (c *Config) validateAudiences() error {
+	return validateAudiencePolicy(c.Audiences, c.AllowAnyAudience)
+}
+
+// validateAudiencePolicy is validateAudiences for any configuration carrying
+// the same Audiences/AllowAnyAudience pair, so every verifier in the package
+// applies one rule.
+func validateAudiencePolicy(audiences []string, allowAny bool) error {
 	switch {
-	case len(c.Audiences) == 0 && !c.AllowAnyAudience:
+	case len(audiences) == 0 && !allowAny:
 		return fmt.Errorf("authn: at least one audience is required (set AllowAnyAudience to disable audience verification)")
-	case len(c.Audiences) > 0 && c.AllowAnyAudience:
-		return fmt.Errorf("authn: AllowAnyAudience must not be set together with %d configured audience(s)", len(c.Audiences))
+	case len(audiences) > 0 && allowAny:
+		return fmt.Errorf("authn: AllowAnyAudience must not be set together with %d configured audience(s)", len(audiences))
 	}
-	for i, aud := range c.Audiences {
+	for i, aud := range audiences {
 		if err := validateAudience(aud); err != nil {
 			return fmt.Errorf("authn: audiences[%d]: %w", i, err)
 		}
@@ -614,6 +726,12 @@ func validateHTTPSURI(field, raw string, insecureAllowHTTP bool) error {
 // under a context derived from ctx with constructionTimeout; that bound must
 // NOT reach jwk.NewCache.
 func (v *Validator) init(ctx context.Context) error {
+	// A provider-only validator has no JWKS to resolve, cache or fetch. Leaving
+	// jwksCache nil is what routes a provider miss to ReasonUnknownKID.
+	if v.cfg.KeyProviderOnly {
+		return nil
+	}
+
 	// Resolve the JWKS URL first: either the explicit Config.JWKSURL or the
 	// jwks_uri discovered from the issuer's OIDC metadata.
 	v.jwksURL = v.cfg.JWKSURL
@@ -718,6 +836,7 @@ func (v *Validator) init(ctx context.Context) error {
 		}
 		return nil
 	}()
+	v.metrics.recordRefresh(fetchCtx, refreshErr)
 
 	// A successful construction fetch is the first freshness proof, regardless
 	// of whether a KeyProvider is set: staleness() has to reflect it, or
@@ -725,11 +844,12 @@ func (v *Validator) init(ctx context.Context) error {
 	// a validator constructed with a provider starts life reporting an
 	// infinite staleness that only an actual JWKS refresh clears. This must run
 	// BEFORE the KeyProvider early return below.
+	v.refreshMu.Lock()
+	v.lastAttempt = time.Now()
 	if refreshErr == nil {
-		v.refreshMu.Lock()
-		v.lastSuccess = time.Now()
-		v.refreshMu.Unlock()
+		v.lastSuccess = v.lastAttempt
 	}
+	v.refreshMu.Unlock()
 
 	// With a KeyProvider the first fetch is attempted but NOT required: an
 	// embedded issuer typically mounts its JWKS route on the very listener that
@@ -57,6 +57,44 @@ func TestNewValidatorConfigValidation(t *testing.T) {
 			mutate:  func(c *Config) { c.Issuer = ""; c.JWKSURL = "" },
 			wantErr: "at least one of issuer or jwks_url is required",
 		},
+		{
+			name:    "key_provider_only without a key provider rejected",
+			mutate:  func(c *Config) { c.KeyProviderOnly = true },
+			wantErr: "key_provider_only requires a key provider",
+		},
+		{
+			name: "key_provider_only without an issuer rejected",
+			mutate: func(c *Config) {
+				c.KeyProviderOnly, c.KeyProvider, c.Issuer = true, &fakeKeyProvider{}, ""
+			},
+			wantErr: "key_provider_only requires an issuer",
+		},
+		{
+			name: "key_provider_only with jwks_url rejected",
+			mutate: func(c *Config) {
+				c.KeyProviderOnly, c.KeyProvider = true, &fakeKeyProvider{}
+				c.JWKSURL = "https://issuer.example.com/jwks"
+			},
+			wantErr: "jwks_url cannot be combined with key_provider_only",
+		},
+		{
+			name: "key_provider_only with max JWKS staleness rejected",
+			mutate: func(c *Config) {
+				c.KeyProviderOnly, c.KeyProvider = true, &fakeKeyProvider{}
+				c.MaxJWKSStaleness = time.Hour
+			},
+			wantErr: "max JWKS staleness cannot be combined with key_provider_only",
+		},
+		{
+			// The issuer host does not resolve: construction succeeding proves
+			// no discovery or JWKS fetch was attempted.
+			name:   "key_provider_only constructs without network I/O",
+			mutate: func(c *Config) { c.KeyProviderOnly, c.KeyProvider = true, &fakeKeyProvider{} },
+			check: func(t *testing.T, cfg Config) {
+				t.Helper()
+				assert.Empty(t, cfg.JWKSURL)
+			},
+		},
 		{
 			name:    "issuer non-https rejected",
 			mutate:  func(c *Config) { c.Issuer = "http://issuer.example.com" },
@@ -199,6 +237,16 @@ func TestNewValidatorConfigValidation(t *testing.T) {
 			mutate:  func(c *Config) { c.MaxTokenLifetime = -time.Hour },
 			wantErr: "negative",
 		},
+		{
+			name:    "HMAC alg cannot be allowed",
+			mutate:  func(c *Config) { c.AllowedAlgs = []string{"RS256", "HS256"} },
+			wantErr: `allowed_algs[1] "HS256"`,
+		},
+		{
+			name:    "alg names are case-sensitive",
+			mutate:  func(c *Config) { c.AllowedAlgs = []string{"rs256"} },
+			wantErr: `allowed_algs[0] "rs256"`,
+		},
 		{
 			name: "defaults applied",
 			mutate: func(c *Config) {
@@ -212,6 +260,8 @@ func TestNewValidatorConfigValidation(t *testing.T) {
 			check: func(t *testing.T, cfg Config) {
 				t.Helper()
 				assert.Equal(t, defaultLeeway, cfg.Leeway)
+				assert.Equal(t, defaultAllowedAlgs, cfg.AllowedAlgs)
+				assert.NotContains(t, cfg.AllowedAlgs, algEdDSA, "EdDSA must be opted into")
 				// MaxTokenLifetime is deliberately NOT defaulted: zero means
 				// "no lifetime bound", so adopting this package cannot start
 				// rejecting long-lived tokens a resource server accepts today.
@@ -332,17 +382,20 @@ func TestValidateConfigNormalizesWithoutTouchingTheCaller(t *testing.T) {
 		t.Parallel()
 		cfg := validConfig()
 		cfg.AcceptedTokenTypes = []string{"at+jwt"}
+		cfg.AllowedAlgs = []string{"ES256"}
 		got, err := ValidateConfig(cfg)
 		require.NoError(t, err)
 
-		// Rewrite both slices through the caller's own headers. Without the clone
+		// Rewrite every slice through the caller's own headers. Without the clone
 		// these writes would land in the returned Config's backing arrays and
 		// silently redefine the trusted policy.
 		cfg.Audiences[0] = "https://attacker.example.com"
 		cfg.AcceptedTokenTypes[0] = idTokenJWT
+		cfg.AllowedAlgs[0] = "HS256"
 
 		assert.Equal(t, []string{"https://api.example.com"}, got.Audiences)
 		assert.Equal(t, []string{"at+jwt"}, got.AcceptedTokenTypes)
+		assert.Equal(t, []string{"ES256"}, got.AllowedAlgs)
 	})
 }
 
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/stacklok/toolhive-core/networking"
+)
+
+const (
+	// clientAssertionLifetime is the exp-iat span of a private_key_jwt client
+	// assertion. It only has to survive one round trip to the endpoint.
+	clientAssertionLifetime = time.Minute
+	// clientAssertionType is the RFC 7523 §2.2 client_assertion_type value.
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// ClientAuthMethod is how this package authenticates itself to an
+// authorization server endpoint it calls with its own client credentials —
+// an Introspector to the introspection endpoint, a TokenExchanger to the
+// token endpoint — named by its RFC 7591 token_endpoint_auth_method value.
+type ClientAuthMethod string
+
+const (
+	// ClientAuthClientSecretBasic sends the client credentials in an HTTP
+	// Basic Authorization header (RFC 6749 §2.3.1). It is the default.
+	ClientAuthClientSecretBasic ClientAuthMethod = "client_secret_basic"
+	// ClientAuthClientSecretPost sends the client credentials as form
+	// parameters in the request body (RFC 6749 §2.3.1).
+	ClientAuthClientSecretPost ClientAuthMethod = "client_secret_post"
+	// ClientAuthPrivateKeyJWT sends a client assertion JWT signed with the
+	// config's ClientAssertionKey (RFC 7523 §2.2, OIDC Core §9).
+	ClientAuthPrivateKeyJWT ClientAuthMethod = "private_key_jwt"
+)
+
+// clientAuth authenticates this package to an authorization server endpoint
+// it calls with its own client credentials: the introspection endpoint, and
+// the token endpoint for token exchange. Both take the same three methods.
+type clientAuth struct {
+	method   ClientAuthMethod
+	clientID string
+	secret   string
+	key      any
+	keyID    string
+	// audience is the aud of a private_key_jwt assertion: the endpoint it is
+	// sent to.
+	audience string
+}
+
+// validateClientAuth checks that the credentials required by method, and only
+// those, are present, defaulting an empty method to client_secret_basic. what
+// names the endpoint in errors.
+func validateClientAuth(what string, method *ClientAuthMethod, secret string, key any) error {
+	if *method == "" {
+		*method = ClientAuthClientSecretBasic
+	}
+	switch *method {
+	case ClientAuthClientSecretBasic, ClientAuthClientSecretPost:
+		if secret == "" {
+			return fmt.Errorf("authn: %s auth method %s requires a client secret", what, *method)
+		}
+	case ClientAuthPrivateKeyJWT:
+		if secret != "" {
+			return fmt.Errorf("authn: %s auth method %s must not be given a client secret", what, *method)
+		}
+		if key == nil {
+			return fmt.Errorf("authn: %s auth method %s requires a client assertion key", what, *method)
+		}
+		if _, err := clientAssertionMethod(key); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("authn: unsupported %s auth method %q", what, *method)
+	}
+	return nil
+}
+
+// clientAssertionMethod selects the JWS algorithm for a client assertion key,
+// rejecting key types and sizes the inbound allow-list would reject too.
+func clientAssertionMethod(key any) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("authn: client assertion RSA key is %d bits, below the %d-bit minimum",
+				k.N.BitLen(), minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("authn: client assertion ECDSA key uses an unsupported curve")
+	default:
+		return nil, fmt.Errorf("authn: unsupported client assertion key type %T", key)
+	}
+}
+
+// apply adds the client credentials to a request: as form parameters for
+// client_secret_post and private_key_jwt, or as the returned Authorization
+// header option for client_secret_basic.
+func (a clientAuth) apply(form url.Values) ([]networking.FetchOption, error) {
+	switch a.method {
+	case ClientAuthClientSecretBasic:
+		// RFC 6749 §2.3.1: the id and secret are form-encoded BEFORE being
+		// joined for Basic, so a colon in either cannot shift the split.
+		creds := url.QueryEscape(a.clientID) + ":" + url.QueryEscape(a.secret)
+		return []networking.FetchOption{networking.WithHeader("Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))}, nil
+	case ClientAuthClientSecretPost:
+		form.Set("client_id", a.clientID)
+		form.Set("client_secret", a.secret)
+	case ClientAuthPrivateKeyJWT:
+		assertion, err := a.assertion()
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_id", a.clientID)
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	}
+	return nil, nil
+}
+
+// assertion signs a fresh private_key_jwt assertion. The audience is the
+// endpoint itself and jti is random, so an assertion captured in transit is
+// useless anywhere else and, at an endpoint that tracks jti, even there.
+func (a clientAuth) assertion() (string, error) {
+	method, err := clientAssertionMethod(a.key)
+	if err != nil {
+		return "", err
+	}
+	var jti [16]byte
+	if _, err := rand.Read(jti[:]); err != nil {
+		return "", fmt.Errorf("failed to generate client assertion jti: %w", err)
+	}
+	now := time.Now()
+	tok := jwt.NewWithClaims(method, jwt.MapClaims{
+		"iss": a.clientID,
+		"sub": a.clientID,
+		"aud": a.audience,
+		"jti": hex.EncodeToString(jti[:]),
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	})
+	if a.keyID != "" {
+		tok.Header["kid"] = a.keyID
+	}
+	signed, err := tok.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign client assertion: %w", err)
+	}
+	return signed, nil
+}
@@ -3,13 +3,63 @@
 
 // Package authn validates INBOUND JWT bearer tokens at a resource server.
 //
-// It performs NO OAuth flows and issues nothing: there is no token endpoint,
-// no authorization-code exchange, and no signing of tokens. The package only
-// verifies bearer tokens presented by clients against configured keys and
-// trusted issuer/audience policy.
+// It issues nothing and runs no user-facing OAuth flows: there is no token
+// endpoint, no authorization-code exchange, and no signing of access tokens.
+// The package verifies bearer tokens presented by clients against configured
+// keys and trusted issuer/audience policy. The one token it obtains is for its
+// own onward calls: TokenExchanger trades a verified caller's token for a
+// downstream one by RFC 8693 token exchange.
 //
 // Failures from Validate and ParseBearer are expressed as *Error (see
 // errors.go), which carries a client-safe Code and Reason alongside a detail
 // string intended for logging. NewValidator, by contrast, returns ordinary
 // construction errors.
+//
+// Validator.Status reports the key material a validator currently holds and
+// when it last fetched it, for health endpoints. Config.Meter records JWKS
+// refresh outcomes and validation outcomes by Reason as OpenTelemetry metrics.
+//
+// An air-gapped deployment that cannot reach its issuer loads keys from disk
+// instead: FileKeyProvider reads a JWKS or PEM file, or a directory of them,
+// and polls it for rotation, and Config.KeyProviderOnly runs a Validator on
+// those keys with no discovery or JWKS fetch at all.
+//
+// Config.RevocationChecker revokes individual tokens, sessions or subjects
+// before they expire, by jti, sid or sub. MemoryRevocationStore holds
+// revocations in process and RedisRevocationStore shares them between
+// replicas; SSFReceiver fills either from OpenID Shared Signals
+// session-revoked and credential-change events pushed by the IdP.
+//
+// A resource server trusting more than one issuer builds a MultiValidator
+// from one Config per issuer; it routes each token by its unverified iss and
+// rejects an unknown issuer before any key material is fetched.
+//
+// Middleware joins the two halves for an HTTP resource server: it runs
+// ParseBearer and Validate per request, answers failures with RFC 6750
+// WWW-Authenticate challenges that point at the resource's RFC 9728 metadata
+// document, and places the verified Principal in the request context
+// (PrincipalFromContext). NewProtectedResourceHandler serves that document;
+// FetchProtectedResourceMetadata is the matching client-side discovery step.
+// Options add a realm and error_description to challenges, let credential-less
+// requests to chosen routes through anonymously, and report every decision to
+// an AuditHook as an audit.AuditEvent.
+//
+// For issuers that hand out opaque access tokens, Introspector validates a
+// token with an RFC 7662 introspection endpoint instead and returns the same
+// Principal. IntrospectionFallback composes the two so that only a token that
+// is not a JWT at all is ever sent to the endpoint.
+//
+// Policy authorizes a verified Principal against required scopes, required
+// claim values and CEL expressions; EnforcePolicy attaches one to any
+// TokenValidator so Middleware answers a shortfall with an RFC 6750
+// insufficient_scope challenge.
+//
+// Sender-constrained tokens are opt-in on Middleware: WithDPoP admits RFC 9449
+// DPoP-bound tokens and checks their proofs with a DPoPVerifier, and
+// WithCertificateBoundTokens enforces RFC 8705 client-certificate binding.
+//
+// A gateway calling a backend on a user's behalf uses TokenExchanger to obtain
+// a token for that backend, with an act claim naming the gateway when it
+// passes its own ActorToken. Issued tokens are cached per subject and target
+// until shortly before they expire.
 package authn
@@ -0,0 +1,393 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/stacklok/toolhive-core/networking"
+)
+
+const (
+	// dpopHeader is the request header carrying the DPoP proof (RFC 9449 §4.1).
+	dpopHeader = "DPoP"
+	// dpopProofType is the required typ header of a DPoP proof (RFC 9449 §4.2).
+	dpopProofType = "dpop+jwt"
+	// defaultDPoPMaxProofAge is the accepted distance between a proof's iat
+	// and now when DPoPConfig.MaxProofAge is zero.
+	defaultDPoPMaxProofAge = time.Minute
+	// maxDPoPMaxProofAge bounds DPoPConfig.MaxProofAge. The replay cache must
+	// hold every jti for this long, so the window is also a memory bound.
+	maxDPoPMaxProofAge = 5 * time.Minute
+	// defaultDPoPReplayCacheSize is the number of proof identifiers remembered
+	// when DPoPConfig.ReplayCacheSize is zero.
+	defaultDPoPReplayCacheSize = 100_000
+	// maxDPoPJTILength bounds a proof's jti. RFC 9449 asks for at least 96
+	// bits of randomness; 256 bytes is far above any real value.
+	maxDPoPJTILength = 256
+)
+
+// DPoPConfig configures a DPoPVerifier.
+type DPoPConfig struct {
+	// Required rejects access tokens that are not DPoP-bound. Unset, a plain
+	// bearer token is still accepted alongside DPoP-bound ones, which is how a
+	// deployment migrates clients one at a time.
+	//
+	// Either way a DPoP-bound token (one carrying cnf.jkt) is never accepted
+	// under the Bearer scheme: RFC 9449 §7.2 forbids that downgrade, since it
+	// would make the binding optional for whoever stole the token.
+	Required bool
+
+	// AllowedAlgs restricts the proof signature algorithms. Empty means the
+	// same default Config.AllowedAlgs has (RS/PS/ES); EdDSA may be listed
+	// explicitly, and an entry this package cannot verify is an error. The
+	// list is advertised in the algs challenge parameter.
+	AllowedAlgs []string
+
+	// MaxProofAge bounds how far a proof's iat may be from now, in either
+	// direction, so it covers clock skew as well as proof lifetime. Zero uses
+	// one minute; negative or above five minutes is an error.
+	MaxProofAge time.Duration
+
+	// ReplayCacheSize bounds the number of proof identifiers remembered to
+	// reject a replayed proof. Zero uses 100,000; negative is an error.
+	//
+	// The cache is per process and evicts least-recently-used entries when
+	// full, so a proof evicted before its iat leaves the MaxProofAge window
+	// could be replayed once. Size it above the request rate multiplied by
+	// twice MaxProofAge, and note that replicas do not share it.
+	ReplayCacheSize int
+
+	// PublicOrigin, when set, is the scheme://host[:port] clients use to reach
+	// this server, for comparing a proof's htu behind a TLS-terminating proxy.
+	// Empty derives the origin from the request: https when it arrived over
+	// TLS, http otherwise, and the Host header.
+	PublicOrigin string
+}
+
+// DPoPVerifier checks RFC 9449 DPoP proofs and binds DPoP access tokens to
+// them. Build one with NewDPoPVerifier and attach it to Middleware with
+// WithDPoP; it is safe for concurrent use.
+type DPoPVerifier struct {
+	cfg    DPoPConfig
+	origin *url.URL
+	// seen remembers proof identifiers, keyed by key thumbprint and jti, until
+	// the proof's iat leaves the MaxProofAge window.
+	seen *lruCache[string, struct{}]
+}
+
+// NewDPoPVerifier validates cfg and returns a DPoPVerifier. Errors are
+// ordinary construction errors, not *Error.
+func NewDPoPVerifier(cfg DPoPConfig) (*DPoPVerifier, error) {
+	cfg.AllowedAlgs = slices.Clone(cfg.AllowedAlgs)
+	if len(cfg.AllowedAlgs) == 0 {
+		cfg.AllowedAlgs = slices.Clone(defaultAllowedAlgs)
+	}
+	for i, alg := range cfg.AllowedAlgs {
+		if !slices.Contains(supportedAlgs, alg) {
+			return nil, fmt.Errorf("authn: dpop allowed_algs[%d] %q is not a supported algorithm", i, alg)
+		}
+	}
+	switch {
+	case cfg.MaxProofAge < 0:
+		return nil, fmt.Errorf("authn: dpop max proof age must not be negative: %s", cfg.MaxProofAge)
+	case cfg.MaxProofAge == 0:
+		cfg.MaxProofAge = defaultDPoPMaxProofAge
+	case cfg.MaxProofAge > maxDPoPMaxProofAge:
+		return nil, fmt.Errorf("authn: dpop max proof age %s exceeds maximum %s", cfg.MaxProofAge, maxDPoPMaxProofAge)
+	}
+	switch {
+	case cfg.ReplayCacheSize < 0:
+		return nil, fmt.Errorf("authn: dpop replay cache size must not be negative: %d", cfg.ReplayCacheSize)
+	case cfg.ReplayCacheSize == 0:
+		cfg.ReplayCacheSize = defaultDPoPReplayCacheSize
+	}
+	d := &DPoPVerifier{cfg: cfg, seen: newLRUCache[string, struct{}](cfg.ReplayCacheSize)}
+	if cfg.PublicOrigin != "" {
+		origin, err := parsePublicOrigin(cfg.PublicOrigin)
+		if err != nil {
+			return nil, err
+		}
+		d.origin = origin
+	}
+	return d, nil
+}
+
+// parsePublicOrigin checks that origin is a bare scheme://host[:port], held to
+// the same https-or-localhost rule as an issuer.
+func parsePublicOrigin(origin string) (*url.URL, error) {
+	if err := networking.ValidateIssuerURL(origin); err != nil {
+		return nil, fmt.Errorf("authn: dpop public origin: %w", err)
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return nil, fmt.Errorf("authn: dpop public origin: %w", err)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("authn: dpop public origin must be scheme://host[:port] only: %s", origin)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// Verify checks the DPoP proof on r for accessToken, which was presented under
+// the DPoP scheme and verified as p (RFC 9449 §4.3 and §7.1):
+//
+//   - exactly one DPoP header carrying a JWT with typ dpop+jwt, an allowed
+//     alg, and an embedded public jwk that verifies its signature.
+//   - htm equal to the request method, and htu equal to the request URI
+//     ignoring query and fragment.
+//   - iat within MaxProofAge of now, and ath equal to the hash of
+//     accessToken.
+//   - the jwk's thumbprint equal to the token's cnf.jkt.
+//   - a jti not seen before within the window.
+//
+// A defective proof fails with CodeInvalidDPoPProof and ReasonDPoPProof (or
+// ReasonDPoPReplay for a reused one); a token not bound to the proof's key
+// fails with CodeInvalidToken and ReasonDPoPBinding. The replay check runs
+// last, so a proof rejected for any other reason does not consume its jti.
+func (d *DPoPVerifier) Verify(r *http.Request, accessToken string, p Principal) error {
+	proofs := r.Header.Values(dpopHeader)
+	if len(proofs) != 1 {
+		return &Error{Code: CodeInvalidDPoPProof, Reason: ReasonDPoPProof,
+			err: fmt.Errorf("expected exactly one DPoP header, got %d", len(proofs))}
+	}
+	if len(proofs[0]) > maxTokenLength {
+		return &Error{Code: CodeInvalidDPoPProof, Reason: ReasonDPoPProof,
+			err: fmt.Errorf("proof length %d exceeds %d byte limit", len(proofs[0]), maxTokenLength)}
+	}
+
+	var proofKey jwk.Key
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(d.cfg.AllowedAlgs), jwt.WithStrictDecoding())
+	_, err := parser.ParseWithClaims(proofs[0], claims, func(tok *jwt.Token) (any, error) {
+		key, raw, err := proofVerificationKey(tok)
+		proofKey = key
+		return raw, err
+	})
+	if err != nil {
+		return &Error{Code: CodeInvalidDPoPProof, Reason: ReasonDPoPProof, err: err}
+	}
+
+	jti, iat, err := d.checkProofClaims(r, accessToken, claims)
+	if err != nil {
+		return err
+	}
+
+	thumbprint, err := proofKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return &Error{Code: CodeInvalidDPoPProof, Reason: ReasonDPoPProof,
+			err: fmt.Errorf("failed to compute proof key thumbprint: %w", err)}
+	}
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+	bound, ok := confirmation(p, "jkt")
+	if !ok {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonDPoPBinding,
+			err: errors.New("token presented under the DPoP scheme is not DPoP-bound (no cnf.jkt)")}
+	}
+	if subtle.ConstantTimeCompare([]byte(bound), []byte(jkt)) != 1 {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonDPoPBinding,
+			err: errors.New("proof key thumbprint does not match the token's cnf.jkt")}
+	}
+
+	now := time.Now()
+	if !d.seen.addIfAbsent(jkt+"|"+jti, struct{}{}, iat.Add(d.cfg.MaxProofAge), now) {
+		return &Error{Code: CodeInvalidDPoPProof, Reason: ReasonDPoPReplay,
+			err: errors.New("proof jti has already been used")}
+	}
+	return nil
+}
+
+// proofVerificationKey is the keyfunc for a DPoP proof: it checks the proof's
+// protected header and returns the embedded public key, both as a jwk.Key (for
+// the thumbprint) and as the crypto key golang-jwt verifies with.
+func proofVerificationKey(tok *jwt.Token) (jwk.Key, any, error) {
+	if typ, _ := tok.Header["typ"].(string); !strings.EqualFold(typ, dpopProofType) {
+		return nil, nil, fmt.Errorf("proof typ must be %s", dpopProofType)
+	}
+	if _, hasCrit := tok.Header["crit"]; hasCrit {
+		return nil, nil, errors.New("proof header carries unsupported crit member")
+	}
+	rawJWK, ok := tok.Header["jwk"].(map[string]any)
+	if !ok {
+		return nil, nil, errors.New("proof header has no jwk object")
+	}
+	encoded, err := json.Marshal(rawJWK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode proof jwk: %w", err)
+	}
+	key, err := jwk.ParseKey(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse proof jwk: %w", err)
+	}
+	// RFC 9449 §4.3 check 6: the header must carry a PUBLIC key. A client
+	// that put its private key there has disclosed it, and accepting the
+	// proof would only encourage that.
+	if private, err := jwk.IsPrivateKey(key); err != nil || private {
+		return nil, nil, errors.New("proof jwk must be a public asymmetric key")
+	}
+	alg := tok.Method.Alg()
+	if why := keyTypeMatchesAlg(key, alg); why != rejectNone {
+		return nil, nil, fmt.Errorf("proof jwk is unusable for %s: %s", alg, why)
+	}
+	raw, err := exportKey(key, alg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to export proof jwk: %w", err)
+	}
+	return key, raw, nil
+}
+
+// checkProofClaims checks the proof's jti, htm, htu, iat and ath, returning
+// the jti and iat for the replay cache.
+func (d *DPoPVerifier) checkProofClaims(r *http.Request, accessToken string, claims jwt.MapClaims) (
+	string, time.Time, error,
+) {
+	fail := func(format string, args ...any) (string, time.Time, error) {
+		return "", time.Time{}, &Error{Code: CodeInvalidDPoPProof, Reason: ReasonDPoPProof,
+			err: fmt.Errorf(format, args...)}
+	}
+
+	jti := stringClaim(claims, "jti")
+	if jti == "" || len(jti) > maxDPoPJTILength {
+		return fail("proof jti must be a non-empty string of at most %d bytes", maxDPoPJTILength)
+	}
+	if htm := stringClaim(claims, "htm"); htm != r.Method {
+		return fail("proof htm does not match the request method")
+	}
+	htu, err := url.Parse(stringClaim(claims, "htu"))
+	if err != nil || !sameHTTPURI(htu, d.requestURI(r)) {
+		return fail("proof htu does not match the request URI")
+	}
+	iatClaim, err := claims.GetIssuedAt()
+	if err != nil || iatClaim == nil {
+		return fail("proof iat is missing or not a number")
+	}
+	iat := iatClaim.Time
+	if age := time.Since(iat); age > d.cfg.MaxProofAge || age < -d.cfg.MaxProofAge {
+		return fail("proof iat is outside the %s acceptance window", d.cfg.MaxProofAge)
+	}
+	want := sha256.Sum256([]byte(accessToken))
+	ath := stringClaim(claims, "ath")
+	if subtle.ConstantTimeCompare([]byte(ath), []byte(base64.RawURLEncoding.EncodeToString(want[:]))) != 1 {
+		return fail("proof ath does not match the access token")
+	}
+	return jti, iat, nil
+}
+
+// requestURI reconstructs the absolute URI r was sent to, without query or
+// fragment, for comparison with a proof's htu.
+func (d *DPoPVerifier) requestURI(r *http.Request) *url.URL {
+	u := &url.URL{Scheme: schemeHTTP, Host: r.Host, Path: r.URL.Path, RawPath: r.URL.RawPath}
+	if r.TLS != nil {
+		u.Scheme = schemeHTTPS
+	}
+	if d.origin != nil {
+		u.Scheme, u.Host = d.origin.Scheme, d.origin.Host
+	}
+	return u
+}
+
+// sameHTTPURI compares two http(s) URIs the way RFC 9449 §4.3 check 9 asks:
+// query and fragment are ignored, scheme and host compare case-insensitively,
+// a default port equals its absence, and an empty path equals "/". The path is
+// otherwise compared exactly, in its escaped form.
+func sameHTTPURI(a, b *url.URL) bool {
+	if !strings.EqualFold(a.Scheme, b.Scheme) {
+		return false
+	}
+	if normalizedHost(a) != normalizedHost(b) {
+		return false
+	}
+	pathA, pathB := a.EscapedPath(), b.EscapedPath()
+	if pathA == "" {
+		pathA = "/"
+	}
+	if pathB == "" {
+		pathB = "/"
+	}
+	return pathA == pathB
+}
+
+// normalizedHost lowercases u's host and drops a port that is the default for
+// its scheme.
+func normalizedHost(u *url.URL) string {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme == schemeHTTPS && port == "443") || (scheme == schemeHTTP && port == "80") {
+		port = ""
+	}
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// checkScheme enforces the scheme rules that apply whether or not a proof was
+// presented: a DPoP-bound token must not arrive under Bearer (RFC 9449 §7.2),
+// and with Required set, neither may any other token.
+func (d *DPoPVerifier) checkScheme(scheme string, p Principal) error {
+	if scheme == schemeDPoP {
+		return nil
+	}
+	if _, bound := confirmation(p, "jkt"); bound {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonDPoPBinding,
+			err: errors.New("DPoP-bound token presented under the Bearer scheme")}
+	}
+	if d.cfg.Required {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonDPoPBinding,
+			err: errors.New("a DPoP-bound token is required")}
+	}
+	return nil
+}
+
+// VerifyCertificateBinding checks an RFC 8705 certificate-bound token against
+// the client certificate of the connection it arrived on.
+//
+// A token without cnf.x5t#S256 is not certificate-bound and passes. A bound
+// token passes only when state carries a client certificate whose SHA-256
+// thumbprint equals the confirmation; otherwise it fails with
+// CodeInvalidToken and ReasonCertificateBinding. Only the leaf certificate is
+// compared, and chain validation is the TLS layer's job: the binding proves
+// possession, not trust.
+func VerifyCertificateBinding(p Principal, state *tls.ConnectionState) error {
+	bound, ok := confirmation(p, "x5t#S256")
+	if !ok {
+		return nil
+	}
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonCertificateBinding,
+			err: errors.New("certificate-bound token presented without a client certificate")}
+	}
+	sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+	if subtle.ConstantTimeCompare([]byte(bound), []byte(base64.RawURLEncoding.EncodeToString(sum[:]))) != 1 {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonCertificateBinding,
+			err: errors.New("client certificate thumbprint does not match the token's cnf.x5t#S256")}
+	}
+	return nil
+}
+
+// confirmation returns the named member of the token's cnf claim (RFC 7800),
+// reporting whether it is present as a non-empty string.
+func confirmation(p Principal, member string) (string, bool) {
+	cnf, ok := p.Claims["cnf"].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	v, ok := cnf[member].(string)
+	return v, ok && v != ""
+}
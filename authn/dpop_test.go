@@ -0,0 +1,445 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// testDPoPURL is where httptest.NewRequest sends a request for /mcp.
+	testDPoPURL = "http://example.com/mcp"
+	testDPoPJTI = "proof-1"
+)
+
+// dpopClient is a DPoP key pair plus the thumbprint a bound token carries.
+type dpopClient struct {
+	key ecPair
+	jkt string
+}
+
+func newDPoPClient(t *testing.T) dpopClient {
+	t.Helper()
+	key := mintEC(t, "")
+	require.NoError(t, key.jwk.Remove(jwk.KeyIDKey))
+	sum, err := key.jwk.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	return dpopClient{key: key, jkt: base64.RawURLEncoding.EncodeToString(sum)}
+}
+
+// proof signs a DPoP proof for a POST to testDPoPURL carrying testToken,
+// after applying mutate to its claims and header.
+func (c dpopClient) proof(t *testing.T, mutate func(claims jwt.MapClaims, header map[string]any)) string {
+	t.Helper()
+	encoded, err := json.Marshal(c.key.jwk)
+	require.NoError(t, err)
+	var pub map[string]any
+	require.NoError(t, json.Unmarshal(encoded, &pub))
+
+	ath := sha256.Sum256([]byte(testToken))
+	claims := jwt.MapClaims{
+		"jti": testDPoPJTI,
+		"htm": http.MethodPost,
+		"htu": testDPoPURL,
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(ath[:]),
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	tok.Header["typ"] = dpopProofType
+	tok.Header["jwk"] = pub
+	if mutate != nil {
+		mutate(claims, tok.Header)
+	}
+	signed, err := tok.SignedString(c.key.priv)
+	require.NoError(t, err)
+	return signed
+}
+
+// boundPrincipal is a verified Principal whose token is bound to jkt.
+func boundPrincipal(jkt string) Principal {
+	return Principal{Subject: testSubject, Claims: map[string]any{"cnf": map[string]any{"jkt": jkt}}}
+}
+
+func dpopRequest(proofs ...string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	for _, p := range proofs {
+		req.Header.Add(dpopHeader, p)
+	}
+	return req
+}
+
+func newTestDPoPVerifier(t *testing.T, cfg DPoPConfig) *DPoPVerifier {
+	t.Helper()
+	d, err := NewDPoPVerifier(cfg)
+	require.NoError(t, err)
+	return d
+}
+
+func TestDPoPVerify(t *testing.T) {
+	t.Parallel()
+
+	client := newDPoPClient(t)
+	other := newDPoPClient(t)
+
+	tests := []struct {
+		name       string
+		req        func(t *testing.T) *http.Request
+		principal  Principal
+		wantCode   Code
+		wantReason Reason
+	}{
+		{
+			name:      "valid proof",
+			req:       func(t *testing.T) *http.Request { return dpopRequest(client.proof(t, nil)) },
+			principal: boundPrincipal(client.jkt),
+		},
+		{
+			name: "htu ignores query and default port",
+			req: func(t *testing.T) *http.Request {
+				req := dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) {
+					c["htu"] = "HTTP://Example.com:80/mcp?ignored=1"
+				}))
+				req.URL.RawQuery = "x=1"
+				return req
+			},
+			principal: boundPrincipal(client.jkt),
+		},
+		{
+			name:       "no proof",
+			req:        func(*testing.T) *http.Request { return dpopRequest() },
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "two proofs",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, nil), client.proof(t, nil))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "wrong typ",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(_ jwt.MapClaims, h map[string]any) { h["typ"] = "JWT" }))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "private jwk in header",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(_ jwt.MapClaims, h map[string]any) {
+					priv, err := jwk.Import(client.key.priv)
+					require.NoError(t, err)
+					encoded, err := json.Marshal(priv)
+					require.NoError(t, err)
+					var m map[string]any
+					require.NoError(t, json.Unmarshal(encoded, &m))
+					h["jwk"] = m
+				}))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "wrong htm",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) { c["htm"] = http.MethodGet }))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "wrong htu path",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) {
+					c["htu"] = "http://example.com/other"
+				}))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "wrong htu scheme",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) {
+					c["htu"] = "https://example.com/mcp"
+				}))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "stale iat",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) {
+					c["iat"] = time.Now().Add(-2 * time.Minute).Unix()
+				}))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "future iat",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) {
+					c["iat"] = time.Now().Add(2 * time.Minute).Unix()
+				}))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "ath for another token",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) { c["ath"] = "bogus" }))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name: "missing jti",
+			req: func(t *testing.T) *http.Request {
+				return dpopRequest(client.proof(t, func(c jwt.MapClaims, _ map[string]any) { delete(c, "jti") }))
+			},
+			principal:  boundPrincipal(client.jkt),
+			wantCode:   CodeInvalidDPoPProof,
+			wantReason: ReasonDPoPProof,
+		},
+		{
+			name:       "token bound to another key",
+			req:        func(t *testing.T) *http.Request { return dpopRequest(client.proof(t, nil)) },
+			principal:  boundPrincipal(other.jkt),
+			wantCode:   CodeInvalidToken,
+			wantReason: ReasonDPoPBinding,
+		},
+		{
+			name:       "unbound token under DPoP",
+			req:        func(t *testing.T) *http.Request { return dpopRequest(client.proof(t, nil)) },
+			principal:  Principal{Subject: testSubject, Claims: map[string]any{}},
+			wantCode:   CodeInvalidToken,
+			wantReason: ReasonDPoPBinding,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			// A fresh verifier per case so the shared jti never replays.
+			d := newTestDPoPVerifier(t, DPoPConfig{})
+			err := d.Verify(tc.req(t), testToken, tc.principal)
+			if tc.wantCode == "" {
+				require.NoError(t, err)
+				return
+			}
+			requireAuthnError(t, err, tc.wantCode, tc.wantReason)
+		})
+	}
+}
+
+func TestDPoPReplay(t *testing.T) {
+	t.Parallel()
+
+	client := newDPoPClient(t)
+	d := newTestDPoPVerifier(t, DPoPConfig{})
+	proof := client.proof(t, nil)
+
+	require.NoError(t, d.Verify(dpopRequest(proof), testToken, boundPrincipal(client.jkt)))
+	err := d.Verify(dpopRequest(proof), testToken, boundPrincipal(client.jkt))
+	requireAuthnError(t, err, CodeInvalidDPoPProof, ReasonDPoPReplay)
+
+	// A proof rejected for another reason does not consume its jti.
+	d = newTestDPoPVerifier(t, DPoPConfig{})
+	err = d.Verify(dpopRequest(proof), "another-token", boundPrincipal(client.jkt))
+	requireAuthnError(t, err, CodeInvalidDPoPProof, ReasonDPoPProof)
+	require.NoError(t, d.Verify(dpopRequest(proof), testToken, boundPrincipal(client.jkt)))
+}
+
+func TestDPoPPublicOrigin(t *testing.T) {
+	t.Parallel()
+
+	client := newDPoPClient(t)
+	d := newTestDPoPVerifier(t, DPoPConfig{PublicOrigin: "https://api.example.com"})
+	proof := client.proof(t, func(c jwt.MapClaims, _ map[string]any) { c["htu"] = "https://api.example.com/mcp" })
+	require.NoError(t, d.Verify(dpopRequest(proof), testToken, boundPrincipal(client.jkt)))
+}
+
+func TestDPoPAllowedAlgs(t *testing.T) {
+	t.Parallel()
+
+	client := newDPoPClient(t)
+	d := newTestDPoPVerifier(t, DPoPConfig{AllowedAlgs: []string{"RS256"}})
+	err := d.Verify(dpopRequest(client.proof(t, nil)), testToken, boundPrincipal(client.jkt))
+	requireAuthnError(t, err, CodeInvalidDPoPProof, ReasonDPoPProof)
+}
+
+func TestNewDPoPVerifierRejectsBadConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     DPoPConfig
+		wantErr string
+	}{
+		{name: "unsupported alg", cfg: DPoPConfig{AllowedAlgs: []string{"HS256"}}, wantErr: `allowed_algs[0] "HS256"`},
+		{name: "negative max proof age", cfg: DPoPConfig{MaxProofAge: -time.Second}, wantErr: "must not be negative"},
+		{name: "max proof age too long", cfg: DPoPConfig{MaxProofAge: time.Hour}, wantErr: "exceeds maximum"},
+		{name: "negative cache size", cfg: DPoPConfig{ReplayCacheSize: -1}, wantErr: "replay cache size"},
+		{name: "http public origin", cfg: DPoPConfig{PublicOrigin: "http://api.example.com"}, wantErr: "public origin"},
+		{
+			name:    "public origin with path",
+			cfg:     DPoPConfig{PublicOrigin: "https://api.example.com/mcp"},
+			wantErr: "scheme://host[:port] only",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewDPoPVerifier(tc.cfg)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestVerifyCertificateBinding(t *testing.T) {
+	t.Parallel()
+
+	cert := &x509.Certificate{Raw: []byte("client certificate DER")}
+	sum := sha256.Sum256(cert.Raw)
+	bound := Principal{Claims: map[string]any{
+		"cnf": map[string]any{"x5t#S256": base64.RawURLEncoding.EncodeToString(sum[:])},
+	}}
+
+	tests := []struct {
+		name    string
+		p       Principal
+		state   *tls.ConnectionState
+		wantErr bool
+	}{
+		{name: "unbound token without TLS", p: Principal{Claims: map[string]any{}}},
+		{name: "matching certificate", p: bound, state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+		{
+			name: "other certificate",
+			p:    bound,
+			state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Raw: []byte("someone else")},
+			}},
+			wantErr: true,
+		},
+		{name: "no client certificate", p: bound, state: &tls.ConnectionState{}, wantErr: true},
+		{name: "no TLS", p: bound, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := VerifyCertificateBinding(tc.p, tc.state)
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			requireAuthnError(t, err, CodeInvalidToken, ReasonCertificateBinding)
+		})
+	}
+}
+
+func TestMiddlewareDPoP(t *testing.T) {
+	t.Parallel()
+
+	client := newDPoPClient(t)
+
+	serveDPoP := func(t *testing.T, d *DPoPVerifier, p Principal, authorization string, proofs ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		h := Middleware(&fakeTokenValidator{principal: p}, WithDPoP(d))(principalEcho())
+		req := dpopRequest(proofs...)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("bound token with a valid proof is admitted", func(t *testing.T) {
+		t.Parallel()
+		d := newTestDPoPVerifier(t, DPoPConfig{})
+		rec := serveDPoP(t, d, boundPrincipal(client.jkt), "DPoP "+testToken, client.proof(t, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, testSubject, rec.Body.String())
+	})
+
+	t.Run("bad proof is challenged under DPoP", func(t *testing.T) {
+		t.Parallel()
+		d := newTestDPoPVerifier(t, DPoPConfig{AllowedAlgs: []string{"ES256"}})
+		rec := serveDPoP(t, d, boundPrincipal(client.jkt), "DPoP "+testToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `DPoP algs="ES256", error="invalid_dpop_proof"`, rec.Header().Get(headerWWWAuth))
+	})
+
+	t.Run("bound token under Bearer is refused", func(t *testing.T) {
+		t.Parallel()
+		d := newTestDPoPVerifier(t, DPoPConfig{})
+		rec := serveDPoP(t, d, boundPrincipal(client.jkt), "Bearer "+testToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get(headerWWWAuth))
+	})
+
+	t.Run("unbound bearer token passes unless required", func(t *testing.T) {
+		t.Parallel()
+		unbound := Principal{Subject: testSubject, Claims: map[string]any{}}
+		rec := serveDPoP(t, newTestDPoPVerifier(t, DPoPConfig{}), unbound, "Bearer "+testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		required := newTestDPoPVerifier(t, DPoPConfig{Required: true, AllowedAlgs: []string{"ES256"}})
+		rec = serveDPoP(t, required, unbound, "Bearer "+testToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `DPoP algs="ES256", error="invalid_token"`, rec.Header().Get(headerWWWAuth))
+	})
+
+	t.Run("DPoP scheme is malformed without WithDPoP", func(t *testing.T) {
+		t.Parallel()
+		h := Middleware(&fakeTokenValidator{principal: boundPrincipal(client.jkt)})(principalEcho())
+		rec := serve(h, http.MethodPost, "/mcp", "DPoP "+testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestMiddlewareCertificateBoundTokens(t *testing.T) {
+	t.Parallel()
+
+	bound := Principal{Subject: testSubject, Claims: map[string]any{"cnf": map[string]any{"x5t#S256": "abc"}}}
+	h := Middleware(&fakeTokenValidator{principal: bound}, WithCertificateBoundTokens())(principalEcho())
+	rec := serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get(headerWWWAuth))
+
+	// Without the option the binding is not enforced.
+	h = Middleware(&fakeTokenValidator{principal: bound})(principalEcho())
+	rec = serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
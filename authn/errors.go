@@ -6,6 +6,8 @@ package authn
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 )
 
 // Code is the OAuth2 error code, safe to send to a client.
@@ -31,6 +33,15 @@ const (
 	// discriminator: map it to a plain HTTP 503 and do not emit it as an
 	// OAuth2 error= parameter.
 	CodeUnavailable Code = "unavailable" // 503 — key material unreachable
+	// CodeInsufficientScope is the OAuth2 "insufficient_scope" code (HTTP
+	// 403): the token is valid but does not grant what the request needs. It
+	// is produced by Policy, never by Validate.
+	CodeInsufficientScope Code = "insufficient_scope" // 403
+	// CodeInvalidDPoPProof is the RFC 9449 §7.1 "invalid_dpop_proof" code
+	// (HTTP 401): the access token may be fine, but the DPoP proof sent with
+	// it is missing, malformed, or replayed. It is produced by DPoPVerifier,
+	// never by Validate, and is challenged under the DPoP scheme.
+	CodeInvalidDPoPProof Code = "invalid_dpop_proof" // 401
 )
 
 // Reason is a finer-grained, client-safe failure cause.
@@ -132,8 +143,61 @@ const (
 	// Error), and a token that parsed as a JWT is not an opaque token worth
 	// introspecting, no matter which claim check subsequently failed it.
 	ReasonInvalidClaims Reason = "invalid_claims"
+	// ReasonInactive indicates an RFC 7662 introspection endpoint reported the
+	// token as not active: revoked, expired, never issued, or issued to a
+	// client the endpoint will not disclose it to. RFC 7662 §2.2 deliberately
+	// does not say which, so neither does this.
+	ReasonInactive Reason = "inactive"
+	// ReasonIntrospectionUnavailable indicates the introspection endpoint
+	// could not be reached or did not return a usable response; paired with
+	// CodeUnavailable. Like ReasonKeysUnavailable it means no determination
+	// was made, not that the token is bad.
+	ReasonIntrospectionUnavailable Reason = "introspection_unavailable"
+	// ReasonScope indicates a scope required by a Policy was not granted;
+	// paired with CodeInsufficientScope.
+	ReasonScope Reason = "scope"
+	// ReasonClaimValue indicates a claim required by a Policy was absent or
+	// carried no acceptable value; paired with CodeInsufficientScope.
+	ReasonClaimValue Reason = "claim_value"
+	// ReasonPolicy indicates a Policy expression evaluated to false or could
+	// not be evaluated against the token's claims; paired with
+	// CodeInsufficientScope.
+	ReasonPolicy Reason = "policy"
+	// ReasonDPoPProof indicates the DPoP proof is absent, duplicated, fails
+	// to verify, or does not match the request (htm, htu, iat, ath); paired
+	// with CodeInvalidDPoPProof.
+	ReasonDPoPProof Reason = "dpop_proof"
+	// ReasonDPoPReplay indicates a DPoP proof whose jti was already used
+	// within the acceptance window; paired with CodeInvalidDPoPProof. It is
+	// distinct from ReasonDPoPProof because a replay is an attack signal, not
+	// a client bug.
+	ReasonDPoPReplay Reason = "dpop_replay"
+	// ReasonDPoPBinding indicates the token's cnf.jkt does not match the
+	// proof key, a DPoP-bound token was sent under the Bearer scheme, a token
+	// without cnf.jkt was sent under the DPoP scheme, or DPoP is required and
+	// the token is not bound; paired with CodeInvalidToken.
+	ReasonDPoPBinding Reason = "dpop_binding"
+	// ReasonCertificateBinding indicates an RFC 8705 certificate-bound token
+	// (cnf.x5t#S256) arrived without a client certificate or with a different
+	// one; paired with CodeInvalidToken.
+	ReasonCertificateBinding Reason = "certificate_binding"
+	// ReasonRevoked indicates Config.RevocationChecker reported the token
+	// revoked, by its jti, its sid or its sub; paired with CodeInvalidToken.
+	ReasonRevoked Reason = "revoked"
+	// ReasonRevocationUnavailable indicates the RevocationChecker could not
+	// answer; paired with CodeUnavailable. Like ReasonKeysUnavailable it means
+	// no determination was made, not that the token is bad.
+	ReasonRevocationUnavailable Reason = "revocation_unavailable"
 )
 
+// description renders r as error_description text: the Reason with its
+// underscores spaced out ("unknown kid"). Every Reason is a fixed token drawn
+// from the RFC 6750 §3 error_description character set, so the result needs
+// no further escaping beyond the quoted-string rules authParam applies.
+func (r Reason) description() string {
+	return strings.ReplaceAll(string(r), "_", " ")
+}
+
 // Error is the only error type Validate and ParseBearer return.
 //
 // Log-vs-wire split:
@@ -161,6 +225,11 @@ type Error struct {
 	Code Code
 	// Reason is the finer-grained, client-safe failure cause.
 	Reason Reason
+	// RequiredScopes, set only with CodeInsufficientScope and ReasonScope,
+	// lists the scopes the resource requires. It is client-safe — it is
+	// policy, not token content — and is sent as the RFC 6750 §3 scope
+	// attribute so the client knows what to ask for.
+	RequiredScopes []string
 	// err is the unexported wrapped detail; never sent to a client.
 	err error
 }
@@ -212,3 +281,23 @@ func PossiblyOpaque(err error) bool {
 	}
 	return authnErr.Code == CodeInvalidToken && authnErr.Reason == ReasonMalformed
 }
+
+// HTTPStatus returns the HTTP status a response carrying this Code should use:
+// 400 for CodeInvalidRequest, 401 for CodeInvalidToken and
+// CodeInvalidDPoPProof, 403 for CodeInsufficientScope and 503 for
+// CodeUnavailable. An unrecognized Code maps to 500, since guessing a 4xx for a
+// failure nobody classified would blame the client for it.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeInvalidRequest:
+		return http.StatusBadRequest
+	case CodeInvalidToken, CodeInvalidDPoPProof:
+		return http.StatusUnauthorized
+	case CodeInsufficientScope:
+		return http.StatusForbidden
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,397 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stacklok/toolhive-core/networking"
+)
+
+const (
+	// grantTypeTokenExchange is the RFC 8693 §2.1 grant_type value.
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// TokenTypeAccessToken is the RFC 8693 §3 token type identifier for an
+	// OAuth 2.0 access token, and the default for subject and actor tokens.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	// TokenTypeJWT is the RFC 8693 §3 token type identifier for a JWT.
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+	// TokenTypeIDToken is the RFC 8693 §3 token type identifier for an OpenID
+	// Connect ID token.
+	TokenTypeIDToken = "urn:ietf:params:oauth:token-type:id_token"
+
+	// defaultTokenExchangeCacheSize is the number of exchanged tokens kept
+	// when TokenExchangeConfig.CacheSize is zero.
+	defaultTokenExchangeCacheSize = 1024
+	// defaultTokenExchangeExpiryMargin is how long before its expiry an
+	// exchanged token stops being served from the cache when
+	// TokenExchangeConfig.ExpiryMargin is zero.
+	defaultTokenExchangeExpiryMargin = 30 * time.Second
+	// maxExpiresIn bounds a response's expires_in, in seconds, well inside
+	// what a time.Duration can hold. Ten years is far beyond any real token.
+	maxExpiresIn = 10 * 365 * 24 * 60 * 60
+)
+
+// TokenExchangeConfig configures a TokenExchanger. Like Config it is validated
+// eagerly by NewTokenExchanger, so a misconfiguration is a startup failure.
+type TokenExchangeConfig struct {
+	// Endpoint is the authorization server's token endpoint. Required. Must be
+	// an https URI unless InsecureAllowHTTP is set: every request carries the
+	// user's token and this client's own credentials.
+	Endpoint string
+
+	// ClientID, ClientSecret, AuthMethod, ClientAssertionKey and
+	// ClientAssertionKeyID authenticate this client to Endpoint, with the same
+	// meaning and rules as on IntrospectionConfig. RFC 8693 §2.1 leaves client
+	// authentication to the token endpoint's usual rules, and an endpoint that
+	// exchanged tokens for unauthenticated callers would mint a token for
+	// anyone holding one.
+	ClientID             string
+	ClientSecret         string
+	AuthMethod           ClientAuthMethod
+	ClientAssertionKey   any
+	ClientAssertionKeyID string
+
+	// CacheSize bounds the number of exchanged tokens cached. Zero uses a
+	// default of 1024; negative is an error.
+	CacheSize int
+
+	// ExpiryMargin is how long before its expiry an exchanged token stops
+	// being served from the cache, so a token handed to a caller does not lapse
+	// on the way to the downstream service. Zero uses 30s; negative is an
+	// error. A response without expires_in is never cached.
+	ExpiryMargin time.Duration
+
+	// HTTPClient, InsecureAllowHTTP, AllowPrivateIP and CACertPath have the
+	// same meaning as on Config. The default client carries networking's
+	// private-IP dial guard, refuses redirects — a redirect would replay the
+	// subject token and client credentials to wherever it points — and caps
+	// response bodies; a supplied client opts out of the dial guard.
+	HTTPClient        *http.Client
+	InsecureAllowHTTP bool
+	AllowPrivateIP    bool
+	CACertPath        string
+}
+
+// TokenExchangeRequest describes one RFC 8693 exchange: the token being
+// exchanged and the downstream target the new token is wanted for.
+type TokenExchangeRequest struct {
+	// SubjectToken is the token representing the party on whose behalf the
+	// request is made, normally the access token the caller presented.
+	// Required.
+	SubjectToken string
+	// SubjectTokenType identifies SubjectToken's kind. Empty means
+	// TokenTypeAccessToken.
+	SubjectTokenType string
+
+	// ActorToken, when set, represents the party acting on the subject's
+	// behalf — typically this service's own credential — and asks for a
+	// delegation token whose act claim names that party (RFC 8693 §4.1).
+	// Empty requests impersonation instead.
+	ActorToken string
+	// ActorTokenType identifies ActorToken's kind. Empty means
+	// TokenTypeAccessToken; it must be empty when ActorToken is.
+	ActorTokenType string
+
+	// Audience lists the logical names of the target services, and Resource
+	// their absolute URIs (RFC 8693 §2.1). Either, both or neither may be set;
+	// the authorization server decides what it requires.
+	Audience []string
+	Resource []string
+
+	// Scopes is the requested scope of the new token. Empty leaves the scope
+	// to the authorization server's policy.
+	Scopes []string
+
+	// RequestedTokenType asks for a particular kind of token. Empty leaves the
+	// choice to the authorization server, which normally issues an access
+	// token.
+	RequestedTokenType string
+}
+
+// ExchangedToken is the token issued by a successful exchange.
+type ExchangedToken struct {
+	// AccessToken is the issued token. Despite the name it is whatever
+	// IssuedTokenType says (RFC 8693 §2.2.1). It is a live credential: do not
+	// log it.
+	AccessToken string
+	// IssuedTokenType identifies AccessToken's kind.
+	IssuedTokenType string
+	// TokenType is how to present AccessToken, usually "Bearer" or, for a
+	// token that is not an access token, "N_A".
+	TokenType string
+	// Expiry is when AccessToken expires, or the zero time when the
+	// authorization server did not say.
+	Expiry time.Time
+	// Scopes is the granted scope, when the authorization server reported it.
+	// Per RFC 8693 §2.2.1 it is omitted when identical to the requested scope.
+	Scopes []string
+}
+
+// ExchangeError is returned by TokenExchanger.Exchange when the authorization
+// server answers with an OAuth 2.0 error response (RFC 6749 §5.2), such as
+// invalid_grant for a subject token it does not accept or invalid_target for
+// an audience it will not issue for.
+type ExchangeError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// ErrorCode is the response's error member, e.g. "invalid_target".
+	ErrorCode string
+	// Description is the response's error_description member, which may be
+	// empty. It is written by the authorization server for developers.
+	Description string
+}
+
+// Error implements error. Both members are quoted, so a crafted value cannot
+// forge log lines.
+func (e *ExchangeError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("authn: token exchange failed with HTTP %d: %q", e.StatusCode, e.ErrorCode)
+	}
+	return fmt.Sprintf("authn: token exchange failed with HTTP %d: %q: %q", e.StatusCode, e.ErrorCode, e.Description)
+}
+
+// TokenExchanger obtains tokens for downstream calls by RFC 8693 token
+// exchange: a gateway hands it the token its caller presented and receives a
+// token for the backend it is about to call, on the caller's behalf.
+//
+// Issued tokens are cached per request — the subject and actor tokens plus
+// the requested audience, resource, scope and token type — until shortly
+// before they expire, so a burst of calls from one user to one backend costs a
+// single exchange. The cache holds live credentials and lives only in memory;
+// its keys are hashes, so the subject tokens themselves are not retained.
+//
+// A TokenExchanger owns no goroutines and needs no Close. It is safe for
+// concurrent use.
+type TokenExchanger struct {
+	// cfg is the validated, default-filled configuration.
+	cfg TokenExchangeConfig
+	// httpClient is used for token requests; see newHTTPClient.
+	httpClient *http.Client
+	// cache holds exchanged tokens keyed by exchangeCacheKey.
+	cache *lruCache[[sha256.Size]byte, ExchangedToken]
+}
+
+// NewTokenExchanger validates cfg and constructs a TokenExchanger. It performs
+// no network I/O. Errors are ordinary construction errors.
+func NewTokenExchanger(cfg TokenExchangeConfig) (*TokenExchanger, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(cfg.HTTPClient, cfg.AllowPrivateIP, cfg.CACertPath, "")
+	if err != nil {
+		return nil, err
+	}
+	return &TokenExchanger{
+		cfg:        cfg,
+		httpClient: httpClient,
+		cache:      newLRUCache[[sha256.Size]byte, ExchangedToken](cfg.CacheSize),
+	}, nil
+}
+
+// validate checks cfg and fills in defaults, performing no I/O.
+func (c *TokenExchangeConfig) validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("authn: token exchange endpoint is required")
+	}
+	if err := validateHTTPSURI("token exchange endpoint", c.Endpoint, c.InsecureAllowHTTP); err != nil {
+		return err
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("authn: token exchange client id is required")
+	}
+	if err := validateClientAuth("token exchange", &c.AuthMethod, c.ClientSecret, c.ClientAssertionKey); err != nil {
+		return err
+	}
+	switch {
+	case c.CacheSize < 0:
+		return fmt.Errorf("authn: token exchange cache size must not be negative: %d", c.CacheSize)
+	case c.CacheSize == 0:
+		c.CacheSize = defaultTokenExchangeCacheSize
+	}
+	switch {
+	case c.ExpiryMargin < 0:
+		return fmt.Errorf("authn: token exchange expiry margin must not be negative: %s", c.ExpiryMargin)
+	case c.ExpiryMargin == 0:
+		c.ExpiryMargin = defaultTokenExchangeExpiryMargin
+	}
+	return nil
+}
+
+// Exchange performs the exchange described by req, or returns a cached token
+// from an identical earlier exchange that is not yet within ExpiryMargin of
+// expiring. Each call returns its own copy.
+//
+// An OAuth error response from the endpoint is returned as *ExchangeError; a
+// malformed request, a transport failure or an unusable success response is
+// an ordinary error. Nothing is cached on failure.
+func (x *TokenExchanger) Exchange(ctx context.Context, req TokenExchangeRequest) (*ExchangedToken, error) {
+	form, err := req.form()
+	if err != nil {
+		return nil, err
+	}
+
+	key := exchangeCacheKey(form)
+	if tok, ok := x.cache.get(key, time.Now()); ok {
+		tok.Scopes = slices.Clone(tok.Scopes)
+		return &tok, nil
+	}
+
+	tok, err := x.exchange(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	if !tok.Expiry.IsZero() {
+		if until := tok.Expiry.Add(-x.cfg.ExpiryMargin); until.After(time.Now()) {
+			cached := *tok
+			cached.Scopes = slices.Clone(tok.Scopes)
+			x.cache.add(key, cached, until)
+		}
+	}
+	return tok, nil
+}
+
+// form validates req and encodes it as the RFC 8693 §2.1 request parameters,
+// without client authentication.
+func (req TokenExchangeRequest) form() (url.Values, error) {
+	if req.SubjectToken == "" {
+		return nil, errors.New("authn: token exchange subject token is required")
+	}
+	if req.ActorToken == "" && req.ActorTokenType != "" {
+		return nil, errors.New("authn: token exchange actor token type given without an actor token")
+	}
+	form := url.Values{
+		"grant_type":         {grantTypeTokenExchange},
+		"subject_token":      {req.SubjectToken},
+		"subject_token_type": {cmp.Or(req.SubjectTokenType, TokenTypeAccessToken)},
+	}
+	if req.ActorToken != "" {
+		form.Set("actor_token", req.ActorToken)
+		form.Set("actor_token_type", cmp.Or(req.ActorTokenType, TokenTypeAccessToken))
+	}
+	for i, aud := range req.Audience {
+		if aud == "" {
+			return nil, fmt.Errorf("authn: token exchange audience[%d] must not be empty", i)
+		}
+		form.Add("audience", aud)
+	}
+	for i, res := range req.Resource {
+		// RFC 8693 §2.1: an absolute URI without a fragment.
+		u, err := url.Parse(res)
+		if err != nil || !u.IsAbs() || strings.Contains(res, "#") {
+			return nil, fmt.Errorf("authn: token exchange resource[%d] must be an absolute URI without a fragment", i)
+		}
+		form.Add("resource", res)
+	}
+	for i, s := range req.Scopes {
+		if !validScopeToken(s) {
+			return nil, fmt.Errorf("authn: token exchange scopes[%d] %q is not a valid scope token", i, s)
+		}
+	}
+	if len(req.Scopes) > 0 {
+		form.Set("scope", strings.Join(req.Scopes, " "))
+	}
+	if req.RequestedTokenType != "" {
+		form.Set("requested_token_type", req.RequestedTokenType)
+	}
+	return form, nil
+}
+
+// exchangeCacheKey hashes every request parameter that shapes the issued
+// token. Hashing keeps the subject and actor tokens out of the cache, and
+// covering the whole request means two exchanges share an entry only when the
+// authorization server would have been asked the same question. url.Values
+// encodes with sorted keys, so the encoding is canonical.
+func exchangeCacheKey(form url.Values) [sha256.Size]byte {
+	return sha256.Sum256([]byte(form.Encode()))
+}
+
+// tokenExchangeResponse is the RFC 8693 §2.2.1 success response.
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       *int64 `json:"expires_in"`
+	Scope           string `json:"scope"`
+}
+
+// oauthErrorResponse is the RFC 6749 §5.2 error response.
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange sends the token request and decodes the issued token.
+func (x *TokenExchanger) exchange(ctx context.Context, form url.Values) (*ExchangedToken, error) {
+	auth := clientAuth{
+		method:   x.cfg.AuthMethod,
+		clientID: x.cfg.ClientID,
+		secret:   x.cfg.ClientSecret,
+		key:      x.cfg.ClientAssertionKey,
+		keyID:    x.cfg.ClientAssertionKeyID,
+		audience: x.cfg.Endpoint,
+	}
+	opts, err := auth.apply(form)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		networking.WithMaxResponseSize(maxResponseBody),
+		networking.WithErrorHandler(parseOAuthError),
+	)
+
+	sent := time.Now()
+	result, err := networking.FetchJSONWithForm[tokenExchangeResponse](ctx, x.httpClient, x.cfg.Endpoint, form, opts...)
+	if err != nil {
+		var exchangeErr *ExchangeError
+		if errors.As(err, &exchangeErr) {
+			return nil, exchangeErr
+		}
+		return nil, fmt.Errorf("authn: token exchange request failed: %w", err)
+	}
+	resp := result.Data
+	if resp.AccessToken == "" || resp.IssuedTokenType == "" || resp.TokenType == "" {
+		return nil, errors.New("authn: token exchange response lacks access_token, issued_token_type or token_type")
+	}
+
+	tok := &ExchangedToken{
+		AccessToken:     resp.AccessToken,
+		IssuedTokenType: resp.IssuedTokenType,
+		TokenType:       resp.TokenType,
+		Scopes:          strings.Fields(resp.Scope),
+	}
+	if resp.ExpiresIn != nil {
+		if *resp.ExpiresIn <= 0 || *resp.ExpiresIn > maxExpiresIn {
+			return nil, fmt.Errorf("authn: token exchange response has out-of-range expires_in %d", *resp.ExpiresIn)
+		}
+		// Measured from when the request was sent, not when the answer
+		// arrived, so round-trip time errs toward expiring early.
+		tok.Expiry = sent.Add(time.Duration(*resp.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// parseOAuthError turns an RFC 6749 §5.2 error body into an *ExchangeError.
+// A body that is not one returns nil, leaving networking's HTTPError in place.
+func parseOAuthError(resp *http.Response, body []byte) error {
+	var oauthErr oauthErrorResponse
+	if err := json.Unmarshal(body, &oauthErr); err != nil || oauthErr.Error == "" {
+		return nil
+	}
+	return &ExchangeError{
+		StatusCode:  resp.StatusCode,
+		ErrorCode:   oauthErr.Error,
+		Description: oauthErr.ErrorDescription,
+	}
+}
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/networking"
+)
+
+const (
+	testExchangedToken = "exchanged-token-value"
+	testBackendAud     = "backend-api"
+)
+
+// tokenEndpoint is an httptest token endpoint answering every request with
+// the status and body set by the test, recording each request form.
+type tokenEndpoint struct {
+	srv      *httptest.Server
+	hits     atomic.Int32
+	status   atomic.Int32
+	response atomic.Pointer[map[string]any]
+	lastForm atomic.Pointer[url.Values]
+	lastReq  atomic.Pointer[http.Request]
+}
+
+func newTokenEndpoint(t *testing.T, response map[string]any) *tokenEndpoint {
+	t.Helper()
+	te := &tokenEndpoint{}
+	te.status.Store(http.StatusOK)
+	te.response.Store(&response)
+	te.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		te.hits.Add(1)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		te.lastForm.Store(&r.PostForm)
+		te.lastReq.Store(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(te.status.Load()))
+		_ = json.NewEncoder(w).Encode(*te.response.Load())
+	}))
+	t.Cleanup(te.srv.Close)
+	return te
+}
+
+func (te *tokenEndpoint) configFor() TokenExchangeConfig {
+	return TokenExchangeConfig{
+		Endpoint:          te.srv.URL + "/token",
+		ClientID:          testClientID,
+		ClientSecret:      testClientSecret,
+		InsecureAllowHTTP: true,
+		AllowPrivateIP:    true,
+	}
+}
+
+func issuedResponse() map[string]any {
+	return map[string]any{
+		"access_token":      testExchangedToken,
+		"issued_token_type": TokenTypeAccessToken,
+		"token_type":        "Bearer",
+		"expires_in":        3600,
+		"scope":             "read",
+	}
+}
+
+func newTestTokenExchanger(t *testing.T, cfg TokenExchangeConfig) *TokenExchanger {
+	t.Helper()
+	x, err := NewTokenExchanger(cfg)
+	require.NoError(t, err)
+	return x
+}
+
+func TestTokenExchangeRequestForm(t *testing.T) {
+	t.Parallel()
+
+	te := newTokenEndpoint(t, issuedResponse())
+	x := newTestTokenExchanger(t, te.configFor())
+
+	tok, err := x.Exchange(context.Background(), TokenExchangeRequest{
+		SubjectToken: testOpaqueToken,
+		ActorToken:   "actor-token",
+		Audience:     []string{testBackendAud},
+		Resource:     []string{"https://backend.example.com/api"},
+		Scopes:       []string{"read", "write"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testExchangedToken, tok.AccessToken)
+	assert.Equal(t, TokenTypeAccessToken, tok.IssuedTokenType)
+	assert.Equal(t, "Bearer", tok.TokenType)
+	assert.Equal(t, []string{"read"}, tok.Scopes)
+	assert.False(t, tok.Expiry.IsZero())
+
+	form := *te.lastForm.Load()
+	assert.Equal(t, grantTypeTokenExchange, form.Get("grant_type"))
+	assert.Equal(t, testOpaqueToken, form.Get("subject_token"))
+	assert.Equal(t, TokenTypeAccessToken, form.Get("subject_token_type"))
+	assert.Equal(t, "actor-token", form.Get("actor_token"))
+	assert.Equal(t, TokenTypeAccessToken, form.Get("actor_token_type"))
+	assert.Equal(t, []string{testBackendAud}, form["audience"])
+	assert.Equal(t, []string{"https://backend.example.com/api"}, form["resource"])
+	assert.Equal(t, "read write", form.Get("scope"))
+	assert.Empty(t, form.Get("requested_token_type"))
+
+	id, secret, ok := te.lastReq.Load().BasicAuth()
+	require.True(t, ok, "client_secret_basic is the default")
+	assert.Equal(t, url.QueryEscape(testClientID), id)
+	assert.Equal(t, url.QueryEscape(testClientSecret), secret)
+}
+
+func TestTokenExchangeCaching(t *testing.T) {
+	t.Parallel()
+
+	te := newTokenEndpoint(t, issuedResponse())
+	x := newTestTokenExchanger(t, te.configFor())
+	req := TokenExchangeRequest{SubjectToken: testOpaqueToken, Audience: []string{testBackendAud}}
+
+	first, err := x.Exchange(context.Background(), req)
+	require.NoError(t, err)
+	first.Scopes[0] = "mutated"
+	second, err := x.Exchange(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), te.hits.Load(), "an identical exchange is served from the cache")
+	assert.Equal(t, []string{"read"}, second.Scopes, "callers must not share the cached value")
+
+	// Another audience or another subject is another exchange.
+	_, err = x.Exchange(context.Background(), TokenExchangeRequest{SubjectToken: testOpaqueToken, Audience: []string{"other"}})
+	require.NoError(t, err)
+	_, err = x.Exchange(context.Background(), TokenExchangeRequest{SubjectToken: "another-user", Audience: []string{testBackendAud}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), te.hits.Load())
+}
+
+func TestTokenExchangeNotCachedNearExpiry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+	}{
+		{name: "no expires_in", mutate: func(r map[string]any) { delete(r, "expires_in") }},
+		{name: "expires within the margin", mutate: func(r map[string]any) { r["expires_in"] = 10 }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := issuedResponse()
+			tc.mutate(resp)
+			te := newTokenEndpoint(t, resp)
+			x := newTestTokenExchanger(t, te.configFor())
+			req := TokenExchangeRequest{SubjectToken: testOpaqueToken}
+
+			for range 2 {
+				_, err := x.Exchange(context.Background(), req)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, int32(2), te.hits.Load())
+		})
+	}
+}
+
+func TestTokenExchangeErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("OAuth error response", func(t *testing.T) {
+		t.Parallel()
+		te := newTokenEndpoint(t, map[string]any{
+			"error":             "invalid_target",
+			"error_description": "audience not allowed\nforged",
+		})
+		te.status.Store(http.StatusBadRequest)
+		x := newTestTokenExchanger(t, te.configFor())
+
+		_, err := x.Exchange(context.Background(), TokenExchangeRequest{SubjectToken: testOpaqueToken})
+		var exchangeErr *ExchangeError
+		require.ErrorAs(t, err, &exchangeErr)
+		assert.Equal(t, http.StatusBadRequest, exchangeErr.StatusCode)
+		assert.Equal(t, "invalid_target", exchangeErr.ErrorCode)
+		assert.NotContains(t, err.Error(), "\n")
+
+		// A failure is not cached.
+		te.status.Store(http.StatusOK)
+		resp := issuedResponse()
+		te.response.Store(&resp)
+		_, err = x.Exchange(context.Background(), TokenExchangeRequest{SubjectToken: testOpaqueToken})
+		require.NoError(t, err)
+	})
+
+	t.Run("non-OAuth error body", func(t *testing.T) {
+		t.Parallel()
+		te := newTokenEndpoint(t, map[string]any{"message": "boom"})
+		te.status.Store(http.StatusInternalServerError)
+		x := newTestTokenExchanger(t, te.configFor())
+
+		_, err := x.Exchange(context.Background(), TokenExchangeRequest{SubjectToken: testOpaqueToken})
+		assert.True(t, networking.IsHTTPError(err, http.StatusInternalServerError))
+		var exchangeErr *ExchangeError
+		assert.False(t, errors.As(err, &exchangeErr))
+	})
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(map[string]any)
+	}{
+		{name: "missing access_token", mutate: func(r map[string]any) { delete(r, "access_token") }},
+		{name: "missing issued_token_type", mutate: func(r map[string]any) { delete(r, "issued_token_type") }},
+		{name: "zero expires_in", mutate: func(r map[string]any) { r["expires_in"] = 0 }},
+		{name: "absurd expires_in", mutate: func(r map[string]any) { r["expires_in"] = int64(1) << 62 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := issuedResponse()
+			tc.mutate(resp)
+			te := newTokenEndpoint(t, resp)
+			x := newTestTokenExchanger(t, te.configFor())
+			_, err := x.Exchange(context.Background(), TokenExchangeRequest{SubjectToken: testOpaqueToken})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTokenExchangeRejectsBadRequest(t *testing.T) {
+	t.Parallel()
+
+	te := newTokenEndpoint(t, issuedResponse())
+	x := newTestTokenExchanger(t, te.configFor())
+
+	tests := []struct {
+		name    string
+		req     TokenExchangeRequest
+		wantErr string
+	}{
+		{name: "no subject token", req: TokenExchangeRequest{}, wantErr: "subject token is required"},
+		{
+			name:    "actor token type without actor token",
+			req:     TokenExchangeRequest{SubjectToken: testOpaqueToken, ActorTokenType: TokenTypeJWT},
+			wantErr: "without an actor token",
+		},
+		{
+			name:    "relative resource",
+			req:     TokenExchangeRequest{SubjectToken: testOpaqueToken, Resource: []string{"/api"}},
+			wantErr: "resource[0]",
+		},
+		{
+			name:    "resource with fragment",
+			req:     TokenExchangeRequest{SubjectToken: testOpaqueToken, Resource: []string{"https://b.example.com/#x"}},
+			wantErr: "resource[0]",
+		},
+		{
+			name:    "empty audience",
+			req:     TokenExchangeRequest{SubjectToken: testOpaqueToken, Audience: []string{""}},
+			wantErr: "audience[0]",
+		},
+		{
+			name:    "scope with whitespace",
+			req:     TokenExchangeRequest{SubjectToken: testOpaqueToken, Scopes: []string{"read write"}},
+			wantErr: "scopes[0]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := x.Exchange(context.Background(), tc.req)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+	assert.Zero(t, te.hits.Load(), "an invalid request must not reach the endpoint")
+}
+
+func TestNewTokenExchangerRejectsBadConfig(t *testing.T) {
+	t.Parallel()
+
+	base := TokenExchangeConfig{
+		Endpoint:     "https://issuer.example.com/token",
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*TokenExchangeConfig)
+		wantErr string
+	}{
+		{name: "no endpoint", mutate: func(c *TokenExchangeConfig) { c.Endpoint = "" }, wantErr: "endpoint is required"},
+		{
+			name:    "http endpoint",
+			mutate:  func(c *TokenExchangeConfig) { c.Endpoint = "http://issuer.example.com/token" },
+			wantErr: "https",
+		},
+		{name: "no client id", mutate: func(c *TokenExchangeConfig) { c.ClientID = "" }, wantErr: "client id is required"},
+		{name: "no secret", mutate: func(c *TokenExchangeConfig) { c.ClientSecret = "" }, wantErr: "requires a client secret"},
+		{
+			name:    "negative cache size",
+			mutate:  func(c *TokenExchangeConfig) { c.CacheSize = -1 },
+			wantErr: "cache size must not be negative",
+		},
+		{
+			name:    "negative expiry margin",
+			mutate:  func(c *TokenExchangeConfig) { c.ExpiryMargin = -1 },
+			wantErr: "expiry margin must not be negative",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := base
+			tc.mutate(&cfg)
+			_, err := NewTokenExchanger(cfg)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
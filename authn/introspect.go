@@ -0,0 +1,359 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/stacklok/toolhive-core/networking"
+)
+
+const (
+	// defaultIntrospectionCacheSize is the number of active introspection
+	// responses kept when IntrospectionConfig.CacheSize is zero.
+	defaultIntrospectionCacheSize = 1024
+)
+
+// IntrospectionConfig configures an Introspector. Like Config it is validated
+// eagerly by NewIntrospector, so a misconfiguration is a startup failure.
+type IntrospectionConfig struct {
+	// Endpoint is the RFC 7662 introspection endpoint. Required. Must be an
+	// https URI unless InsecureAllowHTTP is set: every request carries both a
+	// live token and the resource server's own client credentials.
+	Endpoint string
+
+	// ClientID identifies this resource server to the endpoint. Required.
+	ClientID string
+
+	// ClientSecret is the shared secret for the client_secret_basic and
+	// client_secret_post methods. Required for those, and must be empty for
+	// private_key_jwt so an unused secret is not left lying in configuration.
+	ClientSecret string
+
+	// AuthMethod selects how the Introspector authenticates to Endpoint. Empty
+	// means ClientAuthClientSecretBasic, the method RFC 6749 requires every
+	// authorization server to support.
+	AuthMethod ClientAuthMethod
+
+	// ClientAssertionKey signs the private_key_jwt client assertion: an
+	// *rsa.PrivateKey (RS256, at least 2048 bits) or an *ecdsa.PrivateKey on
+	// P-256, P-384 or P-521 (ES256/384/512). Required for private_key_jwt and
+	// ignored otherwise.
+	ClientAssertionKey any
+
+	// ClientAssertionKeyID, when set, is placed in the assertion's kid header
+	// so the endpoint can select the matching registered key.
+	ClientAssertionKeyID string
+
+	// Issuer, when set, must equal the response's iss member byte-for-byte.
+	// Introspection responses need not carry iss (RFC 7662 §2.2 makes it
+	// optional), so leaving this empty accepts responses with or without one.
+	Issuer string
+
+	// Audiences and AllowAnyAudience have the same meaning and the same
+	// exactly-one-of rule as on Config, applied to the response's aud member.
+	// An endpoint that omits aud therefore fails closed unless
+	// AllowAnyAudience is set: the endpoint vouching that a token is active
+	// says nothing about whom it was minted for.
+	Audiences        []string
+	AllowAnyAudience bool
+
+	// Leeway is the clock-skew tolerance applied to the response's exp and
+	// nbf. Zero uses the 60s default; negative or above 2m is an error.
+	Leeway time.Duration
+
+	// CacheSize bounds the number of active responses cached. Zero uses a
+	// default of 1024; negative is an error.
+	//
+	// Only ACTIVE responses carrying an exp are cached, and each only until
+	// that exp: an inactive answer is never cached, so a token that has just
+	// been issued is not locked out by a stale "no".
+	CacheSize int
+
+	// MaxCacheTTL, when positive, caps how long a response is cached below
+	// its exp. Zero caches until exp; negative is an error.
+	//
+	// A cached response is trusted without asking the endpoint again, so a
+	// token revoked at the authorization server keeps working here until its
+	// cache entry lapses. Set this when exp is long and revocation must be
+	// observed sooner.
+	MaxCacheTTL time.Duration
+
+	// HTTPClient, InsecureAllowHTTP, AllowPrivateIP and CACertPath have the
+	// same meaning as on Config. In particular the default client carries
+	// networking's private-IP dial guard, refuses redirects — a redirect
+	// would replay the client credentials to wherever it points — and caps
+	// response bodies; a supplied client opts out of the dial guard.
+	HTTPClient        *http.Client
+	InsecureAllowHTTP bool
+	AllowPrivateIP    bool
+	CACertPath        string
+}
+
+// Introspector validates tokens by asking an RFC 7662 introspection endpoint,
+// for issuers that hand out opaque (non-JWT) access tokens. It satisfies
+// TokenValidator and returns the same Principal as Validator, so Middleware
+// and everything behind it treat both kinds of token alike.
+//
+// An Introspector owns no goroutines and needs no Close. It is safe for
+// concurrent use.
+type Introspector struct {
+	// cfg is the validated, default-filled configuration.
+	cfg IntrospectionConfig
+	// httpClient is used for introspection requests; see newHTTPClient.
+	httpClient *http.Client
+	// cache holds active responses keyed by the SHA-256 of the token, so the
+	// cache never retains a replayable credential.
+	cache *lruCache[[sha256.Size]byte, Principal]
+}
+
+// Compile-time check that *Introspector satisfies TokenValidator.
+var _ TokenValidator = (*Introspector)(nil)
+
+// NewIntrospector validates cfg and constructs an Introspector. It performs no
+// network I/O. Errors are ordinary construction errors, not *Error.
+func NewIntrospector(cfg IntrospectionConfig) (*Introspector, error) {
+	cfg.Audiences = slices.Clone(cfg.Audiences)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(cfg.HTTPClient, cfg.AllowPrivateIP, cfg.CACertPath, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Introspector{
+		cfg:        cfg,
+		httpClient: httpClient,
+		cache:      newLRUCache[[sha256.Size]byte, Principal](cfg.CacheSize),
+	}, nil
+}
+
+// validate checks cfg and fills in defaults, performing no I/O.
+func (c *IntrospectionConfig) validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("authn: introspection endpoint is required")
+	}
+	if err := validateHTTPSURI("introspection endpoint", c.Endpoint, c.InsecureAllowHTTP); err != nil {
+		return err
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("authn: introspection client id is required")
+	}
+	if err := validateClientAuth("introspection", &c.AuthMethod, c.ClientSecret, c.ClientAssertionKey); err != nil {
+		return err
+	}
+	if err := validateAudiencePolicy(c.Audiences, c.AllowAnyAudience); err != nil {
+		return err
+	}
+	switch {
+	case c.Leeway < 0:
+		return fmt.Errorf("authn: leeway must not be negative: %s", c.Leeway)
+	case c.Leeway == 0:
+		c.Leeway = defaultLeeway
+	case c.Leeway > maxLeeway:
+		return fmt.Errorf("authn: leeway %s exceeds maximum %s", c.Leeway, maxLeeway)
+	}
+	switch {
+	case c.CacheSize < 0:
+		return fmt.Errorf("authn: introspection cache size must not be negative: %d", c.CacheSize)
+	case c.CacheSize == 0:
+		c.CacheSize = defaultIntrospectionCacheSize
+	}
+	if c.MaxCacheTTL < 0 {
+		return fmt.Errorf("authn: introspection max cache TTL must not be negative: %s", c.MaxCacheTTL)
+	}
+	return nil
+}
+
+// Validate introspects token and returns the Principal the endpoint vouches
+// for. Every failure is a *Error:
+//
+//   - the endpoint reports the token inactive: CodeInvalidToken,
+//     ReasonInactive.
+//   - the response is active but fails local policy (exp, nbf, iss, aud,
+//     sub): CodeInvalidToken with the same Reason Validator uses for that
+//     claim. Introspection does not excuse a token from the resource
+//     server's own policy.
+//   - the endpoint cannot be reached or answers with anything but a 200 JSON
+//     document: CodeUnavailable, ReasonIntrospectionUnavailable. A failed
+//     call is not evidence the token is bad.
+//
+// An active response is cached (see IntrospectionConfig.CacheSize), and a
+// cache hit makes no request. Each call returns its own copy of the claims,
+// so a caller mutating Principal.Claims cannot alter what later calls see.
+func (i *Introspector) Validate(ctx context.Context, token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed,
+			err: errors.New("empty token")}
+	}
+	if len(token) > maxTokenLength {
+		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed,
+			err: fmt.Errorf("token length %d exceeds %d byte limit", len(token), maxTokenLength)}
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if p, ok := i.cache.get(key, time.Now()); ok {
+		return clonePrincipal(p), nil
+	}
+
+	resp, err := i.introspect(ctx, token)
+	if err != nil {
+		return Principal{}, &Error{Code: CodeUnavailable, Reason: ReasonIntrospectionUnavailable, err: err}
+	}
+	p, expires, err := i.principalFromResponse(resp)
+	if err != nil {
+		return Principal{}, err
+	}
+	if !expires.IsZero() {
+		now := time.Now()
+		if i.cfg.MaxCacheTTL > 0 && expires.After(now.Add(i.cfg.MaxCacheTTL)) {
+			expires = now.Add(i.cfg.MaxCacheTTL)
+		}
+		if expires.After(now) {
+			i.cache.add(key, p, expires)
+		}
+	}
+	return clonePrincipal(p), nil
+}
+
+// introspect performs the RFC 7662 §2.1 request and returns the decoded
+// response members.
+func (i *Introspector) introspect(ctx context.Context, token string) (map[string]any, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	opts, err := i.clientAuth().apply(form)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, networking.WithMaxResponseSize(maxResponseBody))
+
+	result, err := networking.FetchJSONWithForm[map[string]any](ctx, i.httpClient, i.cfg.Endpoint, form, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	if result.Data == nil {
+		return nil, errors.New("introspection response is not a JSON object")
+	}
+	return result.Data, nil
+}
+
+// clientAuth returns the credentials the Introspector presents to the
+// endpoint.
+func (i *Introspector) clientAuth() clientAuth {
+	return clientAuth{
+		method:   i.cfg.AuthMethod,
+		clientID: i.cfg.ClientID,
+		secret:   i.cfg.ClientSecret,
+		key:      i.cfg.ClientAssertionKey,
+		keyID:    i.cfg.ClientAssertionKeyID,
+		audience: i.cfg.Endpoint,
+	}
+}
+
+// principalFromResponse applies local policy to an introspection response
+// and builds the Principal, also returning the token's exp (zero when the
+// response carries none) for the cache.
+func (i *Introspector) principalFromResponse(resp map[string]any) (Principal, time.Time, error) {
+	// RFC 7662 §2.2: active is REQUIRED and boolean. Anything other than a
+	// literal true — false, absent, "true" — is treated as inactive.
+	if active, _ := resp["active"].(bool); !active {
+		return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonInactive,
+			err: errors.New("introspection endpoint reports the token inactive")}
+	}
+
+	claims := jwt.MapClaims(resp)
+	now := time.Now()
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonInvalidClaims, err: err}
+	}
+	var expires time.Time
+	if exp != nil {
+		// Leeway widens acceptance only; the cache entry lapses at exp itself.
+		expires = exp.Time
+		if !now.Before(expires.Add(i.cfg.Leeway)) {
+			return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonExpired,
+				err: fmt.Errorf("introspected token expired at %s", exp.UTC().Format(time.RFC3339))}
+		}
+	}
+	nbf, err := claims.GetNotBefore()
+	if err != nil {
+		return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonInvalidClaims, err: err}
+	}
+	if nbf != nil && now.Add(i.cfg.Leeway).Before(nbf.Time) {
+		return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonNotYetValid,
+			err: fmt.Errorf("introspected token not valid before %s", nbf.UTC().Format(time.RFC3339))}
+	}
+
+	iss := stringClaim(claims, "iss")
+	if i.cfg.Issuer != "" && iss != i.cfg.Issuer {
+		return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonIssuer,
+			err: fmt.Errorf("introspected iss %q does not match the configured issuer", iss)}
+	}
+	if len(i.cfg.Audiences) > 0 {
+		aud, err := claims.GetAudience()
+		if err != nil || !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(i.cfg.Audiences, a) }) {
+			return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonAudience,
+				err: errors.New("introspected aud matches no configured audience")}
+		}
+	}
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return Principal{}, time.Time{}, &Error{Code: CodeInvalidToken, Reason: ReasonSubject,
+			err: errors.New("missing or empty sub in introspection response")}
+	}
+
+	return Principal{
+		Issuer:  iss,
+		Subject: sub,
+		Name:    stringClaim(claims, "name"),
+		Claims:  resp,
+	}, expires, nil
+}
+
+// clonePrincipal returns p with its own top-level Claims map. Nested values
+// are shared; they are decoded JSON and nothing in this package mutates them.
+func clonePrincipal(p Principal) Principal {
+	p.Claims = maps.Clone(p.Claims)
+	return p
+}
+
+// IntrospectionFallback returns a TokenValidator that verifies a token
+// locally with primary and falls back to introspector ONLY when primary
+// reports PossiblyOpaque — the token did not parse as a JWT at all.
+//
+// Every other primary failure is final. A JWT with a bad signature or the
+// wrong audience must not get a second chance at an endpoint that might judge
+// it differently, and sending such a token off-host would hand a third party
+// a credential it never needed to see.
+func IntrospectionFallback(primary, introspector TokenValidator) TokenValidator {
+	return &fallbackValidator{primary: primary, introspector: introspector}
+}
+
+// fallbackValidator is the TokenValidator returned by IntrospectionFallback.
+type fallbackValidator struct {
+	primary      TokenValidator
+	introspector TokenValidator
+}
+
+// Validate implements TokenValidator.
+func (f *fallbackValidator) Validate(ctx context.Context, token string) (Principal, error) {
+	p, err := f.primary.Validate(ctx, token)
+	if err == nil || !PossiblyOpaque(err) {
+		return p, err
+	}
+	return f.introspector.Validate(ctx, token)
+}
@@ -0,0 +1,433 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID     = "resource-server"
+	testClientSecret = "s3cr:et"
+	testOpaqueToken  = "opaque-token-value"
+)
+
+// introspectionServer is an httptest RFC 7662 endpoint answering with the
+// response set by the test, recording each request form it received.
+type introspectionServer struct {
+	srv      *httptest.Server
+	hits     atomic.Int32
+	response atomic.Pointer[map[string]any]
+	lastReq  atomic.Pointer[http.Request]
+}
+
+func newIntrospectionServer(t *testing.T, response map[string]any) *introspectionServer {
+	t.Helper()
+	is := &introspectionServer{}
+	is.response.Store(&response)
+	is.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.hits.Add(1)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		is.lastReq.Store(r)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(*is.response.Load())
+	}))
+	t.Cleanup(is.srv.Close)
+	return is
+}
+
+func (is *introspectionServer) configFor() IntrospectionConfig {
+	return IntrospectionConfig{
+		Endpoint:          is.srv.URL + "/introspect",
+		ClientID:          testClientID,
+		ClientSecret:      testClientSecret,
+		Audiences:         []string{testAPIAud},
+		InsecureAllowHTTP: true,
+		AllowPrivateIP:    true,
+	}
+}
+
+func activeResponse() map[string]any {
+	return map[string]any{
+		"active": true,
+		"iss":    "https://issuer.example.com",
+		"sub":    testSubject,
+		"aud":    testAPIAud,
+		"name":   "Alice",
+		"scope":  "read write",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestIntrospectorAcceptsActiveToken(t *testing.T) {
+	t.Parallel()
+
+	is := newIntrospectionServer(t, activeResponse())
+	in, err := NewIntrospector(is.configFor())
+	require.NoError(t, err)
+
+	p, err := in.Validate(context.Background(), testOpaqueToken)
+	require.NoError(t, err)
+	assert.Equal(t, testSubject, p.Subject)
+	assert.Equal(t, "https://issuer.example.com", p.Issuer)
+	assert.Equal(t, "Alice", p.Name)
+	assert.Equal(t, "read write", p.Claims["scope"])
+
+	req := is.lastReq.Load()
+	assert.Equal(t, testOpaqueToken, req.PostForm.Get("token"))
+	assert.Equal(t, "access_token", req.PostForm.Get("token_type_hint"))
+	user, pass, ok := req.BasicAuth()
+	require.True(t, ok, "client_secret_basic is the default")
+	// RFC 6749 §2.3.1 form-encodes the credentials before Basic; the colon in
+	// the secret must survive the round trip percent-encoded.
+	assert.Equal(t, testClientID, user)
+	assert.Equal(t, "s3cr%3Aet", pass)
+	assert.Empty(t, req.PostForm.Get("client_secret"))
+}
+
+func TestIntrospectorCachesActiveResponses(t *testing.T) {
+	t.Parallel()
+
+	is := newIntrospectionServer(t, activeResponse())
+	in, err := NewIntrospector(is.configFor())
+	require.NoError(t, err)
+
+	p, err := in.Validate(context.Background(), testOpaqueToken)
+	require.NoError(t, err)
+	p.Claims["sub"] = "mallory"
+
+	p, err = in.Validate(context.Background(), testOpaqueToken)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), is.hits.Load(), "the second call must be served from cache")
+	assert.Equal(t, testSubject, p.Claims["sub"], "a caller's mutation must not reach the cache")
+
+	_, err = in.Validate(context.Background(), "another-token")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), is.hits.Load(), "the cache is keyed by token")
+}
+
+func TestIntrospectorMaxCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	is := newIntrospectionServer(t, activeResponse())
+	cfg := is.configFor()
+	cfg.MaxCacheTTL = time.Nanosecond
+	in, err := NewIntrospector(cfg)
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := in.Validate(context.Background(), testOpaqueToken)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), is.hits.Load())
+}
+
+func TestIntrospectorDoesNotCacheInactive(t *testing.T) {
+	t.Parallel()
+
+	is := newIntrospectionServer(t, map[string]any{"active": false})
+	in, err := NewIntrospector(is.configFor())
+	require.NoError(t, err)
+
+	_, err = in.Validate(context.Background(), testOpaqueToken)
+	requireAuthnError(t, err, CodeInvalidToken, ReasonInactive)
+
+	active := activeResponse()
+	is.response.Store(&active)
+	_, err = in.Validate(context.Background(), testOpaqueToken)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), is.hits.Load())
+}
+
+func TestIntrospectorPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		mutate     func(map[string]any)
+		wantReason Reason
+	}{
+		{
+			name:       "active as a string is inactive",
+			mutate:     func(r map[string]any) { r["active"] = "true" },
+			wantReason: ReasonInactive,
+		},
+		{
+			name:       "active absent is inactive",
+			mutate:     func(r map[string]any) { delete(r, "active") },
+			wantReason: ReasonInactive,
+		},
+		{
+			name:       "expired",
+			mutate:     func(r map[string]any) { r["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantReason: ReasonExpired,
+		},
+		{
+			name:       "not yet valid",
+			mutate:     func(r map[string]any) { r["nbf"] = time.Now().Add(time.Hour).Unix() },
+			wantReason: ReasonNotYetValid,
+		},
+		{
+			name:       "wrong audience",
+			mutate:     func(r map[string]any) { r["aud"] = []string{"other-api"} },
+			wantReason: ReasonAudience,
+		},
+		{
+			name:       "missing audience",
+			mutate:     func(r map[string]any) { delete(r, "aud") },
+			wantReason: ReasonAudience,
+		},
+		{
+			name:       "wrong issuer",
+			mutate:     func(r map[string]any) { r["iss"] = "https://evil.example.com" },
+			wantReason: ReasonIssuer,
+		},
+		{
+			name:       "missing subject",
+			mutate:     func(r map[string]any) { delete(r, "sub") },
+			wantReason: ReasonSubject,
+		},
+		{
+			name:       "exp of the wrong type",
+			mutate:     func(r map[string]any) { r["exp"] = "tomorrow" },
+			wantReason: ReasonInvalidClaims,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := activeResponse()
+			tc.mutate(resp)
+			is := newIntrospectionServer(t, resp)
+			cfg := is.configFor()
+			cfg.Issuer = "https://issuer.example.com"
+			in, err := NewIntrospector(cfg)
+			require.NoError(t, err)
+
+			_, err = in.Validate(context.Background(), testOpaqueToken)
+			requireAuthnError(t, err, CodeInvalidToken, tc.wantReason)
+		})
+	}
+}
+
+func TestIntrospectorUnavailable(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	in, err := NewIntrospector(IntrospectionConfig{
+		Endpoint:          srv.URL,
+		ClientID:          testClientID,
+		ClientSecret:      testClientSecret,
+		AllowAnyAudience:  true,
+		InsecureAllowHTTP: true,
+		AllowPrivateIP:    true,
+	})
+	require.NoError(t, err)
+
+	_, err = in.Validate(context.Background(), testOpaqueToken)
+	requireAuthnError(t, err, CodeUnavailable, ReasonIntrospectionUnavailable)
+	assert.NotContains(t, err.Error(), testOpaqueToken, "the token must not reach the log detail")
+}
+
+func TestIntrospectorClientSecretPost(t *testing.T) {
+	t.Parallel()
+
+	is := newIntrospectionServer(t, activeResponse())
+	cfg := is.configFor()
+	cfg.AuthMethod = ClientAuthClientSecretPost
+	in, err := NewIntrospector(cfg)
+	require.NoError(t, err)
+
+	_, err = in.Validate(context.Background(), testOpaqueToken)
+	require.NoError(t, err)
+
+	req := is.lastReq.Load()
+	_, _, hasBasic := req.BasicAuth()
+	assert.False(t, hasBasic)
+	assert.Equal(t, testClientID, req.PostForm.Get("client_id"))
+	assert.Equal(t, testClientSecret, req.PostForm.Get("client_secret"))
+}
+
+func TestIntrospectorPrivateKeyJWT(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	is := newIntrospectionServer(t, activeResponse())
+	cfg := is.configFor()
+	cfg.AuthMethod = ClientAuthPrivateKeyJWT
+	cfg.ClientSecret = ""
+	cfg.ClientAssertionKey = key
+	cfg.ClientAssertionKeyID = "client-key-1"
+	in, err := NewIntrospector(cfg)
+	require.NoError(t, err)
+
+	_, err = in.Validate(context.Background(), testOpaqueToken)
+	require.NoError(t, err)
+
+	req := is.lastReq.Load()
+	assert.Equal(t, testClientID, req.PostForm.Get("client_id"))
+	assert.Equal(t, clientAssertionType, req.PostForm.Get("client_assertion_type"))
+
+	claims := jwt.MapClaims{}
+	tok, err := jwt.NewParser(jwt.WithValidMethods([]string{"ES256"}), jwt.WithExpirationRequired()).
+		ParseWithClaims(req.PostForm.Get("client_assertion"), claims,
+			func(*jwt.Token) (any, error) { return &key.PublicKey, nil })
+	require.NoError(t, err)
+	assert.Equal(t, "client-key-1", tok.Header["kid"])
+	assert.Equal(t, testClientID, claims["iss"])
+	assert.Equal(t, testClientID, claims["sub"])
+	assert.Equal(t, cfg.Endpoint, claims["aud"])
+	assert.NotEmpty(t, claims["jti"])
+}
+
+func TestNewIntrospectorRejectsBadConfig(t *testing.T) {
+	t.Parallel()
+
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024) //nolint:gosec // deliberately undersized
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		mutate  func(*IntrospectionConfig)
+		wantErr string
+	}{
+		{
+			name:    "missing endpoint",
+			mutate:  func(c *IntrospectionConfig) { c.Endpoint = "" },
+			wantErr: "endpoint is required",
+		},
+		{
+			name:    "http endpoint without InsecureAllowHTTP",
+			mutate:  func(c *IntrospectionConfig) { c.InsecureAllowHTTP = false },
+			wantErr: "must use https",
+		},
+		{
+			name:    "missing client id",
+			mutate:  func(c *IntrospectionConfig) { c.ClientID = "" },
+			wantErr: "client id is required",
+		},
+		{
+			name:    "basic without secret",
+			mutate:  func(c *IntrospectionConfig) { c.ClientSecret = "" },
+			wantErr: "requires a client secret",
+		},
+		{
+			name: "private_key_jwt with a secret",
+			mutate: func(c *IntrospectionConfig) {
+				c.AuthMethod = ClientAuthPrivateKeyJWT
+			},
+			wantErr: "must not be given a client secret",
+		},
+		{
+			name: "private_key_jwt without a key",
+			mutate: func(c *IntrospectionConfig) {
+				c.AuthMethod = ClientAuthPrivateKeyJWT
+				c.ClientSecret = ""
+			},
+			wantErr: "requires a client assertion key",
+		},
+		{
+			name: "private_key_jwt with a short RSA key",
+			mutate: func(c *IntrospectionConfig) {
+				c.AuthMethod = ClientAuthPrivateKeyJWT
+				c.ClientSecret = ""
+				c.ClientAssertionKey = smallRSA
+			},
+			wantErr: "below the 2048-bit minimum",
+		},
+		{
+			name:    "unknown auth method",
+			mutate:  func(c *IntrospectionConfig) { c.AuthMethod = "tls_client_auth" },
+			wantErr: "unsupported introspection auth method",
+		},
+		{
+			name:    "no audience policy",
+			mutate:  func(c *IntrospectionConfig) { c.Audiences = nil },
+			wantErr: "at least one audience is required",
+		},
+		{
+			name:    "negative cache size",
+			mutate:  func(c *IntrospectionConfig) { c.CacheSize = -1 },
+			wantErr: "cache size must not be negative",
+		},
+		{
+			name:    "negative max cache TTL",
+			mutate:  func(c *IntrospectionConfig) { c.MaxCacheTTL = -time.Second },
+			wantErr: "max cache TTL must not be negative",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := IntrospectionConfig{
+				Endpoint:          "http://127.0.0.1:1/introspect",
+				ClientID:          testClientID,
+				ClientSecret:      testClientSecret,
+				Audiences:         []string{testAPIAud},
+				InsecureAllowHTTP: true,
+			}
+			tc.mutate(&cfg)
+			_, err := NewIntrospector(cfg)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestIntrospectionFallback(t *testing.T) {
+	t.Parallel()
+
+	introspector := &fakeTokenValidator{principal: Principal{Subject: "opaque-user"}}
+
+	t.Run("possibly opaque falls back", func(t *testing.T) {
+		t.Parallel()
+		primary := &fakeTokenValidator{err: &Error{Code: CodeInvalidToken, Reason: ReasonMalformed}}
+		in := &fakeTokenValidator{principal: Principal{Subject: "opaque-user"}}
+		p, err := IntrospectionFallback(primary, in).Validate(context.Background(), testOpaqueToken)
+		require.NoError(t, err)
+		assert.Equal(t, "opaque-user", p.Subject)
+		assert.Equal(t, testOpaqueToken, in.got)
+	})
+
+	t.Run("a rejected JWT is final", func(t *testing.T) {
+		t.Parallel()
+		primary := &fakeTokenValidator{err: &Error{Code: CodeInvalidToken, Reason: ReasonSignature}}
+		_, err := IntrospectionFallback(primary, introspector).Validate(context.Background(), testToken)
+		requireAuthnError(t, err, CodeInvalidToken, ReasonSignature)
+	})
+
+	t.Run("a verified JWT is not introspected", func(t *testing.T) {
+		t.Parallel()
+		primary := &fakeTokenValidator{principal: Principal{Subject: testSubject}}
+		in := &fakeTokenValidator{err: &Error{Code: CodeUnavailable, Reason: ReasonIntrospectionUnavailable}}
+		p, err := IntrospectionFallback(primary, in).Validate(context.Background(), testToken)
+		require.NoError(t, err)
+		assert.Equal(t, testSubject, p.Subject)
+		assert.Empty(t, in.got)
+	})
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package issuer mints RFC 9068 JWT access tokens and publishes the keys that
+verify them. It is the other half of package authn: tests use it to produce
+tokens a real Validator accepts, and a server that embeds its own
+authorization server uses it to sign what it hands out.
+
+# Basic Usage
+
+Generate or load a signing key, create an Issuer, and mint:
+
+	key, err := issuer.GenerateSigningKey("ES256")
+	if err != nil {
+	    // handle error
+	}
+	iss, err := issuer.New(issuer.Config{
+	    Issuer:          "https://auth.example.com",
+	    Keys:            []issuer.SigningKey{key},
+	    DefaultClientID: "mcp-gateway",
+	})
+	if err != nil {
+	    // handle error
+	}
+
+	token, err := iss.Mint(issuer.TokenRequest{
+	    Subject:  "user-123",
+	    Audience: []string{"https://mcp.example.com"},
+	    Scopes:   []string{"tools:read"},
+	})
+
+Tokens carry iss, sub, aud, iat, exp, client_id and a random jti, plus scope
+when requested, a kid header naming the signing key and a typ header of
+"at+jwt". RFC 9068 requires client_id, so Mint fails when neither the request
+nor Config.DefaultClientID sets it. TokenRequest.Claims adds or overrides any claim, and deletes one
+given a nil value, so a test can mint exactly the malformed token it needs.
+
+# Verifying
+
+An Issuer is an authn.KeyProvider, so a Validator in the same process can use
+it directly, with no discovery or JWKS fetch:
+
+	v, err := authn.NewValidator(ctx, authn.Config{
+	    Issuer:          iss.URL(),
+	    Audiences:       []string{"https://mcp.example.com"},
+	    KeyProvider:     iss,
+	    KeyProviderOnly: true,
+	})
+
+Handler serves the OpenID Connect discovery document (also at the RFC 8414
+path) and the JWKS relative to the issuer URL, so a Validator pointed at an
+httptest.Server running it exercises the full discovery path.
+
+# Delegation
+
+TokenRequest.Actor sets the RFC 8693 act claim; nesting Actors records a
+chain, the most recent actor outermost:
+
+	token, err := iss.Mint(issuer.TokenRequest{
+	    Subject:  "user-123",
+	    Audience: []string{"https://mcp.example.com"},
+	    Actor:    &issuer.Actor{Subject: "gateway", Actor: &issuer.Actor{Subject: "agent"}},
+	})
+
+# Key Rotation
+
+The first configured key signs; every key is published. Rotate publishes and
+activates a new key in one step. A deployment whose validators cache the JWKS
+should instead AddKey, wait out the cache lifetime, then Activate, and finally
+RemoveKey the old key once tokens it signed have expired.
+
+# Security
+
+Signing keys are RSA (at least 2048 bits, RS* or PS*) or ECDSA on the curve
+the alg names (ES*). HMAC is not offered: authn refuses it, since a symmetric
+key cannot be published. An Issuer holds private keys in memory and keeps no
+record of what it minted, so tokens cannot be revoked before they expire; keep
+lifetimes short.
+
+# Stability
+
+This package is Alpha stability. The API may change without notice.
+*/
+package issuer
@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package issuer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/stacklok/toolhive-core/authn"
+)
+
+const (
+	// defaultLifetime is the token lifetime when neither the request nor
+	// Config sets one. Short, because an embedded issuer's tokens are
+	// re-minted cheaply and cannot be revoked.
+	defaultLifetime = 15 * time.Minute
+
+	// defaultJWKSPath is where the JWKS is served, relative to the issuer.
+	defaultJWKSPath = "/.well-known/jwks.json"
+
+	// oidcDiscoveryPath is appended to the issuer path (OpenID Connect
+	// Discovery 1.0 §4.1); it is the document authn.NewValidator fetches.
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+	// oauthMetadataPath is inserted before the issuer path (RFC 8414 §3.1).
+	oauthMetadataPath = "/.well-known/oauth-authorization-server"
+)
+
+// Config configures an Issuer.
+type Config struct {
+	// Issuer is the iss claim value and the base of every URL the issuer
+	// serves. It must be an absolute http or https URL with no query or
+	// fragment; nothing here enforces https, since tests serve from httptest.
+	Issuer string
+
+	// Keys are the signing keys. The first signs new tokens; the others are
+	// published in the JWKS so tokens they signed earlier still verify. At
+	// least one is required. Use Rotate, AddKey and RemoveKey to change the
+	// set later.
+	Keys []SigningKey
+
+	// DefaultLifetime is the lifetime of a token whose request sets none.
+	// Zero uses 15 minutes; negative is an error.
+	DefaultLifetime time.Duration
+
+	// DefaultClientID is the client_id of a token whose request sets none.
+	// RFC 9068 §2.2 requires the claim, so with neither set Mint fails.
+	DefaultClientID string
+
+	// JWKSPath is where JWKSHandler is mounted by Handler, relative to the
+	// issuer URL; jwks_uri advertises Issuer+JWKSPath. Empty uses
+	// /.well-known/jwks.json.
+	JWKSPath string
+
+	// Metadata holds extra members for the discovery document — a
+	// token_endpoint the embedding server implements, say. issuer and jwks_uri
+	// are always set by the Issuer and cannot be overridden here.
+	Metadata map[string]any
+}
+
+// Issuer signs RFC 9068 JWT access tokens and publishes the keys to verify
+// them, for tests and for an authorization server embedded alongside a
+// resource server.
+//
+// It implements authn.KeyProvider, so a Validator in the same process can
+// verify its tokens without an HTTP round trip, and Handler serves the
+// discovery document and JWKS for everything else. It is safe for concurrent
+// use, including key rotation during minting.
+type Issuer struct {
+	issuer          string
+	jwksURI         string
+	jwksPath        string
+	discoveryPaths  []string
+	defaultLifetime time.Duration
+	defaultClientID string
+	metadata        []byte
+
+	// mu guards keys and jwks. keys[0] is the active signing key.
+	mu   sync.RWMutex
+	keys []SigningKey
+	// jwks is the serialized public key set, rebuilt whenever keys change
+	// so JWKSHandler serves bytes rather than re-encoding per request.
+	jwks []byte
+}
+
+// Compile-time check that *Issuer can back a Validator directly.
+var _ authn.KeyProvider = (*Issuer)(nil)
+
+// New validates cfg and returns an Issuer.
+func New(cfg Config) (*Issuer, error) {
+	u, err := url.Parse(cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("issuer: invalid issuer URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("issuer: issuer must be an absolute http(s) URL without query or fragment: %s", cfg.Issuer)
+	}
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("issuer: at least one signing key is required")
+	}
+	if cfg.DefaultLifetime < 0 {
+		return nil, fmt.Errorf("issuer: default lifetime must not be negative: %s", cfg.DefaultLifetime)
+	}
+	if cfg.DefaultLifetime == 0 {
+		cfg.DefaultLifetime = defaultLifetime
+	}
+	if cfg.JWKSPath == "" {
+		cfg.JWKSPath = defaultJWKSPath
+	}
+	if !strings.HasPrefix(cfg.JWKSPath, "/") {
+		return nil, fmt.Errorf("issuer: jwks path must start with /: %s", cfg.JWKSPath)
+	}
+
+	keys := make([]SigningKey, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		k, err := normalizeKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(keys, func(have SigningKey) bool { return have.KeyID == k.KeyID }) {
+			return nil, fmt.Errorf("issuer: duplicate key id %q", k.KeyID)
+		}
+		keys = append(keys, k)
+	}
+
+	base := strings.TrimSuffix(cfg.Issuer, "/")
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	i := &Issuer{
+		issuer:   cfg.Issuer,
+		jwksURI:  base + cfg.JWKSPath,
+		jwksPath: path + cfg.JWKSPath,
+		// Serve discovery at both conventions: OIDC appends the well-known
+		// suffix to the issuer path, RFC 8414 inserts it before. They differ
+		// only for an issuer with a path.
+		discoveryPaths:  slices.Compact([]string{path + oidcDiscoveryPath, oauthMetadataPath + path}),
+		defaultLifetime: cfg.DefaultLifetime,
+		defaultClientID: cfg.DefaultClientID,
+	}
+
+	doc := maps.Clone(cfg.Metadata)
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	doc["issuer"] = i.issuer
+	doc["jwks_uri"] = i.jwksURI
+	if i.metadata, err = json.Marshal(doc); err != nil {
+		return nil, fmt.Errorf("issuer: failed to encode metadata: %w", err)
+	}
+
+	if err := i.setKeys(keys); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// URL returns the issuer identifier, the iss of every token.
+func (i *Issuer) URL() string {
+	return i.issuer
+}
+
+// JWKSURI returns the URL the discovery document advertises as jwks_uri.
+func (i *Issuer) JWKSURI() string {
+	return i.jwksURI
+}
+
+// PublicKeys returns the public half of every published key, implementing
+// authn.KeyProvider.
+func (i *Issuer) PublicKeys(_ context.Context) ([]authn.PublicKey, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	out := make([]authn.PublicKey, 0, len(i.keys))
+	for _, k := range i.keys {
+		out = append(out, authn.PublicKey{KeyID: k.KeyID, Alg: k.Alg, Key: k.Key.Public()})
+	}
+	return out, nil
+}
+
+// AddKey publishes key without signing with it yet. Publishing a new key
+// ahead of activating it is what lets validators that cache the JWKS learn it
+// before the first token it signs arrives.
+func (i *Issuer) AddKey(key SigningKey) error {
+	key, err := normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if slices.ContainsFunc(i.keys, func(k SigningKey) bool { return k.KeyID == key.KeyID }) {
+		return fmt.Errorf("issuer: duplicate key id %q", key.KeyID)
+	}
+	return i.setKeys(append(slices.Clone(i.keys), key))
+}
+
+// Activate makes the published key kid the one new tokens are signed with.
+func (i *Issuer) Activate(kid string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	idx := slices.IndexFunc(i.keys, func(k SigningKey) bool { return k.KeyID == kid })
+	if idx < 0 {
+		return fmt.Errorf("issuer: unknown key id %q", kid)
+	}
+	keys := slices.Clone(i.keys)
+	keys[0], keys[idx] = keys[idx], keys[0]
+	return i.setKeys(keys)
+}
+
+// Rotate publishes key and signs with it from now on, keeping the previous
+// keys published until RemoveKey retires them. Unlike AddKey followed by
+// Activate, it is one step: no concurrent call sees key published but not
+// active.
+func (i *Issuer) Rotate(key SigningKey) error {
+	key, err := normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if slices.ContainsFunc(i.keys, func(k SigningKey) bool { return k.KeyID == key.KeyID }) {
+		return fmt.Errorf("issuer: duplicate key id %q", key.KeyID)
+	}
+	keys := append(slices.Clone(i.keys), key)
+	last := len(keys) - 1
+	keys[0], keys[last] = keys[last], keys[0]
+	return i.setKeys(keys)
+}
+
+// RemoveKey stops publishing kid; tokens it signed stop verifying once
+// validators refresh. The active key cannot be removed.
+func (i *Issuer) RemoveKey(kid string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	idx := slices.IndexFunc(i.keys, func(k SigningKey) bool { return k.KeyID == kid })
+	switch {
+	case idx < 0:
+		return fmt.Errorf("issuer: unknown key id %q", kid)
+	case idx == 0:
+		return fmt.Errorf("issuer: cannot remove the active key %q", kid)
+	}
+	return i.setKeys(slices.Delete(slices.Clone(i.keys), idx, idx+1))
+}
+
+// setKeys installs keys and re-encodes the JWKS. The caller holds mu for
+// writing, or is New, before the Issuer is shared.
+func (i *Issuer) setKeys(keys []SigningKey) error {
+	set := jwk.NewSet()
+	for _, k := range keys {
+		pub, err := publicJWK(k)
+		if err != nil {
+			return err
+		}
+		if err := set.AddKey(pub); err != nil {
+			return fmt.Errorf("issuer: key %q: %w", k.KeyID, err)
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("issuer: failed to encode JWKS: %w", err)
+	}
+	i.keys, i.jwks = keys, data
+	return nil
+}
+
+// JWKSHandler serves the public key set.
+func (i *Issuer) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		i.mu.RLock()
+		data := i.jwks
+		i.mu.RUnlock()
+		writeJSON(w, data)
+	})
+}
+
+// DiscoveryHandler serves the discovery document: issuer, jwks_uri and any
+// Config.Metadata. The same document answers both the OpenID Connect and the
+// RFC 8414 well-known path.
+func (i *Issuer) DiscoveryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, i.metadata)
+	})
+}
+
+// Handler serves the discovery document at its well-known paths and the JWKS
+// at JWKSPath, all relative to the issuer URL, and 404 for anything else.
+// Mount it at the root of the server the issuer URL names.
+func (i *Issuer) Handler() http.Handler {
+	discovery, jwks := i.DiscoveryHandler(), i.JWKSHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		switch path := r.URL.EscapedPath(); {
+		case path == i.jwksPath:
+			jwks.ServeHTTP(w, r)
+		case slices.Contains(i.discoveryPaths, path):
+			discovery.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package issuer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/authn"
+)
+
+const (
+	testAudience = "https://mcp.example.com"
+	testClientID = "test-client"
+)
+
+// newTestIssuer returns an Issuer for issuerURL signing with a fresh ES256
+// key.
+func newTestIssuer(t *testing.T, issuerURL string) *Issuer {
+	t.Helper()
+	key, err := GenerateSigningKey("ES256")
+	require.NoError(t, err)
+	iss, err := New(Config{Issuer: issuerURL, Keys: []SigningKey{key}, DefaultClientID: testClientID})
+	require.NoError(t, err)
+	return iss
+}
+
+// newProviderValidator returns a Validator that trusts iss as its only key
+// source.
+func newProviderValidator(t *testing.T, iss *Issuer, mutate func(*authn.Config)) *authn.Validator {
+	t.Helper()
+	cfg := authn.Config{
+		Issuer:          iss.URL(),
+		Audiences:       []string{testAudience},
+		KeyProvider:     iss,
+		KeyProviderOnly: true,
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	v, err := authn.NewValidator(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+	return v
+}
+
+func TestNewConfig(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateSigningKey("ES256")
+	require.NoError(t, err)
+	other, err := GenerateSigningKey("ES256")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "valid", cfg: Config{Issuer: "https://auth.example.com", Keys: []SigningKey{key}}},
+		{name: "valid with path", cfg: Config{Issuer: "https://auth.example.com/realms/x", Keys: []SigningKey{key, other}}},
+		{name: "relative issuer", cfg: Config{Issuer: "/auth", Keys: []SigningKey{key}}, wantErr: "absolute http(s) URL"},
+		{name: "issuer with query", cfg: Config{Issuer: "https://auth.example.com?a=b", Keys: []SigningKey{key}}, wantErr: "without query or fragment"},
+		{name: "non-http issuer", cfg: Config{Issuer: "ftp://auth.example.com", Keys: []SigningKey{key}}, wantErr: "absolute http(s) URL"},
+		{name: "no keys", cfg: Config{Issuer: "https://auth.example.com"}, wantErr: "at least one signing key"},
+		{name: "duplicate kid", cfg: Config{Issuer: "https://auth.example.com", Keys: []SigningKey{key, key}}, wantErr: "duplicate key id"},
+		{name: "invalid key", cfg: Config{Issuer: "https://auth.example.com", Keys: []SigningKey{{Alg: "RS256", Key: key.Key}}}, wantErr: "cannot sign with an ECDSA key"},
+		{
+			name:    "negative lifetime",
+			cfg:     Config{Issuer: "https://auth.example.com", Keys: []SigningKey{key}, DefaultLifetime: -time.Minute},
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "relative jwks path",
+			cfg:     Config{Issuer: "https://auth.example.com", Keys: []SigningKey{key}, JWKSPath: "jwks"},
+			wantErr: "must start with /",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			iss, err := New(tt.cfg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, iss)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.cfg.Issuer, iss.URL())
+		})
+	}
+}
+
+// TestIssuerDiscovery points a real Validator at an httptest server running
+// Handler, so construction goes through OIDC discovery and the JWKS fetch.
+func TestIssuerDiscovery(t *testing.T) {
+	t.Parallel()
+
+	var iss *Issuer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iss.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	iss = newTestIssuer(t, srv.URL)
+
+	v, err := authn.NewValidator(context.Background(), authn.Config{
+		Issuer:            srv.URL,
+		Audiences:         []string{testAudience},
+		InsecureAllowHTTP: true,
+		AllowPrivateIP:    true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+
+	token, err := iss.Mint(TokenRequest{Subject: "user-1", Audience: []string{testAudience}})
+	require.NoError(t, err)
+	p, err := v.Validate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", p.Subject)
+	assert.Equal(t, srv.URL, p.Issuer)
+}
+
+func TestIssuerHandler(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateSigningKey("RS256")
+	require.NoError(t, err)
+	iss, err := New(Config{
+		Issuer: "https://auth.example.com/tenant",
+		Keys:   []SigningKey{key},
+		Metadata: map[string]any{
+			"token_endpoint": "https://auth.example.com/tenant/token",
+			"issuer":         "https://attacker.example.com",
+		},
+	})
+	require.NoError(t, err)
+	h := iss.Handler()
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "https://auth.example.com"+path, nil))
+		return rec
+	}
+
+	for _, path := range []string{
+		"/tenant/.well-known/openid-configuration",
+		"/.well-known/oauth-authorization-server/tenant",
+	} {
+		rec := get(http.MethodGet, path)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+		assert.Equal(t, "https://auth.example.com/tenant", doc["issuer"], "Metadata cannot override issuer")
+		assert.Equal(t, "https://auth.example.com/tenant/.well-known/jwks.json", doc["jwks_uri"])
+		assert.Equal(t, "https://auth.example.com/tenant/token", doc["token_endpoint"])
+	}
+
+	rec := get(http.MethodGet, "/tenant/.well-known/jwks.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	set, err := jwk.Parse(rec.Body.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, 1, set.Len())
+	pub, ok := set.Key(0)
+	require.True(t, ok)
+	kid, _ := pub.KeyID()
+	assert.Equal(t, key.KeyID, kid)
+	_, isPrivate := pub.(jwk.RSAPrivateKey)
+	assert.False(t, isPrivate, "the JWKS must carry only public keys")
+
+	assert.Equal(t, http.StatusNotFound, get(http.MethodGet, "/.well-known/openid-configuration").Code)
+	rec = get(http.MethodPost, "/tenant/.well-known/jwks.json")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
+
+func TestIssuerRotation(t *testing.T) {
+	t.Parallel()
+
+	iss := newTestIssuer(t, "https://auth.example.com")
+	v := newProviderValidator(t, iss, nil)
+	ctx := context.Background()
+	mint := func() string {
+		t.Helper()
+		token, err := iss.Mint(TokenRequest{Subject: "user-1", Audience: []string{testAudience}})
+		require.NoError(t, err)
+		return token
+	}
+
+	keys, err := iss.PublicKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	oldKID := keys[0].KeyID
+	oldToken := mint()
+
+	next, err := GenerateSigningKey("PS256")
+	require.NoError(t, err)
+	require.NoError(t, iss.Rotate(next))
+
+	keys, err = iss.PublicKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{next.KeyID, oldKID}, []string{keys[0].KeyID, keys[1].KeyID})
+
+	_, err = v.Validate(ctx, mint())
+	require.NoError(t, err, "tokens from the new key verify")
+	_, err = v.Validate(ctx, oldToken)
+	require.NoError(t, err, "tokens from the retired key verify until it is removed")
+
+	assert.ErrorContains(t, iss.RemoveKey(next.KeyID), "cannot remove the active key")
+	assert.ErrorContains(t, iss.RemoveKey("nope"), "unknown key id")
+	assert.ErrorContains(t, iss.Activate("nope"), "unknown key id")
+	assert.ErrorContains(t, iss.AddKey(next), "duplicate key id")
+	assert.ErrorContains(t, iss.Rotate(next), "duplicate key id")
+
+	require.NoError(t, iss.RemoveKey(oldKID))
+	_, err = v.Validate(ctx, oldToken)
+	var authnErr *authn.Error
+	require.ErrorAs(t, err, &authnErr)
+	assert.Equal(t, authn.ReasonUnknownKID, authnErr.Reason)
+
+	rec := httptest.NewRecorder()
+	iss.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	set, err := jwk.Parse(body)
+	require.NoError(t, err)
+	assert.Equal(t, 1, set.Len(), "the JWKS tracks the key set")
+}
+
+// TestIssuerRotateConcurrentRemove checks that a RemoveKey racing Rotate never
+// sees the new key published but not yet active.
+func TestIssuerRotateConcurrentRemove(t *testing.T) {
+	t.Parallel()
+
+	iss := newTestIssuer(t, "https://auth.example.com")
+	ctx := context.Background()
+	for range 20 {
+		next, err := GenerateSigningKey("ES256")
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range 100 {
+				_ = iss.RemoveKey(next.KeyID)
+			}
+		}()
+		require.NoError(t, iss.Rotate(next))
+		<-done
+
+		keys, err := iss.PublicKeys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, next.KeyID, keys[0].KeyID, "the rotated key is active")
+	}
+}
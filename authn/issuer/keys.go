@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package issuer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+// minRSAKeyBits is the RFC 7518 §3.3 floor authn enforces on verification
+// keys. Refusing a weaker signing key here turns a token every validator would
+// reject into a construction error.
+const minRSAKeyBits = 2048
+
+// generatedRSAKeyBits is the modulus size GenerateSigningKey uses.
+const generatedRSAKeyBits = 2048
+
+// SigningKey is one key an Issuer signs with.
+type SigningKey struct {
+	// KeyID is the kid placed in token headers and the JWKS. Empty derives
+	// the RFC 7638 thumbprint of the public key, which is stable across
+	// restarts for the same key.
+	KeyID string
+
+	// Alg is the JWA algorithm the key signs with: RS256, RS384, RS512,
+	// PS256, PS384 or PS512 for an RSA key, and ES256, ES384 or ES512 for an
+	// ECDSA key on the matching curve.
+	Alg string
+
+	// Key is the private key: *rsa.PrivateKey (at least 2048 bits) or
+	// *ecdsa.PrivateKey.
+	Key crypto.Signer
+}
+
+// GenerateSigningKey creates a fresh key for alg, with a thumbprint kid. It is
+// meant for tests and for an embedded issuer whose keys need not survive a
+// restart; RSA keys are 2048 bits.
+func GenerateSigningKey(alg string) (SigningKey, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch {
+	case isRSAAlg(alg):
+		key, err = rsa.GenerateKey(rand.Reader, generatedRSAKeyBits)
+	case isECAlg(alg):
+		key, err = ecdsa.GenerateKey(curveForAlg(alg), rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("issuer: unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("issuer: failed to generate %s key: %w", alg, err)
+	}
+	return normalizeKey(SigningKey{Alg: alg, Key: key})
+}
+
+// normalizeKey checks that k's key suits its Alg and fills in a missing kid.
+func normalizeKey(k SigningKey) (SigningKey, error) {
+	switch key := k.Key.(type) {
+	case *rsa.PrivateKey:
+		if !isRSAAlg(k.Alg) {
+			return SigningKey{}, fmt.Errorf("issuer: key %q: alg %q cannot sign with an RSA key", k.KeyID, k.Alg)
+		}
+		if key.N.BitLen() < minRSAKeyBits {
+			return SigningKey{}, fmt.Errorf("issuer: key %q: RSA key is %d bits, below the %d-bit minimum",
+				k.KeyID, key.N.BitLen(), minRSAKeyBits)
+		}
+	case *ecdsa.PrivateKey:
+		if !isECAlg(k.Alg) {
+			return SigningKey{}, fmt.Errorf("issuer: key %q: alg %q cannot sign with an ECDSA key", k.KeyID, k.Alg)
+		}
+		if key.Curve != curveForAlg(k.Alg) {
+			return SigningKey{}, fmt.Errorf("issuer: key %q: alg %q requires curve %s",
+				k.KeyID, k.Alg, curveForAlg(k.Alg).Params().Name)
+		}
+	default:
+		return SigningKey{}, fmt.Errorf("issuer: key %q: unsupported key type %T", k.KeyID, k.Key)
+	}
+	if k.KeyID == "" {
+		kid, err := thumbprint(k.Key.Public())
+		if err != nil {
+			return SigningKey{}, err
+		}
+		k.KeyID = kid
+	}
+	return k, nil
+}
+
+// publicJWK renders k's public half as a JWK carrying kid, alg and use=sig.
+func publicJWK(k SigningKey) (jwk.Key, error) {
+	pub, err := jwk.Import(k.Key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("issuer: key %q: %w", k.KeyID, err)
+	}
+	for name, value := range map[string]string{
+		jwk.KeyIDKey:     k.KeyID,
+		jwk.AlgorithmKey: k.Alg,
+		jwk.KeyUsageKey:  "sig",
+	} {
+		if err := pub.Set(name, value); err != nil {
+			return nil, fmt.Errorf("issuer: key %q: %w", k.KeyID, err)
+		}
+	}
+	return pub, nil
+}
+
+// thumbprint returns the base64url RFC 7638 SHA-256 thumbprint of pub.
+func thumbprint(pub crypto.PublicKey) (string, error) {
+	key, err := jwk.Import(pub)
+	if err != nil {
+		return "", fmt.Errorf("issuer: failed to import public key: %w", err)
+	}
+	sum, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("issuer: failed to compute key thumbprint: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(sum), nil
+}
+
+func isRSAAlg(alg string) bool {
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return true
+	default:
+		return false
+	}
+}
+
+func isECAlg(alg string) bool {
+	return curveForAlg(alg) != nil
+}
+
+// curveForAlg returns the curve RFC 7518 §3.4 pairs with an ES* alg, or nil.
+func curveForAlg(alg string) elliptic.Curve {
+	switch alg {
+	case "ES256":
+		return elliptic.P256()
+	case "ES384":
+		return elliptic.P384()
+	case "ES512":
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package issuer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSigningKey(t *testing.T) {
+	t.Parallel()
+
+	for _, alg := range []string{"RS256", "PS384", "ES256", "ES384", "ES512"} {
+		t.Run(alg, func(t *testing.T) {
+			t.Parallel()
+			key, err := GenerateSigningKey(alg)
+			require.NoError(t, err)
+			assert.Equal(t, alg, key.Alg)
+			assert.NotEmpty(t, key.KeyID, "a generated key gets a thumbprint kid")
+
+			again, err := normalizeKey(SigningKey{Alg: alg, Key: key.Key})
+			require.NoError(t, err)
+			assert.Equal(t, key.KeyID, again.KeyID, "the thumbprint kid is stable for the same key")
+		})
+	}
+
+	_, err := GenerateSigningKey("HS256")
+	assert.ErrorContains(t, err, "unsupported signing algorithm")
+}
+
+func TestNormalizeKey(t *testing.T) {
+	t.Parallel()
+
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024) //nolint:gosec // deliberately below the minimum
+	require.NoError(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := GenerateSigningKey("RS256")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		key     SigningKey
+		wantErr string
+	}{
+		{name: "explicit kid kept", key: SigningKey{KeyID: "k1", Alg: "ES256", Key: p256}},
+		{name: "RSA key under an EC alg", key: SigningKey{Alg: "ES256", Key: rsaKey.Key}, wantErr: "cannot sign with an RSA key"},
+		{name: "EC key under an RSA alg", key: SigningKey{Alg: "RS256", Key: p256}, wantErr: "cannot sign with an ECDSA key"},
+		{name: "wrong curve", key: SigningKey{Alg: "ES384", Key: p256}, wantErr: "requires curve P-384"},
+		{name: "weak RSA key", key: SigningKey{Alg: "RS256", Key: weakRSA}, wantErr: "below the 2048-bit minimum"},
+		{name: "unsupported key type", key: SigningKey{Alg: "EdDSA", Key: edKey}, wantErr: "unsupported key type"},
+		{name: "no key", key: SigningKey{Alg: "RS256"}, wantErr: "unsupported key type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := normalizeKey(tt.key)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.key.KeyID, got.KeyID)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package issuer
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultTokenType is the typ header RFC 9068 §2.1 gives JWT access tokens.
+const defaultTokenType = "at+jwt"
+
+// jtiBytes is the entropy in a generated jti.
+const jtiBytes = 16
+
+// TokenRequest describes one token to mint.
+type TokenRequest struct {
+	// Subject is the sub claim. Required.
+	Subject string
+
+	// Audience is the aud claim: the resource servers the token is for. At
+	// least one is required. A single audience is encoded as a string, more as
+	// an array (RFC 7519 §4.1.3).
+	Audience []string
+
+	// ClientID is the client_id claim, which RFC 9068 §2.2 requires. Empty
+	// uses Config.DefaultClientID; with neither, Mint fails unless Claims
+	// sets client_id, to nil to mint a token without one.
+	ClientID string
+
+	// Scopes are joined with spaces into the scope claim. Optional.
+	Scopes []string
+
+	// Lifetime is exp-iat. Zero uses Config.DefaultLifetime; negative mints an
+	// already-expired token, which is occasionally what a test wants.
+	Lifetime time.Duration
+
+	// IssuedAt is the iat claim. Zero uses the current time; setting it lets a
+	// test mint a token that is not yet valid or has long expired.
+	IssuedAt time.Time
+
+	// Actor, when set, becomes the RFC 8693 §4.1 act claim: the party acting
+	// on Subject's behalf, with any earlier actors nested inside it.
+	Actor *Actor
+
+	// Claims are merged in last, so they can add claims or override any set
+	// above, including iss or exp. A nil value deletes the claim — how a test
+	// mints a token missing one.
+	Claims map[string]any
+
+	// Type overrides the typ header, "at+jwt" by default.
+	Type string
+
+	// KeyID signs with this published key rather than the active one, to mint
+	// a token under a key that is about to be, or was just, rotated out.
+	KeyID string
+}
+
+// Actor is one link in an act chain.
+type Actor struct {
+	// Subject is the actor's sub. Required.
+	Subject string
+
+	// Issuer is the actor's iss, when it differs from the token's. Optional.
+	Issuer string
+
+	// Actor is the party that acted before this one, if any.
+	Actor *Actor
+}
+
+// claim renders a as the nested act object.
+func (a *Actor) claim() (map[string]any, error) {
+	if a.Subject == "" {
+		return nil, fmt.Errorf("issuer: actor subject is required")
+	}
+	out := map[string]any{"sub": a.Subject}
+	if a.Issuer != "" {
+		out["iss"] = a.Issuer
+	}
+	if a.Actor != nil {
+		prior, err := a.Actor.claim()
+		if err != nil {
+			return nil, err
+		}
+		out["act"] = prior
+	}
+	return out, nil
+}
+
+// Mint signs a token for req with the active key, or req.KeyID.
+func (i *Issuer) Mint(req TokenRequest) (string, error) {
+	if req.Subject == "" {
+		return "", fmt.Errorf("issuer: subject is required")
+	}
+	if len(req.Audience) == 0 || slices.Contains(req.Audience, "") {
+		return "", fmt.Errorf("issuer: at least one non-empty audience is required")
+	}
+	clientID := cmp.Or(req.ClientID, i.defaultClientID)
+	if _, overridden := req.Claims["client_id"]; clientID == "" && !overridden {
+		return "", fmt.Errorf("issuer: client ID is required")
+	}
+
+	key, err := i.signingKey(req.KeyID)
+	if err != nil {
+		return "", err
+	}
+
+	iat := req.IssuedAt
+	if iat.IsZero() {
+		iat = time.Now()
+	}
+	lifetime := req.Lifetime
+	if lifetime == 0 {
+		lifetime = i.defaultLifetime
+	}
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"iss": i.issuer,
+		"sub": req.Subject,
+		"iat": iat.Unix(),
+		"exp": iat.Add(lifetime).Unix(),
+		"jti": jti,
+	}
+	if len(req.Audience) == 1 {
+		claims["aud"] = req.Audience[0]
+	} else {
+		claims["aud"] = slices.Clone(req.Audience)
+	}
+	if clientID != "" {
+		claims["client_id"] = clientID
+	}
+	if len(req.Scopes) > 0 {
+		claims["scope"] = strings.Join(req.Scopes, " ")
+	}
+	if req.Actor != nil {
+		act, err := req.Actor.claim()
+		if err != nil {
+			return "", err
+		}
+		claims["act"] = act
+	}
+	for name, value := range req.Claims {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+
+	method := jwt.GetSigningMethod(key.Alg)
+	if method == nil {
+		// normalizeKey admits only algorithms golang-jwt registers.
+		return "", fmt.Errorf("issuer: unsupported signing algorithm %q", key.Alg)
+	}
+	tok := jwt.NewWithClaims(method, claims)
+	tok.Header["kid"] = key.KeyID
+	tok.Header["typ"] = defaultTokenType
+	if req.Type != "" {
+		tok.Header["typ"] = req.Type
+	}
+	signed, err := tok.SignedString(key.Key)
+	if err != nil {
+		return "", fmt.Errorf("issuer: failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// signingKey returns the key kid, or the active key when kid is empty.
+func (i *Issuer) signingKey(kid string) (SigningKey, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if kid == "" {
+		return i.keys[0], nil
+	}
+	idx := slices.IndexFunc(i.keys, func(k SigningKey) bool { return k.KeyID == kid })
+	if idx < 0 {
+		return SigningKey{}, fmt.Errorf("issuer: unknown key id %q", kid)
+	}
+	return i.keys[idx], nil
+}
+
+// newJTI returns a random base64url token identifier.
+func newJTI() (string, error) {
+	b := make([]byte, jtiBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("issuer: failed to generate jti: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
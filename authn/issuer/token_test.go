@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package issuer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/authn"
+)
+
+// parseUnverified decodes token's header and claims without verifying it.
+func parseUnverified(t *testing.T, token string) (map[string]any, jwt.MapClaims) {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	tok, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	return tok.Header, claims
+}
+
+func TestMint(t *testing.T) {
+	t.Parallel()
+
+	iss := newTestIssuer(t, "https://auth.example.com")
+	v := newProviderValidator(t, iss, nil)
+
+	token, err := iss.Mint(TokenRequest{
+		Subject:  "user-1",
+		Audience: []string{testAudience},
+		ClientID: "client-a",
+		Scopes:   []string{"tools:read", "tools:call"},
+		Claims:   map[string]any{"name": "Alex"},
+	})
+	require.NoError(t, err)
+
+	p, err := v.Validate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", p.Subject)
+	assert.Equal(t, "Alex", p.Name)
+	assert.Equal(t, "client-a", p.Claims["client_id"])
+	assert.Equal(t, "tools:read tools:call", p.Claims["scope"])
+
+	header, claims := parseUnverified(t, token)
+	assert.Equal(t, "at+jwt", header["typ"])
+	assert.Equal(t, "ES256", header["alg"])
+	assert.Equal(t, iss.keys[0].KeyID, header["kid"])
+	assert.Equal(t, testAudience, claims["aud"], "a single audience is a string")
+	assert.NotEmpty(t, claims["jti"])
+	iat, err := claims.GetIssuedAt()
+	require.NoError(t, err)
+	exp, err := claims.GetExpirationTime()
+	require.NoError(t, err)
+	assert.Equal(t, defaultLifetime, exp.Sub(iat.Time))
+
+	_, other := parseUnverified(t, mustMint(t, iss, TokenRequest{Subject: "user-1", Audience: []string{testAudience}}))
+	assert.NotEqual(t, claims["jti"], other["jti"], "every token gets a fresh jti")
+	assert.Equal(t, testClientID, other["client_id"], "Config.DefaultClientID fills in client_id")
+}
+
+func TestMintOptions(t *testing.T) {
+	t.Parallel()
+
+	iss := newTestIssuer(t, "https://auth.example.com")
+	issuedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		req   TokenRequest
+		check func(t *testing.T, header map[string]any, claims jwt.MapClaims)
+	}{
+		{
+			name: "multiple audiences",
+			req:  TokenRequest{Subject: "s", Audience: []string{"a", "b"}},
+			check: func(t *testing.T, _ map[string]any, claims jwt.MapClaims) {
+				t.Helper()
+				assert.Equal(t, []any{"a", "b"}, claims["aud"])
+			},
+		},
+		{
+			name: "issued at and lifetime",
+			req:  TokenRequest{Subject: "s", Audience: []string{"a"}, IssuedAt: issuedAt, Lifetime: time.Hour},
+			check: func(t *testing.T, _ map[string]any, claims jwt.MapClaims) {
+				t.Helper()
+				assert.InDelta(t, issuedAt.Unix(), claims["iat"], 0)
+				assert.InDelta(t, issuedAt.Add(time.Hour).Unix(), claims["exp"], 0)
+			},
+		},
+		{
+			name: "claims override and delete",
+			req: TokenRequest{Subject: "s", Audience: []string{"a"}, Claims: map[string]any{
+				"iss": "https://other.example.com",
+				"exp": nil,
+			}},
+			check: func(t *testing.T, _ map[string]any, claims jwt.MapClaims) {
+				t.Helper()
+				assert.Equal(t, "https://other.example.com", claims["iss"])
+				assert.NotContains(t, claims, "exp")
+			},
+		},
+		{
+			name: "type override",
+			req:  TokenRequest{Subject: "s", Audience: []string{"a"}, Type: "JWT"},
+			check: func(t *testing.T, header map[string]any, _ jwt.MapClaims) {
+				t.Helper()
+				assert.Equal(t, "JWT", header["typ"])
+			},
+		},
+		{
+			name: "act chain",
+			req: TokenRequest{Subject: "s", Audience: []string{"a"}, Actor: &Actor{
+				Subject: "gateway",
+				Actor:   &Actor{Subject: "agent", Issuer: "https://agents.example.com"},
+			}},
+			check: func(t *testing.T, _ map[string]any, claims jwt.MapClaims) {
+				t.Helper()
+				assert.Equal(t, map[string]any{
+					"sub": "gateway",
+					"act": map[string]any{"sub": "agent", "iss": "https://agents.example.com"},
+				}, claims["act"])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			header, claims := parseUnverified(t, mustMint(t, iss, tt.req))
+			tt.check(t, header, claims)
+		})
+	}
+}
+
+func TestMintErrors(t *testing.T) {
+	t.Parallel()
+
+	iss := newTestIssuer(t, "https://auth.example.com")
+	tests := []struct {
+		name    string
+		req     TokenRequest
+		wantErr string
+	}{
+		{name: "no subject", req: TokenRequest{Audience: []string{"a"}}, wantErr: "subject is required"},
+		{name: "no audience", req: TokenRequest{Subject: "s"}, wantErr: "audience is required"},
+		{name: "empty audience", req: TokenRequest{Subject: "s", Audience: []string{""}}, wantErr: "audience is required"},
+		{name: "unknown key", req: TokenRequest{Subject: "s", Audience: []string{"a"}, KeyID: "nope"}, wantErr: "unknown key id"},
+		{
+			name:    "actor without subject",
+			req:     TokenRequest{Subject: "s", Audience: []string{"a"}, Actor: &Actor{Actor: &Actor{Subject: "x"}}},
+			wantErr: "actor subject is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := iss.Mint(tt.req)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("client ID is required", func(t *testing.T) {
+		t.Parallel()
+		key, err := GenerateSigningKey("ES256")
+		require.NoError(t, err)
+		bare, err := New(Config{Issuer: "https://auth.example.com", Keys: []SigningKey{key}})
+		require.NoError(t, err)
+
+		_, err = bare.Mint(TokenRequest{Subject: "s", Audience: []string{"a"}})
+		assert.ErrorContains(t, err, "client ID is required")
+
+		// Deleting the claim is still how a test mints a token without it.
+		_, claims := parseUnverified(t, mustMint(t, bare, TokenRequest{
+			Subject: "s", Audience: []string{"a"}, Claims: map[string]any{"client_id": nil},
+		}))
+		assert.NotContains(t, claims, "client_id")
+	})
+}
+
+// TestMintValidatorChecks mints the tokens a Validator must reject, the use
+// the package exists for.
+func TestMintValidatorChecks(t *testing.T) {
+	t.Parallel()
+
+	iss := newTestIssuer(t, "https://auth.example.com")
+	v := newProviderValidator(t, iss, func(cfg *authn.Config) {
+		cfg.AcceptedTokenTypes = []string{"at+jwt"}
+	})
+
+	tests := []struct {
+		name       string
+		req        TokenRequest
+		wantReason authn.Reason
+	}{
+		{name: "id token typ", req: TokenRequest{Type: "JWT"}, wantReason: authn.ReasonTokenType},
+		{name: "expired", req: TokenRequest{Lifetime: -time.Hour}, wantReason: authn.ReasonExpired},
+		{name: "wrong audience", req: TokenRequest{Audience: []string{"https://other.example.com"}}, wantReason: authn.ReasonAudience},
+		{name: "missing exp", req: TokenRequest{Claims: map[string]any{"exp": nil}}, wantReason: authn.ReasonExpirationMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := tt.req
+			req.Subject = "user-1"
+			if req.Audience == nil {
+				req.Audience = []string{testAudience}
+			}
+			_, err := v.Validate(context.Background(), mustMint(t, iss, req))
+			var authnErr *authn.Error
+			require.ErrorAs(t, err, &authnErr)
+			assert.Equal(t, tt.wantReason, authnErr.Reason)
+		})
+	}
+
+	t.Run("act chain survives validation", func(t *testing.T) {
+		t.Parallel()
+		p, err := v.Validate(context.Background(), mustMint(t, iss, TokenRequest{
+			Subject:  "user-1",
+			Audience: []string{testAudience},
+			Actor:    &Actor{Subject: "gateway"},
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"sub": "gateway"}, p.Claims["act"])
+	})
+}
+
+func mustMint(t *testing.T, iss *Issuer, req TokenRequest) string {
+	t.Helper()
+	token, err := iss.Mint(req)
+	require.NoError(t, err)
+	return token
+}
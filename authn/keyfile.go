@@ -0,0 +1,431 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+// defaultKeyFilePollInterval is how often a FileKeyProvider checks its path
+// for rotated keys when FileKeyProviderConfig.PollInterval is zero.
+const defaultKeyFilePollInterval = 30 * time.Second
+
+// keyFileExtensions are the file names a FileKeyProvider reads from a
+// directory. Anything else — a README, an editor's swap file, Kubernetes'
+// ..data bookkeeping — is ignored, so a key directory can be a mounted
+// ConfigMap or Secret as-is.
+var keyFileExtensions = []string{".json", ".jwks", ".pem", ".crt", ".cer", ".pub"}
+
+// FileKeyProviderConfig configures a FileKeyProvider.
+type FileKeyProviderConfig struct {
+	// Path is a key file, or a directory whose key files are all loaded.
+	//
+	// A file holds either a JWKS document (or a single JWK) as JSON, or one
+	// or more PEM blocks; the format is detected from the content, not the
+	// name. In a directory only files named *.json, *.jwks, *.pem, *.crt, *.cer
+	// or *.pub are read, subdirectories and hidden files are skipped, and
+	// symlinks are followed.
+	Path string
+
+	// PollInterval is how often Path is re-read to pick up rotated keys. Zero
+	// uses 30s; negative is an error. A change is detected from file content,
+	// not modification times, so a rotation that preserves mtime (a copy with
+	// -p, a ConfigMap update) is still seen.
+	PollInterval time.Duration
+
+	// Logger receives a warning when a reload fails. Nil uses slog.Default().
+	Logger *slog.Logger
+}
+
+// FileKeyProvider is a KeyProvider that loads verification keys from local
+// files, for a deployment that cannot reach its issuer over the network. Pair
+// it with Config.KeyProviderOnly to run without discovery or a JWKS endpoint.
+//
+// Keys are re-read every PollInterval by a background goroutine, without
+// fsnotify. A reload that fails — a half-written file, a parse error, a set
+// that came out empty — is logged and the previous keys stay in service: a
+// rotation caught mid-copy must not take authentication down, and the next poll
+// retries. Only construction requires a clean load.
+//
+// It is safe for concurrent use.
+type FileKeyProvider struct {
+	path   string
+	logger *slog.Logger
+
+	// keys is the last successfully loaded key set, swapped whole on reload
+	// so PublicKeys never takes a lock on the request path.
+	keys atomic.Pointer[[]PublicKey]
+
+	// reloadMu serializes reloads (the poll loop and Reload) and guards
+	// digest, the hash of the file content keys was loaded from.
+	reloadMu sync.Mutex
+	digest   [sha256.Size]byte
+
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// NewFileKeyProvider loads the keys at cfg.Path and starts polling it for
+// changes. It fails if the path cannot be read or yields no usable key.
+//
+// As with NewValidator, ctx governs the lifetime of the polling goroutine: it
+// stops when ctx is canceled or Close is called.
+func NewFileKeyProvider(ctx context.Context, cfg FileKeyProviderConfig) (*FileKeyProvider, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("authn: key file path is required")
+	}
+	if cfg.PollInterval < 0 {
+		return nil, fmt.Errorf("authn: key file poll interval must not be negative: %s", cfg.PollInterval)
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultKeyFilePollInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	p := &FileKeyProvider{path: cfg.Path, logger: cfg.Logger}
+	if _, err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	go p.poll(ctx, cfg.PollInterval)
+	return p, nil
+}
+
+// PublicKeys returns the keys from the last successful load. It never fails;
+// the error is there to satisfy KeyProvider.
+func (p *FileKeyProvider) PublicKeys(_ context.Context) ([]PublicKey, error) {
+	return slices.Clone(*p.keys.Load()), nil
+}
+
+// Reload re-reads the path immediately instead of waiting for the next poll,
+// for a caller that knows the keys just changed (a SIGHUP handler, say). On
+// failure the previous keys stay in service and the error is returned.
+func (p *FileKeyProvider) Reload() error {
+	_, err := p.reload()
+	return err
+}
+
+// Close stops polling. It is idempotent; PublicKeys keeps serving the last
+// loaded keys afterwards.
+func (p *FileKeyProvider) Close() {
+	p.closeOnce.Do(p.cancel)
+}
+
+// poll reloads the path every interval until ctx is done.
+func (p *FileKeyProvider) poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.reload(); err != nil {
+				p.logger.WarnContext(ctx, "authn: key file reload failed; keeping previous keys",
+					slog.String("path", p.path), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// reload reads the path and, when its content changed since the last good
+// load, parses and installs the new keys. It reports whether keys changed.
+//
+// The digest is recorded only after a successful parse, so content that failed
+// is retried on the next poll rather than remembered as current.
+func (p *FileKeyProvider) reload() (bool, error) {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	files, err := readKeyFiles(p.path)
+	if err != nil {
+		return false, err
+	}
+	digest := digestKeyFiles(files)
+	if p.keys.Load() != nil && digest == p.digest {
+		return false, nil
+	}
+	keys, err := parseKeyFiles(files)
+	if err != nil {
+		return false, err
+	}
+	p.keys.Store(&keys)
+	p.digest = digest
+	return true, nil
+}
+
+// LoadPublicKeys reads the keys at path once, with the same file and
+// directory handling as FileKeyProviderConfig.Path. It is the building block
+// for a caller that wants the keys without polling; NewStaticKeyProvider turns
+// the result into a KeyProvider.
+func LoadPublicKeys(path string) ([]PublicKey, error) {
+	files, err := readKeyFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyFiles(files)
+}
+
+// keyFile is one file's name and content, as read by readKeyFiles.
+type keyFile struct {
+	name string
+	data []byte
+}
+
+// readKeyFiles reads path, or the key files in it when it is a directory, in
+// name order so the digest is stable.
+func readKeyFiles(path string) ([]keyFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("authn: failed to read key path: %w", err)
+	}
+	if !info.IsDir() {
+		data, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []keyFile{{name: path, data: data}}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("authn: failed to read key directory: %w", err)
+	}
+	var files []keyFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !slices.Contains(keyFileExtensions, strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		full := filepath.Join(path, name)
+		// Stat rather than entry.Type(): a mounted ConfigMap presents its keys
+		// as symlinks, which must be followed, while a symlink to a directory
+		// must still be skipped.
+		if info, err := os.Stat(full); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := readKeyFile(full)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, keyFile{name: name, data: data})
+	}
+	return files, nil
+}
+
+// readKeyFile reads one key file, bounded at maxResponseBody: key material is
+// small, and the bound keeps a misconfigured path (a log file, a device) from
+// being read whole every poll.
+func readKeyFile(path string) ([]byte, error) {
+	f, err := os.Open(path) // #nosec G304 - path is operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("authn: failed to open key file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(io.LimitReader(f, maxResponseBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("authn: failed to read key file %s: %w", path, err)
+	}
+	if len(data) > maxResponseBody {
+		return nil, fmt.Errorf("authn: key file %s exceeds %d bytes", path, maxResponseBody)
+	}
+	return data, nil
+}
+
+// digestKeyFiles hashes names and contents together, so a rename is a change
+// as much as an edit is.
+func digestKeyFiles(files []keyFile) [sha256.Size]byte {
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00", f.name, len(f.data))
+		h.Write(f.data)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// parseKeyFiles parses every file and requires at least one key overall. An
+// empty result is an error rather than an empty provider: a directory caught
+// between the old keys being removed and the new ones landing would otherwise
+// reject every token until the next poll.
+func parseKeyFiles(files []keyFile) ([]PublicKey, error) {
+	var keys []PublicKey
+	for _, f := range files {
+		parsed, err := parseKeyData(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("authn: key file %s: %w", f.name, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("authn: no verification keys found")
+	}
+	return keys, nil
+}
+
+// parseKeyData detects a JSON document by its opening brace and parses
+// anything else as PEM.
+func parseKeyData(data []byte) ([]PublicKey, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return ParseJWKS(trimmed)
+	}
+	return ParsePEMPublicKeys(data)
+}
+
+// ParseJWKS converts a JWKS document, or a single JWK, into PublicKeys
+// carrying each key's kid and alg.
+//
+// Keys marked for encryption (use "enc") are skipped, since they never verify
+// a signature. A private key is reduced to its public half. A key of any
+// other type than RSA, EC or OKP/Ed25519 — a symmetric "oct" key, say — is an
+// error: this package verifies asymmetric signatures only, and silently
+// dropping a key the operator put there would hide the misconfiguration.
+func ParseJWKS(data []byte) ([]PublicKey, error) {
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	var keys []PublicKey
+	for i := range set.Len() {
+		key, ok := set.Key(i)
+		if !ok {
+			continue
+		}
+		kid, _ := key.KeyID()
+		if use, ok := key.KeyUsage(); ok && use != "sig" {
+			continue
+		}
+		pub, err := jwk.PublicKeyOf(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		var raw any
+		if err := jwk.Export(pub, &raw); err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		if !isVerificationKey(raw) {
+			return nil, fmt.Errorf("key %q: unsupported key type %s", kid, key.KeyType())
+		}
+		pk := PublicKey{KeyID: kid, Key: raw}
+		if alg, ok := key.Algorithm(); ok {
+			pk.Alg = alg.String()
+		}
+		keys = append(keys, pk)
+	}
+	return keys, nil
+}
+
+// ParsePEMPublicKeys converts PEM-encoded public keys into PublicKeys.
+//
+// Accepted blocks are PUBLIC KEY (PKIX), RSA PUBLIC KEY (PKCS #1) and
+// CERTIFICATE, whose subject public key is used as-is: the certificate is a
+// container for the key here, and its validity period and chain are NOT
+// checked. Optional "kid" and "alg" PEM headers set KeyID and Alg; without a
+// kid the key is a candidate for every token (see PublicKey.KeyID).
+//
+// A private key block is an error rather than something to derive a public key
+// from: a signing key has no business in a verification key directory, and
+// finding one there is worth stopping for.
+func ParsePEMPublicKeys(data []byte) ([]PublicKey, error) {
+	var keys []PublicKey
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		raw, err := parsePEMBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, PublicKey{KeyID: block.Headers["kid"], Alg: block.Headers["alg"], Key: raw})
+	}
+	if len(keys) == 0 && len(bytes.TrimSpace(data)) > 0 {
+		return nil, errors.New("no PEM data found")
+	}
+	return keys, nil
+}
+
+// parsePEMBlock extracts the public key from one PEM block.
+func parsePEMBlock(block *pem.Block) (crypto.PublicKey, error) {
+	var (
+		raw any
+		err error
+	)
+	switch block.Type {
+	case "PUBLIC KEY":
+		raw, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		raw, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			raw = cert.PublicKey
+		}
+	default:
+		if strings.Contains(block.Type, "PRIVATE KEY") {
+			return nil, fmt.Errorf("PEM block %q is a private key; supply public keys only", block.Type)
+		}
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PEM block %q: %w", block.Type, err)
+	}
+	if !isVerificationKey(raw) {
+		return nil, fmt.Errorf("PEM block %q: unsupported key type %T", block.Type, raw)
+	}
+	return raw, nil
+}
+
+// isVerificationKey reports whether raw is one of the public key types the
+// validator verifies with.
+func isVerificationKey(raw any) bool {
+	switch raw.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// staticKeyProvider is the KeyProvider NewStaticKeyProvider returns.
+type staticKeyProvider []PublicKey
+
+// NewStaticKeyProvider returns a KeyProvider that always offers keys, for
+// key material that is fixed for the life of the process — compiled in,
+// loaded once with LoadPublicKeys, or parsed from configuration with
+// ParseJWKS. The slice is copied, so later changes to keys do not reach it.
+func NewStaticKeyProvider(keys ...PublicKey) KeyProvider {
+	return staticKeyProvider(slices.Clone(keys))
+}
+
+// PublicKeys implements KeyProvider.
+func (s staticKeyProvider) PublicKeys(_ context.Context) ([]PublicKey, error) {
+	return slices.Clone([]PublicKey(s)), nil
+}
@@ -0,0 +1,347 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksJSON marshals keys as a JWKS document.
+func jwksJSON(t *testing.T, keys ...jwk.Key) []byte {
+	t.Helper()
+	set := jwk.NewSet()
+	for _, k := range keys {
+		require.NoError(t, set.AddKey(k))
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+// pkixPEM encodes key as a PUBLIC KEY block with the given headers.
+func pkixPEM(t *testing.T, key any, headers map[string]string) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Headers: headers, Bytes: der})
+}
+
+// certPEM self-signs a certificate for priv and encodes it as PEM.
+func certPEM(t *testing.T, priv *rsa.PrivateKey) []byte {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "issuer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		// Already expired: the certificate is only a key container, so its
+		// validity period must not matter.
+		NotAfter: time.Now().Add(-time.Minute),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func writeKeyFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mintRSA(t, "rsa")
+	require.NoError(t, rsaKey.jwk.Set(jwk.AlgorithmKey, testAlgRS256))
+	ecKey := mintEC(t, "ec")
+	edKey := mintEd25519(t, "ed")
+	encKey := mintRSA(t, "enc")
+	require.NoError(t, encKey.jwk.Set(jwk.KeyUsageKey, "enc"))
+	private, err := jwk.Import(mintRSA(t, "private").priv)
+	require.NoError(t, err)
+	require.NoError(t, private.Set(jwk.KeyIDKey, "private"))
+
+	keys, err := ParseJWKS(jwksJSON(t, rsaKey.jwk, ecKey.jwk, edKey.jwk, encKey.jwk, private))
+	require.NoError(t, err)
+	require.Len(t, keys, 4, "the enc key is skipped")
+
+	assert.Equal(t, "rsa", keys[0].KeyID)
+	assert.Equal(t, testAlgRS256, keys[0].Alg)
+	assert.Equal(t, &rsaKey.priv.PublicKey, keys[0].Key)
+	assert.Equal(t, "ec", keys[1].KeyID)
+	assert.Equal(t, &ecKey.priv.PublicKey, keys[1].Key)
+	assert.Equal(t, edKey.priv.Public(), keys[2].Key)
+	assert.Equal(t, "private", keys[3].KeyID)
+	assert.IsType(t, &rsa.PublicKey{}, keys[3].Key, "a private JWK is reduced to its public half")
+
+	t.Run("single JWK", func(t *testing.T) {
+		t.Parallel()
+		data, err := json.Marshal(rsaKey.jwk)
+		require.NoError(t, err)
+		keys, err := ParseJWKS(data)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Equal(t, "rsa", keys[0].KeyID)
+	})
+
+	t.Run("symmetric key rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}]}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported key type")
+	})
+
+	t.Run("malformed document rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseJWKS([]byte(`{"keys":`))
+		require.Error(t, err)
+	})
+}
+
+func TestParsePEMPublicKeys(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mintRSA(t, "")
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var data []byte
+	data = append(data, pkixPEM(t, &rsaKey.priv.PublicKey, map[string]string{"kid": "k1", "alg": testAlgRS256})...)
+	data = append(data, pem.EncodeToMemory(&pem.Block{
+		Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.priv.PublicKey),
+	})...)
+	data = append(data, certPEM(t, rsaKey.priv)...)
+	data = append(data, pkixPEM(t, edPub, nil)...)
+
+	keys, err := ParsePEMPublicKeys(data)
+	require.NoError(t, err)
+	require.Len(t, keys, 4)
+	assert.Equal(t, PublicKey{KeyID: "k1", Alg: testAlgRS256, Key: &rsaKey.priv.PublicKey}, keys[0])
+	assert.Equal(t, PublicKey{Key: &rsaKey.priv.PublicKey}, keys[1])
+	assert.Equal(t, PublicKey{Key: &rsaKey.priv.PublicKey}, keys[2], "an expired certificate still yields its key")
+	assert.Equal(t, PublicKey{Key: edPub}, keys[3])
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name: "private key rejected",
+			data: pem.EncodeToMemory(&pem.Block{
+				Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey.priv),
+			}),
+			wantErr: "private key",
+		},
+		{
+			name:    "unknown block rejected",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: []byte{0}}),
+			wantErr: "unsupported PEM block",
+		},
+		{
+			name:    "corrupt key rejected",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{0}}),
+			wantErr: "PUBLIC KEY",
+		},
+		{
+			name:    "non-PEM content rejected",
+			data:    []byte("not a key"),
+			wantErr: "no PEM data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParsePEMPublicKeys(tt.data)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadPublicKeysDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rsaKey := mintRSA(t, "a")
+	ecKey := mintEC(t, "b")
+	writeKeyFile(t, filepath.Join(dir, "a.json"), jwksJSON(t, rsaKey.jwk))
+	writeKeyFile(t, filepath.Join(dir, "b.pem"), pkixPEM(t, &ecKey.priv.PublicKey, map[string]string{"kid": "b"}))
+	// None of these may be read: a hidden file, an unknown extension and a
+	// subdirectory with a key-like name.
+	writeKeyFile(t, filepath.Join(dir, ".c.pem"), []byte("garbage"))
+	writeKeyFile(t, filepath.Join(dir, "README.md"), []byte("garbage"))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "d.pem"), 0o700))
+	// A symlinked key file, as a mounted ConfigMap presents it, is followed.
+	edKey := mintEd25519(t, "e")
+	writeKeyFile(t, filepath.Join(dir, "e.target"), jwksJSON(t, edKey.jwk))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "e.target"), filepath.Join(dir, "e.jwks")))
+
+	keys, err := LoadPublicKeys(dir)
+	require.NoError(t, err)
+	kids := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kids = append(kids, k.KeyID)
+	}
+	assert.Equal(t, []string{"a", "b", "e"}, kids)
+
+	t.Run("empty directory rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := LoadPublicKeys(t.TempDir())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no verification keys found")
+	})
+
+	t.Run("one bad file fails the load", func(t *testing.T) {
+		t.Parallel()
+		bad := t.TempDir()
+		writeKeyFile(t, filepath.Join(bad, "a.json"), jwksJSON(t, rsaKey.jwk))
+		writeKeyFile(t, filepath.Join(bad, "b.pem"), []byte("garbage"))
+		_, err := LoadPublicKeys(bad)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "b.pem")
+	})
+
+	t.Run("missing path rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := LoadPublicKeys(filepath.Join(dir, "missing"))
+		require.Error(t, err)
+	})
+}
+
+func TestFileKeyProviderRotation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	oldKey := mintRSA(t, "old")
+	writeKeyFile(t, path, jwksJSON(t, oldKey.jwk))
+
+	p, err := NewFileKeyProvider(context.Background(), FileKeyProviderConfig{
+		Path:         path,
+		PollInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+
+	kidsOf := func() []string {
+		keys, err := p.PublicKeys(context.Background())
+		require.NoError(t, err)
+		out := make([]string, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, k.KeyID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"old"}, kidsOf())
+
+	// A rotation is picked up by the poll loop.
+	newKey := mintRSA(t, "new")
+	writeKeyFile(t, path, jwksJSON(t, oldKey.jwk, newKey.jwk))
+	require.Eventually(t, func() bool { return len(kidsOf()) == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	// A broken file keeps the previous keys in service.
+	writeKeyFile(t, path, []byte(`{"keys":`))
+	require.Error(t, p.Reload())
+	assert.Equal(t, []string{"old", "new"}, kidsOf())
+
+	// Reload picks up a fix without waiting for the poll.
+	writeKeyFile(t, path, jwksJSON(t, newKey.jwk))
+	require.NoError(t, p.Reload())
+	assert.Equal(t, []string{"new"}, kidsOf())
+}
+
+func TestFileKeyProviderConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	writeKeyFile(t, path, pkixPEM(t, &mintRSA(t, "").priv.PublicKey, nil))
+
+	tests := []struct {
+		name    string
+		cfg     FileKeyProviderConfig
+		wantErr string
+	}{
+		{name: "path required", cfg: FileKeyProviderConfig{}, wantErr: "path is required"},
+		{
+			name:    "negative poll interval",
+			cfg:     FileKeyProviderConfig{Path: path, PollInterval: -time.Second},
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "unreadable path",
+			cfg:     FileKeyProviderConfig{Path: path + ".missing"},
+			wantErr: "failed to read key path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewFileKeyProvider(context.Background(), tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// TestKeyProviderOnlyValidator runs a validator on a key file alone: the
+// issuer host is never contacted, a key from the file verifies, and a kid the
+// file does not hold is unknown rather than unavailable.
+func TestKeyProviderOnlyValidator(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	key := mintRSA(t, "offline")
+	writeKeyFile(t, path, jwksJSON(t, key.jwk))
+	p, err := NewFileKeyProvider(context.Background(), FileKeyProviderConfig{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+
+	cfg := validConfig()
+	cfg.KeyProvider = p
+	cfg.KeyProviderOnly = true
+	v, err := NewValidator(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+
+	principal, err := v.Validate(context.Background(), key.mint(t, cfg.Issuer))
+	require.NoError(t, err)
+	assert.Equal(t, testSubject, principal.Subject)
+
+	_, err = v.Validate(context.Background(), mintRSA(t, "stranger").mint(t, cfg.Issuer))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonUnknownKID)
+
+	st := v.Status(context.Background())
+	assert.Empty(t, st.JWKSURL)
+	assert.True(t, st.LastRefreshAttempt.IsZero(), "no JWKS fetch is ever attempted")
+	assert.Equal(t, []KeyStatus{{KeyID: "offline", KeyType: "RSA", Source: KeySourceProvider}}, st.Keys)
+}
+
+func TestStaticKeyProvider(t *testing.T) {
+	t.Parallel()
+
+	key := mintRSA(t, "static")
+	keys := []PublicKey{{KeyID: "static", Key: &key.priv.PublicKey}}
+	p := NewStaticKeyProvider(keys...)
+	keys[0].KeyID = "mutated"
+
+	got, err := p.PublicKeys(context.Background())
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "static", got[0].KeyID, "the provider must not share the caller's slice")
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"fmt"
 	"strings"
@@ -54,9 +55,10 @@ type PublicKey struct {
 	// JWKS keys.
 	Alg string
 
-	// Key is the public key itself: *rsa.PublicKey or *ecdsa.PublicKey. Any
-	// other type is rejected, since the algorithm allow-list admits only RSA
-	// and ECDSA families.
+	// Key is the public key itself: *rsa.PublicKey, *ecdsa.PublicKey or
+	// ed25519.PublicKey (as a value or pointer). Any other type is rejected,
+	// since the algorithm allow-list admits only those families. An Ed25519 key
+	// is used only when Config.AllowedAlgs lists EdDSA.
 	Key crypto.PublicKey
 }
 
@@ -92,7 +94,7 @@ func providerCandidates(keys []PublicKey, kid, alg string) ([]crypto.PublicKey,
 			}
 			continue
 		}
-		out = append(out, pk.Key)
+		out = append(out, verifierKey(pk.Key))
 		if len(out) == maxKeyCandidates {
 			break
 		}
@@ -144,12 +146,42 @@ func providerKeyTypeMatchesAlg(key crypto.PublicKey, alg string) keyRejection {
 			return rejectCurve
 		}
 		return rejectNone
+	case alg == algEdDSA:
+		var pub ed25519.PublicKey
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			pub = k
+		case *ed25519.PublicKey:
+			if k == nil {
+				return rejectKeyType
+			}
+			pub = *k
+		default:
+			return rejectKeyType
+		}
+		// golang-jwt would reject a wrong-length key at verification anyway;
+		// reporting it here keeps the cause diagnosable.
+		if len(pub) != ed25519.PublicKeySize {
+			return rejectExport
+		}
+		return rejectNone
 	default:
-		// Unreachable via Validate: the alg gate admits only RS/PS/ES.
+		// Unreachable via Validate: the alg gate admits only supportedAlgs.
 		return rejectKeyType
 	}
 }
 
+// verifierKey returns key in the form golang-jwt verifies with. That is key
+// itself except for a *ed25519.PublicKey, which golang-jwt accepts only as a
+// value. It is called only on keys providerKeyTypeMatchesAlg has accepted, so
+// the pointer is non-nil.
+func verifierKey(key crypto.PublicKey) crypto.PublicKey {
+	if k, ok := key.(*ed25519.PublicKey); ok {
+		return *k
+	}
+	return key
+}
+
 // keysFromProvider asks the provider for candidates. A provider error is
 // reported as CodeUnavailable, not as an invalid token: the verifier could not
 // make a determination, exactly as with an unreachable JWKS.
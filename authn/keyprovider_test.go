@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"errors"
@@ -188,6 +189,7 @@ func TestProviderCandidateEligibility(t *testing.T) {
 	rsaPub := mintRSA(t, "r").priv.Public()
 	ecPub := mintEC(t, "e").priv.Public()
 	ec384Pub := mintECCurve(t, "e384", elliptic.P384()).priv.Public()
+	edPub := mintEd25519(t, "ed").priv.Public().(ed25519.PublicKey)
 
 	tests := []struct {
 		name      string
@@ -290,6 +292,48 @@ func TestProviderCandidateEligibility(t *testing.T) {
 			alg:       testAlgES256,
 			wantCount: 0,
 		},
+		{
+			name:      "Ed25519 key accepted for EdDSA",
+			keys:      []PublicKey{{KeyID: "a", Key: edPub}},
+			kid:       "a",
+			alg:       algEdDSA,
+			wantCount: 1,
+		},
+		{
+			name:      "Ed25519 key pointer accepted for EdDSA",
+			keys:      []PublicKey{{KeyID: "a", Key: &edPub}},
+			kid:       "a",
+			alg:       algEdDSA,
+			wantCount: 1,
+		},
+		{
+			name:      "Ed25519 key not offered to an EC alg",
+			keys:      []PublicKey{{KeyID: "a", Key: edPub}},
+			kid:       "a",
+			alg:       testAlgES256,
+			wantCount: 0,
+		},
+		{
+			name:      "EC key not offered to EdDSA",
+			keys:      []PublicKey{{KeyID: "a", Key: ecPub}},
+			kid:       "a",
+			alg:       algEdDSA,
+			wantCount: 0,
+		},
+		{
+			name:      "truncated Ed25519 key skipped",
+			keys:      []PublicKey{{KeyID: "a", Key: edPub[:16]}},
+			kid:       "a",
+			alg:       algEdDSA,
+			wantCount: 0,
+		},
+		{
+			name:      "typed-nil Ed25519 key pointer skipped",
+			keys:      []PublicKey{{KeyID: "a", Key: (*ed25519.PublicKey)(nil)}},
+			kid:       "a",
+			alg:       algEdDSA,
+			wantCount: 0,
+		},
 		{
 			name:      "untyped nil key skipped",
 			keys:      []PublicKey{{KeyID: "a", Key: nil}},
@@ -308,6 +352,24 @@ func TestProviderCandidateEligibility(t *testing.T) {
 	}
 }
 
+// TestKeyProviderEd25519 verifies an EdDSA token end-to-end against a provider
+// key handed over as a pointer, the form golang-jwt itself would refuse.
+func TestKeyProviderEd25519(t *testing.T) {
+	t.Parallel()
+
+	edKey := mintEd25519(t, "ed-1")
+	pub := edKey.priv.Public().(ed25519.PublicKey)
+	kp := &fakeKeyProvider{keys: []PublicKey{{KeyID: "ed-1", Key: &pub}}}
+	cfg := providerConfig(t, kp)
+	cfg.AllowedAlgs = []string{algEdDSA}
+	v, err := NewValidator(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+
+	_, err = v.Validate(context.Background(), edKey.mint(t, validConfig().Issuer))
+	require.NoError(t, err)
+}
+
 // TestKeyProviderMalformedKeyDoesNotPanic covers the same finding end-to-end
 // through the public API: a KeyProvider is caller-implemented, so a
 // malformed key it returns (nil modulus/curve) must make Validate return an
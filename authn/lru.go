@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is a bounded, concurrency-safe cache whose entries also carry an
+// absolute expiry. It backs the package's result caches (introspection
+// responses, DPoP proof identifiers), where an unbounded map would let a
+// caller presenting a stream of distinct tokens grow memory without limit.
+//
+// Eviction is least-recently-used once size entries are held; an expired entry
+// is dropped when it is next looked up rather than by a background sweep, so
+// the cache owns no goroutine and needs no Close.
+type lruCache[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[K]*list.Element
+}
+
+// lruEntry is the value stored in each list element.
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// newLRUCache returns a cache holding at most size entries. size must be
+// positive; callers validate it as configuration before getting here.
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		size:  size,
+		order: list.New(),
+		items: make(map[K]*list.Element, size),
+	}
+}
+
+// get returns the value for key if present and not expired at now, marking it
+// most recently used.
+func (c *lruCache[K, V]) get(key K, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := el.Value.(*lruEntry[K, V])
+	if !now.Before(entry.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// add stores value under key until expires, replacing any existing entry and
+// evicting the least recently used one when the cache is full.
+func (c *lruCache[K, V]) add(key K, value V, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addLocked(key, value, expires)
+}
+
+// addIfAbsent stores value under key until expires unless an entry unexpired
+// at now already exists, reporting whether it stored. The check and the store
+// share one critical section, which is what a replay check needs: of two
+// concurrent callers presenting the same key, exactly one sees it as new.
+func (c *lruCache[K, V]) addIfAbsent(key K, value V, expires, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok && now.Before(el.Value.(*lruEntry[K, V]).expires) {
+		return false
+	}
+	c.addLocked(key, value, expires)
+	return true
+}
+
+// addLocked is add for a caller already holding c.mu.
+func (c *lruCache[K, V]) addLocked(key K, value V, expires time.Time) {
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.size {
+		if oldest := c.order.Back(); oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+		}
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+}
+
+// len returns the number of entries held, including any that have expired but
+// not yet been looked up.
+func (c *lruCache[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
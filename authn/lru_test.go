@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		t.Parallel()
+		c := newLRUCache[string, int](2)
+		c.add("a", 1, later)
+		c.add("b", 2, later)
+		_, _ = c.get("a", now) // a is now most recent
+		c.add("c", 3, later)
+
+		_, ok := c.get("b", now)
+		assert.False(t, ok, "b was least recently used")
+		v, ok := c.get("a", now)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+		assert.Equal(t, 2, c.len())
+	})
+
+	t.Run("expired entries are dropped on lookup", func(t *testing.T) {
+		t.Parallel()
+		c := newLRUCache[string, int](2)
+		c.add("a", 1, now)
+		_, ok := c.get("a", now)
+		assert.False(t, ok, "an entry is expired at its expiry instant")
+		assert.Equal(t, 0, c.len())
+	})
+
+	t.Run("re-adding replaces value and expiry", func(t *testing.T) {
+		t.Parallel()
+		c := newLRUCache[string, int](2)
+		c.add("a", 1, now)
+		c.add("a", 2, later)
+		v, ok := c.get("a", now)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+		assert.Equal(t, 1, c.len())
+	})
+
+	t.Run("addIfAbsent stores only once per live entry", func(t *testing.T) {
+		t.Parallel()
+		c := newLRUCache[string, struct{}](2)
+		assert.True(t, c.addIfAbsent("jti", struct{}{}, later, now))
+		assert.False(t, c.addIfAbsent("jti", struct{}{}, later, now))
+		assert.True(t, c.addIfAbsent("jti", struct{}{}, later.Add(time.Hour), later),
+			"an expired entry no longer blocks")
+	})
+}
@@ -0,0 +1,381 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TokenValidator verifies a bare bearer token and returns the Principal it
+// carries. *Validator is the canonical implementation; the interface exists so
+// Middleware can front any verifier with the same contract, and so a handler
+// chain can be tested without a JWKS endpoint.
+//
+// Implementations must report failures as *Error: Middleware derives the HTTP
+// status and WWW-Authenticate challenge from its Code and Reason.
+type TokenValidator interface {
+	Validate(ctx context.Context, token string) (Principal, error)
+}
+
+// Compile-time check that *Validator satisfies TokenValidator.
+var _ TokenValidator = (*Validator)(nil)
+
+// principalContextKey is the context key under which Middleware stores the
+// verified Principal. An unexported struct type cannot collide with a key from
+// any other package.
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying p. Middleware calls it
+// for every admitted request; it is exported for hosts that authenticate by
+// other means (a stdio transport, a test) but feed the same downstream code.
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext returns the Principal stored by Middleware (or
+// ContextWithPrincipal), reporting whether one was present.
+//
+// This is how code behind the middleware — an mcpcompat CallGate, a tool
+// handler reached through the Streamable HTTP transport — learns who is
+// calling: the middleware runs outermost and the request context it hands on
+// is the one those layers see.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalContextKey{}).(Principal)
+	return p, ok
+}
+
+// MiddlewareOption configures Middleware.
+type MiddlewareOption func(*middlewareConfig)
+
+// middlewareConfig holds the resolved Middleware options.
+type middlewareConfig struct {
+	// resourceMetadataURL, when set, is advertised as the RFC 9728
+	// resource_metadata parameter on every Bearer challenge.
+	resourceMetadataURL string
+	// resource, when set, is served unauthenticated at its well-known path.
+	resource *ProtectedResourceHandler
+	// dpop, when set, admits the DPoP scheme and checks DPoP proofs.
+	dpop *DPoPVerifier
+	// certificateBound enables RFC 8705 certificate binding checks.
+	certificateBound bool
+	// realm, when set, is sent as the realm parameter on every challenge.
+	realm string
+	// errorDescription enables the error_description challenge parameter.
+	errorDescription bool
+	// anonymous, when set, reports whether a request with no credentials may
+	// pass through unauthenticated.
+	anonymous func(*http.Request) bool
+	// auditHook and auditComponent, when the hook is set, receive an audit
+	// event for every authentication decision.
+	auditHook      AuditHook
+	auditComponent string
+}
+
+// WithResourceMetadataURL advertises metadataURL as the resource_metadata
+// parameter (RFC 9728 §5.1) on every Bearer challenge, so a client that
+// receives a 401 can discover the authorization server. Use it when the
+// metadata document is served somewhere the middleware does not front; when it
+// is, prefer WithProtectedResource, which sets this too.
+func WithResourceMetadataURL(metadataURL string) MiddlewareOption {
+	return func(c *middlewareConfig) { c.resourceMetadataURL = metadataURL }
+}
+
+// WithProtectedResource makes the middleware answer requests for h's
+// well-known path itself, WITHOUT authentication — a client has to be able to
+// read the metadata before it holds a token — and advertises h.MetadataURL()
+// on every Bearer challenge. A later WithResourceMetadataURL overrides the
+// advertised URL; the document is still served.
+func WithProtectedResource(h *ProtectedResourceHandler) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.resource = h
+		if h != nil {
+			c.resourceMetadataURL = h.MetadataURL()
+		}
+	}
+}
+
+// WithDPoP makes the middleware accept RFC 9449 DPoP-bound access tokens:
+// the Authorization header may use the DPoP scheme, and a token presented
+// that way must come with a proof d accepts (see DPoPVerifier.Verify). A
+// DPoP-bound token presented under Bearer is rejected, as is any non-DPoP
+// token when DPoPConfig.Required is set. Challenges switch to the DPoP scheme,
+// with an algs parameter, when the request used it or DPoP is required.
+//
+// Without this option the DPoP scheme is refused as malformed and cnf.jkt is
+// ignored: a DPoP-bound token is then accepted as a plain bearer token, which
+// is the behaviour of a resource server that does not implement RFC 9449.
+func WithDPoP(d *DPoPVerifier) MiddlewareOption {
+	return func(c *middlewareConfig) { c.dpop = d }
+}
+
+// WithCertificateBoundTokens makes the middleware enforce RFC 8705
+// certificate binding: a token carrying cnf.x5t#S256 is admitted only over a
+// TLS connection whose client certificate matches it (see
+// VerifyCertificateBinding). Unbound tokens are unaffected. The server must
+// request client certificates (tls.Config.ClientAuth) for a bound token to
+// ever pass.
+func WithCertificateBoundTokens() MiddlewareOption {
+	return func(c *middlewareConfig) { c.certificateBound = true }
+}
+
+// WithRealm sends realm as the RFC 6750 §3 realm parameter, first on every
+// challenge. A client uses it only to tell protection spaces apart, so it is
+// rarely needed; the default omits it.
+func WithRealm(realm string) MiddlewareOption {
+	return func(c *middlewareConfig) { c.realm = realm }
+}
+
+// WithErrorDescription adds an error_description parameter (RFC 6750 §3) to
+// every challenge that carries an error code, spelling out the Reason — "token
+// expired" rather than just invalid_token — so a client developer can tell an
+// expired token from a wrong audience without server logs.
+//
+// It is off by default because it tells any caller WHY a token failed, which
+// helps an attacker probing with forged tokens as much as it helps a
+// developer. Reason is client-safe in that it never carries token content or
+// server detail, but exposing it is still a choice to make deliberately.
+func WithErrorDescription() MiddlewareOption {
+	return func(c *middlewareConfig) { c.errorDescription = true }
+}
+
+// WithAnonymous lets a request that presents NO credentials through to the
+// next handler when allow reports true for it, without a Principal in its
+// context; code behind the middleware tells the two apart with
+// PrincipalFromContext. Use it for routes that serve anonymous callers and
+// authenticated ones differently, such as a public tool listing.
+//
+// Only the absence of an Authorization header qualifies. A request that
+// presents a credential is verified as usual and rejected if the credential
+// is bad: silently downgrading an invalid token to anonymous would hide an
+// expired session from the client and let a stolen-then-revoked token keep
+// reaching whatever anonymous callers can.
+func WithAnonymous(allow func(*http.Request) bool) MiddlewareOption {
+	return func(c *middlewareConfig) { c.anonymous = allow }
+}
+
+// WithAuditHook calls hook with an audit event for every authentication
+// decision the middleware makes; component is the event's Component. See
+// AuditHook for the event's contents and AuditToLogger for a ready-made
+// hook.
+func WithAuditHook(component string, hook AuditHook) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.auditHook = hook
+		c.auditComponent = component
+	}
+}
+
+// Middleware returns HTTP middleware that authenticates every request with an
+// RFC 6750 bearer token.
+//
+// For each request it reads the Authorization header with ParseBearer, verifies
+// the token with v, and on success hands the request on with the Principal in
+// its context (see PrincipalFromContext). On failure the request stops here and
+// the response is derived from the *Error:
+//
+//   - no Authorization header: 401 with a bare Bearer challenge, carrying no
+//     error code (RFC 6750 §3.1 — the client simply has not authenticated).
+//   - CodeInvalidRequest: 400, error="invalid_request".
+//   - CodeInvalidToken: 401, error="invalid_token".
+//   - CodeInsufficientScope: 403, error="insufficient_scope", with a scope
+//     parameter listing the required scopes when the error carries them
+//     (see EnforcePolicy).
+//   - CodeInvalidDPoPProof (with WithDPoP): 401, error="invalid_dpop_proof"
+//     under the DPoP scheme.
+//   - CodeUnavailable: a plain 503 with no challenge. The verifier could not
+//     make a determination, and "unavailable" is not an RFC 6750 error code.
+//
+// Challenges carry resource_metadata when one of WithResourceMetadataURL or
+// WithProtectedResource is set, and realm when WithRealm is. Only Code reaches
+// the wire by default: Reason is added as error_description only with
+// WithErrorDescription, and the detail in Error() always stays server-side.
+//
+// WithAnonymous lets credential-less requests to chosen routes through
+// unauthenticated, and WithAuditHook reports every decision as an audit event.
+//
+// Wrap it OUTERMOST around an mcpcompat Streamable HTTP server so the
+// server's CallGate and handlers observe the Principal:
+//
+//	handler := authn.Middleware(validator, authn.WithProtectedResource(prm))(
+//		server.NewStreamableHTTPServer(mcpServer))
+func Middleware(v TokenValidator, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	v, policies := splitPolicies(v)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.resource != nil && r.URL.EscapedPath() == cfg.resource.Path() {
+				cfg.resource.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, err := cfg.credentialFromRequest(r)
+			if err != nil {
+				if cfg.anonymous != nil && isMissingCredential(err) && cfg.anonymous(r) {
+					cfg.auditAnonymous(r)
+					next.ServeHTTP(w, r)
+					return
+				}
+				cfg.auditFailure(r, scheme, err)
+				cfg.writeError(w, scheme, err)
+				return
+			}
+			p, err := v.Validate(r.Context(), token)
+			if err == nil {
+				err = cfg.checkBinding(r, scheme, token, p)
+			}
+			if err == nil {
+				err = authorize(policies, p)
+			}
+			if err != nil {
+				cfg.auditFailure(r, scheme, err)
+				cfg.writeError(w, scheme, err)
+				return
+			}
+			cfg.auditSuccess(r, scheme, p)
+			next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// credentialFromRequest extracts the token from r's Authorization header,
+// returning the auth-scheme it was presented under. The DPoP scheme is
+// accepted only when WithDPoP is set.
+//
+// More than one Authorization header is a malformed request rather than a
+// choice to make: picking one would let an intermediary and this server
+// disagree about which credential was presented.
+func (c *middlewareConfig) credentialFromRequest(r *http.Request) (string, string, error) {
+	values := r.Header.Values("Authorization")
+	if len(values) > 1 {
+		return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
+			err: errors.New("multiple Authorization headers")}
+	}
+	var header string
+	if len(values) == 1 {
+		header = values[0]
+	}
+	if c.dpop != nil {
+		return parseCredential(header, schemeBearer, schemeDPoP)
+	}
+	return parseCredential(header, schemeBearer)
+}
+
+// isMissingCredential reports whether err is the "no Authorization header"
+// failure, the only one WithAnonymous lets through.
+func isMissingCredential(err error) bool {
+	var authnErr *Error
+	return errors.As(err, &authnErr) && authnErr.Reason == ReasonMissingHeader
+}
+
+// checkBinding applies the sender-constraint checks enabled by WithDPoP and
+// WithCertificateBoundTokens to a verified token.
+func (c *middlewareConfig) checkBinding(r *http.Request, scheme, token string, p Principal) error {
+	if c.dpop != nil {
+		if err := c.dpop.checkScheme(scheme, p); err != nil {
+			return err
+		}
+		if scheme == schemeDPoP {
+			if err := c.dpop.Verify(r, token, p); err != nil {
+				return err
+			}
+		}
+	}
+	if c.certificateBound {
+		return VerifyCertificateBinding(p, r.TLS)
+	}
+	return nil
+}
+
+// writeError writes the response for a failed authentication.
+//
+// An error that is not an *Error breaks the TokenValidator contract. It is
+// answered with a plain 500 rather than a challenge: inventing an OAuth error
+// code for a failure nobody classified would tell the client something untrue.
+func (c *middlewareConfig) writeError(w http.ResponseWriter, scheme string, err error) {
+	var authnErr *Error
+	if !errors.As(err, &authnErr) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// Only the registered RFC 6750 codes earn a challenge; CodeUnavailable (and
+	// anything unrecognized) is a server-side condition the client cannot fix
+	// by re-authenticating.
+	switch authnErr.Code {
+	case CodeInvalidRequest, CodeInvalidToken, CodeInsufficientScope, CodeInvalidDPoPProof:
+		w.Header().Set("WWW-Authenticate", c.challenge(scheme, authnErr))
+	case CodeUnavailable:
+	}
+	status := authnErr.Code.HTTPStatus()
+	// ParseBearer reports an absent header as CodeInvalidRequest so callers can
+	// tell it apart from a broken one, but on the wire "no credentials" is a 401
+	// (RFC 6750 §3.1), not a 400: the client should authenticate, not fix a
+	// request it never made.
+	if authnErr.Reason == ReasonMissingHeader {
+		status = http.StatusUnauthorized
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// challenge renders the WWW-Authenticate value for authnErr. It uses the
+// DPoP scheme, advertising the accepted proof algorithms, when DPoP is enabled
+// and the request used it, DPoP is required, or the proof itself failed;
+// otherwise Bearer.
+func (c *middlewareConfig) challenge(scheme string, authnErr *Error) string {
+	challengeScheme := schemeBearer
+	var params []string
+	// RFC 6750 §3 puts realm first when present.
+	if c.realm != "" {
+		params = append(params, authParam("realm", c.realm))
+	}
+	if c.dpop != nil &&
+		(scheme == schemeDPoP || c.dpop.cfg.Required || authnErr.Code == CodeInvalidDPoPProof) {
+		challengeScheme = schemeDPoP
+		params = append(params, authParam("algs", strings.Join(c.dpop.cfg.AllowedAlgs, " ")))
+	}
+	// RFC 6750 §3.1: a request with no authentication information gets a
+	// challenge without an error code.
+	if authnErr.Reason != ReasonMissingHeader {
+		params = append(params, authParam("error", string(authnErr.Code)))
+		if c.errorDescription && authnErr.Reason != "" {
+			params = append(params, authParam("error_description", authnErr.Reason.description()))
+		}
+	}
+	// RFC 6750 §3: scope lists, space-delimited, the scopes needed to access
+	// the resource.
+	if len(authnErr.RequiredScopes) > 0 {
+		params = append(params, authParam("scope", strings.Join(authnErr.RequiredScopes, " ")))
+	}
+	if c.resourceMetadataURL != "" {
+		params = append(params, authParam("resource_metadata", c.resourceMetadataURL))
+	}
+	if len(params) == 0 {
+		return challengeScheme
+	}
+	return challengeScheme + " " + strings.Join(params, ", ")
+}
+
+// authParam renders name="value" as an RFC 9110 §11.2 auth-param, escaping the
+// value as a quoted-string. Backslash and double quote are the only characters
+// that need escaping. Values are a fixed Code or Reason, or operator
+// configuration, never request input, and net/http folds any CR/LF in a header value to a
+// space when writing, so a challenge cannot split the response.
+func authParam(name, value string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteString(`="`)
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/stacklok/toolhive-core/audit"
+)
+
+// Keys this package adds to the audit events Middleware emits, alongside the
+// audit package's well-known keys.
+const (
+	// auditSubjectKeyIssuer carries the verified iss in Subjects: (iss, sub)
+	// is what identifies a caller, since sub is unique only per issuer.
+	auditSubjectKeyIssuer = "issuer"
+	// auditExtraKeyScheme carries the Authorization auth-scheme in
+	// Metadata.Extra.
+	auditExtraKeyScheme = "auth_scheme"
+	// auditExtraKeyReason carries the failure Reason in Metadata.Extra.
+	auditExtraKeyReason = "reason"
+	// auditExtraKeyAnonymous marks a WithAnonymous pass-through in
+	// Metadata.Extra.
+	auditExtraKeyAnonymous = "anonymous"
+)
+
+// AuditHook receives the audit event for one authentication decision made by
+// Middleware (see WithAuditHook). It runs synchronously on the request path,
+// before the response is written or the next handler runs, so it should hand
+// the event off rather than do slow work itself.
+//
+// The event has Type audit.EventTypeAuthentication and:
+//
+//   - Outcome: audit.OutcomeSuccess for a verified token or an anonymous
+//     pass-through; audit.OutcomeDenied for CodeInsufficientScope;
+//     audit.OutcomeError when the verifier could not decide (CodeUnavailable,
+//     or an error that is not an *Error); audit.OutcomeFailure for every other
+//     rejection.
+//   - Subjects: the verified sub, iss and, when present, name. They are set
+//     only for a verified token: a rejected token's claims are unverified, and
+//     recording them would let anyone write any identity into the audit log.
+//   - DelegationChain: the verified act claim, when present.
+//   - Source: the connection's remote address, with the User-Agent.
+//     X-Forwarded-For is deliberately not consulted; it is client-controlled
+//     unless a trusted proxy rewrites it, which this package cannot know.
+//   - Target: the request method and path.
+//   - Metadata.Extra: the auth-scheme the credential used, the Reason of a
+//     failure, and anonymous=true for a pass-through.
+//
+// The raw token never appears in the event.
+type AuditHook func(ctx context.Context, event *audit.AuditEvent)
+
+// AuditToLogger returns an AuditHook that writes each event to logger at
+// audit.LevelAudit, such as one built by audit.NewAuditLogger.
+func AuditToLogger(logger *slog.Logger) AuditHook {
+	return func(ctx context.Context, event *audit.AuditEvent) {
+		event.LogTo(ctx, logger, audit.LevelAudit)
+	}
+}
+
+// auditSuccess records a verified request.
+func (c *middlewareConfig) auditSuccess(r *http.Request, scheme string, p Principal) {
+	if c.auditHook == nil {
+		return
+	}
+	subjects, chain := auditIdentity(p)
+	event := c.auditEvent(r, audit.OutcomeSuccess, subjects)
+	event.Metadata.Extra[auditExtraKeyScheme] = scheme
+	event.WithDelegationChain(chain)
+	c.auditHook(r.Context(), event)
+}
+
+// AuditIdentity returns the audit Subjects and DelegationChain for the
+// Principal in ctx, recorded as Middleware records them, so that events
+// emitted behind the middleware (such as those of mcpcompat/server's
+// WithAudit) identify the caller the same way. Without a Principal it
+// returns an empty map and a nil chain.
+func AuditIdentity(ctx context.Context) (map[string]string, *audit.DelegationChain) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return map[string]string{}, nil
+	}
+	return auditIdentity(p)
+}
+
+// auditIdentity returns the Subjects and DelegationChain recording p: the
+// verified sub, iss and, when present, name, and the act claim's chain.
+func auditIdentity(p Principal) (map[string]string, *audit.DelegationChain) {
+	subjects := map[string]string{
+		audit.SubjectKeyUserID: p.Subject,
+		auditSubjectKeyIssuer:  p.Issuer,
+	}
+	if p.Name != "" {
+		subjects[audit.SubjectKeyUser] = p.Name
+	}
+	var chain *audit.DelegationChain
+	if act, ok := p.Claims["act"]; ok {
+		chain = audit.ParseDelegationChain(act, 0)
+	}
+	return subjects, chain
+}
+
+// auditFailure records a rejected request.
+func (c *middlewareConfig) auditFailure(r *http.Request, scheme string, err error) {
+	if c.auditHook == nil {
+		return
+	}
+	outcome := audit.OutcomeError
+	var authnErr *Error
+	if errors.As(err, &authnErr) {
+		switch authnErr.Code {
+		case CodeInsufficientScope:
+			outcome = audit.OutcomeDenied
+		case CodeUnavailable:
+		default:
+			outcome = audit.OutcomeFailure
+		}
+	}
+	event := c.auditEvent(r, outcome, map[string]string{})
+	if scheme != "" {
+		event.Metadata.Extra[auditExtraKeyScheme] = scheme
+	}
+	if authnErr != nil {
+		event.Metadata.Extra[auditExtraKeyReason] = string(authnErr.Reason)
+	}
+	c.auditHook(r.Context(), event)
+}
+
+// auditAnonymous records a WithAnonymous pass-through.
+func (c *middlewareConfig) auditAnonymous(r *http.Request) {
+	if c.auditHook == nil {
+		return
+	}
+	event := c.auditEvent(r, audit.OutcomeSuccess, map[string]string{})
+	event.Metadata.Extra[auditExtraKeyAnonymous] = true
+	c.auditHook(r.Context(), event)
+}
+
+// auditEvent builds the fields every authentication event shares.
+func (c *middlewareConfig) auditEvent(r *http.Request, outcome string, subjects map[string]string) *audit.AuditEvent {
+	source := audit.EventSource{Type: audit.SourceTypeNetwork, Value: remoteHost(r)}
+	if ua := r.UserAgent(); ua != "" {
+		source.Extra = map[string]any{audit.SourceExtraKeyUserAgent: ua}
+	}
+	event := audit.NewAuditEvent(audit.EventTypeAuthentication, source, outcome, subjects, c.auditComponent).
+		WithTarget(map[string]string{
+			audit.TargetKeyMethod:   r.Method,
+			audit.TargetKeyEndpoint: r.URL.Path,
+		})
+	event.Metadata.Extra = map[string]any{}
+	return event
+}
+
+// remoteHost returns the host part of r.RemoteAddr, or all of it when it has
+// no port.
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
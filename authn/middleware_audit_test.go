@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/audit"
+)
+
+// auditRecorder is an AuditHook that keeps every event it receives.
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []*audit.AuditEvent
+}
+
+func (a *auditRecorder) hook(_ context.Context, e *audit.AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, e)
+}
+
+func TestMiddlewareAuditHook(t *testing.T) {
+	t.Parallel()
+
+	const component = "test-server"
+	verified := Principal{
+		Issuer:  "https://issuer.example.com",
+		Subject: testSubject,
+		Name:    "Alice",
+		Claims:  map[string]any{"act": map[string]any{"sub": "gateway", "iss": "https://issuer.example.com"}},
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		principal     Principal
+		validateErr   error
+		wantOutcome   string
+		wantSubjects  map[string]string
+		wantExtra     map[string]any
+	}{
+		{
+			name:          "verified token",
+			path:          "/mcp",
+			authorization: "Bearer " + testToken,
+			principal:     verified,
+			wantOutcome:   audit.OutcomeSuccess,
+			wantSubjects: map[string]string{
+				audit.SubjectKeyUserID: testSubject,
+				audit.SubjectKeyUser:   "Alice",
+				auditSubjectKeyIssuer:  "https://issuer.example.com",
+			},
+			wantExtra: map[string]any{auditExtraKeyScheme: schemeBearer},
+		},
+		{
+			name:          "rejected token",
+			path:          "/mcp",
+			authorization: "Bearer " + testToken,
+			principal:     verified,
+			validateErr:   &Error{Code: CodeInvalidToken, Reason: ReasonExpired},
+			wantOutcome:   audit.OutcomeFailure,
+			wantSubjects:  map[string]string{},
+			wantExtra:     map[string]any{auditExtraKeyScheme: schemeBearer, auditExtraKeyReason: string(ReasonExpired)},
+		},
+		{
+			name:          "insufficient scope is denied",
+			path:          "/mcp",
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeInsufficientScope, Reason: ReasonScope},
+			wantOutcome:   audit.OutcomeDenied,
+			wantSubjects:  map[string]string{},
+			wantExtra:     map[string]any{auditExtraKeyScheme: schemeBearer, auditExtraKeyReason: string(ReasonScope)},
+		},
+		{
+			name:          "unavailable keys are an error",
+			path:          "/mcp",
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeUnavailable, Reason: ReasonKeysUnavailable},
+			wantOutcome:   audit.OutcomeError,
+			wantSubjects:  map[string]string{},
+			wantExtra: map[string]any{
+				auditExtraKeyScheme: schemeBearer, auditExtraKeyReason: string(ReasonKeysUnavailable),
+			},
+		},
+		{
+			name:          "unclassified failure is an error",
+			path:          "/mcp",
+			authorization: "Bearer " + testToken,
+			validateErr:   errors.New("boom"),
+			wantOutcome:   audit.OutcomeError,
+			wantSubjects:  map[string]string{},
+			wantExtra:     map[string]any{auditExtraKeyScheme: schemeBearer},
+		},
+		{
+			name:         "missing credentials",
+			path:         "/mcp",
+			wantOutcome:  audit.OutcomeFailure,
+			wantSubjects: map[string]string{},
+			wantExtra:    map[string]any{auditExtraKeyReason: string(ReasonMissingHeader)},
+		},
+		{
+			name:         "anonymous pass-through",
+			path:         "/public",
+			wantOutcome:  audit.OutcomeSuccess,
+			wantSubjects: map[string]string{},
+			wantExtra:    map[string]any{auditExtraKeyAnonymous: true},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rec := &auditRecorder{}
+			fv := &fakeTokenValidator{principal: tc.principal, err: tc.validateErr}
+			h := Middleware(fv,
+				WithAnonymous(func(r *http.Request) bool { return r.URL.Path == "/public" }),
+				WithAuditHook(component, rec.hook),
+			)(principalEcho())
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			req.RemoteAddr = "192.0.2.7:4711"
+			req.Header.Set("User-Agent", "test-client/1.0")
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			require.Len(t, rec.events, 1, "exactly one event per decision")
+			e := rec.events[0]
+			assert.Equal(t, audit.EventTypeAuthentication, e.Type)
+			assert.Equal(t, component, e.Component)
+			assert.Equal(t, tc.wantOutcome, e.Outcome)
+			assert.Equal(t, tc.wantSubjects, e.Subjects)
+			assert.Equal(t, tc.wantExtra, e.Metadata.Extra)
+			assert.Equal(t, audit.EventSource{
+				Type:  audit.SourceTypeNetwork,
+				Value: "192.0.2.7",
+				Extra: map[string]any{audit.SourceExtraKeyUserAgent: "test-client/1.0"},
+			}, e.Source)
+			assert.Equal(t, map[string]string{
+				audit.TargetKeyMethod:   http.MethodPost,
+				audit.TargetKeyEndpoint: tc.path,
+			}, e.Target)
+			if tc.wantOutcome == audit.OutcomeSuccess && tc.authorization != "" {
+				require.NotNil(t, e.DelegationChain)
+				require.Len(t, e.DelegationChain.Chain, 1)
+				assert.Equal(t, "gateway", e.DelegationChain.Chain[0].Subject)
+			} else {
+				assert.Nil(t, e.DelegationChain, "an unverified act claim must not be recorded")
+			}
+		})
+	}
+}
+
+func TestAuditToLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	fv := &fakeTokenValidator{principal: Principal{Subject: testSubject}}
+	h := Middleware(fv, WithAuditHook("test-server", AuditToLogger(audit.NewAuditLogger(&buf))))(principalEcho())
+
+	rec := serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, buf.String(), `"level":"AUDIT"`)
+	assert.Contains(t, buf.String(), `"type":"authentication"`)
+	assert.Contains(t, buf.String(), testSubject)
+	assert.NotContains(t, buf.String(), testToken, "the raw token must never be logged")
+}
+
+func TestAuditIdentity(t *testing.T) {
+	t.Parallel()
+
+	subjects, chain := AuditIdentity(context.Background())
+	assert.Empty(t, subjects)
+	assert.Nil(t, chain)
+
+	ctx := ContextWithPrincipal(context.Background(), Principal{
+		Issuer:  "https://issuer.example.com",
+		Subject: testSubject,
+		Claims:  map[string]any{"act": map[string]any{"sub": "gateway", "iss": "https://issuer.example.com"}},
+	})
+	subjects, chain = AuditIdentity(ctx)
+	assert.Equal(t, map[string]string{
+		audit.SubjectKeyUserID: testSubject,
+		auditSubjectKeyIssuer:  "https://issuer.example.com",
+	}, subjects)
+	require.NotNil(t, chain)
+	require.Len(t, chain.Chain, 1)
+	assert.Equal(t, "gateway", chain.Chain[0].Subject)
+}
@@ -0,0 +1,321 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testResource    = "https://api.example.com/mcp"
+	testMetadataURL = "https://api.example.com/.well-known/oauth-protected-resource/mcp"
+	headerWWWAuth   = "WWW-Authenticate"
+)
+
+// fakeTokenValidator is a TokenValidator returning a fixed result, recording
+// the token it was asked about.
+type fakeTokenValidator struct {
+	principal Principal
+	err       error
+	got       string
+}
+
+func (f *fakeTokenValidator) Validate(_ context.Context, token string) (Principal, error) {
+	f.got = token
+	return f.principal, f.err
+}
+
+// principalEcho is a downstream handler that writes the context Principal's
+// subject, or 418 when none is present.
+func principalEcho() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		_, _ = w.Write([]byte(p.Subject))
+	})
+}
+
+func serve(h http.Handler, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddlewareChallenges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		authorization string
+		validateErr   error
+		wantStatus    int
+		wantChallenge string
+	}{
+		{
+			name:          "missing header gets a bare challenge",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer resource_metadata="` + testMetadataURL + `"`,
+		},
+		{
+			name:          "malformed header is invalid_request",
+			authorization: "Basic dXNlcjpwYXNz",
+			wantStatus:    http.StatusBadRequest,
+			wantChallenge: `Bearer error="invalid_request", resource_metadata="` + testMetadataURL + `"`,
+		},
+		{
+			name:          "rejected token is invalid_token",
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeInvalidToken, Reason: ReasonExpired},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token", resource_metadata="` + testMetadataURL + `"`,
+		},
+		{
+			name:          "unavailable is a plain 503",
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeUnavailable, Reason: ReasonKeysUnavailable},
+			wantStatus:    http.StatusServiceUnavailable,
+		},
+		{
+			name:          "non-*Error is a plain 500",
+			authorization: "Bearer " + testToken,
+			validateErr:   errors.New("boom"),
+			wantStatus:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			fv := &fakeTokenValidator{err: tc.validateErr}
+			h := Middleware(fv, WithResourceMetadataURL(testMetadataURL))(principalEcho())
+
+			rec := serve(h, http.MethodPost, "/mcp", tc.authorization)
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantChallenge, rec.Header().Get(headerWWWAuth))
+			assert.NotContains(t, rec.Body.String(), string(ReasonExpired),
+				"Reason must never reach the response")
+		})
+	}
+}
+
+func TestMiddlewareAdmitsWithPrincipal(t *testing.T) {
+	t.Parallel()
+
+	fv := &fakeTokenValidator{principal: Principal{Subject: testSubject}}
+	h := Middleware(fv)(principalEcho())
+
+	rec := serve(h, http.MethodPost, "/mcp", "bearer "+testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, testSubject, rec.Body.String())
+	assert.Equal(t, testToken, fv.got, "the validator must see the bare token")
+	assert.Empty(t, rec.Header().Get(headerWWWAuth))
+}
+
+func TestMiddlewareChallengeWithoutMetadata(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(&fakeTokenValidator{})(principalEcho())
+	rec := serve(h, http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get(headerWWWAuth))
+}
+
+func TestMiddlewareRejectsMultipleAuthorizationHeaders(t *testing.T) {
+	t.Parallel()
+
+	fv := &fakeTokenValidator{principal: Principal{Subject: testSubject}}
+	h := Middleware(fv)(principalEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("Authorization", "Bearer "+testToken)
+	req.Header.Add("Authorization", "Bearer other.token.value")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, fv.got, "the validator must not be consulted")
+}
+
+func TestMiddlewareServesProtectedResourceMetadata(t *testing.T) {
+	t.Parallel()
+
+	prm, err := NewProtectedResourceHandler(ProtectedResourceMetadata{
+		Resource:             testResource,
+		AuthorizationServers: []string{"https://issuer.example.com"},
+	})
+	require.NoError(t, err)
+
+	fv := &fakeTokenValidator{err: &Error{Code: CodeInvalidToken, Reason: ReasonSignature}}
+	h := Middleware(fv, WithProtectedResource(prm))(principalEcho())
+
+	// The metadata document is reachable without a token.
+	rec := serve(h, http.MethodGet, prm.Path(), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var doc ProtectedResourceMetadata
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, testResource, doc.Resource)
+
+	// Everything else is still authenticated, and challenges point at it.
+	rec = serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer error="invalid_token", resource_metadata="`+testMetadataURL+`"`,
+		rec.Header().Get(headerWWWAuth))
+}
+
+func TestMiddlewareWithValidator(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mintRSA(t, "rsa-1")
+	js := newJWKSServer(t, rsaKey.jwk)
+	v, err := NewValidator(context.Background(), js.configFor())
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+
+	h := Middleware(v)(principalEcho())
+
+	rec := serve(h, http.MethodPost, "/mcp", "Bearer "+rsaKey.mint(t, js.srv.URL))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, testSubject, rec.Body.String())
+
+	rec = serve(h, http.MethodPost, "/mcp", "Bearer "+rsaKey.mint(t, "https://other.example.com"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get(headerWWWAuth))
+}
+
+func TestMiddlewareRealmAndErrorDescription(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		opts          []MiddlewareOption
+		authorization string
+		validateErr   error
+		wantChallenge string
+	}{
+		{
+			name:          "realm leads a bare challenge",
+			opts:          []MiddlewareOption{WithRealm("mcp")},
+			wantChallenge: `Bearer realm="mcp"`,
+		},
+		{
+			name:          "realm leads an error challenge",
+			opts:          []MiddlewareOption{WithRealm("mcp"), WithResourceMetadataURL(testMetadataURL)},
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeInvalidToken, Reason: ReasonExpired},
+			wantChallenge: `Bearer realm="mcp", error="invalid_token", resource_metadata="` + testMetadataURL + `"`,
+		},
+		{
+			name:          "error_description spells out the reason",
+			opts:          []MiddlewareOption{WithErrorDescription()},
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeInvalidToken, Reason: ReasonUnknownKID},
+			wantChallenge: `Bearer error="invalid_token", error_description="unknown kid"`,
+		},
+		{
+			name:          "error_description is omitted without an error",
+			opts:          []MiddlewareOption{WithErrorDescription()},
+			wantChallenge: `Bearer`,
+		},
+		{
+			name:          "error_description is off by default",
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeInvalidToken, Reason: ReasonUnknownKID},
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			h := Middleware(&fakeTokenValidator{err: tc.validateErr}, tc.opts...)(principalEcho())
+			rec := serve(h, http.MethodPost, "/mcp", tc.authorization)
+			assert.Equal(t, tc.wantChallenge, rec.Header().Get(headerWWWAuth))
+		})
+	}
+}
+
+func TestMiddlewareAnonymous(t *testing.T) {
+	t.Parallel()
+
+	public := func(r *http.Request) bool { return r.URL.Path == "/public" }
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		validateErr   error
+		wantStatus    int
+	}{
+		{
+			// principalEcho answers 418 when no Principal is present.
+			name:       "no credentials on an anonymous route pass without a principal",
+			path:       "/public",
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:          "valid credentials on an anonymous route still authenticate",
+			path:          "/public",
+			authorization: "Bearer " + testToken,
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "a bad token on an anonymous route is rejected, not downgraded",
+			path:          "/public",
+			authorization: "Bearer " + testToken,
+			validateErr:   &Error{Code: CodeInvalidToken, Reason: ReasonExpired},
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "a malformed header on an anonymous route is rejected",
+			path:          "/public",
+			authorization: "Basic dXNlcjpwYXNz",
+			wantStatus:    http.StatusBadRequest,
+		},
+		{
+			name:       "no credentials elsewhere are challenged",
+			path:       "/private",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			fv := &fakeTokenValidator{principal: Principal{Subject: testSubject}, err: tc.validateErr}
+			h := Middleware(fv, WithAnonymous(public))(principalEcho())
+			rec := serve(h, http.MethodGet, tc.path, tc.authorization)
+			assert.Equal(t, tc.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestAuthParamEscapesQuotedString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `k="a\"b\\c"`, authParam("k", `a"b\c`))
+}
+
+func TestCodeHTTPStatus(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, http.StatusBadRequest, CodeInvalidRequest.HTTPStatus())
+	assert.Equal(t, http.StatusUnauthorized, CodeInvalidToken.HTTPStatus())
+	assert.Equal(t, http.StatusForbidden, CodeInsufficientScope.HTTPStatus())
+	assert.Equal(t, http.StatusServiceUnavailable, CodeUnavailable.HTTPStatus())
+	assert.Equal(t, http.StatusInternalServerError, Code("bogus").HTTPStatus())
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MultiValidator verifies tokens from several trusted issuers, dispatching
+// each token to the Validator configured for its issuer.
+//
+// Each issuer keeps its own Config — audiences, leeway, token types, JWKS
+// cache and KeyProvider — so trusting an enterprise IdP alongside an
+// in-cluster issuer does not force the two to share a policy. A token whose
+// iss names no configured issuer is rejected before any key material is
+// touched, so an attacker cannot make the resource server fetch anything by
+// minting a token for an issuer of their choosing.
+//
+// Like Validator it owns background refresh goroutines; always call Close.
+type MultiValidator struct {
+	// validators maps each configured Config.Issuer, byte-exact, to its
+	// Validator. It is never written after construction.
+	validators map[string]*Validator
+}
+
+// Compile-time check that *MultiValidator satisfies TokenValidator.
+var _ TokenValidator = (*MultiValidator)(nil)
+
+// NewMultiValidator constructs one Validator per config and returns a
+// MultiValidator routing between them.
+//
+// Every config must set Issuer — it is the routing key, so the Issuer-less
+// JWKSURL-only form Config otherwise permits cannot be expressed here — and no
+// two may set the same one. ctx has the same lifetime meaning as for
+// NewValidator. Validators are built in order, each under NewValidator's own
+// construction timeout; if any fails, those already built are closed and the
+// error names the failing issuer.
+func NewMultiValidator(ctx context.Context, configs ...Config) (*MultiValidator, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("authn: at least one issuer config is required")
+	}
+	for i, cfg := range configs {
+		if cfg.Issuer == "" {
+			return nil, fmt.Errorf("authn: configs[%d]: issuer is required to route tokens", i)
+		}
+		for j := range i {
+			if configs[j].Issuer == cfg.Issuer {
+				return nil, fmt.Errorf("authn: configs[%d] and configs[%d] both configure issuer %q", j, i, cfg.Issuer)
+			}
+		}
+	}
+
+	m := &MultiValidator{validators: make(map[string]*Validator, len(configs))}
+	for _, cfg := range configs {
+		v, err := NewValidator(ctx, cfg)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("authn: issuer %q: %w", cfg.Issuer, err)
+		}
+		m.validators[cfg.Issuer] = v
+	}
+	return m, nil
+}
+
+// Validate routes token by its UNVERIFIED iss claim and verifies it with that
+// issuer's Validator, returning the verified Principal.
+//
+// Every failure is a *Error. A token that is not a structurally valid JWT
+// yields CodeInvalidToken/ReasonMalformed, exactly as from Validator, so
+// PossiblyOpaque and IntrospectionFallback behave the same in front of either.
+// A missing or unconfigured iss yields ReasonIssuer without any network I/O.
+//
+// The routing read is never trusted on its own: the selected Validator has
+// Config.Issuer set, so it re-verifies iss against the signed claims, and a
+// token cannot be routed to one issuer while verifying as another.
+func (m *MultiValidator) Validate(ctx context.Context, token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed,
+			err: errors.New("empty token")}
+	}
+	if len(token) > maxTokenLength {
+		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed,
+			err: fmt.Errorf("token length %d exceeds %d byte limit", len(token), maxTokenLength)}
+	}
+	iss, err := unverifiedIssuer(token)
+	if err != nil {
+		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed, err: err}
+	}
+	v, ok := m.validators[iss]
+	if !ok {
+		// iss is attacker-controlled; %q keeps a crafted value from forging
+		// log lines.
+		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonIssuer,
+			err: fmt.Errorf("issuer %q is not trusted", iss)}
+	}
+	return v.Validate(ctx, token)
+}
+
+// Close closes every Validator. It is idempotent and safe to call
+// concurrently, since Validator.Close is.
+func (m *MultiValidator) Close() {
+	for _, v := range m.validators {
+		v.Close()
+	}
+}
+
+// unverifiedIssuer extracts iss from a token's UNVERIFIED payload, used only
+// to pick a Validator. A missing or non-string iss returns "", which matches
+// no configured issuer. A non-nil error means the token is not a structurally
+// valid three-segment JWT with a JSON payload.
+func unverifiedIssuer(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("token contains an invalid number of segments: %d", len(parts))
+	}
+	payload, err := jwt.NewParser().DecodeSegment(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode payload segment: %w", err)
+	}
+	var claims struct {
+		Iss any `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("failed to parse payload JSON: %w", err)
+	}
+	iss, _ := claims.Iss.(string)
+	return iss, nil
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiValidatorRoutesByIssuer(t *testing.T) {
+	t.Parallel()
+
+	keyA := mintRSA(t, "a-1")
+	keyB := mintRSA(t, "b-1")
+	jsA := newJWKSServer(t, keyA.jwk)
+	jsB := newJWKSServer(t, keyB.jwk)
+
+	cfgB := jsB.configFor()
+	cfgB.Audiences = []string{"internal-api"}
+
+	m, err := NewMultiValidator(context.Background(), jsA.configFor(), cfgB)
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+	jsA.settle(t)
+	jsB.settle(t)
+
+	p, err := m.Validate(context.Background(), keyA.mint(t, jsA.srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, jsA.srv.URL, p.Issuer)
+
+	// Each issuer keeps its own audience policy.
+	p, err = m.Validate(context.Background(), keyB.mint(t, jsB.srv.URL, withClaim(claimAud, "internal-api")))
+	require.NoError(t, err)
+	assert.Equal(t, jsB.srv.URL, p.Issuer)
+	_, err = m.Validate(context.Background(), keyB.mint(t, jsB.srv.URL))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonAudience)
+
+	// Routed to A by iss but signed with B's key: A's keys do not verify it.
+	_, err = m.Validate(context.Background(), keyB.mint(t, jsA.srv.URL, withKid("a-1")))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonSignature)
+}
+
+func TestMultiValidatorRejectsUnknownIssuerWithoutFetching(t *testing.T) {
+	t.Parallel()
+
+	key := mintRSA(t, "a-1")
+	js := newJWKSServer(t, key.jwk)
+	m, err := NewMultiValidator(context.Background(), js.configFor())
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+	js.settle(t)
+	before := js.hits.Load()
+
+	for _, tok := range []string{
+		key.mint(t, "https://attacker.example.com", withKid("unknown-kid")),
+		key.mint(t, "", withoutClaim(claimIss), withKid("unknown-kid")),
+		key.mint(t, "", withClaim(claimIss, 42), withKid("unknown-kid")),
+	} {
+		_, err := m.Validate(context.Background(), tok)
+		requireAuthnError(t, err, CodeInvalidToken, ReasonIssuer)
+	}
+	assert.Equal(t, before, js.hits.Load(), "an untrusted issuer must not trigger a JWKS fetch")
+}
+
+func TestMultiValidatorMalformedIsPossiblyOpaque(t *testing.T) {
+	t.Parallel()
+
+	key := mintRSA(t, "a-1")
+	js := newJWKSServer(t, key.jwk)
+	m, err := NewMultiValidator(context.Background(), js.configFor())
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+
+	for _, tok := range []string{"", "opaque-token", "a.b", "a.!!!.c"} {
+		_, err := m.Validate(context.Background(), tok)
+		requireAuthnError(t, err, CodeInvalidToken, ReasonMalformed)
+		assert.True(t, PossiblyOpaque(err))
+	}
+}
+
+func TestNewMultiValidatorRejectsBadConfig(t *testing.T) {
+	t.Parallel()
+
+	jwksOnly := validConfig()
+	jwksOnly.Issuer = ""
+	jwksOnly.JWKSURL = "https://issuer.example.com/jwks.json"
+
+	tests := []struct {
+		name    string
+		configs []Config
+		wantErr string
+	}{
+		{name: "no configs", wantErr: "at least one issuer config"},
+		{name: "issuer-less config", configs: []Config{jwksOnly}, wantErr: "configs[0]: issuer is required"},
+		{
+			name:    "duplicate issuer",
+			configs: []Config{validConfig(), validConfig()},
+			wantErr: "configs[0] and configs[1] both configure issuer",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewMultiValidator(context.Background(), tc.configs...)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("a failing issuer names itself", func(t *testing.T) {
+		t.Parallel()
+		key := mintRSA(t, "a-1")
+		js := newJWKSServer(t, key.jwk)
+		bad := validConfig()
+		bad.Issuer = "http://127.0.0.1:1"
+		bad.JWKSURL = unreachableJWKSURL
+		bad.InsecureAllowHTTP = true
+		bad.AllowPrivateIP = true
+
+		_, err := NewMultiValidator(context.Background(), js.configFor(), bad)
+		require.ErrorContains(t, err, `issuer "http://127.0.0.1:1"`)
+	})
+}
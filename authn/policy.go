@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	celgo "cel.dev/cel-go/cel"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+// PolicyConfig describes what a verified Principal must carry to be
+// authorized. Every configured requirement must hold: scopes, claim values and
+// expressions are ANDed.
+//
+// An empty PolicyConfig authorizes every Principal. That is deliberate — it is
+// the identity the package would apply without a policy — but it means a
+// policy built from configuration should be checked for emptiness by the
+// caller when "no rules" is not a sensible deployment.
+type PolicyConfig struct {
+	// RequiredScopes lists scopes that must ALL be granted to the token (see
+	// Scopes for where they are read from). A missing one fails with
+	// CodeInsufficientScope and the full list is advertised to the client
+	// (RFC 6750 §3), so it can request a token that satisfies the resource.
+	RequiredScopes []string
+
+	// RequiredClaims maps a claim name to its acceptable values. A string
+	// claim passes when it equals ANY listed value; an array claim passes when
+	// ANY of its string elements does. A missing claim, or one of another JSON
+	// type, fails. Values are compared byte-exact.
+	RequiredClaims map[string][]string
+
+	// Expressions are CEL expressions that must ALL evaluate to true. Each is
+	// compiled by NewPolicy, so a syntax or type error is a startup failure.
+	// They see these variables:
+	//
+	//   - claims:  map(string, dyn), the verified claim set.
+	//   - scopes:  list(string), the granted scopes, as returned by Scopes.
+	//   - subject: string, Principal.Subject.
+	//   - issuer:  string, Principal.Issuer.
+	//
+	// An expression that fails to evaluate (indexing a claim the token does
+	// not carry, say) denies the request rather than skipping the rule. Guard
+	// optional claims with `has(claims.x)` or `"x" in claims`.
+	Expressions []string
+}
+
+// Policy is a compiled PolicyConfig. Build one with NewPolicy; it is
+// immutable and safe for concurrent use.
+type Policy struct {
+	scopes      []string
+	claims      map[string][]string
+	expressions []*cel.CompiledExpression
+}
+
+// NewPolicy validates cfg and compiles its expressions. Errors are ordinary
+// construction errors, not *Error; a CEL compilation failure wraps the cel
+// package's *ParseError or *CheckError so its location detail is reachable
+// with errors.As.
+func NewPolicy(cfg PolicyConfig) (*Policy, error) {
+	for i, scope := range cfg.RequiredScopes {
+		if !validScopeToken(scope) {
+			return nil, fmt.Errorf("authn: required_scopes[%d] is not a valid scope token: %q", i, scope)
+		}
+	}
+	for name, values := range cfg.RequiredClaims {
+		if name == "" {
+			return nil, fmt.Errorf("authn: required_claims must not contain an empty claim name")
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("authn: required_claims[%q] must list at least one acceptable value", name)
+		}
+	}
+
+	p := &Policy{
+		scopes: slices.Clone(cfg.RequiredScopes),
+		claims: make(map[string][]string, len(cfg.RequiredClaims)),
+	}
+	for name, values := range cfg.RequiredClaims {
+		p.claims[name] = slices.Clone(values)
+	}
+	if len(cfg.Expressions) > 0 {
+		engine := newPolicyEngine()
+		for i, expr := range cfg.Expressions {
+			if strings.TrimSpace(expr) == "" {
+				return nil, fmt.Errorf("authn: expressions[%d] must not be empty", i)
+			}
+			compiled, err := engine.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("authn: expressions[%d]: %w", i, err)
+			}
+			p.expressions = append(p.expressions, compiled)
+		}
+	}
+	return p, nil
+}
+
+// newPolicyEngine returns the CEL engine policy expressions are compiled
+// against; the variables are documented on PolicyConfig.Expressions.
+func newPolicyEngine() *cel.Engine {
+	return cel.NewEngine(
+		celgo.Variable("claims", celgo.MapType(celgo.StringType, celgo.DynType)),
+		celgo.Variable("scopes", celgo.ListType(celgo.StringType)),
+		celgo.Variable("subject", celgo.StringType),
+		celgo.Variable("issuer", celgo.StringType),
+	)
+}
+
+// Authorize checks p against the policy. It returns nil when every
+// requirement holds and otherwise a *Error with CodeInsufficientScope:
+//
+//   - ReasonScope when a required scope is missing. RequiredScopes on the
+//     error lists every scope the policy requires, not only the missing ones,
+//     since the client needs a token carrying all of them.
+//   - ReasonClaimValue when a required claim is absent or has no acceptable
+//     value.
+//   - ReasonPolicy when an expression is false or fails to evaluate.
+//
+// Requirements are checked in that order, so the cheap checks reject first.
+func (pol *Policy) Authorize(p Principal) error {
+	granted := Scopes(p)
+	for _, scope := range pol.scopes {
+		if !slices.Contains(granted, scope) {
+			return &Error{Code: CodeInsufficientScope, Reason: ReasonScope,
+				RequiredScopes: slices.Clone(pol.scopes),
+				err:            fmt.Errorf("required scope %q not granted", scope)}
+		}
+	}
+	// Sorted so that with several unmet claims the reported one is stable.
+	for _, name := range slices.Sorted(maps.Keys(pol.claims)) {
+		if !claimHasValue(p.Claims[name], pol.claims[name]) {
+			return &Error{Code: CodeInsufficientScope, Reason: ReasonClaimValue,
+				err: fmt.Errorf("claim %q is missing or has no acceptable value", name)}
+		}
+	}
+	if len(pol.expressions) == 0 {
+		return nil
+	}
+	claims := p.Claims
+	if claims == nil {
+		claims = map[string]any{}
+	}
+	activation := map[string]any{
+		"claims":  claims,
+		"scopes":  granted,
+		"subject": p.Subject,
+		"issuer":  p.Issuer,
+	}
+	for _, expr := range pol.expressions {
+		ok, err := expr.EvaluateBool(activation)
+		if err != nil {
+			return &Error{Code: CodeInsufficientScope, Reason: ReasonPolicy,
+				err: fmt.Errorf("policy expression %q: %w", expr.Source(), err)}
+		}
+		if !ok {
+			return &Error{Code: CodeInsufficientScope, Reason: ReasonPolicy,
+				err: fmt.Errorf("policy expression %q evaluated to false", expr.Source())}
+		}
+	}
+	return nil
+}
+
+// claimHasValue reports whether a claim value is, or for an array contains,
+// one of the accepted strings.
+func claimHasValue(claim any, accepted []string) bool {
+	switch v := claim.(type) {
+	case string:
+		return slices.Contains(accepted, v)
+	case []any:
+		return slices.ContainsFunc(v, func(elem any) bool {
+			s, ok := elem.(string)
+			return ok && slices.Contains(accepted, s)
+		})
+	case []string:
+		return slices.ContainsFunc(v, func(s string) bool { return slices.Contains(accepted, s) })
+	default:
+		return false
+	}
+}
+
+// Scopes returns the scopes granted to p, deduplicated in first-seen order.
+//
+// Issuers disagree on where scopes live, so both common claims are read and
+// merged, and each may take either shape:
+//
+//   - scope: a space-delimited string (RFC 8693 §4.2, RFC 9068 §2.2.3).
+//   - scp: a JSON array of strings (Okta, Keycloak) or, as Entra ID emits it,
+//     a space-delimited string.
+//
+// Elements that are not strings are ignored. The result is never nil.
+func Scopes(p Principal) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	for _, name := range []string{"scope", "scp"} {
+		switch v := p.Claims[name].(type) {
+		case string:
+			for _, s := range strings.Fields(v) {
+				add(s)
+			}
+		case []any:
+			for _, elem := range v {
+				if s, ok := elem.(string); ok {
+					add(s)
+				}
+			}
+		case []string:
+			for _, s := range v {
+				add(s)
+			}
+		}
+	}
+	return out
+}
+
+// EnforcePolicy returns a TokenValidator that verifies a token with v and then
+// authorizes the resulting Principal with pol, so Middleware can apply both
+// in one step. A verification failure is returned unchanged and the policy is
+// not consulted.
+//
+// Middleware answers a policy failure with 403 and an RFC 6750
+// error="insufficient_scope" challenge carrying the required scopes. It
+// consults the policy only after the sender-constraint checks of WithDPoP and
+// WithCertificateBoundTokens, so a bound token presented without its proof
+// is a 401, not a 403 naming the scopes it lacks.
+func EnforcePolicy(v TokenValidator, pol *Policy) TokenValidator {
+	return &policyValidator{validator: v, policy: pol}
+}
+
+// policyValidator is the TokenValidator returned by EnforcePolicy.
+type policyValidator struct {
+	validator TokenValidator
+	policy    *Policy
+}
+
+// Validate implements TokenValidator.
+func (pv *policyValidator) Validate(ctx context.Context, token string) (Principal, error) {
+	p, err := pv.validator.Validate(ctx, token)
+	if err != nil {
+		return Principal{}, err
+	}
+	if err := pv.policy.Authorize(p); err != nil {
+		return Principal{}, err
+	}
+	return p, nil
+}
+
+// splitPolicies unwraps the policies EnforcePolicy put around v, innermost
+// first, returning them and the validator that verifies tokens.
+func splitPolicies(v TokenValidator) (TokenValidator, []*Policy) {
+	var policies []*Policy
+	for {
+		pv, ok := v.(*policyValidator)
+		if !ok {
+			slices.Reverse(policies)
+			return v, policies
+		}
+		policies = append(policies, pv.policy)
+		v = pv.validator
+	}
+}
+
+// authorize applies each of policies to p in turn, returning the first
+// failure.
+func authorize(policies []*Policy, p Principal) error {
+	for _, pol := range policies {
+		if err := pol.Authorize(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+func TestScopes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		want   []string
+	}{
+		{name: "no scope claims", claims: map[string]any{}, want: []string{}},
+		{
+			name:   "space-delimited scope",
+			claims: map[string]any{"scope": "read  write"},
+			want:   []string{"read", "write"},
+		},
+		{
+			name:   "scp array",
+			claims: map[string]any{"scp": []any{"read", 7, "write"}},
+			want:   []string{"read", "write"},
+		},
+		{
+			name:   "space-delimited scp",
+			claims: map[string]any{"scp": "User.Read Mail.Send"},
+			want:   []string{"User.Read", "Mail.Send"},
+		},
+		{
+			name:   "both claims merged and deduplicated",
+			claims: map[string]any{"scope": "read write", "scp": []any{"write", "admin"}},
+			want:   []string{"read", "write", "admin"},
+		},
+		{
+			name:   "wrong type ignored",
+			claims: map[string]any{"scope": 42},
+			want:   []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, Scopes(Principal{Claims: tc.claims}))
+		})
+	}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	t.Parallel()
+
+	pol, err := NewPolicy(PolicyConfig{
+		RequiredScopes: []string{"mcp:tools", "read"},
+		RequiredClaims: map[string][]string{"tenant": {"acme", "globex"}},
+		Expressions:    []string{`"admins" in claims.groups`, `subject.startsWith("a")`},
+	})
+	require.NoError(t, err)
+
+	base := func() Principal {
+		return Principal{
+			Subject: testSubject,
+			Claims: map[string]any{
+				"scope":  "read mcp:tools",
+				"tenant": "acme",
+				"groups": []any{"devs", "admins"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		mutate     func(*Principal)
+		wantReason Reason
+	}{
+		{name: "all requirements met", mutate: func(*Principal) {}},
+		{
+			name:       "missing scope",
+			mutate:     func(p *Principal) { p.Claims["scope"] = "read" },
+			wantReason: ReasonScope,
+		},
+		{
+			name:       "scope satisfied via scp",
+			mutate:     func(p *Principal) { p.Claims["scope"] = "read"; p.Claims["scp"] = []any{"mcp:tools"} },
+			wantReason: "",
+		},
+		{
+			name:       "wrong claim value",
+			mutate:     func(p *Principal) { p.Claims["tenant"] = "initech" },
+			wantReason: ReasonClaimValue,
+		},
+		{
+			name:       "array claim containing an accepted value",
+			mutate:     func(p *Principal) { p.Claims["tenant"] = []any{"initech", "globex"} },
+			wantReason: "",
+		},
+		{
+			name:       "missing claim",
+			mutate:     func(p *Principal) { delete(p.Claims, "tenant") },
+			wantReason: ReasonClaimValue,
+		},
+		{
+			name:       "expression false",
+			mutate:     func(p *Principal) { p.Claims["groups"] = []any{"devs"} },
+			wantReason: ReasonPolicy,
+		},
+		{
+			name:       "expression evaluation error denies",
+			mutate:     func(p *Principal) { delete(p.Claims, "groups") },
+			wantReason: ReasonPolicy,
+		},
+		{
+			name:       "subject expression",
+			mutate:     func(p *Principal) { p.Subject = "bob" },
+			wantReason: ReasonPolicy,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p := base()
+			tc.mutate(&p)
+			err := pol.Authorize(p)
+			if tc.wantReason == "" {
+				require.NoError(t, err)
+				return
+			}
+			requireAuthnError(t, err, CodeInsufficientScope, tc.wantReason)
+		})
+	}
+}
+
+func TestPolicyScopeErrorListsRequiredScopes(t *testing.T) {
+	t.Parallel()
+
+	pol, err := NewPolicy(PolicyConfig{RequiredScopes: []string{"read", "write"}})
+	require.NoError(t, err)
+
+	err = pol.Authorize(Principal{Claims: map[string]any{"scope": "read"}})
+	var authnErr *Error
+	require.ErrorAs(t, err, &authnErr)
+	assert.Equal(t, []string{"read", "write"}, authnErr.RequiredScopes)
+}
+
+func TestEmptyPolicyAuthorizesEverything(t *testing.T) {
+	t.Parallel()
+
+	pol, err := NewPolicy(PolicyConfig{})
+	require.NoError(t, err)
+	assert.NoError(t, pol.Authorize(Principal{}))
+}
+
+func TestNewPolicyRejectsBadConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     PolicyConfig
+		wantErr string
+	}{
+		{
+			name:    "scope with whitespace",
+			cfg:     PolicyConfig{RequiredScopes: []string{"read write"}},
+			wantErr: "required_scopes[0]",
+		},
+		{
+			name:    "empty claim name",
+			cfg:     PolicyConfig{RequiredClaims: map[string][]string{"": {"x"}}},
+			wantErr: "empty claim name",
+		},
+		{
+			name:    "claim with no values",
+			cfg:     PolicyConfig{RequiredClaims: map[string][]string{"tenant": nil}},
+			wantErr: "at least one acceptable value",
+		},
+		{
+			name:    "empty expression",
+			cfg:     PolicyConfig{Expressions: []string{"  "}},
+			wantErr: "expressions[0] must not be empty",
+		},
+		{
+			name:    "expression referencing an unknown variable",
+			cfg:     PolicyConfig{Expressions: []string{`token.sub == "x"`}},
+			wantErr: "expressions[0]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPolicy(tc.cfg)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("CEL errors stay inspectable", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewPolicy(PolicyConfig{Expressions: []string{`claims["sub"`}})
+		var parseErr *cel.ParseError
+		assert.True(t, errors.As(err, &parseErr))
+	})
+}
+
+func TestEnforcePolicyThroughMiddleware(t *testing.T) {
+	t.Parallel()
+
+	pol, err := NewPolicy(PolicyConfig{RequiredScopes: []string{"mcp:tools", "read"}})
+	require.NoError(t, err)
+
+	t.Run("insufficient scope is a 403 naming the scopes", func(t *testing.T) {
+		t.Parallel()
+		fv := &fakeTokenValidator{principal: Principal{Subject: testSubject,
+			Claims: map[string]any{"scope": "read"}}}
+		h := Middleware(EnforcePolicy(fv, pol), WithResourceMetadataURL(testMetadataURL))(principalEcho())
+
+		rec := serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t,
+			`Bearer error="insufficient_scope", scope="mcp:tools read", resource_metadata="`+testMetadataURL+`"`,
+			rec.Header().Get(headerWWWAuth))
+	})
+
+	t.Run("sufficient scope is admitted", func(t *testing.T) {
+		t.Parallel()
+		fv := &fakeTokenValidator{principal: Principal{Subject: testSubject,
+			Claims: map[string]any{"scope": "mcp:tools read"}}}
+		h := Middleware(EnforcePolicy(fv, pol))(principalEcho())
+
+		rec := serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("a bound token without its proof is a 401 before the policy", func(t *testing.T) {
+		t.Parallel()
+		bound := Principal{Subject: testSubject, Claims: map[string]any{
+			"scope": "read",
+			"cnf":   map[string]any{"x5t#S256": "abc"},
+		}}
+		h := Middleware(EnforcePolicy(&fakeTokenValidator{principal: bound}, pol),
+			WithCertificateBoundTokens())(principalEcho())
+
+		rec := serve(h, http.MethodPost, "/mcp", "Bearer "+testToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get(headerWWWAuth))
+	})
+
+	t.Run("a verification failure skips the policy", func(t *testing.T) {
+		t.Parallel()
+		fv := &fakeTokenValidator{err: &Error{Code: CodeInvalidToken, Reason: ReasonExpired}}
+		_, err := EnforcePolicy(fv, pol).Validate(context.Background(), testToken)
+		requireAuthnError(t, err, CodeInvalidToken, ReasonExpired)
+	})
+}
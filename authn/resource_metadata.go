@@ -0,0 +1,324 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/stacklok/toolhive-core/networking"
+	httpvalidation "github.com/stacklok/toolhive-core/validation/http"
+)
+
+// protectedResourceWellKnown is the well-known URI suffix for OAuth 2.0
+// Protected Resource Metadata (RFC 9728 §3).
+const protectedResourceWellKnown = "/.well-known/oauth-protected-resource"
+
+// maxProtectedResourceMetadataSize caps a fetched metadata document. Real
+// documents are a few hundred bytes; the cap bounds what a hostile or broken
+// endpoint can make a client buffer.
+const maxProtectedResourceMetadataSize = 64 << 10
+
+// ProtectedResourceMetadata is the OAuth 2.0 Protected Resource Metadata
+// document (RFC 9728 §2) a resource server publishes so a client that received
+// a 401 can discover which authorization server to obtain a token from, and
+// how to present it.
+//
+// Only the parameters a ToolHive resource server has a use for are modeled.
+// Every optional parameter is omitted from the JSON when unset, so a document
+// carries no claims the resource server did not make.
+type ProtectedResourceMetadata struct {
+	// Resource is the protected resource's identifier: an https URI (http for
+	// localhost only) with no fragment and no query. Required. It is also what
+	// the document's well-known location is derived from (RFC 9728 §3.1).
+	Resource string `json:"resource"`
+
+	// AuthorizationServers lists the issuer identifiers of the authorization
+	// servers that can mint tokens for this resource. Each must satisfy
+	// networking.ValidateIssuerURL.
+	AuthorizationServers []string `json:"authorization_servers,omitempty"`
+
+	// JWKSURI is the resource server's own JWK Set, for a resource that signs
+	// responses. It is NOT where token verification keys come from; those are
+	// the authorization server's.
+	JWKSURI string `json:"jwks_uri,omitempty"`
+
+	// ScopesSupported lists the scope values a client can request to access
+	// this resource (RFC 6749 §3.3 scope-tokens).
+	ScopesSupported []string `json:"scopes_supported,omitempty"`
+
+	// BearerMethodsSupported lists how a bearer token may be presented: any of
+	// "header", "body" and "query" (RFC 6750 §2). Middleware reads only the
+	// Authorization header, so a document fronted by it should advertise
+	// exactly BearerMethodHeader.
+	BearerMethodsSupported []string `json:"bearer_methods_supported,omitempty"`
+
+	// ResourceName is a human-readable name for display to an end user.
+	ResourceName string `json:"resource_name,omitempty"`
+
+	// ResourceDocumentation is a URL of developer documentation for the
+	// resource.
+	ResourceDocumentation string `json:"resource_documentation,omitempty"`
+
+	// ResourcePolicyURI is a URL describing how client data is used.
+	ResourcePolicyURI string `json:"resource_policy_uri,omitempty"`
+
+	// ResourceTOSURI is a URL of the resource's terms of service.
+	ResourceTOSURI string `json:"resource_tos_uri,omitempty"`
+
+	// TLSClientCertificateBoundAccessTokens advertises support for RFC 8705
+	// certificate-bound access tokens.
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+
+	// DPoPSigningAlgValuesSupported lists the JWS algorithms accepted for RFC
+	// 9449 DPoP proofs.
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported,omitempty"`
+
+	// DPoPBoundAccessTokensRequired advertises that every access token must be
+	// DPoP-bound.
+	DPoPBoundAccessTokensRequired bool `json:"dpop_bound_access_tokens_required,omitempty"`
+}
+
+// Bearer token presentation methods for
+// ProtectedResourceMetadata.BearerMethodsSupported (RFC 6750 §2).
+const (
+	// BearerMethodHeader is the Authorization request header (RFC 6750 §2.1).
+	BearerMethodHeader = "header"
+	// BearerMethodBody is the form-encoded body parameter (RFC 6750 §2.2).
+	BearerMethodBody = "body"
+	// BearerMethodQuery is the URI query parameter (RFC 6750 §2.3).
+	BearerMethodQuery = "query"
+)
+
+// Validate checks the document against RFC 9728 and returns the first
+// violation. It performs no I/O.
+//
+// The resource identifier is checked with validation/http's RFC 8707 rules plus
+// the https and no-query requirements described on Resource; each
+// authorization server with networking.ValidateIssuerURL, since it is an
+// issuer identifier a client will run discovery against.
+func (m *ProtectedResourceMetadata) Validate() error {
+	if _, err := parseResourceIdentifier(m.Resource); err != nil {
+		return err
+	}
+	for i, as := range m.AuthorizationServers {
+		if err := networking.ValidateIssuerURL(as); err != nil {
+			return fmt.Errorf("authn: authorization_servers[%d]: %w", i, err)
+		}
+	}
+	for _, u := range []struct{ field, value string }{
+		{"jwks_uri", m.JWKSURI},
+		{"resource_documentation", m.ResourceDocumentation},
+		{"resource_policy_uri", m.ResourcePolicyURI},
+		{"resource_tos_uri", m.ResourceTOSURI},
+	} {
+		if u.value == "" {
+			continue
+		}
+		if err := httpvalidation.ValidateResourceURI(u.value); err != nil {
+			return fmt.Errorf("authn: %s: %w", u.field, err)
+		}
+	}
+	for i, scope := range m.ScopesSupported {
+		if !validScopeToken(scope) {
+			return fmt.Errorf("authn: scopes_supported[%d] is not a valid scope token: %q", i, scope)
+		}
+	}
+	seen := make(map[string]bool, len(m.BearerMethodsSupported))
+	for i, method := range m.BearerMethodsSupported {
+		switch method {
+		case BearerMethodHeader, BearerMethodBody, BearerMethodQuery:
+		default:
+			return fmt.Errorf("authn: bearer_methods_supported[%d] is not one of header, body, query: %q", i, method)
+		}
+		if seen[method] {
+			return fmt.Errorf("authn: bearer_methods_supported lists %q more than once", method)
+		}
+		seen[method] = true
+	}
+	return nil
+}
+
+// validScopeToken reports whether s is an RFC 6749 §3.3 scope-token: one or
+// more characters from %x21 / %x23-5B / %x5D-7E, i.e. printable ASCII other
+// than space, double quote and backslash.
+func validScopeToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// ProtectedResourceHandler serves a ProtectedResourceMetadata document at its
+// RFC 9728 well-known location. Build one with NewProtectedResourceHandler.
+//
+// The document is encoded once at construction, so serving it does no work
+// beyond a write and a later mutation of the caller's value is never
+// observed.
+type ProtectedResourceHandler struct {
+	path        string
+	metadataURL string
+	body        []byte
+}
+
+// NewProtectedResourceHandler validates doc and returns a handler serving it.
+//
+// Errors are ordinary construction errors, not *Error: a malformed document is
+// a deployment mistake to surface at startup, not a per-request failure.
+func NewProtectedResourceHandler(doc ProtectedResourceMetadata) (*ProtectedResourceHandler, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	metadataURL, err := ProtectedResourceMetadataURL(doc.Resource)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(metadataURL)
+	if err != nil {
+		// ProtectedResourceMetadataURL built this from a parsed URL, so this
+		// is unreachable; checked to keep errcheck and future edits honest.
+		return nil, fmt.Errorf("authn: protected resource metadata URL: %w", err)
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("authn: failed to encode protected resource metadata: %w", err)
+	}
+	return &ProtectedResourceHandler{path: u.EscapedPath(), metadataURL: metadataURL, body: body}, nil
+}
+
+// Path returns the request path the document is served at, e.g.
+// "/.well-known/oauth-protected-resource/mcp" for the resource
+// "https://api.example.com/mcp".
+func (h *ProtectedResourceHandler) Path() string {
+	return h.path
+}
+
+// MetadataURL returns the absolute URL of the document, suitable for the
+// resource_metadata parameter of a WWW-Authenticate challenge (RFC 9728 §5.1).
+func (h *ProtectedResourceHandler) MetadataURL() string {
+	return h.metadataURL
+}
+
+// ServeHTTP implements http.Handler. Only GET and HEAD are answered; the path
+// is not checked, so mount the handler at Path().
+func (h *ProtectedResourceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(h.body)
+	}
+}
+
+// ProtectedResourceMetadataURL derives the metadata document URL for a
+// resource identifier per RFC 9728 §3.1: the well-known suffix is inserted
+// between the host and the path, and a terminating slash on the path is
+// dropped. "https://api.example.com/mcp" therefore maps to
+// "https://api.example.com/.well-known/oauth-protected-resource/mcp".
+func ProtectedResourceMetadataURL(resource string) (string, error) {
+	u, err := parseResourceIdentifier(resource)
+	if err != nil {
+		return "", err
+	}
+	derived := &url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   protectedResourceWellKnown + strings.TrimSuffix(u.Path, "/"),
+	}
+	if u.RawPath != "" {
+		derived.RawPath = protectedResourceWellKnown + strings.TrimSuffix(u.RawPath, "/")
+	}
+	return derived.String(), nil
+}
+
+// parseResourceIdentifier validates a protected resource identifier and
+// returns it parsed.
+//
+// The identifier must be an absolute URI with a host and no fragment (the
+// shared RFC 8707 check), and must use https — http is tolerated for a
+// localhost resource only, the same development exception
+// networking.ValidateIssuerURL makes for issuers. A query component is
+// rejected as well: RFC 9728 §1.2 says a resource identifier SHOULD NOT carry
+// one, and the §3.1 well-known derivation has no defined place for it.
+func parseResourceIdentifier(resource string) (*url.URL, error) {
+	if err := httpvalidation.ValidateResourceURI(resource); err != nil {
+		return nil, fmt.Errorf("authn: resource: %w", err)
+	}
+	u, err := url.Parse(resource)
+	if err != nil {
+		return nil, fmt.Errorf("authn: resource: %w", err)
+	}
+	switch {
+	case u.Scheme == schemeHTTPS:
+	case u.Scheme == schemeHTTP && networking.IsLocalhost(u.Host):
+	default:
+		return nil, fmt.Errorf("authn: resource must use https (http is allowed for localhost only): %s", resource)
+	}
+	if u.RawQuery != "" || u.ForceQuery {
+		return nil, fmt.Errorf("authn: resource must not contain a query component: %s", resource)
+	}
+	return u, nil
+}
+
+// FetchProtectedResourceMetadata retrieves and validates the metadata document
+// for resource, for a client that has to discover where to get a token.
+//
+// metadataURL is normally the resource_metadata parameter of the challenge the
+// client received; when empty it is derived from resource with
+// ProtectedResourceMetadataURL. Either way, RFC 9728 §3.3 requires the
+// document's resource to be identical to the identifier the client expected,
+// so a document served for one resource cannot steer a client to another
+// resource's authorization server — a mismatch is an error, not a warning.
+// The returned document has passed Validate.
+//
+// The URL may come from an untrusted 401 response, so client should be one
+// built with networking.NewHttpClientBuilder (private IPs blocked, timeouts
+// set), ideally with networking.SameHostRedirectPolicy so a redirect cannot
+// move the fetch to another host.
+func FetchProtectedResourceMetadata(
+	ctx context.Context,
+	client networking.HTTPClient,
+	metadataURL, resource string,
+) (*ProtectedResourceMetadata, error) {
+	if metadataURL == "" {
+		derived, err := ProtectedResourceMetadataURL(resource)
+		if err != nil {
+			return nil, err
+		}
+		metadataURL = derived
+	} else if _, err := parseResourceIdentifier(resource); err != nil {
+		return nil, err
+	}
+	if err := networking.ValidateIssuerURL(metadataURL); err != nil {
+		return nil, fmt.Errorf("authn: protected resource metadata URL: %w", err)
+	}
+
+	result, err := networking.FetchJSON[ProtectedResourceMetadata](ctx, client, metadataURL,
+		networking.WithMaxResponseSize(maxProtectedResourceMetadataSize))
+	if err != nil {
+		return nil, fmt.Errorf("authn: failed to fetch protected resource metadata: %w", err)
+	}
+	doc := &result.Data
+	if doc.Resource != resource {
+		return nil, fmt.Errorf("authn: protected resource metadata is for %q, expected %q", doc.Resource, resource)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
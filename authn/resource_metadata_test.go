@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectedResourceMetadataURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "host only",
+			resource: "https://api.example.com",
+			want:     "https://api.example.com/.well-known/oauth-protected-resource",
+		},
+		{
+			name:     "path is appended after the well-known suffix",
+			resource: testResource,
+			want:     testMetadataURL,
+		},
+		{
+			name:     "terminating slash is dropped",
+			resource: "https://api.example.com/mcp/",
+			want:     testMetadataURL,
+		},
+		{
+			name:     "port is preserved",
+			resource: "https://api.example.com:8443/v1",
+			want:     "https://api.example.com:8443/.well-known/oauth-protected-resource/v1",
+		},
+		{
+			name:     "http allowed for localhost",
+			resource: "http://localhost:8080/mcp",
+			want:     "http://localhost:8080/.well-known/oauth-protected-resource/mcp",
+		},
+		{
+			name:     "http rejected elsewhere",
+			resource: "http://api.example.com/mcp",
+			wantErr:  "must use https",
+		},
+		{
+			name:     "fragment rejected",
+			resource: "https://api.example.com/mcp#frag",
+			wantErr:  "fragment",
+		},
+		{
+			name:     "query rejected",
+			resource: "https://api.example.com/mcp?x=1",
+			wantErr:  "query",
+		},
+		{
+			name:     "empty rejected",
+			resource: "",
+			wantErr:  "cannot be empty",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ProtectedResourceMetadataURL(tc.resource)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestProtectedResourceHandler(t *testing.T) {
+	t.Parallel()
+
+	doc := ProtectedResourceMetadata{
+		Resource:             testResource,
+		AuthorizationServers: []string{"https://issuer.example.com"},
+	}
+	h, err := NewProtectedResourceHandler(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "/.well-known/oauth-protected-resource/mcp", h.Path())
+	assert.Equal(t, testMetadataURL, h.MetadataURL())
+
+	// Mutating the caller's document after construction is not observed.
+	doc.AuthorizationServers[0] = "https://evil.example.com"
+
+	rec := serve(h, http.MethodGet, h.Path(), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t,
+		`{"resource":"`+testResource+`","authorization_servers":["https://issuer.example.com"]}`,
+		rec.Body.String())
+
+	rec = serve(h, http.MethodHead, h.Path(), "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serve(h, http.MethodPost, h.Path(), "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
+
+func TestNewProtectedResourceHandlerRejectsBadResource(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProtectedResourceHandler(ProtectedResourceMetadata{Resource: "not a uri"})
+	require.Error(t, err)
+}
+
+func TestProtectedResourceMetadataValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() ProtectedResourceMetadata {
+		return ProtectedResourceMetadata{
+			Resource:               testResource,
+			AuthorizationServers:   []string{"https://issuer.example.com", "http://localhost:9000"},
+			ScopesSupported:        []string{"mcp:tools", "read"},
+			BearerMethodsSupported: []string{BearerMethodHeader},
+			ResourceDocumentation:  "https://docs.example.com/mcp",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*ProtectedResourceMetadata)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(*ProtectedResourceMetadata) {}},
+		{
+			name:    "missing resource",
+			mutate:  func(m *ProtectedResourceMetadata) { m.Resource = "" },
+			wantErr: "resource",
+		},
+		{
+			name:    "http authorization server",
+			mutate:  func(m *ProtectedResourceMetadata) { m.AuthorizationServers = []string{"http://issuer.example.com"} },
+			wantErr: "authorization_servers[0]",
+		},
+		{
+			name:    "scope with space",
+			mutate:  func(m *ProtectedResourceMetadata) { m.ScopesSupported = []string{"read write"} },
+			wantErr: "scopes_supported[0]",
+		},
+		{
+			name:    "empty scope",
+			mutate:  func(m *ProtectedResourceMetadata) { m.ScopesSupported = []string{""} },
+			wantErr: "scopes_supported[0]",
+		},
+		{
+			name:    "unknown bearer method",
+			mutate:  func(m *ProtectedResourceMetadata) { m.BearerMethodsSupported = []string{"cookie"} },
+			wantErr: "bearer_methods_supported[0]",
+		},
+		{
+			name: "duplicate bearer method",
+			mutate: func(m *ProtectedResourceMetadata) {
+				m.BearerMethodsSupported = []string{BearerMethodHeader, BearerMethodHeader}
+			},
+			wantErr: "more than once",
+		},
+		{
+			name:    "relative documentation URL",
+			mutate:  func(m *ProtectedResourceMetadata) { m.ResourceDocumentation = "/docs" },
+			wantErr: "resource_documentation",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			m := valid()
+			tc.mutate(&m)
+			err := m.Validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+// newMetadataServer serves body as JSON at the RFC 9728 well-known path for
+// the "/mcp" resource, returning the server and that resource identifier.
+func newMetadataServer(t *testing.T, body func(resource string) string) (*httptest.Server, string) {
+	t.Helper()
+	var resource string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != protectedResourceWellKnown+"/mcp" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body(resource)))
+	}))
+	t.Cleanup(srv.Close)
+	resource = srv.URL + "/mcp"
+	return srv, resource
+}
+
+func TestFetchProtectedResourceMetadata(t *testing.T) {
+	t.Parallel()
+
+	srv, resource := newMetadataServer(t, func(resource string) string {
+		return `{"resource":"` + resource + `","authorization_servers":["https://issuer.example.com"],` +
+			`"scopes_supported":["mcp:tools"],"bearer_methods_supported":["header"]}`
+	})
+
+	// Derived from the resource identifier.
+	doc, err := FetchProtectedResourceMetadata(context.Background(), srv.Client(), "", resource)
+	require.NoError(t, err)
+	assert.Equal(t, resource, doc.Resource)
+	assert.Equal(t, []string{"https://issuer.example.com"}, doc.AuthorizationServers)
+	assert.Equal(t, []string{"mcp:tools"}, doc.ScopesSupported)
+
+	// Taken from a challenge's resource_metadata parameter.
+	metadataURL := strings.Replace(resource, "/mcp", protectedResourceWellKnown+"/mcp", 1)
+	doc, err = FetchProtectedResourceMetadata(context.Background(), srv.Client(), metadataURL, resource)
+	require.NoError(t, err)
+	assert.Equal(t, resource, doc.Resource)
+}
+
+func TestFetchProtectedResourceMetadataRejectsResourceMismatch(t *testing.T) {
+	t.Parallel()
+
+	srv, resource := newMetadataServer(t, func(string) string {
+		return `{"resource":"https://other.example.com/mcp","authorization_servers":["https://evil.example.com"]}`
+	})
+
+	_, err := FetchProtectedResourceMetadata(context.Background(), srv.Client(), "", resource)
+	require.ErrorContains(t, err, "expected")
+}
+
+func TestFetchProtectedResourceMetadataRejectsInvalidDocument(t *testing.T) {
+	t.Parallel()
+
+	srv, resource := newMetadataServer(t, func(resource string) string {
+		return `{"resource":"` + resource + `","bearer_methods_supported":["cookie"]}`
+	})
+
+	_, err := FetchProtectedResourceMetadata(context.Background(), srv.Client(), "", resource)
+	require.ErrorContains(t, err, "bearer_methods_supported")
+}
+
+func TestFetchProtectedResourceMetadataRejectsPlainHTTPURL(t *testing.T) {
+	t.Parallel()
+
+	_, err := FetchProtectedResourceMetadata(context.Background(), http.DefaultClient,
+		"http://api.example.com/.well-known/oauth-protected-resource/mcp", testResource)
+	require.ErrorContains(t, err, "HTTPS")
+}
@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RevocationKind names what a Revocation matches a token by.
+type RevocationKind string
+
+const (
+	// RevokeToken matches a single token by its jti claim.
+	RevokeToken RevocationKind = "jti"
+	// RevokeSession matches every token carrying an OIDC sid claim — the
+	// tokens of one login session, however many were refreshed from it.
+	RevokeSession RevocationKind = "sid"
+	// RevokeSubject matches every token issued to a sub.
+	RevokeSubject RevocationKind = "sub"
+)
+
+// Revocation records that some tokens must no longer be accepted.
+type Revocation struct {
+	// Kind says which claim Value is compared with.
+	Kind RevocationKind
+
+	// Issuer scopes Value: jti, sid and sub are unique only per issuer (RFC
+	// 7519 §4.1.2, §4.1.7), so a revocation for one issuer must not reach a
+	// token another issuer happened to give the same identifier. Required.
+	Issuer string
+
+	// Value is the jti, sid or sub being revoked. Required.
+	Value string
+
+	// Cutoff, when set, limits the revocation to tokens issued at or before
+	// it — the tokens that predate a password change or logout — so the
+	// subject can sign in again and use fresh tokens. A token without an iat
+	// is treated as issued before any cutoff, since nothing shows otherwise.
+	// Zero revokes matching tokens regardless of when they were issued.
+	//
+	// "At or before" because iat has one-second granularity: a token minted
+	// in the same second as the event cannot be shown to postdate it.
+	Cutoff time.Time
+
+	// ExpiresAt is when the record may be forgotten: once every token it could
+	// match has expired anyway. For RevokeToken that is the token's exp; for
+	// the other kinds, Cutoff plus the longest lifetime the issuer grants.
+	// Zero keeps the record indefinitely.
+	ExpiresAt time.Time
+}
+
+// validate checks that r is complete.
+func (r Revocation) validate() error {
+	switch r.Kind {
+	case RevokeToken, RevokeSession, RevokeSubject:
+	default:
+		return fmt.Errorf("authn: unknown revocation kind %q", r.Kind)
+	}
+	if r.Issuer == "" {
+		return errors.New("authn: revocation issuer is required")
+	}
+	if r.Value == "" {
+		return fmt.Errorf("authn: revocation %s is required", r.Kind)
+	}
+	return nil
+}
+
+// RevocationCheck identifies a verified token to a RevocationChecker. Every
+// field comes from the token's verified claims; JTI, SessionID and IssuedAt
+// are empty or zero when the token lacks the claim.
+type RevocationCheck struct {
+	// Issuer is the verified iss.
+	Issuer string
+	// Subject is the verified sub, always non-empty.
+	Subject string
+	// JTI is the jti claim.
+	JTI string
+	// SessionID is the OIDC sid claim.
+	SessionID string
+	// IssuedAt is the iat claim.
+	IssuedAt time.Time
+}
+
+// revocationCheck builds the RevocationCheck for a verified claim set.
+func revocationCheck(claims jwt.MapClaims, iss, sub string) RevocationCheck {
+	c := RevocationCheck{
+		Issuer:    iss,
+		Subject:   sub,
+		JTI:       stringClaim(claims, "jti"),
+		SessionID: stringClaim(claims, "sid"),
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		c.IssuedAt = iat.Time
+	}
+	return c
+}
+
+// value returns the claim of c that a Revocation of kind compares with.
+func (c RevocationCheck) value(kind RevocationKind) string {
+	switch kind {
+	case RevokeToken:
+		return c.JTI
+	case RevokeSession:
+		return c.SessionID
+	case RevokeSubject:
+		return c.Subject
+	default:
+		return ""
+	}
+}
+
+// revokedBy reports whether a revocation with cutoff covers a token issued at
+// iat (zero for a token without one). A zero cutoff covers every token.
+func revokedBy(cutoff, iat time.Time) bool {
+	return cutoff.IsZero() || iat.IsZero() || !iat.After(cutoff)
+}
+
+// RevocationChecker decides whether a verified token has been revoked. Set
+// Config.RevocationChecker to have Validate consult it after every other
+// check has passed, so it only ever sees tokens that would otherwise be
+// accepted, and only ever sees verified claims.
+//
+// Validate fails a revoked token with CodeInvalidToken/ReasonRevoked. An error
+// fails it with CodeUnavailable/ReasonRevocationUnavailable: a checker that
+// cannot answer is treated like unreachable key material, closed rather than
+// open, since failing open would let any revoked token through an outage of
+// the revocation store.
+//
+// Revoked runs on every request, so it must be fast; implementations must be
+// safe for concurrent use.
+type RevocationChecker interface {
+	Revoked(ctx context.Context, token RevocationCheck) (bool, error)
+}
+
+// RevocationStore is a RevocationChecker that can also record revocations,
+// such as a MemoryRevocationStore or RedisRevocationStore. SSFReceiver writes
+// to one.
+type RevocationStore interface {
+	RevocationChecker
+
+	// Revoke records r. Recording a revocation that already exists for the
+	// same kind, issuer and value keeps the broader of the two: the later
+	// Cutoff (a zero Cutoff being latest of all) and the later ExpiresAt.
+	Revoke(ctx context.Context, r Revocation) error
+}
+
+// checkRevocation consults the configured RevocationChecker for a token that
+// has passed every other check.
+func (v *Validator) checkRevocation(ctx context.Context, claims jwt.MapClaims, iss, sub string) error {
+	if v.cfg.RevocationChecker == nil {
+		return nil
+	}
+	revoked, err := v.cfg.RevocationChecker.Revoked(ctx, revocationCheck(claims, iss, sub))
+	if err != nil {
+		return &Error{Code: CodeUnavailable, Reason: ReasonRevocationUnavailable,
+			err: fmt.Errorf("revocation check failed: %w", err)}
+	}
+	if revoked {
+		return &Error{Code: CodeInvalidToken, Reason: ReasonRevoked,
+			err: errors.New("token has been revoked")}
+	}
+	return nil
+}
+
+// revocationKey identifies one revocation record.
+type revocationKey struct {
+	kind   RevocationKind
+	issuer string
+	value  string
+}
+
+// revocationEntry is the stored part of a Revocation.
+type revocationEntry struct {
+	cutoff    time.Time
+	expiresAt time.Time
+}
+
+// merge returns the broader of e and o (see RevocationStore.Revoke).
+func (e revocationEntry) merge(o revocationEntry) revocationEntry {
+	if e.cutoff.IsZero() || (!o.cutoff.IsZero() && e.cutoff.After(o.cutoff)) {
+		o.cutoff = e.cutoff
+	}
+	if e.expiresAt.IsZero() || (!o.expiresAt.IsZero() && e.expiresAt.After(o.expiresAt)) {
+		o.expiresAt = e.expiresAt
+	}
+	return o
+}
+
+// expired reports whether e may be forgotten at now.
+func (e revocationEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryRevocationStore is an in-process RevocationStore. It suits a single
+// replica, or tests; replicas do not share it, so a deployment with several
+// should use RedisRevocationStore. It is safe for concurrent use.
+//
+// Expired records are dropped as new ones are recorded, so memory is bounded
+// by the revocations still in force rather than by every one ever made.
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	entries map[revocationKey]revocationEntry
+	// nextSweep is when Revoke next walks entries for expired records. A
+	// sweep per write would make a burst of revocations quadratic.
+	nextSweep time.Time
+}
+
+// memorySweepInterval spaces MemoryRevocationStore's expiry sweeps.
+const memorySweepInterval = time.Minute
+
+// Compile-time check that MemoryRevocationStore is a RevocationStore.
+var _ RevocationStore = (*MemoryRevocationStore)(nil)
+
+// NewMemoryRevocationStore returns an empty MemoryRevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{entries: map[revocationKey]revocationEntry{}}
+}
+
+// Revoke records r.
+func (s *MemoryRevocationStore) Revoke(_ context.Context, r Revocation) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	key := revocationKey{kind: r.Kind, issuer: r.Issuer, value: r.Value}
+	entry := revocationEntry{cutoff: r.Cutoff, expiresAt: r.ExpiresAt}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if e.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(memorySweepInterval)
+	}
+	if have, ok := s.entries[key]; ok && !have.expired(now) {
+		entry = have.merge(entry)
+	}
+	if !entry.expired(now) {
+		s.entries[key] = entry
+	}
+	return nil
+}
+
+// Revoked reports whether any record matches token. It never fails.
+func (s *MemoryRevocationStore) Revoked(_ context.Context, token RevocationCheck) (bool, error) {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, kind := range []RevocationKind{RevokeToken, RevokeSession, RevokeSubject} {
+		value := token.value(kind)
+		if value == "" {
+			continue
+		}
+		e, ok := s.entries[revocationKey{kind: kind, issuer: token.Issuer, value: value}]
+		if ok && !e.expired(now) && revokedBy(e.cutoff, token.IssuedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
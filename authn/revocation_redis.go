@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// revokeScript records one revocation, merging it with any record already
+// held under the key (see RevocationStore.Revoke). It runs as a script so the
+// read-compare-write cannot interleave with a concurrent Revoke from another
+// replica.
+//
+// KEYS[1] is the record key; ARGV[1] the cutoff in Unix milliseconds, 0 for
+// none; ARGV[2] the time to live in milliseconds, 0 for none.
+var revokeScript = goredis.NewScript(`
+local cutoff = ARGV[1]
+local ttl = tonumber(ARGV[2])
+local have = redis.call('GET', KEYS[1])
+if have then
+  if have == '0' or (cutoff ~= '0' and tonumber(have) > tonumber(cutoff)) then
+    cutoff = have
+  end
+  local left = redis.call('PTTL', KEYS[1])
+  if left == -1 then
+    ttl = 0
+  elseif ttl ~= 0 and left > ttl then
+    ttl = left
+  end
+end
+if ttl == 0 then
+  redis.call('SET', KEYS[1], cutoff)
+else
+  redis.call('SET', KEYS[1], cutoff, 'PX', ttl)
+end
+return 1
+`)
+
+// RedisRevocationConfig configures a RedisRevocationStore.
+type RedisRevocationConfig struct {
+	// Client is the Redis connection, typically built by the redis package's
+	// NewClient so standalone, cluster and sentinel deployments all work.
+	// Required.
+	Client goredis.UniversalClient
+
+	// KeyPrefix namespaces the store's keys, e.g. "thv:authn:revoked:".
+	// Required, so the store never writes into a keyspace it does not own.
+	KeyPrefix string
+}
+
+// RedisRevocationStore is a RevocationStore shared through Redis, so a
+// revocation recorded by any replica — or by an SSFReceiver on one — is seen
+// by all of them. It is safe for concurrent use.
+//
+// Each record is one key holding its cutoff, set to expire at its ExpiresAt,
+// so Redis forgets revocations by itself once they no longer matter. A check
+// reads at most three keys in one pipelined round trip. A Redis error fails
+// Revoked, which Validate turns into CodeUnavailable: an outage of the store
+// rejects tokens rather than silently accepting revoked ones.
+type RedisRevocationStore struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// Compile-time check that RedisRevocationStore is a RevocationStore.
+var _ RevocationStore = (*RedisRevocationStore)(nil)
+
+// NewRedisRevocationStore validates cfg and returns a RedisRevocationStore.
+// It does not contact Redis.
+func NewRedisRevocationStore(cfg RedisRevocationConfig) (*RedisRevocationStore, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("authn: redis revocation store requires a client")
+	}
+	if cfg.KeyPrefix == "" {
+		return nil, errors.New("authn: redis revocation store requires a key prefix")
+	}
+	return &RedisRevocationStore{client: cfg.Client, prefix: cfg.KeyPrefix}, nil
+}
+
+// key returns the Redis key for one record. Issuer and value are hashed
+// rather than concatenated: they are arbitrary strings that could contain the
+// separator, and a hash keeps an attacker-influenced jti from making keys of
+// unbounded length.
+func (s *RedisRevocationStore) key(kind RevocationKind, issuer, value string) string {
+	sum := sha256.Sum256([]byte(issuer + "\x00" + value))
+	return s.prefix + string(kind) + ":" + base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Revoke records r.
+func (s *RedisRevocationStore) Revoke(ctx context.Context, r Revocation) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	var ttl int64
+	if !r.ExpiresAt.IsZero() {
+		ttl = time.Until(r.ExpiresAt).Milliseconds()
+		if ttl <= 0 {
+			// Every token it could match has expired; nothing to record.
+			return nil
+		}
+	}
+	var cutoff int64
+	if !r.Cutoff.IsZero() {
+		cutoff = r.Cutoff.UnixMilli()
+	}
+	err := revokeScript.Run(ctx, s.client, []string{s.key(r.Kind, r.Issuer, r.Value)},
+		strconv.FormatInt(cutoff, 10), strconv.FormatInt(ttl, 10)).Err()
+	if err != nil {
+		return fmt.Errorf("authn: failed to record revocation: %w", err)
+	}
+	return nil
+}
+
+// Revoked reports whether any record matches token.
+func (s *RedisRevocationStore) Revoked(ctx context.Context, token RevocationCheck) (bool, error) {
+	// GETs in a pipeline rather than one MGET: in cluster mode the keys hash
+	// to different slots, which MGET refuses and a pipeline handles.
+	var cmds []*goredis.StringCmd
+	_, err := s.client.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		for _, kind := range []RevocationKind{RevokeToken, RevokeSession, RevokeSubject} {
+			if value := token.value(kind); value != "" {
+				cmds = append(cmds, p.Get(ctx, s.key(kind, token.Issuer, value)))
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return false, fmt.Errorf("authn: revocation lookup failed: %w", err)
+	}
+	for _, cmd := range cmds {
+		raw, err := cmd.Result()
+		if errors.Is(err, goredis.Nil) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("authn: revocation lookup failed: %w", err)
+		}
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("authn: malformed revocation record: %w", err)
+		}
+		var cutoff time.Time
+		if ms != 0 {
+			cutoff = time.UnixMilli(ms)
+		}
+		if revokedBy(cutoff, token.IssuedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisRevocationStore, *miniredis.Miniredis) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	store, err := NewRedisRevocationStore(RedisRevocationConfig{Client: client, KeyPrefix: "test:revoked:"})
+	require.NoError(t, err)
+	return store, srv
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	t.Parallel()
+	revocationStoreContract(t, func(t *testing.T) RevocationStore {
+		store, _ := newTestRedisStore(t)
+		return store
+	})
+
+	t.Run("records expire with ExpiresAt", func(t *testing.T) {
+		t.Parallel()
+		store, srv := newTestRedisStore(t)
+		ctx := context.Background()
+		check := RevocationCheck{Issuer: "i", Subject: "s", JTI: "token-identifier"}
+
+		require.NoError(t, store.Revoke(ctx, Revocation{Kind: RevokeToken, Issuer: "i", Value: "token-identifier",
+			ExpiresAt: time.Now().Add(time.Minute)}))
+		keys := srv.Keys()
+		require.Len(t, keys, 1)
+		assert.Contains(t, keys[0], "test:revoked:jti:")
+		assert.NotContains(t, keys[0], "token-identifier", "issuer and value are hashed into the key")
+
+		srv.FastForward(2 * time.Minute)
+		revoked, err := store.Revoked(ctx, check)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("merge keeps the longer lifetime", func(t *testing.T) {
+		t.Parallel()
+		store, srv := newTestRedisStore(t)
+		ctx := context.Background()
+		r := Revocation{Kind: RevokeSubject, Issuer: "i", Value: "s", ExpiresAt: time.Now().Add(time.Hour)}
+		require.NoError(t, store.Revoke(ctx, r))
+		r.ExpiresAt = time.Now().Add(time.Minute)
+		require.NoError(t, store.Revoke(ctx, r))
+		assert.Greater(t, srv.TTL(srv.Keys()[0]), 30*time.Minute)
+
+		r.ExpiresAt = time.Time{}
+		require.NoError(t, store.Revoke(ctx, r))
+		assert.Zero(t, srv.TTL(srv.Keys()[0]), "a record without expiry is kept indefinitely")
+	})
+
+	t.Run("unreachable redis is an error", func(t *testing.T) {
+		t.Parallel()
+		store, srv := newTestRedisStore(t)
+		srv.Close()
+		_, err := store.Revoked(context.Background(), RevocationCheck{Issuer: "i", Subject: "s"})
+		assert.Error(t, err)
+		assert.Error(t, store.Revoke(context.Background(), Revocation{Kind: RevokeSubject, Issuer: "i", Value: "s"}))
+	})
+
+	t.Run("config", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewRedisRevocationStore(RedisRevocationConfig{KeyPrefix: "p:"})
+		assert.ErrorContains(t, err, "requires a client")
+		_, err = NewRedisRevocationStore(RedisRevocationConfig{Client: goredis.NewClient(&goredis.Options{})})
+		assert.ErrorContains(t, err, "requires a key prefix")
+	})
+}
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newProviderValidator returns a Validator that trusts only ep's key, with
+// mutate applied to its Config, so tests need no JWKS server.
+func newProviderValidator(t *testing.T, ep ecPair, mutate func(*Config)) *Validator {
+	t.Helper()
+	kid, _ := ep.jwk.KeyID()
+	cfg := validConfig()
+	cfg.KeyProvider = NewStaticKeyProvider(PublicKey{KeyID: kid, Key: &ep.priv.PublicKey})
+	cfg.KeyProviderOnly = true
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	v, err := NewValidator(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+	return v
+}
+
+// failingChecker is a RevocationChecker whose store is unreachable.
+type failingChecker struct{}
+
+func (failingChecker) Revoked(context.Context, RevocationCheck) (bool, error) {
+	return false, errors.New("store unreachable")
+}
+
+// revocationStoreContract runs the behavior every RevocationStore shares
+// against the store newStore returns.
+func revocationStoreContract(t *testing.T, newStore func(t *testing.T) RevocationStore) {
+	t.Helper()
+	const iss = "https://issuer.example.com"
+	now := time.Now().Truncate(time.Second)
+	token := RevocationCheck{Issuer: iss, Subject: "alice", JTI: "jti-1", SessionID: "sid-1", IssuedAt: now}
+
+	tests := []struct {
+		name   string
+		revoke []Revocation
+		check  RevocationCheck
+		want   bool
+	}{
+		{name: "nothing revoked", check: token},
+		{
+			name:   "by jti",
+			revoke: []Revocation{{Kind: RevokeToken, Issuer: iss, Value: "jti-1", ExpiresAt: now.Add(time.Hour)}},
+			check:  token,
+			want:   true,
+		},
+		{
+			name:   "by sid",
+			revoke: []Revocation{{Kind: RevokeSession, Issuer: iss, Value: "sid-1"}},
+			check:  token,
+			want:   true,
+		},
+		{
+			name:   "by sub",
+			revoke: []Revocation{{Kind: RevokeSubject, Issuer: iss, Value: "alice"}},
+			check:  token,
+			want:   true,
+		},
+		{
+			name:   "other issuer's subject",
+			revoke: []Revocation{{Kind: RevokeSubject, Issuer: "https://other.example.com", Value: "alice"}},
+			check:  token,
+		},
+		{
+			name:   "sid revocation does not match a jti of the same value",
+			revoke: []Revocation{{Kind: RevokeSession, Issuer: iss, Value: "jti-1"}},
+			check:  token,
+		},
+		{
+			name:   "token issued after the cutoff survives",
+			revoke: []Revocation{{Kind: RevokeSubject, Issuer: iss, Value: "alice", Cutoff: now.Add(-time.Minute)}},
+			check:  token,
+		},
+		{
+			name:   "token issued at the cutoff is revoked",
+			revoke: []Revocation{{Kind: RevokeSubject, Issuer: iss, Value: "alice", Cutoff: now}},
+			check:  token,
+			want:   true,
+		},
+		{
+			name:   "token without iat is revoked by any cutoff",
+			revoke: []Revocation{{Kind: RevokeSubject, Issuer: iss, Value: "alice", Cutoff: now.Add(-time.Minute)}},
+			check:  RevocationCheck{Issuer: iss, Subject: "alice"},
+			want:   true,
+		},
+		{
+			name: "later cutoff wins",
+			revoke: []Revocation{
+				{Kind: RevokeSubject, Issuer: iss, Value: "alice", Cutoff: now.Add(time.Minute)},
+				{Kind: RevokeSubject, Issuer: iss, Value: "alice", Cutoff: now.Add(-time.Minute)},
+			},
+			check: token,
+			want:  true,
+		},
+		{
+			name: "zero cutoff wins",
+			revoke: []Revocation{
+				{Kind: RevokeSubject, Issuer: iss, Value: "alice", Cutoff: now.Add(-time.Minute)},
+				{Kind: RevokeSubject, Issuer: iss, Value: "alice"},
+			},
+			check: token,
+			want:  true,
+		},
+		{
+			name:   "already expired revocation is not recorded",
+			revoke: []Revocation{{Kind: RevokeToken, Issuer: iss, Value: "jti-1", ExpiresAt: now.Add(-time.Second)}},
+			check:  token,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			store := newStore(t)
+			for _, r := range tt.revoke {
+				require.NoError(t, store.Revoke(context.Background(), r))
+			}
+			got, err := store.Revoked(context.Background(), tt.check)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("invalid revocations", func(t *testing.T) {
+		t.Parallel()
+		store := newStore(t)
+		for _, r := range []Revocation{
+			{Kind: "email", Issuer: iss, Value: "x"},
+			{Kind: RevokeSubject, Value: "alice"},
+			{Kind: RevokeSubject, Issuer: iss},
+		} {
+			assert.Error(t, store.Revoke(context.Background(), r), "%+v", r)
+		}
+	})
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	t.Parallel()
+	revocationStoreContract(t, func(*testing.T) RevocationStore { return NewMemoryRevocationStore() })
+
+	t.Run("expired records are swept", func(t *testing.T) {
+		t.Parallel()
+		s := NewMemoryRevocationStore()
+		ctx := context.Background()
+		require.NoError(t, s.Revoke(ctx, Revocation{Kind: RevokeToken, Issuer: "i", Value: "old",
+			ExpiresAt: time.Now().Add(20 * time.Millisecond)}))
+		time.Sleep(30 * time.Millisecond)
+
+		revoked, err := s.Revoked(ctx, RevocationCheck{Issuer: "i", Subject: "s", JTI: "old"})
+		require.NoError(t, err)
+		assert.False(t, revoked, "an expired record no longer matches")
+
+		s.nextSweep = time.Time{}
+		require.NoError(t, s.Revoke(ctx, Revocation{Kind: RevokeToken, Issuer: "i", Value: "new"}))
+		assert.Len(t, s.entries, 1, "the sweep dropped the expired record")
+	})
+}
+
+func TestValidateRevocation(t *testing.T) {
+	t.Parallel()
+
+	ep := mintEC(t, "k1")
+	issuer := validConfig().Issuer
+	store := NewMemoryRevocationStore()
+	v := newProviderValidator(t, ep, func(cfg *Config) { cfg.RevocationChecker = store })
+	ctx := context.Background()
+
+	_, err := v.Validate(ctx, ep.mint(t, issuer, withClaim("jti", "t1")))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Revoke(ctx, Revocation{Kind: RevokeToken, Issuer: issuer, Value: "t1"}))
+	_, err = v.Validate(ctx, ep.mint(t, issuer, withClaim("jti", "t1")))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonRevoked)
+	_, err = v.Validate(ctx, ep.mint(t, issuer, withClaim("jti", "t2")))
+	require.NoError(t, err, "other tokens of the subject are unaffected")
+
+	require.NoError(t, store.Revoke(ctx, Revocation{Kind: RevokeSession, Issuer: issuer, Value: "s1"}))
+	_, err = v.Validate(ctx, ep.mint(t, issuer, withClaim("sid", "s1")))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonRevoked)
+
+	require.NoError(t, store.Revoke(ctx, Revocation{Kind: RevokeSubject, Issuer: issuer, Value: testSubject,
+		Cutoff: time.Now()}))
+	_, err = v.Validate(ctx, ep.mint(t, issuer))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonRevoked)
+	_, err = v.Validate(ctx, ep.mint(t, issuer, withClaim(claimIat, time.Now().Add(2*time.Second).Unix())))
+	require.NoError(t, err, "a token issued after the cutoff is accepted")
+
+	t.Run("checker failure fails closed", func(t *testing.T) {
+		t.Parallel()
+		v := newProviderValidator(t, ep, func(cfg *Config) { cfg.RevocationChecker = failingChecker{} })
+		_, err := v.Validate(context.Background(), ep.mint(t, issuer))
+		requireAuthnError(t, err, CodeUnavailable, ReasonRevocationUnavailable)
+	})
+
+	t.Run("checker not consulted for a rejected token", func(t *testing.T) {
+		t.Parallel()
+		v := newProviderValidator(t, ep, func(cfg *Config) { cfg.RevocationChecker = failingChecker{} })
+		_, err := v.Validate(context.Background(), ep.mint(t, issuer, withClaim(claimAud, "https://other.example.com")))
+		requireAuthnError(t, err, CodeInvalidToken, ReasonAudience)
+	})
+}
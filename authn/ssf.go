@@ -0,0 +1,319 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// SSFEventSessionRevoked is the CAEP session-revoked event type: the
+	// session identified by the subject has ended.
+	SSFEventSessionRevoked = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+	// SSFEventCredentialChange is the CAEP credential-change event type: a
+	// credential of the subject was created, changed, revoked or deleted.
+	SSFEventCredentialChange = "https://schemas.openid.net/secevent/caep/event-type/credential-change"
+
+	// setTokenType is the typ header of a Security Event Token (RFC 8417 §2.3).
+	setTokenType = "secevent+jwt"
+	// setContentType is the push-delivery request media type (RFC 8935 §2).
+	setContentType = "application/secevent+jwt"
+	// defaultSSFRetention is SSFReceiverConfig.Retention when zero.
+	defaultSSFRetention = 24 * time.Hour
+)
+
+// SSF push-delivery error codes (RFC 8935 §2.4), sent in the err member of a
+// 400 response so the transmitter knows whether resending can help.
+const (
+	ssfErrInvalidRequest       = "invalid_request"
+	ssfErrInvalidKey           = "invalid_key"
+	ssfErrInvalidIssuer        = "invalid_issuer"
+	ssfErrInvalidAudience      = "invalid_audience"
+	ssfErrAuthenticationFailed = "authentication_failed"
+)
+
+// SSFReceiverConfig configures an SSFReceiver.
+type SSFReceiverConfig struct {
+	// Validator supplies how SETs are verified: its Issuer is the
+	// transmitter's, and its key material and AllowedAlgs verify the SET
+	// signature. The transmitter is commonly the IdP itself, so the Validator
+	// that checks access tokens is usually the right one to pass. It must have
+	// an Issuer. Required.
+	Validator *Validator
+
+	// Audiences are the aud values the stream was configured with; a SET must
+	// carry one of them. Required: a SET addressed to another receiver of the
+	// same transmitter must not revoke anything here.
+	Audiences []string
+
+	// Store records the revocations events produce. Pass the same store as
+	// Config.RevocationChecker so validation sees them. Required.
+	Store RevocationStore
+
+	// Retention is how long a session or subject revocation is kept after its
+	// cutoff. It must be at least the longest lifetime the issuer gives its
+	// tokens, or a token issued just before the event outlives the record
+	// revoking it. Zero uses 24 hours; negative is an error.
+	Retention time.Duration
+
+	// Logger receives events that were accepted but could not be acted on —
+	// a subject format this receiver does not understand, say. Nil uses
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// SSFReceiver is an http.Handler accepting OpenID Shared Signals Framework
+// events by push delivery (RFC 8935) and turning them into revocations:
+//
+//   - CAEP session-revoked revokes the session by its sid when the subject
+//     names one, and otherwise every token of the user.
+//   - CAEP credential-change revokes every token of the user.
+//
+// Either way the revocation applies only to tokens issued at or before the
+// event's event_timestamp (the SET's iat when absent), so the user can sign in
+// again. Subjects are read from the SET's sub_id, or the event's subject
+// member for transmitters following earlier drafts, in the iss_sub or complex
+// (user plus session) formats of RFC 9493; a session is an opaque id.
+//
+// Each request body is one SET, verified like an access token against the
+// configured Validator except that it must carry typ secevent+jwt, a jti and
+// an events claim, and need not carry exp. An accepted SET gets 202; a
+// rejected one a 400 with an RFC 8935 error code; a store failure a 503, so
+// the transmitter retries. Other event types are accepted and ignored.
+type SSFReceiver struct {
+	cfg SSFReceiverConfig
+}
+
+// NewSSFReceiver validates cfg and returns an SSFReceiver. Errors are
+// ordinary construction errors, not *Error.
+func NewSSFReceiver(cfg SSFReceiverConfig) (*SSFReceiver, error) {
+	switch {
+	case cfg.Validator == nil:
+		return nil, errors.New("authn: ssf receiver requires a validator")
+	case cfg.Validator.cfg.Issuer == "":
+		return nil, errors.New("authn: ssf receiver requires a validator with an issuer")
+	case len(cfg.Audiences) == 0:
+		return nil, errors.New("authn: ssf receiver requires at least one audience")
+	case cfg.Store == nil:
+		return nil, errors.New("authn: ssf receiver requires a revocation store")
+	case cfg.Retention < 0:
+		return nil, fmt.Errorf("authn: ssf retention must not be negative: %s", cfg.Retention)
+	}
+	if cfg.Retention == 0 {
+		cfg.Retention = defaultSSFRetention
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &SSFReceiver{cfg: cfg}, nil
+}
+
+// ServeHTTP implements RFC 8935 push delivery.
+func (s *SSFReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != setContentType {
+		writeSSFError(w, ssfErrInvalidRequest, "content type must be "+setContentType)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxTokenLength+1))
+	if err != nil {
+		writeSSFError(w, ssfErrInvalidRequest, "could not read request body")
+		return
+	}
+	if len(body) > maxTokenLength {
+		writeSSFError(w, ssfErrInvalidRequest, "security event token is too large")
+		return
+	}
+
+	err = s.Receive(r.Context(), string(body))
+	var authnErr *Error
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errors.As(err, &authnErr) && authnErr.Code != CodeUnavailable:
+		s.cfg.Logger.DebugContext(r.Context(), "rejected security event token", "error", err)
+		writeSSFError(w, ssfErrorCode(authnErr.Reason), authnErr.Reason.description())
+	default:
+		s.cfg.Logger.WarnContext(r.Context(), "failed to process security event token", "error", err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+}
+
+// Receive verifies one SET and records the revocations its events call for.
+// It is what ServeHTTP runs per request, exposed for a transmitter reached by
+// poll delivery (RFC 8936) instead.
+//
+// A SET that fails verification is an *Error with CodeInvalidToken; one that
+// cannot be verified or recorded for now (keys or store unreachable) is an
+// *Error with CodeUnavailable or a store error, and is worth retrying.
+func (s *SSFReceiver) Receive(ctx context.Context, set string) error {
+	claims, err := s.verify(ctx, set)
+	if err != nil {
+		return err
+	}
+	events, _ := claims["events"].(map[string]any)
+	iat, _ := claims.GetIssuedAt()
+	setIssuer := stringClaim(claims, "iss")
+	topSubject, _ := claims["sub_id"].(map[string]any)
+
+	for eventType, raw := range events {
+		if eventType != SSFEventSessionRevoked && eventType != SSFEventCredentialChange {
+			continue
+		}
+		payload, _ := raw.(map[string]any)
+		subject := topSubject
+		if subject == nil {
+			subject, _ = payload["subject"].(map[string]any)
+		}
+		cutoff := iat.Time
+		if ts, ok := payload["event_timestamp"].(float64); ok && ts > 0 {
+			cutoff = time.Unix(int64(ts), 0)
+		}
+
+		rev, ok := ssfRevocation(eventType, subject, setIssuer)
+		if !ok {
+			s.cfg.Logger.WarnContext(ctx, "security event names no subject this receiver can revoke",
+				"event_type", eventType, "jti", stringClaim(claims, "jti"))
+			continue
+		}
+		rev.Cutoff = cutoff
+		rev.ExpiresAt = cutoff.Add(s.cfg.Retention)
+		if err := s.cfg.Store.Revoke(ctx, rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify checks set's signature and the claims RFC 8417 requires, returning
+// the verified claims.
+func (s *SSFReceiver) verify(ctx context.Context, set string) (jwt.MapClaims, error) {
+	v := s.cfg.Validator
+	if v.closed() {
+		return nil, &Error{Code: CodeUnavailable, Reason: ReasonKeysUnavailable,
+			err: errors.New("validator is closed")}
+	}
+	if len(set) > maxTokenLength {
+		return nil, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed,
+			err: fmt.Errorf("SET length %d exceeds %d byte limit", len(set), maxTokenLength)}
+	}
+
+	leeway := v.cfg.Leeway
+	if v.cfg.DisableLeeway {
+		leeway = 0
+	}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(v.cfg.AllowedAlgs),
+		jwt.WithStrictDecoding(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(leeway),
+		jwt.WithIssuer(v.cfg.Issuer),
+		jwt.WithAudience(s.cfg.Audiences...),
+	)
+	claims := jwt.MapClaims{}
+	var kidSeen bool
+	_, err := parser.ParseWithClaims(set, claims, func(tok *jwt.Token) (any, error) {
+		if _, hasCrit := tok.Header["crit"]; hasCrit {
+			return nil, &Error{Code: CodeInvalidToken, Reason: ReasonCriticalHeader,
+				err: errors.New("SET header carries unsupported crit member")}
+		}
+		// Requiring the SET typ is what stops an access token from the same
+		// issuer being replayed here as an event.
+		if typ, _ := tok.Header["typ"].(string); normalizeTokenType(typ) != setTokenType {
+			return nil, &Error{Code: CodeInvalidToken, Reason: ReasonTokenType,
+				err: fmt.Errorf("SET typ %q is not %s", typ, setTokenType)}
+		}
+		kid, _ := tok.Header["kid"].(string)
+		kidSeen = kid != ""
+		return v.verificationKeys(ctx, kid, tok.Method.Alg())
+	})
+	if err != nil {
+		return nil, mapParseError(err, kidSeen)
+	}
+
+	if iat, err := claims.GetIssuedAt(); err != nil || iat == nil {
+		return nil, &Error{Code: CodeInvalidToken, Reason: ReasonMissingClaim,
+			err: errors.New("SET has no iat claim")}
+	}
+	if stringClaim(claims, "jti") == "" {
+		return nil, &Error{Code: CodeInvalidToken, Reason: ReasonMissingClaim,
+			err: errors.New("SET has no jti claim")}
+	}
+	if _, ok := claims["events"].(map[string]any); !ok {
+		return nil, &Error{Code: CodeInvalidToken, Reason: ReasonMissingClaim,
+			err: errors.New("SET has no events object")}
+	}
+	return claims, nil
+}
+
+// ssfRevocation maps an event and its subject identifier (RFC 9493) to the
+// revocation it calls for, without Cutoff or ExpiresAt. setIssuer scopes a
+// session id when the subject does not name the user's issuer.
+func ssfRevocation(eventType string, subject map[string]any, setIssuer string) (Revocation, bool) {
+	var userIss, userSub, sid string
+	switch subject["format"] {
+	case "iss_sub":
+		userIss, _ = subject["iss"].(string)
+		userSub, _ = subject["sub"].(string)
+	case "complex":
+		if user, ok := subject["user"].(map[string]any); ok && user["format"] == "iss_sub" {
+			userIss, _ = user["iss"].(string)
+			userSub, _ = user["sub"].(string)
+		}
+		if session, ok := subject["session"].(map[string]any); ok && session["format"] == "opaque" {
+			sid, _ = session["id"].(string)
+		}
+	}
+
+	if eventType == SSFEventSessionRevoked && sid != "" {
+		issuer := userIss
+		if issuer == "" {
+			issuer = setIssuer
+		}
+		return Revocation{Kind: RevokeSession, Issuer: issuer, Value: sid}, true
+	}
+	// A session-revoked event without a session id, and every
+	// credential-change, revoke the user's tokens as a whole.
+	if userIss == "" || userSub == "" {
+		return Revocation{}, false
+	}
+	return Revocation{Kind: RevokeSubject, Issuer: userIss, Value: userSub}, true
+}
+
+// ssfErrorCode maps a SET verification failure to an RFC 8935 §2.4 code.
+func ssfErrorCode(reason Reason) string {
+	switch reason {
+	case ReasonUnknownKID:
+		return ssfErrInvalidKey
+	case ReasonSignature:
+		return ssfErrAuthenticationFailed
+	case ReasonIssuer:
+		return ssfErrInvalidIssuer
+	case ReasonAudience:
+		return ssfErrInvalidAudience
+	default:
+		return ssfErrInvalidRequest
+	}
+}
+
+// writeSSFError writes an RFC 8935 §2.3 error response.
+func writeSSFError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"err": code, "description": description})
+}
@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSSFAudience = "https://receiver.example.com"
+
+// failingStore is a RevocationStore whose backend is unreachable.
+type failingStore struct{ failingChecker }
+
+func (failingStore) Revoke(context.Context, Revocation) error {
+	return errors.New("store unreachable")
+}
+
+// recordingStore is a RevocationStore that keeps what it was asked to record.
+type recordingStore struct {
+	*MemoryRevocationStore
+	got []Revocation
+}
+
+func (s *recordingStore) Revoke(ctx context.Context, r Revocation) error {
+	s.got = append(s.got, r)
+	return s.MemoryRevocationStore.Revoke(ctx, r)
+}
+
+// setClaims returns the claims of a SET from issuer carrying events.
+func setClaims(issuer string, events map[string]any) jwt.MapClaims {
+	return jwt.MapClaims{
+		claimIss: issuer,
+		claimAud: testSSFAudience,
+		claimIat: time.Now().Unix(),
+		"jti":    "set-1",
+		"events": events,
+	}
+}
+
+// mintSET signs claims as a SET with ep, applying opts afterwards.
+func (ep ecPair) mintSET(t *testing.T, claims jwt.MapClaims, opts ...mintOption) string {
+	t.Helper()
+	kid, _ := ep.jwk.KeyID()
+	spec := mintSpec{
+		method:      jwt.SigningMethodES256,
+		key:         ep.priv,
+		kid:         kid,
+		claims:      claims,
+		extraHeader: map[string]any{"typ": setTokenType},
+	}
+	for _, o := range opts {
+		o(&spec)
+	}
+	return sign(t, spec)
+}
+
+// postSET delivers set to h as RFC 8935 push delivery.
+func postSET(h http.Handler, set string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/ssf", strings.NewReader(set))
+	req.Header.Set("Content-Type", setContentType)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestSSFReceiver(t *testing.T, v *Validator, store RevocationStore) *SSFReceiver {
+	t.Helper()
+	r, err := NewSSFReceiver(SSFReceiverConfig{Validator: v, Audiences: []string{testSSFAudience}, Store: store})
+	require.NoError(t, err)
+	return r
+}
+
+func TestNewSSFReceiverConfig(t *testing.T) {
+	t.Parallel()
+
+	v := newProviderValidator(t, mintEC(t, "k1"), nil)
+	store := NewMemoryRevocationStore()
+	tests := []struct {
+		name    string
+		cfg     SSFReceiverConfig
+		wantErr string
+	}{
+		{name: "valid", cfg: SSFReceiverConfig{Validator: v, Audiences: []string{testSSFAudience}, Store: store}},
+		{name: "no validator", cfg: SSFReceiverConfig{Audiences: []string{testSSFAudience}, Store: store}, wantErr: "requires a validator"},
+		{name: "no audience", cfg: SSFReceiverConfig{Validator: v, Store: store}, wantErr: "at least one audience"},
+		{name: "no store", cfg: SSFReceiverConfig{Validator: v, Audiences: []string{testSSFAudience}}, wantErr: "revocation store"},
+		{
+			name:    "negative retention",
+			cfg:     SSFReceiverConfig{Validator: v, Audiences: []string{testSSFAudience}, Store: store, Retention: -time.Hour},
+			wantErr: "must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r, err := NewSSFReceiver(tt.cfg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, r)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSSFReceiverEvents(t *testing.T) {
+	t.Parallel()
+
+	ep := mintEC(t, "k1")
+	issuer := validConfig().Issuer
+	eventTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	user := map[string]any{"format": "iss_sub", "iss": issuer, "sub": testSubject}
+
+	tests := []struct {
+		name   string
+		subID  map[string]any
+		claims jwt.MapClaims
+		want   []Revocation
+	}{
+		{
+			name: "session revoked by sid",
+			subID: map[string]any{
+				"format":  "complex",
+				"user":    user,
+				"session": map[string]any{"format": "opaque", "id": "sid-1"},
+			},
+			claims: setClaims(issuer, map[string]any{SSFEventSessionRevoked: map[string]any{
+				"event_timestamp": eventTime.Unix(),
+			}}),
+			want: []Revocation{{Kind: RevokeSession, Issuer: issuer, Value: "sid-1", Cutoff: eventTime}},
+		},
+		{
+			name:  "credential change",
+			subID: user,
+			claims: setClaims(issuer, map[string]any{SSFEventCredentialChange: map[string]any{
+				"event_timestamp": eventTime.Unix(), "change_type": "update", "credential_type": "password",
+			}}),
+			want: []Revocation{{Kind: RevokeSubject, Issuer: issuer, Value: testSubject, Cutoff: eventTime}},
+		},
+		{
+			name: "legacy subject member",
+			claims: setClaims(issuer, map[string]any{SSFEventCredentialChange: map[string]any{
+				"event_timestamp": eventTime.Unix(), "subject": user,
+			}}),
+			want: []Revocation{{Kind: RevokeSubject, Issuer: issuer, Value: testSubject, Cutoff: eventTime}},
+		},
+		{
+			name:  "unknown event type is ignored",
+			subID: user,
+			claims: setClaims(issuer, map[string]any{
+				"https://schemas.openid.net/secevent/caep/event-type/token-claims-change": map[string]any{},
+			}),
+		},
+		{
+			name: "unsupported subject format is ignored",
+			claims: setClaims(issuer, map[string]any{SSFEventCredentialChange: map[string]any{
+				"subject": map[string]any{"format": "email", "email": "alice@example.com"},
+			}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			store := &recordingStore{MemoryRevocationStore: NewMemoryRevocationStore()}
+			v := newProviderValidator(t, ep, func(cfg *Config) { cfg.RevocationChecker = store })
+			r := newTestSSFReceiver(t, v, store)
+			if tt.subID != nil {
+				tt.claims["sub_id"] = tt.subID
+			}
+
+			rec := postSET(r, ep.mintSET(t, tt.claims))
+			require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+			for i := range tt.want {
+				tt.want[i].ExpiresAt = tt.want[i].Cutoff.Add(defaultSSFRetention)
+			}
+			assert.Equal(t, tt.want, store.got)
+		})
+	}
+
+	t.Run("revocation reaches validation", func(t *testing.T) {
+		t.Parallel()
+		store := NewMemoryRevocationStore()
+		v := newProviderValidator(t, ep, func(cfg *Config) { cfg.RevocationChecker = store })
+		r := newTestSSFReceiver(t, v, store)
+
+		before := ep.mint(t, issuer, withClaim(claimIat, eventTime.Add(-time.Minute).Unix()))
+		after := ep.mint(t, issuer, withClaim(claimIat, eventTime.Add(time.Second).Unix()))
+		claims := setClaims(issuer, map[string]any{SSFEventCredentialChange: map[string]any{
+			"event_timestamp": eventTime.Unix(),
+		}})
+		claims["sub_id"] = user
+		require.NoError(t, r.Receive(context.Background(), ep.mintSET(t, claims)))
+
+		_, err := v.Validate(context.Background(), before)
+		requireAuthnError(t, err, CodeInvalidToken, ReasonRevoked)
+		_, err = v.Validate(context.Background(), after)
+		require.NoError(t, err, "a token issued after the credential change is accepted")
+	})
+}
+
+func TestSSFReceiverRejection(t *testing.T) {
+	t.Parallel()
+
+	ep := mintEC(t, "k1")
+	other := mintEC(t, "k1")
+	issuer := validConfig().Issuer
+	v := newProviderValidator(t, ep, nil)
+	r := newTestSSFReceiver(t, v, NewMemoryRevocationStore())
+	events := map[string]any{SSFEventCredentialChange: map[string]any{}}
+
+	tests := []struct {
+		name    string
+		set     string
+		wantErr string
+	}{
+		{name: "access token", set: ep.mint(t, issuer), wantErr: ssfErrInvalidRequest},
+		{name: "wrong typ", set: ep.mintSET(t, setClaims(issuer, events), withHeader("typ", "at+jwt")), wantErr: ssfErrInvalidRequest},
+		{name: "missing jti", set: ep.mintSET(t, setClaims(issuer, events), withoutClaim("jti")), wantErr: ssfErrInvalidRequest},
+		{name: "missing iat", set: ep.mintSET(t, setClaims(issuer, events), withoutClaim(claimIat)), wantErr: ssfErrInvalidRequest},
+		{name: "missing events", set: ep.mintSET(t, setClaims(issuer, events), withoutClaim("events")), wantErr: ssfErrInvalidRequest},
+		{name: "wrong issuer", set: ep.mintSET(t, setClaims("https://evil.example.com", events)), wantErr: ssfErrInvalidIssuer},
+		{
+			name:    "wrong audience",
+			set:     ep.mintSET(t, setClaims(issuer, events), withClaim(claimAud, testAPIAud)),
+			wantErr: ssfErrInvalidAudience,
+		},
+		{name: "unknown key", set: ep.mintSET(t, setClaims(issuer, events), withKid("k2")), wantErr: ssfErrInvalidKey},
+		{name: "bad signature", set: other.mintSET(t, setClaims(issuer, events)), wantErr: ssfErrAuthenticationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rec := postSET(r, tt.set)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantErr, body["err"])
+			assert.NotEmpty(t, body["description"])
+		})
+	}
+
+	t.Run("wrong content type", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest(http.MethodPost, "/ssf", strings.NewReader(ep.mintSET(t, setClaims(issuer, events))))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ssf", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("store failure is retryable", func(t *testing.T) {
+		t.Parallel()
+		r := newTestSSFReceiver(t, v, failingStore{})
+		claims := setClaims(issuer, events)
+		claims["sub_id"] = map[string]any{"format": "iss_sub", "iss": issuer, "sub": testSubject}
+		rec := postSET(r, ep.mintSET(t, claims))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
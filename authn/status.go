@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/stacklok/toolhive-core/telemetry/metrics"
+)
+
+// Metric names recorded on Config.Meter. They follow the stacklok.<service>
+// naming of the telemetry/metrics vocabulary, with outcome carried on a label
+// rather than split across _succeeded/_failed names.
+const (
+	// MetricJWKSRefreshes counts JWKS fetches this package initiates, labelled
+	// with metrics.LabelOutcome (success or error).
+	MetricJWKSRefreshes = "stacklok.authn.jwks.refreshes"
+
+	// MetricValidations counts Validate calls, labelled with
+	// metrics.LabelOutcome and, on failure, metrics.LabelErrorType carrying
+	// the Reason.
+	MetricValidations = "stacklok.authn.validations"
+)
+
+// Key sources reported in KeyStatus.Source.
+const (
+	// KeySourceJWKS marks a key served from the cached JWKS.
+	KeySourceJWKS = "jwks"
+	// KeySourceProvider marks a key offered by Config.KeyProvider.
+	KeySourceProvider = "provider"
+)
+
+// errorTypeInternal labels a validation failure that did not surface as an
+// *Error. Validate does not return one today; the label keeps the series
+// bounded if that ever changes.
+const errorTypeInternal = "internal"
+
+// validatorMetrics holds the instruments a Validator records into. The zero
+// value is not usable; newValidatorMetrics always returns working instruments,
+// backed by a no-op meter when none is configured, so the recording sites need
+// no nil checks.
+type validatorMetrics struct {
+	refreshes   metric.Int64Counter
+	validations metric.Int64Counter
+}
+
+// newValidatorMetrics creates the validator's instruments on meter, or on a
+// no-op meter when meter is nil.
+func newValidatorMetrics(meter metric.Meter) (validatorMetrics, error) {
+	if meter == nil {
+		meter = noop.NewMeterProvider().Meter("")
+	}
+	refreshes, err := meter.Int64Counter(MetricJWKSRefreshes,
+		metric.WithUnit("{refresh}"),
+		metric.WithDescription("JWKS fetches initiated by the token validator, by outcome."))
+	if err != nil {
+		return validatorMetrics{}, fmt.Errorf("authn: failed to create %s counter: %w", MetricJWKSRefreshes, err)
+	}
+	validations, err := meter.Int64Counter(MetricValidations,
+		metric.WithUnit("{token}"),
+		metric.WithDescription("Bearer token validations, by outcome and failure reason."))
+	if err != nil {
+		return validatorMetrics{}, fmt.Errorf("authn: failed to create %s counter: %w", MetricValidations, err)
+	}
+	return validatorMetrics{refreshes: refreshes, validations: validations}, nil
+}
+
+// recordRefresh counts one JWKS fetch. The error itself is not labelled: its
+// text carries URLs and transport detail, which would make the label
+// unbounded, and the log line at the call site already has it.
+func (m validatorMetrics) recordRefresh(ctx context.Context, err error) {
+	outcome := metrics.OutcomeSuccess
+	if err != nil {
+		outcome = metrics.OutcomeError
+	}
+	m.refreshes.Add(ctx, 1, metric.WithAttributes(attribute.String(metrics.LabelOutcome, outcome)))
+}
+
+// recordValidation counts one Validate call.
+//
+// A token the validator refused is "rejected"; a failure to reach key material
+// (CodeUnavailable) is "error", since the token was never judged. Keeping the
+// two apart is what lets an alert fire on an unreachable issuer without also
+// firing on a client sending expired tokens. The Reason rides error_type: it is
+// drawn from a fixed set, so the series stay bounded.
+func (m validatorMetrics) recordValidation(ctx context.Context, err error) {
+	if err == nil {
+		m.validations.Add(ctx, 1, metric.WithAttributes(
+			attribute.String(metrics.LabelOutcome, metrics.OutcomeSuccess)))
+		return
+	}
+	outcome, errorType := metrics.OutcomeError, errorTypeInternal
+	var authnErr *Error
+	if errors.As(err, &authnErr) {
+		errorType = string(authnErr.Reason)
+		if authnErr.Code != CodeUnavailable {
+			outcome = metrics.OutcomeRejected
+		}
+	}
+	m.validations.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(metrics.LabelOutcome, outcome),
+		attribute.String(metrics.LabelErrorType, errorType),
+	))
+}
+
+// Status is a point-in-time snapshot of a Validator's key material, for health
+// endpoints and debugging. It carries no secrets: key ids, algorithms and the
+// JWKS URL are all public.
+type Status struct {
+	// JWKSURL is the JWKS endpoint in use: Config.JWKSURL, or the jwks_uri
+	// discovered from the issuer. It is empty when discovery failed at
+	// construction and a KeyProvider let the validator start anyway.
+	JWKSURL string
+
+	// LastRefreshAttempt is when a JWKS fetch this package initiated was last
+	// attempted, and LastSuccessfulRefresh when one last succeeded; both are
+	// zero if none has been. "Initiated by this package" means the construction
+	// fetch, unknown-kid recovery and the MaxJWKSStaleness refresh: the jwx
+	// cache's own background refreshes are invisible to this package (httprc
+	// exposes no hook for them), so a healthy validator that has needed neither
+	// recovery path reports its construction time here.
+	LastRefreshAttempt    time.Time
+	LastSuccessfulRefresh time.Time
+
+	// Staleness is the time since LastSuccessfulRefresh. It is meaningful only
+	// when LastSuccessfulRefresh is non-zero, and is subject to the same
+	// background-refresh blind spot.
+	Staleness time.Duration
+
+	// Stale reports whether Staleness exceeds Config.MaxJWKSStaleness, i.e.
+	// whether the next JWKS-keyed validation will first have to refresh. It
+	// is always false when the bound is disabled.
+	Stale bool
+
+	// Keys lists the verification keys currently available, JWKS keys first,
+	// then KeyProvider keys, each sorted by key id.
+	Keys []KeyStatus
+
+	// KeyProviderError is the error the KeyProvider returned while building
+	// this snapshot, if any. It is server-side detail, like Error.Error.
+	KeyProviderError error
+
+	// KnownBadKIDs is the number of key ids currently negative-cached: seen on
+	// a token, absent from the JWKS even after a refresh, and so rejected
+	// without another fetch until their entry expires. A steady climb usually
+	// means a client is presenting tokens from the wrong issuer — or someone is
+	// spraying kids.
+	KnownBadKIDs int
+}
+
+// KeyStatus describes one verification key in a Status.
+type KeyStatus struct {
+	// KeyID is the key's kid; it may be empty.
+	KeyID string
+	// Algorithm is the key's declared alg, empty when the key does not
+	// restrict itself to one.
+	Algorithm string
+	// KeyType is the JWK kty: "RSA", "EC" or "OKP".
+	KeyType string
+	// Source is KeySourceJWKS or KeySourceProvider.
+	Source string
+}
+
+// Status returns a snapshot of the validator's key material.
+//
+// It performs no network I/O: JWKS keys are read from the cache as it stands,
+// and a Status taken before the first fetch lands simply lists none. It does
+// call Config.KeyProvider, which is expected to be cheap (see KeyProvider), and
+// ctx bounds that call. A closed validator reports its timestamps and counts
+// but no JWKS keys.
+func (v *Validator) Status(ctx context.Context) Status {
+	v.refreshMu.Lock()
+	st := Status{
+		JWKSURL:               v.jwksURL,
+		LastRefreshAttempt:    v.lastAttempt,
+		LastSuccessfulRefresh: v.lastSuccess,
+	}
+	v.refreshMu.Unlock()
+
+	if !st.LastSuccessfulRefresh.IsZero() {
+		st.Staleness = time.Since(st.LastSuccessfulRefresh)
+	}
+	st.Stale = v.cfg.MaxJWKSStaleness > 0 && st.Staleness > v.cfg.MaxJWKSStaleness
+	st.KnownBadKIDs = v.knownBadKIDCount()
+
+	lifetimeCtx, cancel := v.withLifetime(ctx)
+	defer cancel()
+	if v.jwksCache != nil && !v.closed() {
+		st.Keys = append(st.Keys, v.jwksKeyStatus(lifetimeCtx)...)
+	}
+	if v.cfg.KeyProvider != nil {
+		keys, err := providerKeyStatus(lifetimeCtx, v.cfg.KeyProvider)
+		st.Keys = append(st.Keys, keys...)
+		st.KeyProviderError = err
+	}
+	return st
+}
+
+// jwksKeyStatus lists the keys in the cached JWKS, or none if the cache has
+// not been populated yet.
+func (v *Validator) jwksKeyStatus(ctx context.Context) []KeyStatus {
+	set, err := v.jwksCache.Lookup(ctx, v.jwksURL)
+	if err != nil {
+		return nil
+	}
+	out := make([]KeyStatus, 0, set.Len())
+	for i := range set.Len() {
+		key, ok := set.Key(i)
+		if !ok {
+			continue
+		}
+		ks := KeyStatus{KeyType: key.KeyType().String(), Source: KeySourceJWKS}
+		ks.KeyID, _ = key.KeyID()
+		if alg, ok := key.Algorithm(); ok {
+			ks.Algorithm = alg.String()
+		}
+		out = append(out, ks)
+	}
+	sortKeyStatus(out)
+	return out
+}
+
+// providerKeyStatus lists the keys p currently offers.
+func providerKeyStatus(ctx context.Context, p KeyProvider) ([]KeyStatus, error) {
+	keys, err := p.PublicKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]KeyStatus, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, KeyStatus{
+			KeyID:     k.KeyID,
+			Algorithm: k.Alg,
+			KeyType:   publicKeyType(k.Key),
+			Source:    KeySourceProvider,
+		})
+	}
+	sortKeyStatus(out)
+	return out, nil
+}
+
+// publicKeyType maps a KeyProvider key to its JWK kty, or "" for a type the
+// validator would reject anyway.
+func publicKeyType(key any) string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return "RSA"
+	case *ecdsa.PublicKey:
+		return "EC"
+	case ed25519.PublicKey, *ed25519.PublicKey:
+		return "OKP"
+	default:
+		return ""
+	}
+}
+
+// sortKeyStatus orders keys by key id, so a Status is stable across calls
+// however the issuer orders its JWKS.
+func sortKeyStatus(keys []KeyStatus) {
+	slices.SortStableFunc(keys, func(a, b KeyStatus) int { return strings.Compare(a.KeyID, b.KeyID) })
+}
+
+// knownBadKIDCount returns the number of unexpired negative-cache entries.
+func (v *Validator) knownBadKIDCount() int {
+	v.negativeMu.Lock()
+	defer v.negativeMu.Unlock()
+	now := time.Now()
+	n := 0
+	for _, expiry := range v.negativeKids {
+		if now.Before(expiry) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package authn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/stacklok/toolhive-core/telemetry/metrics"
+)
+
+func TestValidatorStatus(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mintRSA(t, "b")
+	ecKey := mintEC(t, "a")
+	require.NoError(t, ecKey.jwk.Set(jwk.AlgorithmKey, testAlgES256))
+	js := newJWKSServer(t, rsaKey.jwk, ecKey.jwk)
+	cfg := js.configFor()
+	v, err := NewValidator(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+
+	st := v.Status(context.Background())
+	assert.Equal(t, cfg.JWKSURL, st.JWKSURL)
+	assert.False(t, st.LastRefreshAttempt.IsZero(), "the construction fetch is an attempt")
+	assert.Equal(t, st.LastRefreshAttempt, st.LastSuccessfulRefresh)
+	assert.Less(t, st.Staleness, time.Minute)
+	assert.False(t, st.Stale, "the bound is disabled by default")
+	assert.Equal(t, []KeyStatus{
+		{KeyID: "a", Algorithm: testAlgES256, KeyType: "EC", Source: KeySourceJWKS},
+		{KeyID: "b", KeyType: "RSA", Source: KeySourceJWKS},
+	}, st.Keys, "keys are listed sorted by kid")
+	assert.NoError(t, st.KeyProviderError)
+	assert.Zero(t, st.KnownBadKIDs)
+
+	// An unknown kid outside the refresh floor triggers a recovery fetch and,
+	// still unresolved, lands in the negative cache.
+	setLastRefresh(v, time.Time{})
+	stranger := mintRSA(t, "stranger")
+	_, err = v.Validate(context.Background(), stranger.mint(t, js.srv.URL))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonUnknownKID)
+
+	after := v.Status(context.Background())
+	assert.Equal(t, 1, after.KnownBadKIDs)
+	assert.True(t, after.LastRefreshAttempt.After(st.LastRefreshAttempt),
+		"the recovery fetch must advance the attempt time")
+	assert.Equal(t, after.LastRefreshAttempt, after.LastSuccessfulRefresh)
+
+	// A closed validator still reports its timestamps, but no JWKS keys.
+	v.Close()
+	closed := v.Status(context.Background())
+	assert.Equal(t, after.LastSuccessfulRefresh, closed.LastSuccessfulRefresh)
+	assert.Empty(t, closed.Keys)
+}
+
+func TestValidatorStatusKeyProvider(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mintRSA(t, "rsa")
+	edKey := mintEd25519(t, "ed")
+	kp := &fakeKeyProvider{keys: []PublicKey{
+		{KeyID: "rsa", Alg: testAlgRS256, Key: &rsaKey.priv.PublicKey},
+		{KeyID: "ed", Key: edKey.priv.Public()},
+	}}
+	v, err := NewValidator(context.Background(), providerConfig(t, kp))
+	require.NoError(t, err)
+	t.Cleanup(v.Close)
+
+	st := v.Status(context.Background())
+	assert.Equal(t, unreachableJWKSURL, st.JWKSURL)
+	assert.False(t, st.LastRefreshAttempt.IsZero(), "the failed construction fetch is still an attempt")
+	assert.True(t, st.LastSuccessfulRefresh.IsZero())
+	assert.Zero(t, st.Staleness)
+	assert.Equal(t, []KeyStatus{
+		{KeyID: "ed", KeyType: "OKP", Source: KeySourceProvider},
+		{KeyID: "rsa", Algorithm: testAlgRS256, KeyType: "RSA", Source: KeySourceProvider},
+	}, st.Keys)
+
+	kp.err = errors.New("signer offline")
+	st = v.Status(context.Background())
+	assert.Empty(t, st.Keys)
+	assert.ErrorIs(t, st.KeyProviderError, kp.err)
+}
+
+func TestValidatorMetrics(t *testing.T) {
+	t.Parallel()
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("authn_test")
+
+	rsaKey := mintRSA(t, "k1")
+	js := newJWKSServer(t, rsaKey.jwk)
+	cfg := js.configFor()
+	cfg.Meter = meter
+	v, err := NewValidator(context.Background(), cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = v.Validate(ctx, rsaKey.mint(t, js.srv.URL))
+	require.NoError(t, err)
+	_, err = v.Validate(ctx, rsaKey.mint(t, js.srv.URL,
+		withClaim(claimExp, time.Now().Add(-2*time.Hour).Unix()),
+		withClaim(claimIat, time.Now().Add(-3*time.Hour).Unix())))
+	requireAuthnError(t, err, CodeInvalidToken, ReasonExpired)
+
+	// An unknown kid forces a recovery fetch; make it fail so the refresh
+	// counter sees both outcomes.
+	js.setOverride(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	setLastRefresh(v, time.Time{})
+	_, err = v.Validate(ctx, mintRSA(t, "k2").mint(t, js.srv.URL))
+	requireAuthnError(t, err, CodeUnavailable, ReasonKeysUnavailable)
+
+	// A closed validator cannot reach key material: an error, not a rejection.
+	v.Close()
+	_, err = v.Validate(ctx, rsaKey.mint(t, js.srv.URL))
+	requireAuthnError(t, err, CodeUnavailable, ReasonKeysUnavailable)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	refreshes := counterValues(t, rm, MetricJWKSRefreshes)
+	assert.Equal(t, int64(1), refreshes[attrKey(metrics.OutcomeSuccess, "")], "the construction fetch")
+	assert.Equal(t, int64(1), refreshes[attrKey(metrics.OutcomeError, "")], "the failed recovery fetch")
+
+	validations := counterValues(t, rm, MetricValidations)
+	assert.Equal(t, map[string]int64{
+		attrKey(metrics.OutcomeSuccess, ""):                          1,
+		attrKey(metrics.OutcomeRejected, string(ReasonExpired)):      1,
+		attrKey(metrics.OutcomeError, string(ReasonKeysUnavailable)): 2,
+	}, validations)
+}
+
+// counterValues returns the data points of the named int64 sum keyed by
+// attrKey of their outcome and error_type labels.
+func counterValues(t *testing.T, rm metricdata.ResourceMetrics, name string) map[string]int64 {
+	t.Helper()
+	out := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "%s must be an int64 counter", name)
+			for _, dp := range sum.DataPoints {
+				outcome, _ := dp.Attributes.Value(attribute.Key(metrics.LabelOutcome))
+				errorType, _ := dp.Attributes.Value(attribute.Key(metrics.LabelErrorType))
+				out[attrKey(outcome.AsString(), errorType.AsString())] = dp.Value
+			}
+		}
+	}
+	return out
+}
+
+// attrKey joins an outcome and error_type into a comparable map key.
+func attrKey(outcome, errorType string) string {
+	return outcome + "/" + errorType
+}
@@ -6,6 +6,7 @@ package authn
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/json"
 	"errors"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/lestrrat-go/httprc/v3"
+	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwk"
 )
 
@@ -96,12 +98,27 @@ func (r keyRejection) String() string {
 	}
 }
 
-// allowedAlgs is the hardcoded signature-algorithm allowlist. `none` and all
-// HMAC algs are excluded (RFC 8725 §3.1/§3.2 — HMAC with a public JWKS is the
-// algorithm-confusion attack). Excluding EdDSA (Ed25519/Ed448) is a policy
-// choice, not a spec requirement: none of the current consumers have an
-// EdDSA-issuing IdP, and widening later is purely additive.
-var allowedAlgs = []string{
+// algEdDSA is the JWA name of the EdDSA family (RFC 8037 §3.1). Only the
+// Ed25519 curve is accepted under it: golang-jwt has no Ed448 verifier.
+const algEdDSA = "EdDSA"
+
+// supportedAlgs is every signature algorithm this package can verify, and so
+// the set Config.AllowedAlgs may select from. `none` and all HMAC algs are
+// excluded outright (RFC 8725 §3.1/§3.2 — HMAC with a public JWKS is the
+// algorithm-confusion attack), so no configuration can admit them.
+var supportedAlgs = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	algEdDSA,
+}
+
+// defaultAllowedAlgs is the allow-list used when Config.AllowedAlgs is empty.
+// EdDSA is left out of it as a policy choice, not a spec requirement: it
+// predates the field, and a deployment that does not expect Ed25519 keys
+// should not start honouring them because an issuer published one. Issuers
+// that sign with Ed25519 opt in by listing EdDSA.
+var defaultAllowedAlgs = []string{
 	"RS256", "RS384", "RS512",
 	"PS256", "PS384", "PS512",
 	"ES256", "ES384", "ES512",
@@ -150,8 +167,16 @@ type Principal struct {
 // disclosure as a primary threat). Errors therefore report only the structural
 // category and, where useful, a length.
 func ParseBearer(headerValue string) (string, error) {
+	_, token, err := parseCredential(headerValue, schemeBearer)
+	return token, err
+}
+
+// parseCredential is ParseBearer for any of the given auth schemes, returning
+// which one matched (in its canonical spelling) alongside the token. Every
+// ParseBearer rule applies unchanged; only the accepted scheme set differs.
+func parseCredential(headerValue string, schemes ...string) (string, string, error) {
 	if headerValue == "" {
-		return "", &Error{Code: CodeInvalidRequest, Reason: ReasonMissingHeader,
+		return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMissingHeader,
 			err: errors.New("no Authorization header")}
 	}
 	// RFC 7235 credentials are `auth-scheme 1*SP token68`: one or more spaces
@@ -160,26 +185,27 @@ func ParseBearer(headerValue string) (string, error) {
 	// separator spaces (1*SP, not exactly one).
 	scheme, rest, found := strings.Cut(headerValue, " ")
 	if !found || scheme == "" {
-		return "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
-			err: errors.New("expected 'Bearer <token>': no scheme/credential separator")}
+		return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
+			err: errors.New("expected '<scheme> <token>': no scheme/credential separator")}
 	}
-	if !strings.EqualFold(scheme, "Bearer") {
+	idx := slices.IndexFunc(schemes, func(s string) bool { return strings.EqualFold(scheme, s) })
+	if idx < 0 {
 		// The scheme is not echoed either: on a header with no separator the
 		// whole credential can land in this position.
-		return "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
-			err: errors.New("unsupported auth scheme: expected Bearer")}
+		return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
+			err: fmt.Errorf("unsupported auth scheme: expected %s", strings.Join(schemes, " or "))}
 	}
 	token := strings.TrimLeft(rest, " ")
 	if token == "" {
-		return "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
-			err: errors.New("expected 'Bearer <token>': empty credential")}
+		return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
+			err: errors.New("expected '<scheme> <token>': empty credential")}
 	}
 	// Length first, then the character scan: the bound is O(1) and the scan is
 	// O(n), so rejecting an oversized credential before walking it avoids doing
 	// work proportional to attacker-chosen input. net/http's MaxHeaderBytes
 	// already bounds this, so the ordering is hygiene rather than a fix.
 	if len(token) > maxTokenLength {
-		return "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
+		return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
 			err: fmt.Errorf("token length %d exceeds %d byte limit", len(token), maxTokenLength)}
 	}
 	// Reject anything outside printable ASCII (0x21-0x7E). This is
@@ -190,11 +216,11 @@ func ParseBearer(headerValue string) (string, error) {
 	// case token68 exists to rule out) without that risk.
 	for i := 0; i < len(token); i++ {
 		if token[i] < 0x21 || token[i] > 0x7e {
-			return "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
+			return "", "", &Error{Code: CodeInvalidRequest, Reason: ReasonMalformed,
 				err: errors.New("credential contains a non-printable-ASCII character")}
 		}
 	}
-	return token, nil
+	return schemes[idx], token, nil
 }
 
 // Validate verifies a bare JWT (no "Bearer " prefix — callers use
@@ -205,6 +231,13 @@ func ParseBearer(headerValue string) (string, error) {
 // touching key material runs before the algorithm gate (RFC 8725 §3.1) and
 // nothing expensive runs before the cheap structural rejects.
 func (v *Validator) Validate(ctx context.Context, token string) (Principal, error) {
+	p, err := v.validate(ctx, token)
+	v.metrics.recordValidation(ctx, err)
+	return p, err
+}
+
+// validate is Validate without the outcome metric.
+func (v *Validator) validate(ctx context.Context, token string) (Principal, error) {
 	// A closed validator cannot reach key material, so say so plainly rather
 	// than letting the caller discover it as a context error from deeper down.
 	if v.closed() {
@@ -229,7 +262,7 @@ func (v *Validator) Validate(ctx context.Context, token string) (Principal, erro
 	if malformedErr != nil {
 		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonMalformed, err: malformedErr}
 	}
-	if !slices.Contains(allowedAlgs, alg) {
+	if !slices.Contains(v.cfg.AllowedAlgs, alg) {
 		return Principal{}, &Error{Code: CodeInvalidToken, Reason: ReasonUnsupportedAlg,
 			err: fmt.Errorf("alg %q is not in the allow-list", alg)}
 	}
@@ -288,8 +321,16 @@ func (v *Validator) Validate(ctx context.Context, token string) (Principal, erro
 	// Config.Issuer is set the parser has already proven they are byte-equal,
 	// and when it is empty (JWKSURL-only, iss verification disabled) the claim
 	// is the only thing that reports who actually issued the token.
+	iss := stringClaim(claims, "iss")
+
+	// Revocation runs last: it may be a network round trip, and only a token
+	// that is otherwise acceptable is worth one.
+	if err := v.checkRevocation(ctx, claims, iss, sub); err != nil {
+		return Principal{}, err
+	}
+
 	return Principal{
-		Issuer:  stringClaim(claims, "iss"),
+		Issuer:  iss,
 		Subject: sub,
 		Name:    stringClaim(claims, "name"),
 		Claims:  claims,
@@ -305,7 +346,7 @@ func (v *Validator) parserOptions() []jwt.ParserOption {
 	// the verified path cannot be bypassed by a header that disagrees with
 	// itself, and so the gate cannot be forgotten by a second key path.
 	opts := []jwt.ParserOption{
-		jwt.WithValidMethods(allowedAlgs),
+		jwt.WithValidMethods(v.cfg.AllowedAlgs),
 		// Strict base64url, no padding (RFC 7515 §2); padding-allowed decode
 		// is the WithPaddingAllowed escape hatch and is NOT enabled.
 		jwt.WithStrictDecoding(),
@@ -804,9 +845,23 @@ func keyTypeMatchesAlg(key jwk.Key, alg string) keyRejection {
 			return rejectCurve
 		}
 		return rejectNone
+	case alg == algEdDSA:
+		// RFC 8037 §2: EdDSA keys are kty OKP, and the curve is named by crv
+		// rather than implied by the alg, so it must be checked separately.
+		if key.KeyType().String() != "OKP" {
+			return rejectKeyType
+		}
+		okp, ok := key.(jwk.OKPPublicKey)
+		if !ok {
+			return rejectExport
+		}
+		if crv, ok := okp.Crv(); !ok || crv != jwa.Ed25519() {
+			return rejectCurve
+		}
+		return rejectNone
 	default:
 		// Unreachable via Validate: the alg gate rejects any alg off the
-		// allowlist (RS/PS/ES only) before a keyfunc runs.
+		// allowlist before a keyfunc runs.
 		return rejectKeyType
 	}
 }
@@ -842,8 +897,8 @@ func exportCandidates(keys []jwk.Key, alg string) (any, error) {
 	return set, nil
 }
 
-// exportKey converts a single JWK to the *rsa.PublicKey or *ecdsa.PublicKey
-// appropriate for the token alg.
+// exportKey converts a single JWK to the *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey appropriate for the token alg.
 func exportKey(key jwk.Key, alg string) (any, error) {
 	switch {
 	case strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS"):
@@ -858,8 +913,16 @@ func exportKey(key jwk.Key, alg string) (any, error) {
 			return nil, err
 		}
 		return &raw, nil
+	case alg == algEdDSA:
+		// golang-jwt verifies EdDSA with an ed25519.PublicKey value, not a
+		// pointer.
+		var raw ed25519.PublicKey
+		if err := jwk.Export(key, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
 	default:
-		// Unreachable via Validate: the alg gate admits only RS/PS/ES algs.
+		// Unreachable via Validate: the alg gate admits only supportedAlgs.
 		return nil, fmt.Errorf("unsupported alg %q", alg)
 	}
 }
@@ -893,9 +956,11 @@ func (v *Validator) refreshOnce() (bool, error) {
 	_, err := v.jwksCache.Refresh(refreshCtx, v.jwksURL)
 	now := time.Now()
 	v.lastRefresh = now
+	v.lastAttempt = now
 	if err == nil {
 		v.lastSuccess = now
 	}
+	v.metrics.recordRefresh(refreshCtx, err)
 	return true, err
 }
 
@@ -5,7 +5,9 @@ package authn
 
 import (
 	"context"
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -56,6 +58,11 @@ type ecPair struct {
 	jwk  jwk.Key
 }
 
+type edPair struct {
+	priv ed25519.PrivateKey
+	jwk  jwk.Key
+}
+
 func mintRSA(t *testing.T, kid string) rsaPair {
 	t.Helper()
 	return mintRSABits(t, kid, 2048)
@@ -115,6 +122,16 @@ func mintECCurve(t *testing.T, kid string, curve elliptic.Curve) ecPair {
 	return ecPair{priv: priv, jwk: pub}
 }
 
+func mintEd25519(t *testing.T, kid string) edPair {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.Import(pub)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, kid))
+	return edPair{priv: priv, jwk: key}
+}
+
 // --- JWKS-serving test server ----------------------------------------------
 
 // jwksServer serves a JWKS built from the given keys and counts fetches.
@@ -305,6 +322,22 @@ func (ep ecPair) mint(t *testing.T, issuer string, opts ...mintOption) string {
 	return sign(t, spec)
 }
 
+func (ep edPair) mint(t *testing.T, issuer string, opts ...mintOption) string {
+	t.Helper()
+	kid, _ := ep.jwk.KeyID()
+	spec := mintSpec{
+		method:      jwt.SigningMethodEdDSA,
+		key:         ep.priv,
+		kid:         kid,
+		claims:      defaultClaims(issuer),
+		extraHeader: map[string]any{},
+	}
+	for _, o := range opts {
+		o(&spec)
+	}
+	return sign(t, spec)
+}
+
 // requireAuthnError asserts err is an *Error with the given code/reason.
 func requireAuthnError(t *testing.T, err error, code Code, reason Reason) {
 	t.Helper()
@@ -1494,7 +1527,7 @@ func TestKeyAlgFilter(t *testing.T) {
 }
 
 // TestKeyExportEdgeCases unit-tests the internal key helpers directly. The
-// alg gate in Validate admits only RS/PS/ES algs, so the off-allowlist default
+// alg gate in Validate admits only supported algs, so the off-allowlist default
 // branches below are unreachable through Validate; this test keeps them honest
 // as defense-in-depth and covers cross-type export failures.
 func TestKeyExportEdgeCases(t *testing.T) {
@@ -1503,7 +1536,7 @@ func TestKeyExportEdgeCases(t *testing.T) {
 	ecKey := mintEC(t, "ec-1")
 	rsaKey := mintRSA(t, "rsa-1")
 
-	// Non-RS/PS/ES algs hit the default (defensive) branches.
+	// Unsupported algs hit the default (defensive) branches.
 	assert.Equal(t, rejectKeyType, keyTypeMatchesAlg(ecKey.jwk, "HS256"))
 	assert.False(t, curveMatchesAlg("P-256", "HS256"))
 	_, err := exportKey(rsaKey.jwk, "HS256")
@@ -1517,6 +1550,81 @@ func TestKeyExportEdgeCases(t *testing.T) {
 	assert.Error(t, err, "an EC JWK must not export as an RSA key")
 }
 
+// TestAllowedAlgs covers Config.AllowedAlgs: EdDSA is verified only when
+// listed, and a subset shuts out every family it omits before any key is
+// consulted.
+func TestAllowedAlgs(t *testing.T) {
+	t.Parallel()
+
+	edKey := mintEd25519(t, "ed-1")
+	ecKey := mintEC(t, "ec-1")
+	js := newJWKSServer(t, edKey.jwk, ecKey.jwk)
+
+	newValidator := func(t *testing.T, algs ...string) *Validator {
+		t.Helper()
+		cfg := js.configFor()
+		cfg.AllowedAlgs = algs
+		v, err := NewValidator(context.Background(), cfg)
+		require.NoError(t, err)
+		t.Cleanup(v.Close)
+		return v
+	}
+
+	t.Run("EdDSA rejected by default", func(t *testing.T) {
+		t.Parallel()
+		v := newValidator(t)
+		_, err := v.Validate(context.Background(), edKey.mint(t, js.srv.URL))
+		requireAuthnError(t, err, CodeInvalidToken, ReasonUnsupportedAlg)
+	})
+
+	t.Run("EdDSA accepted when allowed", func(t *testing.T) {
+		t.Parallel()
+		v := newValidator(t, algEdDSA, testAlgES256)
+		p, err := v.Validate(context.Background(), edKey.mint(t, js.srv.URL))
+		require.NoError(t, err)
+		assert.Equal(t, testSubject, p.Subject)
+		_, err = v.Validate(context.Background(), ecKey.mint(t, js.srv.URL))
+		require.NoError(t, err)
+	})
+
+	t.Run("a subset excludes the rest", func(t *testing.T) {
+		t.Parallel()
+		v := newValidator(t, algEdDSA)
+		_, err := v.Validate(context.Background(), ecKey.mint(t, js.srv.URL))
+		requireAuthnError(t, err, CodeInvalidToken, ReasonUnsupportedAlg)
+	})
+
+	t.Run("EdDSA token naming an EC key", func(t *testing.T) {
+		t.Parallel()
+		v := newValidator(t, algEdDSA, testAlgES256)
+		_, err := v.Validate(context.Background(), edKey.mint(t, js.srv.URL, withKid("ec-1")))
+		requireAuthnError(t, err, CodeInvalidToken, ReasonKeyUnsupported)
+	})
+}
+
+// TestEdDSAKeyChecks unit-tests the OKP branch of the kty/curve backstop: an
+// EdDSA token needs an OKP key on Ed25519, and an Ed25519 key serves no other
+// alg.
+func TestEdDSAKeyChecks(t *testing.T) {
+	t.Parallel()
+
+	edKey := mintEd25519(t, "ed-1")
+	ecKey := mintEC(t, "ec-1")
+	xPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	x25519, err := jwk.Import(xPriv.PublicKey())
+	require.NoError(t, err)
+
+	assert.Equal(t, rejectNone, keyTypeMatchesAlg(edKey.jwk, algEdDSA))
+	assert.Equal(t, rejectKeyType, keyTypeMatchesAlg(ecKey.jwk, algEdDSA))
+	assert.Equal(t, rejectCurve, keyTypeMatchesAlg(x25519, algEdDSA), "X25519 is a key-agreement curve")
+	assert.Equal(t, rejectKeyType, keyTypeMatchesAlg(edKey.jwk, testAlgES256))
+
+	raw, err := exportKey(edKey.jwk, algEdDSA)
+	require.NoError(t, err)
+	assert.IsType(t, ed25519.PublicKey{}, raw, "golang-jwt verifies EdDSA with a value, not a pointer")
+}
+
 // TestNegativeCacheEviction proves the negative cache is bounded in entry
 // count: filling it past its bound evicts the oldest entry rather than
 // growing unboundedly.
@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"cel.dev/cel-go/checker"
+	celast "cel.dev/cel-go/common/ast"
+	"cel.dev/cel-go/common/operators"
+	"cel.dev/cel-go/common/types"
+)
+
+// Analysis is what an expression reads and what it may cost, found without
+// evaluating it. See Engine.Analyze.
+type Analysis struct {
+	// Source is the analyzed expression.
+	Source string `json:"source"`
+
+	// Variables are the declared variables the expression references,
+	// sorted.
+	Variables []string `json:"variables"`
+
+	// FieldPaths are the longest static paths the expression reads, sorted:
+	// the variable, then each field selected or map key indexed with a
+	// string constant, joined by dots. claims["groups"] and claims.groups
+	// are both "claims.groups". A variable used any other way — passed to a
+	// function, indexed with a computed key — appears on its own, since any
+	// part of it may be read.
+	FieldPaths []string `json:"fieldPaths"`
+
+	// Cost is the static estimate of the expression's runtime cost.
+	Cost CostEstimate `json:"cost"`
+}
+
+// CostEstimate bounds an expression's runtime cost, in the units
+// WithCostLimit uses.
+type CostEstimate struct {
+	// Min and Max are the best and worst case. Unless WithSizeHints bounds
+	// the size of its inputs, an expression that iterates over or scans a
+	// variable has an unbounded Max, near or at the largest uint64.
+	Min uint64 `json:"min"`
+	Max uint64 `json:"max"`
+	// Limit is the engine's cost limit, and ExceedsLimit whether Max is
+	// over it, so evaluation may be stopped by the limit.
+	Limit        uint64 `json:"limit"`
+	ExceedsLimit bool   `json:"exceedsLimit"`
+}
+
+// Analyze compiles expr, as Compile does, and reports the variables and
+// field paths it references and its worst-case cost against the engine's cost
+// limit, so a policy can be rejected before it is ever evaluated. Compilation
+// errors are returned as from Compile.
+//
+// The cost of functions from Library is estimated from the size of their
+// inputs, as they are charged at runtime.
+func (e *Engine) Analyze(expr string) (*Analysis, error) {
+	compiled, err := e.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	env, err := e.getEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CEL environment: %w", err)
+	}
+
+	refs := references{
+		ast:   compiled.ast.NativeRep(),
+		vars:  map[string]struct{}{},
+		paths: map[string]struct{}{},
+		ids:   map[int64]string{},
+	}
+	refs.walk(refs.ast.Expr(), nil)
+
+	estimate, err := env.EstimateCost(compiled.ast, sizeEstimator{hints: e.sizeHints, paths: refs.ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate cost of %q: %w", expr, err)
+	}
+
+	return &Analysis{
+		Source:     expr,
+		Variables:  sortedKeys(refs.vars),
+		FieldPaths: sortedKeys(refs.paths),
+		Cost: CostEstimate{
+			Min:          estimate.Min,
+			Max:          estimate.Max,
+			Limit:        e.costLimit,
+			ExceedsLimit: estimate.Max > e.costLimit,
+		},
+	}, nil
+}
+
+// References reports whether the expression may read path, a dot-joined
+// path as in FieldPaths: whether it reads path itself, a field within it, or
+// a value containing it.
+func (a *Analysis) References(path string) bool {
+	for _, p := range a.FieldPaths {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// AsJSON returns the Analysis as a JSON string.
+func (a *Analysis) AsJSON() string {
+	aBytes, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Sprintf(`{"error": "failed to marshal JSON: %s"}`, err)
+	}
+	return string(aBytes)
+}
+
+// sizeEstimator is a checker.CostEstimator bounding the inputs at the paths
+// set with WithSizeHints, leaving everything else to CEL's defaults and the
+// library's estimators. Paths are matched as FieldPaths reports them, so a
+// hint applies whether a field is selected or indexed.
+type sizeEstimator struct {
+	hints map[string]uint64
+	// paths maps the ID of each expression that reads a static path to the
+	// path, as references.ids.
+	paths map[int64]string
+}
+
+func (s sizeEstimator) EstimateSize(node checker.AstNode) *checker.SizeEstimate {
+	path, ok := s.paths[node.Expr().ID()]
+	if !ok {
+		return nil
+	}
+	if maxSize, ok := s.hints[path]; ok {
+		return &checker.SizeEstimate{Min: 0, Max: maxSize}
+	}
+	return nil
+}
+
+func (sizeEstimator) EstimateCallCost(string, string, *checker.AstNode, []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+// references collects the variables and field paths a checked expression
+// reads.
+type references struct {
+	ast   *celast.AST
+	vars  map[string]struct{}
+	paths map[string]struct{}
+	// ids maps the ID of each expression recorded in paths to its path.
+	ids map[int64]string
+}
+
+// walk visits e. bound holds the comprehension variables in scope, which
+// shadow declared variables of the same name.
+func (r *references) walk(e celast.Expr, bound []string) {
+	if path, root, ok := r.staticPath(e); ok {
+		if !slices.Contains(bound, root) {
+			r.vars[root] = struct{}{}
+			r.paths[path] = struct{}{}
+			r.ids[e.ID()] = path
+		}
+		return
+	}
+
+	switch e.Kind() {
+	case celast.SelectKind:
+		r.walk(e.AsSelect().Operand(), bound)
+	case celast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			r.walk(call.Target(), bound)
+		}
+		for _, arg := range call.Args() {
+			r.walk(arg, bound)
+		}
+	case celast.ListKind:
+		for _, el := range e.AsList().Elements() {
+			r.walk(el, bound)
+		}
+	case celast.MapKind:
+		for _, entry := range e.AsMap().Entries() {
+			r.walk(entry.AsMapEntry().Key(), bound)
+			r.walk(entry.AsMapEntry().Value(), bound)
+		}
+	case celast.StructKind:
+		for _, field := range e.AsStruct().Fields() {
+			r.walk(field.AsStructField().Value(), bound)
+		}
+	case celast.ComprehensionKind:
+		comp := e.AsComprehension()
+		r.walk(comp.IterRange(), bound)
+		r.walk(comp.AccuInit(), bound)
+		inner := append(slices.Clip(bound), comp.IterVar(), comp.AccuVar())
+		if comp.HasIterVar2() {
+			inner = append(inner, comp.IterVar2())
+		}
+		r.walk(comp.LoopCondition(), inner)
+		r.walk(comp.LoopStep(), inner)
+		r.walk(comp.Result(), inner)
+	default:
+		// Literals, and identifiers that are not variables, read nothing.
+	}
+}
+
+// sortedKeys returns m's keys in order, empty rather than nil so the report
+// lists them as [] rather than null.
+func sortedKeys(m map[string]struct{}) []string {
+	keys := slices.AppendSeq(make([]string, 0, len(m)), maps.Keys(m))
+	slices.Sort(keys)
+	return keys
+}
+
+// staticPath returns the dot-joined path e reads and its root variable, when
+// e is a variable followed by field selections and indexes with string
+// constants.
+func (r *references) staticPath(e celast.Expr) (path, root string, ok bool) {
+	switch e.Kind() {
+	case celast.IdentKind:
+		name, isVar := r.variable(e)
+		return name, name, isVar
+	case celast.SelectKind:
+		sel := e.AsSelect()
+		if path, root, ok = r.staticPath(sel.Operand()); ok {
+			return path + "." + sel.FieldName(), root, true
+		}
+	case celast.CallKind:
+		call := e.AsCall()
+		if call.FunctionName() != operators.Index || len(call.Args()) != 2 ||
+			call.Args()[1].Kind() != celast.LiteralKind {
+			return "", "", false
+		}
+		key, isString := call.Args()[1].AsLiteral().(types.String)
+		if !isString {
+			return "", "", false
+		}
+		if path, root, ok = r.staticPath(call.Args()[0]); ok {
+			return path + "." + string(key), root, true
+		}
+	default:
+	}
+	return "", "", false
+}
+
+// variable returns the name the checker resolved identifier e to, and
+// whether that is a variable rather than a type name such as string or a
+// constant.
+func (r *references) variable(e celast.Expr) (string, bool) {
+	ref, ok := r.ast.ReferenceMap()[e.ID()]
+	if !ok || len(ref.OverloadIDs) > 0 || ref.Value != nil {
+		return "", false
+	}
+	if r.ast.GetType(e.ID()).Kind() == types.TypeKind {
+		return "", false
+	}
+	return ref.Name, true
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	celgo "cel.dev/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+func newTestAnalysisEngine() *cel.Engine {
+	return cel.NewEngine(
+		celgo.Variable(claimsVar, celgo.MapType(celgo.StringType, celgo.DynType)),
+		celgo.Variable("request", celgo.DynType),
+		celgo.Variable("tool", celgo.StringType),
+		cel.Library(),
+	)
+}
+
+func TestEngine_Analyze(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestAnalysisEngine()
+
+	tests := []struct {
+		expr      string
+		wantVars  []string
+		wantPaths []string
+	}{
+		{`tool == "x"`, []string{"tool"}, []string{"tool"}},
+		{`claims["sub"] == "a" && has(claims.email)`, []string{claimsVar}, []string{"claims.email", "claims.sub"}},
+		{`request.params.arguments.repo == "x"`, []string{"request"}, []string{"request.params.arguments.repo"}},
+		{`claims[tool] == 1`, []string{claimsVar, "tool"}, []string{claimsVar, "tool"}},
+		{`mcp.toolName(request) == tool`, []string{"request", "tool"}, []string{"request", "tool"}},
+		{`claims.groups.exists(g, g == tool)`, []string{claimsVar, "tool"}, []string{"claims.groups", "tool"}},
+		{`[1, 2].exists(tool, tool == 1)`, []string{}, []string{}},
+		{`type(tool) == string && type(claims.n) == int`, []string{claimsVar, "tool"}, []string{"claims.n", "tool"}},
+		{`true`, []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			a, err := engine.Analyze(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expr, a.Source)
+			assert.Equal(t, tt.wantVars, a.Variables)
+			assert.Equal(t, tt.wantPaths, a.FieldPaths)
+			assert.Equal(t, uint64(cel.DefaultCostLimit), a.Cost.Limit)
+		})
+	}
+}
+
+func TestAnalysis_References(t *testing.T) {
+	t.Parallel()
+
+	a, err := newTestAnalysisEngine().Analyze(`"admins" in claims.groups && request.params.name == tool`)
+	require.NoError(t, err)
+	assert.True(t, a.References("claims.groups"))
+	assert.True(t, a.References("claims"), "claims.groups is part of claims")
+	assert.True(t, a.References("claims.groups.0"), "the whole list is read")
+	assert.True(t, a.References("request.params"))
+	assert.False(t, a.References("claims.secret"))
+	assert.False(t, a.References("request.params.arguments"))
+	assert.False(t, a.References("claim"))
+
+	// A computed key may read any field.
+	a, err = newTestAnalysisEngine().Analyze(`claims[tool] != ""`)
+	require.NoError(t, err)
+	assert.True(t, a.References("claims.secret"))
+}
+
+func TestEngine_AnalyzeCost(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestAnalysisEngine().WithCostLimit(100)
+
+	a, err := engine.Analyze(`tool == "x"`)
+	require.NoError(t, err)
+	assert.False(t, a.Cost.ExceedsLimit)
+	assert.LessOrEqual(t, a.Cost.Min, a.Cost.Max)
+	assert.Equal(t, uint64(100), a.Cost.Limit)
+
+	// Scanning an input of unknown size is unbounded, including through the
+	// library's functions.
+	for _, expr := range []string{`claims.groups.exists(g, g == tool)`, `glob.match("a*", tool)`} {
+		a, err = engine.Analyze(expr)
+		require.NoError(t, err)
+		assert.True(t, a.Cost.ExceedsLimit, expr)
+	}
+
+	// Size hints bound it.
+	hinted := newTestAnalysisEngine().WithCostLimit(100).WithSizeHints(map[string]uint64{
+		"claims.groups": 10,
+		"tool":          64,
+	})
+	a, err = hinted.Analyze(`claims.groups.exists(g, g == "admins")`)
+	require.NoError(t, err)
+	assert.False(t, a.Cost.ExceedsLimit, "max %d", a.Cost.Max)
+	a, err = hinted.Analyze(`claims["groups"].exists(g, g == "admins")`)
+	require.NoError(t, err)
+	assert.False(t, a.Cost.ExceedsLimit, "index form: max %d", a.Cost.Max)
+	assert.Equal(t, []string{"claims.groups"}, a.FieldPaths)
+	a, err = hinted.Analyze(`glob.match("a*", tool)`)
+	require.NoError(t, err)
+	assert.False(t, a.Cost.ExceedsLimit, "max %d", a.Cost.Max)
+
+	for _, expr := range []string{`jwt.scopes(claims)`, `jwt.hasScope(claims, "read")`} {
+		a, err = engine.Analyze(expr)
+		require.NoError(t, err)
+		assert.True(t, a.Cost.ExceedsLimit, expr)
+		a, err = newTestAnalysisEngine().WithCostLimit(100).WithSizeHints(map[string]uint64{"claims": 5}).Analyze(expr)
+		require.NoError(t, err)
+		assert.False(t, a.Cost.ExceedsLimit, "%s: max %d", expr, a.Cost.Max)
+	}
+
+	wide := newTestAnalysisEngine().WithCostLimit(100).WithSizeHints(map[string]uint64{"claims.groups": 1000})
+	a, err = wide.Analyze(`claims.groups.exists(g, g == "admins")`)
+	require.NoError(t, err)
+	assert.True(t, a.Cost.ExceedsLimit, "max %d", a.Cost.Max)
+}
+
+func TestEngine_AnalyzeReport(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestAnalysisEngine()
+	a, err := engine.Analyze(`claims["sub"] == tool`)
+	require.NoError(t, err)
+
+	var report map[string]any
+	require.NoError(t, json.Unmarshal([]byte(a.AsJSON()), &report))
+	assert.Equal(t, `claims["sub"] == tool`, report["source"])
+	assert.Equal(t, []any{claimsVar, "tool"}, report["variables"])
+	assert.Equal(t, []any{"claims.sub", "tool"}, report["fieldPaths"])
+
+	a, err = engine.Analyze(`true`)
+	require.NoError(t, err)
+	assert.Contains(t, a.AsJSON(), `"variables":[],"fieldPaths":[]`)
+	assert.Contains(t, report["cost"], "exceedsLimit")
+
+	// Compilation errors carry ErrDetails as from Compile.
+	_, err = engine.Analyze(`undefined == 1`)
+	var checkErr *cel.CheckError
+	require.True(t, errors.As(err, &checkErr), "expected CheckError, got %T", err)
+	assert.NotEmpty(t, checkErr.Errors)
+}
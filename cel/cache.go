@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCompileCacheSize is how many compiled expressions an Engine keeps by
+// default. See WithCompileCache.
+const DefaultCompileCacheSize = 256
+
+// CacheStats reports the activity of an Engine's compile cache.
+type CacheStats struct {
+	// Hits and Misses count Compile calls answered from the cache and
+	// compiled afresh. Calls rejected before the cache is consulted, for
+	// length, are not counted.
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Evictions counts expressions dropped to make room for others.
+	Evictions uint64 `json:"evictions"`
+	// Size is how many expressions the cache holds, Capacity how many it
+	// may.
+	Size     int `json:"size"`
+	Capacity int `json:"capacity"`
+}
+
+// compileCache is a bounded, concurrency-safe cache of compiled expressions
+// keyed by source, evicting the least recently used. Only successful
+// compilations are stored: an invalid expression is recompiled, and its
+// error reported afresh, every time.
+type compileCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+	stats CacheStats
+}
+
+// newCompileCache returns a cache holding at most size expressions, or nil,
+// which caches nothing, when size is not positive.
+func newCompileCache(size int) *compileCache {
+	if size <= 0 {
+		return nil
+	}
+	return &compileCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+		stats: CacheStats{Capacity: size},
+	}
+}
+
+// get returns the expression compiled from source, marking it most recently
+// used.
+func (c *compileCache) get(source string) (*CompiledExpression, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[source]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*CompiledExpression), true
+}
+
+// add stores expr, evicting the least recently used expression when the
+// cache is full.
+func (c *compileCache) add(expr *CompiledExpression) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[expr.source]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.size {
+		if oldest := c.order.Back(); oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*CompiledExpression).source)
+			c.stats.Evictions++
+		}
+	}
+	c.items[expr.source] = c.order.PushFront(expr)
+}
+
+// snapshot returns the cache's statistics.
+func (c *compileCache) snapshot() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel_test
+
+import (
+	"testing"
+
+	celgo "cel.dev/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+func TestEngine_CompileCache(t *testing.T) {
+	t.Parallel()
+
+	engine := cel.NewEngine(celgo.Variable("x", celgo.IntType)).WithCompileCache(2)
+
+	a, err := engine.Compile("x == 1")
+	require.NoError(t, err)
+	again, err := engine.Compile("x == 1")
+	require.NoError(t, err)
+	assert.Same(t, a, again, "second compile is served from the cache")
+
+	_, err = engine.Compile("x == 2")
+	require.NoError(t, err)
+	_, err = engine.Compile("x == 1") // now most recently used
+	require.NoError(t, err)
+	_, err = engine.Compile("x == 3") // evicts x == 2
+	require.NoError(t, err)
+
+	_, err = engine.Compile("x ==")
+	require.Error(t, err)
+	_, err = engine.Compile("x ==")
+	require.Error(t, err, "failed compilations are not cached")
+
+	assert.Equal(t, cel.CacheStats{Hits: 2, Misses: 5, Evictions: 1, Size: 2, Capacity: 2}, engine.CacheStats())
+
+	b, err := engine.Compile("x == 1")
+	require.NoError(t, err)
+	assert.Same(t, a, b, "x == 1 survived eviction")
+}
+
+func TestEngine_CompileCacheDisabled(t *testing.T) {
+	t.Parallel()
+
+	engine := cel.NewEngine(celgo.Variable("x", celgo.IntType)).WithCompileCache(0)
+	a, err := engine.Compile("x == 1")
+	require.NoError(t, err)
+	b, err := engine.Compile("x == 1")
+	require.NoError(t, err)
+	assert.NotSame(t, a, b)
+	assert.Equal(t, cel.CacheStats{}, engine.CacheStats())
+}
+
+func TestEngine_CompileCacheDefault(t *testing.T) {
+	t.Parallel()
+
+	engine := cel.NewEngine(celgo.Variable("x", celgo.IntType))
+	assert.Equal(t, cel.DefaultCompileCacheSize, engine.CacheStats().Capacity)
+
+	_, err := engine.Compile("x == 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, engine.CacheStats().Size)
+
+	// Programs are planned with the cost limit, so changing it drops them.
+	engine.WithCostLimit(10)
+	assert.Equal(t, cel.CacheStats{Capacity: cel.DefaultCompileCacheSize}, engine.CacheStats())
+}
@@ -27,6 +27,22 @@ Create an engine with variable declarations, compile an expression, and evaluate
 	result, err := expr.EvaluateBool(ctx)
 	// result == true
 
+# Typed Results and Go Values
+
+EvaluateAs converts a result to a Go type, such as string, int, []string or
+map[string]any:
+
+	scopes, err := cel.EvaluateAs[[]string](expr, ctx)
+
+Go structs can be passed to expressions two ways. NativeTypes registers them
+as CEL object types, without protobuf, so they go into the activation as they
+are; ObjectType declares a variable of such a type. Activation instead converts
+structs and maps into plain variables by way of their JSON form, for variables
+declared as dyn or maps:
+
+	vars, err := cel.Activation(map[string]any{"claims": principal.Claims, "request": req})
+	ok, err := expr.EvaluateBool(vars)
+
 # Expression Validation
 
 Use Check to validate an expression without creating a compiled program. This is
@@ -54,6 +70,64 @@ Compilation errors are returned as structured types with location information:
 	    fmt.Println(checkErr.AsJSON()) // structured JSON error details
 	}
 
+# ToolHive Functions
+
+Library is an opt-in set of functions the stock CEL library lacks: glob and
+CIDR matching, semantic version comparison, JWT scope parsing, URL parsing,
+and accessors for MCP requests and tools:
+
+	engine := cel.NewEngine(
+	    celgo.Variable("request", celgo.DynType),
+	    celgo.Variable("source_ip", celgo.StringType),
+	    cel.Library(),
+	)
+
+	expr, err := engine.Compile(
+	    `glob.match("github.*", mcp.toolName(request)) && ip.inCIDR(source_ip, "10.0.0.0/8")`)
+
+Each function is charged by the size of its inputs, so WithCostLimit bounds
+expressions that use them.
+
+# Policy Sets
+
+A PolicySet combines named allow and deny rules into one decision. Rules are
+ordered by priority, then by position, and combined by FirstMatch or
+DenyOverrides; when none matches, the default effect (deny unless set)
+applies. Every expression is compiled when the set is built, so an invalid
+rule fails at load time rather than at request time:
+
+	ps, err := cel.LoadPolicySet(engine, "policy.yaml")
+	if err != nil {
+	    // handle invalid policy
+	}
+
+	decision := ps.Evaluate(map[string]any{"claims": claims, "tool": "delete_repo"})
+	if !decision.Allowed() {
+	    fmt.Println(decision.Rule, decision.Reason) // which rule denied, and why
+	}
+
+A rule that fails to evaluate fails closed: an allow rule does not match and
+a deny rule does. Such rules are listed in Decision.Errors.
+
+# Caching and Hot Reload
+
+Compile keeps successfully compiled expressions in an LRU cache keyed by
+source, so recompiling the same expression is a map lookup. WithCompileCache
+sizes it (zero disables it) and CacheStats reports hits, misses and evictions.
+
+WatchExpressions and WatchPolicySet compile a YAML file and recompile it when
+its content changes. The new value replaces the old one only if everything
+compiles; otherwise the failure is logged and the previous value stays in
+service:
+
+	w, err := cel.WatchPolicySet(ctx, engine, cel.WatchConfig{Path: "policy.yaml"})
+	if err != nil {
+	    // handle invalid policy
+	}
+	defer w.Close()
+
+	decision := w.Current().Evaluate(vars)
+
 # DoS Protection
 
 The engine includes configurable safeguards against denial-of-service:
@@ -62,6 +136,22 @@ The engine includes configurable safeguards against denial-of-service:
 	    WithMaxExpressionLength(5000). // reject overly long expressions
 	    WithCostLimit(500000)          // limit runtime evaluation cost
 
+# Static Analysis
+
+Analyze reports, without evaluating an expression, the variables and field
+paths it reads and its estimated worst-case cost against the cost limit, so a
+submitted policy can be rejected up front. WithSizeHints bounds the inputs the
+estimate would otherwise take as unbounded:
+
+	a, err := engine.WithSizeHints(map[string]uint64{"claims.groups": 100}).
+	    Analyze(`"admins" in claims.groups`)
+	if err != nil {
+	    // handle compilation error
+	}
+	if a.Cost.ExceedsLimit || a.References("claims.secret") {
+	    fmt.Println(a.AsJSON()) // reject, with the report
+	}
+
 # Concurrency
 
 The Engine and CompiledExpression types are safe for concurrent use. A compiled
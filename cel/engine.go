@@ -5,9 +5,12 @@ package cel
 
 import (
 	"fmt"
+	"maps"
+	"reflect"
 	"sync"
 
 	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/common/types/ref"
 )
 
 const (
@@ -27,6 +30,8 @@ type Engine struct {
 	factory             envFactory
 	maxExpressionLength int
 	costLimit           uint64
+	cache               *compileCache
+	sizeHints           map[string]uint64
 }
 
 // envFactory is a function that creates a CEL environment.
@@ -41,8 +46,10 @@ type envCache struct {
 
 // CompiledExpression represents a pre-compiled CEL program ready for evaluation.
 type CompiledExpression struct {
-	source  string
-	program cel.Program
+	source     string
+	program    cel.Program
+	ast        *cel.Ast
+	outputType *cel.Type
 }
 
 // Source returns the original expression source string.
@@ -55,7 +62,8 @@ func (ce *CompiledExpression) Source() string {
 //
 // The engine is created with default limits for expression length and evaluation cost
 // to prevent denial-of-service attacks. Use WithMaxExpressionLength and WithCostLimit
-// to customize these limits if needed.
+// to customize these limits if needed. It caches up to DefaultCompileCacheSize
+// compiled expressions; use WithCompileCache to change that.
 //
 // Example usage:
 //
@@ -67,6 +75,7 @@ func NewEngine(options ...cel.EnvOption) *Engine {
 		envCache:            &envCache{},
 		maxExpressionLength: DefaultMaxExpressionLength,
 		costLimit:           DefaultCostLimit,
+		cache:               newCompileCache(DefaultCompileCacheSize),
 		factory: func() (*cel.Env, error) {
 			return cel.NewEnv(options...)
 		},
@@ -84,9 +93,37 @@ func (e *Engine) WithMaxExpressionLength(maxLen int) *Engine {
 // Programs that exceed this cost during evaluation will return an error.
 func (e *Engine) WithCostLimit(limit uint64) *Engine {
 	e.costLimit = limit
+	e.cache = newCompileCache(e.cache.snapshot().Capacity)
 	return e
 }
 
+// WithCompileCache sets how many compiled expressions the engine keeps,
+// keyed by source, so that compiling the same expression again returns the
+// same CompiledExpression without recompiling it. The least recently used
+// expression is evicted when the cache is full. Zero or negative disables
+// caching. Any expressions already cached are dropped.
+func (e *Engine) WithCompileCache(size int) *Engine {
+	e.cache = newCompileCache(size)
+	return e
+}
+
+// WithSizeHints sets the largest size, in characters, bytes, elements or
+// entries, that the string, bytes, list or map at each path may have, so
+// Analyze can bound the cost of expressions that scan them. A path is a
+// variable and the fields selected from it, joined by dots as in
+// Analysis.FieldPaths; "claims.groups", say, which bounds claims.groups and
+// claims["groups"] alike. It does not limit evaluation.
+func (e *Engine) WithSizeHints(hints map[string]uint64) *Engine {
+	e.sizeHints = maps.Clone(hints)
+	return e
+}
+
+// CacheStats returns the compile cache's statistics. They are zero when
+// caching is disabled.
+func (e *Engine) CacheStats() CacheStats {
+	return e.cache.snapshot()
+}
+
 // getEnv returns the CEL environment, creating it lazily on first access.
 func (e *Engine) getEnv() (*cel.Env, error) {
 	e.envCache.once.Do(func() {
@@ -101,6 +138,9 @@ func (e *Engine) getEnv() (*cel.Env, error) {
 // Returns an error if the expression exceeds the maximum length, a ParseError
 // if the expression has syntax errors, or a CheckError if the expression has
 // type checking errors.
+//
+// A successfully compiled expression is cached (see WithCompileCache), so
+// callers need not keep their own map of compiled expressions.
 func (e *Engine) Compile(expr string) (*CompiledExpression, error) {
 	// Check expression length to prevent DoS via excessively long expressions
 	if len(expr) > e.maxExpressionLength {
@@ -108,6 +148,19 @@ func (e *Engine) Compile(expr string) (*CompiledExpression, error) {
 			ErrExpressionCheck, len(expr), e.maxExpressionLength)
 	}
 
+	if compiled, ok := e.cache.get(expr); ok {
+		return compiled, nil
+	}
+	compiled, err := e.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	e.cache.add(compiled)
+	return compiled, nil
+}
+
+// compile parses, checks and plans expr, bypassing the cache.
+func (e *Engine) compile(expr string) (*CompiledExpression, error) {
 	env, err := e.getEnv()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get CEL environment: %w", err)
@@ -132,8 +185,10 @@ func (e *Engine) Compile(expr string) (*CompiledExpression, error) {
 	}
 
 	return &CompiledExpression{
-		source:  expr,
-		program: program,
+		source:     expr,
+		program:    program,
+		ast:        checkedAst,
+		outputType: checkedAst.OutputType(),
 	}, nil
 }
 
@@ -178,11 +233,20 @@ func (e *Engine) Check(expr string) error {
 //
 //	ctx := map[string]any{"myVar": someValue}
 func (ce *CompiledExpression) Evaluate(ctx map[string]any) (any, error) {
+	out, err := ce.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return out.Value(), nil
+}
+
+// eval executes the compiled expression, returning the CEL value.
+func (ce *CompiledExpression) eval(ctx map[string]any) (ref.Val, error) {
 	out, _, err := ce.program.Eval(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrEvaluation, err)
 	}
-	return out.Value(), nil
+	return out, nil
 }
 
 // EvaluateBool executes the compiled expression and returns the result as a bool.
@@ -200,3 +264,35 @@ func (ce *CompiledExpression) EvaluateBool(ctx map[string]any) (bool, error) {
 
 	return boolResult, nil
 }
+
+// EvaluateAs executes the compiled expression and converts the result to T,
+// the way CEL converts values to Go: an int to any Go integer type that holds
+// it, a list to a slice of a convertible element type (such as []string), a
+// map to a map of convertible keys and values (such as map[string]any), an
+// object registered with NativeTypes to its struct. Returns an error wrapping
+// ErrInvalidResult if the result cannot be converted.
+//
+// Example:
+//
+//	scopes, err := cel.EvaluateAs[[]string](expr, ctx)
+func EvaluateAs[T any](ce *CompiledExpression, ctx map[string]any) (T, error) {
+	var zero T
+	out, err := ce.eval(ctx)
+	if err != nil {
+		return zero, err
+	}
+	if v, ok := out.Value().(T); ok {
+		return v, nil
+	}
+
+	want := reflect.TypeFor[T]()
+	native, err := out.ConvertToNative(want)
+	if err != nil {
+		return zero, fmt.Errorf("%w: expected %s, got %s: %s", ErrInvalidResult, want, out.Type(), err)
+	}
+	v, ok := native.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: expected %s, got %T", ErrInvalidResult, want, native)
+	}
+	return v, nil
+}
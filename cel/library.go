@@ -0,0 +1,476 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+
+	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/checker"
+	"cel.dev/cel-go/common"
+	"cel.dev/cel-go/common/types"
+	"cel.dev/cel-go/common/types/ref"
+	"cel.dev/cel-go/common/types/traits"
+	"cel.dev/cel-go/interpreter"
+	"golang.org/x/mod/semver"
+)
+
+// Library returns the ToolHive function library, for NewEngine:
+//
+//	engine := cel.NewEngine(
+//	    celgo.Variable("request", celgo.DynType),
+//	    cel.Library(),
+//	)
+//
+// It declares:
+//
+//   - glob.match(pattern, s) bool: s matches the path.Match pattern, where *
+//     matches any run of characters except /. A malformed pattern is an
+//     evaluation error.
+//   - ip.inCIDR(ip, cidr) bool and ip.inCIDR(ip, list(cidr)) bool: the IPv4
+//     or IPv6 address is in the prefix, or in any of the prefixes.
+//   - semver.compare(a, b) int: -1, 0 or 1 as a is lower than, equal to or
+//     higher than b. The leading "v" is optional; an invalid version is an
+//     evaluation error.
+//   - jwt.scopes(claims) list(string): the scopes granted by a claim set,
+//     read from the scope and scp claims as space-delimited strings or
+//     arrays, deduplicated in first-seen order. jwt.hasScope(claims, scope)
+//     bool tests for one.
+//   - url.parse(s) map(string, string): the scheme, host, hostname, port,
+//     path, query (raw) and fragment of a URL.
+//   - mcp.toolName(request) string and mcp.arguments(request) map(string,
+//     dyn): the tool name and arguments of a tools/call request, given as
+//     the JSON-RPC request or as its params. mcp.annotations(tool)
+//     map(string, dyn): a tool's annotations, empty when it has none.
+//
+// Every function has a static cost estimate and a runtime cost that scale
+// with the size of its inputs, so WithCostLimit bounds expressions using them
+// as it does the standard functions.
+func Library() cel.EnvOption {
+	return cel.Lib(toolhiveLib{})
+}
+
+// toolhiveLib implements cel.Library for Library.
+type toolhiveLib struct{}
+
+// LibraryName makes the library a singleton, so that passing it twice is
+// harmless.
+func (toolhiveLib) LibraryName() string {
+	return "toolhive"
+}
+
+// Overload IDs of the library's functions, which key their cost functions.
+const (
+	overloadGlobMatch      = "toolhive_glob_match_string_string"
+	overloadIPInCIDR       = "toolhive_ip_in_cidr_string_string"
+	overloadIPInCIDRList   = "toolhive_ip_in_cidr_string_list"
+	overloadSemverCompare  = "toolhive_semver_compare_string_string"
+	overloadJWTScopes      = "toolhive_jwt_scopes_dyn"
+	overloadJWTHasScope    = "toolhive_jwt_has_scope_dyn_string"
+	overloadURLParse       = "toolhive_url_parse_string"
+	overloadMCPToolName    = "toolhive_mcp_tool_name_dyn"
+	overloadMCPArguments   = "toolhive_mcp_arguments_dyn"
+	overloadMCPAnnotations = "toolhive_mcp_annotations_dyn"
+)
+
+// Base costs of the library's calls, on top of the traversal of their inputs.
+const (
+	callCost  = 1
+	parseCost = 10
+)
+
+var stringMapType = cel.MapType(cel.StringType, cel.StringType)
+
+// CompileOptions implements cel.Library.
+func (toolhiveLib) CompileOptions() []cel.EnvOption {
+	dynMap := cel.MapType(cel.StringType, cel.DynType)
+	return []cel.EnvOption{
+		cel.Function("glob.match",
+			cel.Overload(overloadGlobMatch, []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(globMatch))),
+		cel.Function("ip.inCIDR",
+			cel.Overload(overloadIPInCIDR, []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(ipInCIDR)),
+			cel.Overload(overloadIPInCIDRList, []*cel.Type{cel.StringType, cel.ListType(cel.StringType)}, cel.BoolType,
+				cel.BinaryBinding(ipInCIDRList))),
+		cel.Function("semver.compare",
+			cel.Overload(overloadSemverCompare, []*cel.Type{cel.StringType, cel.StringType}, cel.IntType,
+				cel.BinaryBinding(semverCompare))),
+		cel.Function("jwt.scopes",
+			cel.Overload(overloadJWTScopes, []*cel.Type{cel.DynType}, cel.ListType(cel.StringType),
+				cel.UnaryBinding(jwtScopes))),
+		cel.Function("jwt.hasScope",
+			cel.Overload(overloadJWTHasScope, []*cel.Type{cel.DynType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(jwtHasScope))),
+		cel.Function("url.parse",
+			cel.Overload(overloadURLParse, []*cel.Type{cel.StringType}, stringMapType,
+				cel.UnaryBinding(urlParse))),
+		cel.Function("mcp.toolName",
+			cel.Overload(overloadMCPToolName, []*cel.Type{cel.DynType}, cel.StringType,
+				cel.UnaryBinding(mcpToolName))),
+		cel.Function("mcp.arguments",
+			cel.Overload(overloadMCPArguments, []*cel.Type{cel.DynType}, dynMap,
+				cel.UnaryBinding(func(v ref.Val) ref.Val { return mcpParamsMap(v, "arguments") }))),
+		cel.Function("mcp.annotations",
+			cel.Overload(overloadMCPAnnotations, []*cel.Type{cel.DynType}, dynMap,
+				cel.UnaryBinding(func(v ref.Val) ref.Val { return mapField(v, "annotations") }))),
+		cel.CostEstimatorOptions(
+			checker.OverloadCostEstimate(overloadGlobMatch, estimateGlob),
+			checker.OverloadCostEstimate(overloadIPInCIDR, estimateParse),
+			checker.OverloadCostEstimate(overloadIPInCIDRList, estimateIPInCIDRList),
+			checker.OverloadCostEstimate(overloadSemverCompare, estimateParse),
+			checker.OverloadCostEstimate(overloadJWTScopes, estimateJWTScopes),
+			checker.OverloadCostEstimate(overloadJWTHasScope, estimateJWTScopes),
+			checker.OverloadCostEstimate(overloadURLParse, estimateURLParse),
+			checker.OverloadCostEstimate(overloadMCPToolName, estimateLookup),
+			checker.OverloadCostEstimate(overloadMCPArguments, estimateLookup),
+			checker.OverloadCostEstimate(overloadMCPAnnotations, estimateLookup),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library.
+func (toolhiveLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{
+		cel.CostTrackerOptions(
+			interpreter.OverloadCostTracker(overloadGlobMatch, trackGlob),
+			interpreter.OverloadCostTracker(overloadIPInCIDR, trackParse),
+			interpreter.OverloadCostTracker(overloadIPInCIDRList, trackParse),
+			interpreter.OverloadCostTracker(overloadSemverCompare, trackParse),
+			interpreter.OverloadCostTracker(overloadJWTScopes, trackJWTScopes),
+			interpreter.OverloadCostTracker(overloadJWTHasScope, trackJWTScopes),
+			interpreter.OverloadCostTracker(overloadURLParse, trackURLParse),
+			interpreter.OverloadCostTracker(overloadMCPToolName, trackLookup),
+			interpreter.OverloadCostTracker(overloadMCPArguments, trackLookup),
+			interpreter.OverloadCostTracker(overloadMCPAnnotations, trackLookup),
+		),
+	}
+}
+
+func globMatch(pattern, s ref.Val) ref.Val {
+	ok, err := path.Match(string(pattern.(types.String)), string(s.(types.String)))
+	if err != nil {
+		return types.NewErr("glob.match: invalid pattern %q: %v", pattern, err)
+	}
+	return types.Bool(ok)
+}
+
+func ipInCIDR(ip, cidr ref.Val) ref.Val {
+	addr, err := netip.ParseAddr(string(ip.(types.String)))
+	if err != nil {
+		return types.NewErr("ip.inCIDR: %v", err)
+	}
+	return inPrefix(addr, cidr)
+}
+
+func ipInCIDRList(ip, cidrs ref.Val) ref.Val {
+	addr, err := netip.ParseAddr(string(ip.(types.String)))
+	if err != nil {
+		return types.NewErr("ip.inCIDR: %v", err)
+	}
+	it := cidrs.(traits.Lister).Iterator()
+	for it.HasNext() == types.True {
+		res := inPrefix(addr, it.Next())
+		if res != types.False {
+			return res
+		}
+	}
+	return types.False
+}
+
+// inPrefix reports whether addr is in the prefix cidr, a string.
+func inPrefix(addr netip.Addr, cidr ref.Val) ref.Val {
+	s, ok := cidr.(types.String)
+	if !ok {
+		return types.NewErr("ip.inCIDR: CIDR must be a string, got %s", cidr.Type())
+	}
+	prefix, err := netip.ParsePrefix(string(s))
+	if err != nil {
+		return types.NewErr("ip.inCIDR: %v", err)
+	}
+	return types.Bool(prefix.Contains(addr.Unmap()))
+}
+
+func semverCompare(a, b ref.Val) ref.Val {
+	va, vb := canonicalSemver(string(a.(types.String))), canonicalSemver(string(b.(types.String)))
+	for _, v := range []string{va, vb} {
+		if !semver.IsValid(v) {
+			return types.NewErr("semver.compare: invalid version %q", strings.TrimPrefix(v, "v"))
+		}
+	}
+	return types.Int(semver.Compare(va, vb))
+}
+
+// canonicalSemver adds the "v" prefix x/mod/semver requires.
+func canonicalSemver(v string) string {
+	if strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
+
+func jwtScopes(claims ref.Val) ref.Val {
+	scopes, err := scopesOf(claims)
+	if err != nil {
+		return types.NewErr("jwt.scopes: %v", err)
+	}
+	return types.NewStringList(types.DefaultTypeAdapter, scopes)
+}
+
+func jwtHasScope(claims, scope ref.Val) ref.Val {
+	scopes, err := scopesOf(claims)
+	if err != nil {
+		return types.NewErr("jwt.hasScope: %v", err)
+	}
+	want := string(scope.(types.String))
+	for _, s := range scopes {
+		if s == want {
+			return types.True
+		}
+	}
+	return types.False
+}
+
+// scopesOf reads the scope and scp claims, as the authn package's Scopes
+// does: each a space-delimited string or an array of strings.
+func scopesOf(claims ref.Val) ([]string, error) {
+	m, ok := claims.(traits.Mapper)
+	if !ok {
+		return nil, fmt.Errorf("claims must be a map, got %s", claims.Type())
+	}
+	out := []string{}
+	seen := map[string]bool{}
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	for _, name := range []string{"scope", "scp"} {
+		v, found := m.Find(types.String(name))
+		if !found {
+			continue
+		}
+		switch v := v.(type) {
+		case types.String:
+			for _, s := range strings.Fields(string(v)) {
+				add(s)
+			}
+		case traits.Lister:
+			it := v.Iterator()
+			for it.HasNext() == types.True {
+				if s, ok := it.Next().(types.String); ok {
+					add(string(s))
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func urlParse(s ref.Val) ref.Val {
+	u, err := url.Parse(string(s.(types.String)))
+	if err != nil {
+		return types.NewErr("url.parse: %v", err)
+	}
+	return types.NewStringStringMap(types.DefaultTypeAdapter, map[string]string{
+		"scheme":   u.Scheme,
+		"host":     u.Host,
+		"hostname": u.Hostname(),
+		"port":     u.Port(),
+		"path":     u.Path,
+		"query":    u.RawQuery,
+		"fragment": u.Fragment,
+	})
+}
+
+func mcpToolName(request ref.Val) ref.Val {
+	params, err := mcpParams(request)
+	if err != nil {
+		return types.NewErr("mcp.toolName: %v", err)
+	}
+	name, found := params.Find(types.String("name"))
+	if !found {
+		return types.NewErr("mcp.toolName: request has no tool name")
+	}
+	if _, ok := name.(types.String); !ok {
+		return types.NewErr("mcp.toolName: tool name must be a string, got %s", name.Type())
+	}
+	return name
+}
+
+// mcpParamsMap returns the map-valued field of request's params, or an empty
+// map when it is absent.
+func mcpParamsMap(request ref.Val, field string) ref.Val {
+	params, err := mcpParams(request)
+	if err != nil {
+		return types.NewErr("mcp.%s: %v", field, err)
+	}
+	return mapField(params, field)
+}
+
+// mcpParams returns a request's params, or the request itself when it has
+// none: callers may pass either the JSON-RPC request or its params.
+func mcpParams(request ref.Val) (traits.Mapper, error) {
+	m, ok := request.(traits.Mapper)
+	if !ok {
+		return nil, fmt.Errorf("request must be a map, got %s", request.Type())
+	}
+	if params, found := m.Find(types.String("params")); found {
+		if pm, ok := params.(traits.Mapper); ok {
+			return pm, nil
+		}
+	}
+	return m, nil
+}
+
+// mapField returns the map-valued field of v, or an empty map when it is
+// absent or null.
+func mapField(v ref.Val, field string) ref.Val {
+	m, ok := v.(traits.Mapper)
+	if !ok {
+		return types.NewErr("mcp.%s: value must be a map, got %s", field, v.Type())
+	}
+	f, found := m.Find(types.String(field))
+	if !found || f == types.NullValue {
+		return types.NewStringInterfaceMap(types.DefaultTypeAdapter, map[string]any{})
+	}
+	if _, ok := f.(traits.Mapper); !ok {
+		return types.NewErr("mcp.%s: %s must be a map, got %s", field, field, f.Type())
+	}
+	return f
+}
+
+// estimateSize returns the size of node, unknown when neither CEL nor the
+// estimator knows it.
+func estimateSize(estimator checker.CostEstimator, node checker.AstNode) checker.SizeEstimate {
+	if sz := node.ComputedSize(); sz != nil {
+		return *sz
+	}
+	if sz := estimator.EstimateSize(node); sz != nil {
+		return *sz
+	}
+	return checker.SizeEstimate{Min: 0, Max: math.MaxUint64}
+}
+
+// estimateGlob costs a match as the product of the pattern and string
+// lengths, the worst case of path.Match's backtracking on *.
+func estimateGlob(estimator checker.CostEstimator, _ *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	if len(args) != 2 {
+		return nil
+	}
+	product := estimateSize(estimator, args[0]).Multiply(estimateSize(estimator, args[1]))
+	cost := product.MultiplyByCostFactor(common.StringTraversalCostFactor).Add(checker.FixedCostEstimate(callCost))
+	return &checker.CallEstimate{CostEstimate: cost}
+}
+
+// estimateParse costs a call that parses its string arguments once.
+func estimateParse(estimator checker.CostEstimator, _ *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	cost := checker.FixedCostEstimate(parseCost)
+	for _, arg := range args {
+		cost = cost.Add(estimateSize(estimator, arg).MultiplyByCostFactor(common.StringTraversalCostFactor))
+	}
+	return &checker.CallEstimate{CostEstimate: cost}
+}
+
+// estimateIPInCIDRList costs the scan of the address and a prefix parse per
+// list element.
+func estimateIPInCIDRList(estimator checker.CostEstimator, _ *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	if len(args) != 2 {
+		return nil
+	}
+	cost := estimateSize(estimator, args[1]).MultiplyByCost(checker.FixedCostEstimate(parseCost)).
+		Add(estimateSize(estimator, args[0]).MultiplyByCostFactor(common.StringTraversalCostFactor)).
+		Add(checker.FixedCostEstimate(parseCost))
+	return &checker.CallEstimate{CostEstimate: cost}
+}
+
+// estimateJWTScopes costs the scan of the scope claims and the allocation of
+// the result list, both bounded by the size of the claims, so a size hint on
+// the claims bounds the call.
+func estimateJWTScopes(estimator checker.CostEstimator, _ *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	if len(args) == 0 {
+		return nil
+	}
+	size := estimateSize(estimator, args[0])
+	cost := size.MultiplyByCostFactor(common.StringTraversalCostFactor).
+		Add(checker.FixedCostEstimate(common.ListCreateBaseCost))
+	return &checker.CallEstimate{CostEstimate: cost, ResultSize: &size}
+}
+
+// estimateURLParse costs the scan of the URL and the allocation of the
+// result map, whose seven entries each hold at most the whole URL.
+func estimateURLParse(estimator checker.CostEstimator, _ *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	if len(args) != 1 {
+		return nil
+	}
+	cost := estimateSize(estimator, args[0]).MultiplyByCostFactor(common.StringTraversalCostFactor).
+		Add(checker.FixedCostEstimate(common.MapCreateBaseCost))
+	size := checker.FixedSizeEstimate(7)
+	return &checker.CallEstimate{CostEstimate: cost, ResultSize: &size}
+}
+
+// estimateLookup costs a map lookup, which does not copy.
+func estimateLookup(_ checker.CostEstimator, _ *checker.AstNode, _ []checker.AstNode) *checker.CallEstimate {
+	return &checker.CallEstimate{CostEstimate: checker.FixedCostEstimate(callCost)}
+}
+
+// actualSize returns the size of v, 1 when it has none.
+func actualSize(v ref.Val) uint64 {
+	if sz, ok := v.(traits.Sizer); ok {
+		if n, ok := sz.Size().(types.Int); ok && n > 0 {
+			return uint64(n)
+		}
+	}
+	return 1
+}
+
+// traversalCost returns the cost of scanning n characters.
+func traversalCost(n uint64) uint64 {
+	return uint64(math.Ceil(float64(n) * common.StringTraversalCostFactor))
+}
+
+func trackGlob(args []ref.Val, _ ref.Val) *uint64 {
+	c := traversalCost(actualSize(args[0])*actualSize(args[1])) + callCost
+	return &c
+}
+
+func trackParse(args []ref.Val, _ ref.Val) *uint64 {
+	c := uint64(parseCost)
+	for _, arg := range args {
+		if _, ok := arg.(traits.Lister); ok {
+			c += actualSize(arg) * parseCost
+			continue
+		}
+		c += traversalCost(actualSize(arg))
+	}
+	return &c
+}
+
+func trackJWTScopes(args []ref.Val, _ ref.Val) *uint64 {
+	c := uint64(common.ListCreateBaseCost)
+	if m, ok := args[0].(traits.Mapper); ok {
+		for _, name := range []string{"scope", "scp"} {
+			if v, found := m.Find(types.String(name)); found {
+				c += traversalCost(actualSize(v))
+			}
+		}
+	}
+	return &c
+}
+
+func trackURLParse(args []ref.Val, _ ref.Val) *uint64 {
+	c := traversalCost(actualSize(args[0])) + common.MapCreateBaseCost
+	return &c
+}
+
+func trackLookup(_ []ref.Val, _ ref.Val) *uint64 {
+	c := uint64(callCost)
+	return &c
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	celgo "cel.dev/cel-go/cel"
+	"cel.dev/cel-go/checker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+// newTestLibraryEngine declares the variables the library tests evaluate
+// against, with the ToolHive library.
+func newTestLibraryEngine() *cel.Engine {
+	return cel.NewEngine(
+		celgo.Variable(claimsVar, celgo.MapType(celgo.StringType, celgo.DynType)),
+		celgo.Variable("request", celgo.DynType),
+		celgo.Variable("tool", celgo.DynType),
+		celgo.Variable("s", celgo.StringType),
+		cel.Library(),
+		cel.Library(),
+	)
+}
+
+func TestLibrary(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestLibraryEngine()
+	ctx := map[string]any{
+		claimsVar: map[string]any{
+			"scope": "repo:read repo:write",
+			"scp":   []any{"repo:read", "admin", 7},
+		},
+		"request": map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "tools/call",
+			"params": map[string]any{
+				"name":      "github.create_issue",
+				"arguments": map[string]any{"title": "bug", "labels": []any{"p1"}},
+			},
+		},
+		"tool": map[string]any{
+			"name":        "github.delete_repo",
+			"annotations": map[string]any{"destructiveHint": true},
+		},
+		"s": "",
+	}
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`glob.match("github.*", "github.create_issue")`, true},
+		{`glob.match("github.*", "gitlab.create_issue")`, false},
+		{`glob.match("*_issue", mcp.toolName(request))`, true},
+		{`ip.inCIDR("10.1.2.3", "10.0.0.0/8")`, true},
+		{`ip.inCIDR("192.168.1.1", "10.0.0.0/8")`, false},
+		{`ip.inCIDR("::ffff:10.1.2.3", "10.0.0.0/8")`, true},
+		{`ip.inCIDR("2001:db8::1", ["10.0.0.0/8", "2001:db8::/32"])`, true},
+		{`ip.inCIDR("172.16.0.1", ["10.0.0.0/8", "2001:db8::/32"])`, false},
+		{`semver.compare("1.2.3", "v1.10.0")`, int64(-1)},
+		{`semver.compare("v2.0.0", "2.0.0")`, int64(0)},
+		{`semver.compare("1.0.0", "1.0.0-rc.1") > 0`, true},
+		{`jwt.scopes(claims)`, []string{"repo:read", "repo:write", "admin"}},
+		{`jwt.hasScope(claims, "admin")`, true},
+		{`jwt.hasScope(claims, "repo:delete")`, false},
+		{`jwt.scopes({})`, []string{}},
+		{`url.parse("https://api.example.com:8443/v1/x?a=1#top").hostname`, "api.example.com"},
+		{`url.parse("https://api.example.com:8443/v1/x?a=1#top").port`, "8443"},
+		{`url.parse("https://api.example.com/v1/x?a=1").path`, "/v1/x"},
+		{`url.parse("https://api.example.com/v1/x?a=1").query`, "a=1"},
+		{`mcp.toolName(request)`, "github.create_issue"},
+		{`mcp.toolName(request.params)`, "github.create_issue"},
+		{`mcp.arguments(request).title`, "bug"},
+		{`"p1" in mcp.arguments(request).labels`, true},
+		{`size(mcp.arguments({"params": {"name": "x"}}))`, int64(0)},
+		{`mcp.annotations(tool).destructiveHint`, true},
+		{`size(mcp.annotations({"name": "x"}))`, int64(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			expr, err := engine.Compile(tt.expr)
+			require.NoError(t, err)
+			got, err := expr.Evaluate(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLibrary_EvaluationErrors(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestLibraryEngine()
+	ctx := map[string]any{
+		claimsVar: map[string]any{},
+		"request": map[string]any{"params": map[string]any{"name": 3}},
+		"tool":    "not a map",
+		"s":       "",
+	}
+
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`glob.match("[", "x")`, "invalid pattern"},
+		{`ip.inCIDR("not-an-ip", "10.0.0.0/8")`, "ip.inCIDR"},
+		{`ip.inCIDR("10.0.0.1", "10.0.0.0")`, "ip.inCIDR"},
+		{`semver.compare("1.2", "banana")`, "invalid version"},
+		{`mcp.toolName(request)`, "must be a string"},
+		{`mcp.toolName({"params": {}})`, "no tool name"},
+		{`mcp.annotations(tool)`, "must be a map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			expr, err := engine.Compile(tt.expr)
+			require.NoError(t, err)
+			_, err = expr.Evaluate(ctx)
+			require.ErrorIs(t, err, cel.ErrEvaluation)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestLibrary_CostLimit checks that the library's functions are charged by
+// the size of their inputs, so the cost limit still bounds them.
+func TestLibrary_CostLimit(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestLibraryEngine().WithCostLimit(1000)
+	expr, err := engine.Compile(`glob.match("*a*a*a*a*b", s)`)
+	require.NoError(t, err)
+
+	ok, err := expr.EvaluateBool(map[string]any{claimsVar: map[string]any{}, "request": nil, "tool": nil, "s": "aaaa"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = expr.EvaluateBool(map[string]any{
+		claimsVar: map[string]any{}, "request": nil, "tool": nil, "s": strings.Repeat("a", 10000),
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, cel.ErrEvaluation))
+	assert.ErrorContains(t, err, "cost limit")
+}
+
+// boundedSizes is a checker.CostEstimator that bounds every variable's size.
+type boundedSizes uint64
+
+func (b boundedSizes) EstimateSize(node checker.AstNode) *checker.SizeEstimate {
+	if len(node.Path()) == 0 {
+		return nil
+	}
+	return &checker.SizeEstimate{Min: 0, Max: uint64(b)}
+}
+
+func (boundedSizes) EstimateCallCost(string, string, *checker.AstNode, []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+// TestLibrary_EstimateCoversActualCost checks that no function is charged
+// more at runtime than its static estimate allows, so an estimate checked
+// against WithCostLimit is not an undercount.
+func TestLibrary_EstimateCoversActualCost(t *testing.T) {
+	t.Parallel()
+
+	env, err := celgo.NewEnv(celgo.Variable("s", celgo.StringType), cel.Library())
+	require.NoError(t, err)
+	long := strings.Repeat("a", 5000)
+
+	for _, expr := range []string{
+		`glob.match("*a", s)`,
+		`ip.inCIDR(s, "10.0.0.0/8")`,
+		`ip.inCIDR(s, ["10.0.0.0/8", "192.168.0.0/16"])`,
+		`semver.compare(s, "1.0.0")`,
+		`url.parse(s)`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			t.Parallel()
+			ast, iss := env.Compile(expr)
+			require.NoError(t, iss.Err())
+			estimate, err := env.EstimateCost(ast, boundedSizes(len(long)))
+			require.NoError(t, err)
+
+			prg, err := env.Program(ast, celgo.EvalOptions(celgo.OptTrackCost))
+			require.NoError(t, err)
+			_, details, _ := prg.Eval(map[string]any{"s": long})
+			require.NotNil(t, details.ActualCost())
+			assert.GreaterOrEqual(t, estimate.Max, *details.ActualCost())
+		})
+	}
+}
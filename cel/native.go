@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cel.dev/cel-go/cel"
+	celtypes "cel.dev/cel-go/common/types"
+)
+
+// NativeTypes registers Go struct types as CEL object types, without
+// protobuf, so values of those types can be passed in the activation as they
+// are and their fields selected in expressions. Each argument is a value of
+// the type, a pointer to one, or its reflect.Type. Fields reachable from a
+// registered struct whose types are structs are registered too.
+//
+// A type is named in CEL by its package's last path element and its name, as
+// ObjectType reports: authn.Principal, say. Fields are named by their json
+// tag, falling back to the Go field name; fields tagged "-" and unexported
+// fields are hidden. A field whose type involves an interface, such as
+// authn.Principal.Claims, is dyn. An embedded struct is a field named after
+// its type, not flattened as encoding/json does.
+//
+//	engine := cel.NewEngine(
+//	    cel.NativeTypes(authn.Principal{}),
+//	    celgo.Variable("principal", cel.ObjectType(authn.Principal{})),
+//	)
+//	expr, err := engine.Compile(`principal.Subject == "alice"`)
+//	ok, err := expr.EvaluateBool(map[string]any{"principal": p})
+func NativeTypes(types ...any) cel.EnvOption {
+	args := make([]any, 0, len(types)+1)
+	dynFields := map[string]map[string]reflect.StructField{}
+	for _, t := range types {
+		rt, err := structType(t)
+		if err != nil {
+			return func(*cel.Env) (*cel.Env, error) { return nil, err }
+		}
+		args = append(args, rt)
+		collectDynFields(rt, dynFields)
+	}
+	args = append(args, celtypes.ParseStructField(jsonFieldName))
+
+	return func(env *cel.Env) (*cel.Env, error) {
+		provider, adapter, err := celtypes.ComposeTypes(env.CELTypeProvider(), env.CELTypeAdapter(), args...)
+		if err != nil {
+			return nil, err
+		}
+		env, err = cel.CustomTypeAdapter(adapter)(env)
+		if err != nil {
+			return nil, err
+		}
+		return cel.CustomTypeProvider(&nativeTypeProvider{Provider: provider, dynFields: dynFields})(env)
+	}
+}
+
+// nativeTypeProvider declares the fields of native types that cel-go cannot
+// type — an any, a map[string]any such as authn.Principal.Claims — as dyn
+// instead of hiding them. Values are read from the struct as cel-go reads
+// any other field.
+type nativeTypeProvider struct {
+	celtypes.Provider
+	// dynFields maps a CEL type name and field name to the struct field.
+	dynFields map[string]map[string]reflect.StructField
+}
+
+// FindStructFieldType implements types.Provider.
+func (p *nativeTypeProvider) FindStructFieldType(structType, fieldName string) (*celtypes.FieldType, bool) {
+	if ft, ok := p.Provider.FindStructFieldType(structType, fieldName); ok {
+		return ft, true
+	}
+	field, ok := p.dynFields[structType][fieldName]
+	if !ok {
+		return nil, false
+	}
+	value := func(obj any) reflect.Value {
+		v, err := reflect.Indirect(reflect.ValueOf(obj)).FieldByIndexErr(field.Index)
+		if err != nil {
+			return reflect.Value{}
+		}
+		return v
+	}
+	return &celtypes.FieldType{
+		Type: cel.DynType,
+		IsSet: func(obj any) bool {
+			v := value(obj)
+			return v.IsValid() && !v.IsZero()
+		},
+		GetFrom: func(obj any) (any, error) {
+			v := value(obj)
+			if !v.IsValid() {
+				return nil, nil
+			}
+			return v.Interface(), nil
+		},
+	}, true
+}
+
+// collectDynFields records, for rt and every struct type reachable from its
+// fields, the exported fields cel-go leaves untyped because an interface
+// appears in their type.
+func collectDynFields(rt reflect.Type, out map[string]map[string]reflect.StructField) {
+	name := objectTypeName(rt)
+	if _, seen := out[name]; seen {
+		return
+	}
+	fields := map[string]reflect.StructField{}
+	out[name] = fields
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if celName := jsonFieldName(f); celName != "-" && hasInterface(f.Type) {
+			fields[celName] = f
+		}
+		for _, st := range reachableStructs(f.Type) {
+			collectDynFields(st, out)
+		}
+	}
+}
+
+// hasInterface reports whether an interface appears in rt, short of a struct.
+func hasInterface(rt reflect.Type) bool {
+	switch rt.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return hasInterface(rt.Elem())
+	case reflect.Map:
+		return hasInterface(rt.Key()) || hasInterface(rt.Elem())
+	}
+	return false
+}
+
+// reachableStructs returns the named struct types rt is or contains.
+func reachableStructs(rt reflect.Type) []reflect.Type {
+	switch rt.Kind() {
+	case reflect.Struct:
+		if rt.Name() != "" {
+			return []reflect.Type{rt}
+		}
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return reachableStructs(rt.Elem())
+	case reflect.Map:
+		return append(reachableStructs(rt.Key()), reachableStructs(rt.Elem())...)
+	}
+	return nil
+}
+
+// ObjectType returns the CEL type NativeTypes registers for v's struct type,
+// for declaring a variable of that type. v is as for NativeTypes; it panics
+// if v is not a struct, like the cel-go declarations it is used among.
+func ObjectType(v any) *cel.Type {
+	rt, err := structType(v)
+	if err != nil {
+		panic(err)
+	}
+	return cel.ObjectType(objectTypeName(rt))
+}
+
+// objectTypeName is the name cel-go gives a native struct type.
+func objectTypeName(rt reflect.Type) string {
+	pkg := rt.PkgPath()
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + rt.Name()
+}
+
+// structType returns the named struct type v is, points to, or describes.
+func structType(v any) (reflect.Type, error) {
+	rt, ok := v.(reflect.Type)
+	if !ok {
+		rt = reflect.TypeOf(v)
+	}
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct || rt.Name() == "" {
+		return nil, fmt.Errorf("native type must be a named struct, got %v", rt)
+	}
+	return rt, nil
+}
+
+// jsonFieldName names a struct field in CEL by its json tag, as encoding/json
+// names it on the wire. cel-go skips fields named "-".
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// Activation converts v, a struct or a map, into the variables for
+// Evaluate, by way of its JSON form: each top-level field or key becomes a
+// variable, and every value becomes the maps, lists, strings, numbers, bools
+// and nulls CEL declares as dyn or map types. Custom marshalling, json tags
+// and embedded structs apply exactly as they do on the wire, so an
+// mcp.CallToolRequest is seen as the JSON-RPC request it is sent as:
+//
+//	vars, err := cel.Activation(map[string]any{"claims": principal.Claims, "request": req})
+//	ok, err := expr.EvaluateBool(vars)
+//
+// Whole numbers become int, others double. Values of types registered with
+// NativeTypes are converted like any other; to keep them typed, put them in
+// the activation directly instead.
+func Activation(v any) (map[string]any, error) {
+	val, err := ToValue(v)
+	if err != nil {
+		return nil, err
+	}
+	vars, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("activation must be a struct or map, got %T", v)
+	}
+	return vars, nil
+}
+
+// ToValue converts v to a CEL value by way of its JSON form, as Activation
+// converts each variable.
+func ToValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %T: %w", v, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to convert %T: %w", v, err)
+	}
+	return jsonValue(out), nil
+}
+
+// jsonValue replaces the json.Numbers in a decoded JSON value with int64 or
+// float64.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonValue(e)
+		}
+	}
+	return v
+}
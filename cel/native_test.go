@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel_test
+
+import (
+	"reflect"
+	"testing"
+
+	celgo "cel.dev/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/authn"
+	"github.com/stacklok/toolhive-core/cel"
+	"github.com/stacklok/toolhive-core/mcpcompat/mcp"
+)
+
+func TestEvaluateAs(t *testing.T) {
+	t.Parallel()
+
+	engine := cel.NewEngine(celgo.Variable(claimsVar, celgo.MapType(celgo.StringType, celgo.DynType)))
+	ctx := map[string]any{claimsVar: map[string]any{
+		"sub":    "alice",
+		"groups": []any{groupAdmins, groupUsers},
+		"level":  3,
+	}}
+	compile := func(t *testing.T, expr string) *cel.CompiledExpression {
+		t.Helper()
+		compiled, err := engine.Compile(expr)
+		require.NoError(t, err)
+		return compiled
+	}
+
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+		got, err := cel.EvaluateAs[string](compile(t, `claims["sub"]`), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", got)
+	})
+	t.Run("int", func(t *testing.T) {
+		t.Parallel()
+		got, err := cel.EvaluateAs[int](compile(t, `claims["level"] + 1`), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 4, got)
+	})
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+		got, err := cel.EvaluateAs[[]string](compile(t, `claims["groups"]`), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{groupAdmins, groupUsers}, got)
+	})
+	t.Run("map", func(t *testing.T) {
+		t.Parallel()
+		got, err := cel.EvaluateAs[map[string]any](compile(t, `{"user": claims["sub"], "admin": true}`), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"user": "alice", "admin": true}, got)
+	})
+	t.Run("any", func(t *testing.T) {
+		t.Parallel()
+		got, err := cel.EvaluateAs[any](compile(t, `claims["sub"]`), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", got)
+	})
+	t.Run("wrong type", func(t *testing.T) {
+		t.Parallel()
+		_, err := cel.EvaluateAs[int](compile(t, `claims["sub"]`), ctx)
+		require.ErrorIs(t, err, cel.ErrInvalidResult)
+		_, err = cel.EvaluateAs[[]string](compile(t, `[1, 2]`), ctx)
+		require.ErrorIs(t, err, cel.ErrInvalidResult)
+	})
+	t.Run("evaluation error", func(t *testing.T) {
+		t.Parallel()
+		_, err := cel.EvaluateAs[string](compile(t, `claims["missing"]`), ctx)
+		require.ErrorIs(t, err, cel.ErrEvaluation)
+	})
+}
+
+func TestNativeTypes(t *testing.T) {
+	t.Parallel()
+
+	engine := cel.NewEngine(
+		cel.NativeTypes(authn.Principal{}, &mcp.CallToolRequest{}),
+		celgo.Variable("principal", cel.ObjectType(authn.Principal{})),
+		celgo.Variable("request", cel.ObjectType(reflect.TypeFor[mcp.CallToolRequest]())),
+	)
+	ctx := map[string]any{
+		"principal": authn.Principal{
+			Issuer:  "https://idp.example.com",
+			Subject: "alice",
+			Claims:  map[string]any{"groups": []any{groupAdmins}},
+		},
+		"request": &mcp.CallToolRequest{
+			Request: mcp.Request{Method: "tools/call"},
+			Params:  mcp.CallToolParams{Name: "delete_repo", Arguments: map[string]any{"repo": "x"}},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`principal.Subject`, "alice"},
+		{`"admins" in principal.Claims["groups"]`, true},
+		{`request.params.name`, "delete_repo"},
+		{`request.params.arguments.repo`, "x"},
+		{`request.Request.method`, "tools/call"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			expr, err := engine.Compile(tt.expr)
+			require.NoError(t, err)
+			got, err := cel.EvaluateAs[any](expr, ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := engine.Compile(`has(request.Header)`)
+	require.Error(t, err, `fields tagged json:"-" are hidden`)
+
+	_, err = cel.NewEngine(cel.NativeTypes("not a struct")).Compile("true")
+	assert.ErrorContains(t, err, "named struct")
+	assert.Panics(t, func() { cel.ObjectType(42) })
+}
+
+func TestActivation(t *testing.T) {
+	t.Parallel()
+
+	engine := cel.NewEngine(
+		celgo.Variable("principal", celgo.DynType),
+		celgo.Variable("request", celgo.DynType),
+		cel.Library(),
+	)
+	expr, err := engine.Compile(
+		`principal.Subject == "alice" && principal.Claims.level >= 2 && ` +
+			`mcp.toolName(request) == "delete_repo" && request.method == "tools/call"`)
+	require.NoError(t, err)
+
+	vars, err := cel.Activation(struct {
+		Principal authn.Principal     `json:"principal"`
+		Request   mcp.CallToolRequest `json:"request"`
+	}{
+		Principal: authn.Principal{Subject: "alice", Claims: map[string]any{"level": 2}},
+		Request: mcp.CallToolRequest{
+			Request: mcp.Request{Method: "tools/call"},
+			Params:  mcp.CallToolParams{Name: "delete_repo"},
+		},
+	})
+	require.NoError(t, err)
+	ok, err := expr.EvaluateBool(vars)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	level, err := cel.ToValue(map[string]any{"n": 2, "f": 1.5})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"n": int64(2), "f": 1.5}, level)
+
+	_, err = cel.Activation([]string{"x"})
+	assert.ErrorContains(t, err, "struct or map")
+	_, err = cel.Activation(map[string]any{"ch": make(chan int)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"cel.dev/cel-go/cel"
+
+	"github.com/stacklok/toolhive-core/config"
+)
+
+// Effect is what a policy rule decides when its expression matches.
+type Effect string
+
+const (
+	// EffectAllow permits the request.
+	EffectAllow Effect = "allow"
+	// EffectDeny refuses the request.
+	EffectDeny Effect = "deny"
+)
+
+// CombiningAlgorithm decides how the rules of a PolicySet combine into one
+// decision.
+type CombiningAlgorithm string
+
+const (
+	// FirstMatch evaluates rules in priority order and decides by the first
+	// that matches.
+	FirstMatch CombiningAlgorithm = "first-match"
+	// DenyOverrides evaluates every rule: any matching deny rule denies, and
+	// otherwise any matching allow rule allows.
+	DenyOverrides CombiningAlgorithm = "deny-overrides"
+)
+
+// RuleConfig configures one rule of a PolicySet.
+type RuleConfig struct {
+	// Name identifies the rule in decisions and errors. Required and unique
+	// within the set.
+	Name string `yaml:"name" json:"name"`
+	// Description is a human-readable note on the rule's intent.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Effect is what the rule decides when it matches. Required.
+	Effect Effect `yaml:"effect" json:"effect"`
+	// Priority orders the rules: higher first, and rules of equal priority
+	// in the order they are listed.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+	// Expression is a CEL expression returning bool; the rule matches when
+	// it evaluates to true. Required.
+	Expression string `yaml:"expression" json:"expression"`
+}
+
+// PolicySetConfig configures a PolicySet. It is the YAML document
+// LoadPolicySet reads:
+//
+//	name: tools
+//	algorithm: deny-overrides
+//	defaultEffect: deny
+//	rules:
+//	  - name: admins
+//	    effect: allow
+//	    expression: '"admins" in claims["groups"]'
+//	  - name: no-delete
+//	    effect: deny
+//	    priority: 10
+//	    expression: 'tool.startsWith("delete_")'
+type PolicySetConfig struct {
+	// Name identifies the set in decisions.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Algorithm combines the rules. Empty uses FirstMatch.
+	Algorithm CombiningAlgorithm `yaml:"algorithm,omitempty" json:"algorithm,omitempty"`
+	// DefaultEffect is the decision when no rule matches. Empty uses
+	// EffectDeny.
+	DefaultEffect Effect `yaml:"defaultEffect,omitempty" json:"defaultEffect,omitempty"`
+	// Rules are the named rules. At least one is required.
+	Rules []RuleConfig `yaml:"rules" json:"rules"`
+}
+
+// PolicySet is a compiled set of named allow and deny rules. Build one with
+// NewPolicySet or LoadPolicySet; it is immutable and safe for concurrent
+// use.
+type PolicySet struct {
+	name          string
+	algorithm     CombiningAlgorithm
+	defaultEffect Effect
+	rules         []policyRule
+}
+
+// policyRule is a compiled RuleConfig.
+type policyRule struct {
+	RuleConfig
+	expr *CompiledExpression
+}
+
+// RuleError reports a rule that failed to evaluate.
+type RuleError struct {
+	Rule  string `json:"rule"`
+	Error string `json:"error"`
+}
+
+// Decision is the outcome of evaluating a PolicySet, with what produced it.
+type Decision struct {
+	// Effect is the decision.
+	Effect Effect `json:"effect"`
+	// Policy is the PolicySet's name.
+	Policy string `json:"policy,omitempty"`
+	// Rule is the name of the rule that decided, empty when the default
+	// effect applied.
+	Rule string `json:"rule,omitempty"`
+	// Expression is the deciding rule's expression.
+	Expression string `json:"expression,omitempty"`
+	// Reason explains the decision in a sentence, for logs and audit.
+	Reason string `json:"reason"`
+	// Matched lists, in evaluation order, every rule that matched. Under
+	// FirstMatch it holds the deciding rule only.
+	Matched []string `json:"matched,omitempty"`
+	// Errors lists the rules that failed to evaluate.
+	Errors []RuleError `json:"errors,omitempty"`
+}
+
+// Allowed reports whether the decision permits the request.
+func (d *Decision) Allowed() bool {
+	return d.Effect == EffectAllow
+}
+
+// NewPolicySet validates cfg and compiles every rule's expression with
+// engine, so a syntax or type error, or an expression that cannot return
+// bool, is a load-time failure. All invalid rules are reported together; a
+// compilation failure wraps the *ParseError or *CheckError so its location
+// detail is reachable with errors.As.
+func NewPolicySet(engine *Engine, cfg PolicySetConfig) (*PolicySet, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = FirstMatch
+	}
+	if cfg.DefaultEffect == "" {
+		cfg.DefaultEffect = EffectDeny
+	}
+	if cfg.Algorithm != FirstMatch && cfg.Algorithm != DenyOverrides {
+		return nil, fmt.Errorf("policy set %q: unknown combining algorithm %q", cfg.Name, cfg.Algorithm)
+	}
+	if !validEffect(cfg.DefaultEffect) {
+		return nil, fmt.Errorf("policy set %q: unknown default effect %q", cfg.Name, cfg.DefaultEffect)
+	}
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("policy set %q: at least one rule is required", cfg.Name)
+	}
+
+	ps := &PolicySet{name: cfg.Name, algorithm: cfg.Algorithm, defaultEffect: cfg.DefaultEffect}
+	seen := make(map[string]bool, len(cfg.Rules))
+	var errs []error
+	for i, rc := range cfg.Rules {
+		expr, err := compileRule(engine, rc, seen)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: %w", i, err))
+			continue
+		}
+		ps.rules = append(ps.rules, policyRule{RuleConfig: rc, expr: expr})
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("policy set %q: %w", cfg.Name, errors.Join(errs...))
+	}
+	slices.SortStableFunc(ps.rules, func(a, b policyRule) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	return ps, nil
+}
+
+// compileRule validates rc and compiles its expression.
+func compileRule(engine *Engine, rc RuleConfig, seen map[string]bool) (*CompiledExpression, error) {
+	switch {
+	case rc.Name == "":
+		return nil, errors.New("rule name is required")
+	case seen[rc.Name]:
+		return nil, fmt.Errorf("rule %q: duplicate rule name", rc.Name)
+	case !validEffect(rc.Effect):
+		return nil, fmt.Errorf("rule %q: unknown effect %q", rc.Name, rc.Effect)
+	case strings.TrimSpace(rc.Expression) == "":
+		return nil, fmt.Errorf("rule %q: expression is required", rc.Name)
+	}
+	seen[rc.Name] = true
+
+	expr, err := engine.Compile(rc.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", rc.Name, err)
+	}
+	if t := expr.outputType; t != nil && !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("rule %q: %w: expression returns %s, not bool",
+			rc.Name, ErrExpressionCheck, t)
+	}
+	return expr, nil
+}
+
+func validEffect(e Effect) bool {
+	return e == EffectAllow || e == EffectDeny
+}
+
+// LoadPolicySet reads a PolicySetConfig from the YAML file at path, strictly
+// as config.Load does, and compiles it with NewPolicySet.
+func LoadPolicySet(engine *Engine, path string) (*PolicySet, error) {
+	var cfg PolicySetConfig
+	if err := config.Load(path, &cfg); err != nil {
+		return nil, err
+	}
+	return NewPolicySet(engine, cfg)
+}
+
+// DecodePolicySet reads a PolicySetConfig from YAML, strictly as
+// config.Decode does, and compiles it with NewPolicySet.
+func DecodePolicySet(engine *Engine, r io.Reader) (*PolicySet, error) {
+	var cfg PolicySetConfig
+	if err := config.Decode(r, &cfg); err != nil {
+		return nil, err
+	}
+	return NewPolicySet(engine, cfg)
+}
+
+// Name returns the policy set's name.
+func (ps *PolicySet) Name() string {
+	return ps.name
+}
+
+// Evaluate decides on ctx, the variables the engine declared.
+//
+// A rule that fails to evaluate (indexing a missing key, say, or exceeding
+// the cost limit) is listed in Decision.Errors and fails closed: an allow
+// rule is taken not to match, and a deny rule to match. Guard optional
+// fields with has() or "in" so that errors stay exceptional.
+func (ps *PolicySet) Evaluate(ctx map[string]any) Decision {
+	d := Decision{Policy: ps.name}
+	var firstDeny, firstAllow *policyRule
+	for i := range ps.rules {
+		r := &ps.rules[i]
+		matched, err := r.expr.EvaluateBool(ctx)
+		if err != nil {
+			d.Errors = append(d.Errors, RuleError{Rule: r.Name, Error: err.Error()})
+			matched = r.Effect == EffectDeny
+		}
+		if !matched {
+			continue
+		}
+		d.Matched = append(d.Matched, r.Name)
+
+		if ps.algorithm == FirstMatch {
+			ps.decide(&d, r, err)
+			return d
+		}
+		if r.Effect == EffectDeny && firstDeny == nil {
+			firstDeny = r
+		}
+		if r.Effect == EffectAllow && firstAllow == nil {
+			firstAllow = r
+		}
+	}
+
+	switch {
+	case firstDeny != nil:
+		ps.decide(&d, firstDeny, ruleErr(d.Errors, firstDeny.Name))
+	case firstAllow != nil:
+		ps.decide(&d, firstAllow, nil)
+	default:
+		d.Effect = ps.defaultEffect
+		d.Reason = fmt.Sprintf("no rule matched; default effect %s", ps.defaultEffect)
+	}
+	return d
+}
+
+// decide records r as the deciding rule.
+func (*PolicySet) decide(d *Decision, r *policyRule, evalErr error) {
+	d.Effect = r.Effect
+	d.Rule = r.Name
+	d.Expression = r.Expression
+	if evalErr != nil {
+		d.Reason = fmt.Sprintf("rule %q (%s, priority %d) failed to evaluate and fails closed", r.Name, r.Effect, r.Priority)
+		return
+	}
+	d.Reason = fmt.Sprintf("rule %q (%s, priority %d) matched", r.Name, r.Effect, r.Priority)
+}
+
+// ruleErr returns an error for the rule when it is listed in errs.
+func ruleErr(errs []RuleError, name string) error {
+	for _, e := range errs {
+		if e.Rule == name {
+			return errors.New(e.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	celgo "cel.dev/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+// newTestPolicyEngine declares the claims and tool variables policy rules see.
+func newTestPolicyEngine() *cel.Engine {
+	return cel.NewEngine(
+		celgo.Variable(claimsVar, celgo.MapType(celgo.StringType, celgo.DynType)),
+		celgo.Variable("tool", celgo.StringType),
+	)
+}
+
+const testPolicyYAML = `
+name: tools
+algorithm: %s
+rules:
+  - name: admins
+    effect: allow
+    expression: '"admins" in claims["groups"]'
+  - name: readers
+    effect: allow
+    expression: 'tool.startsWith("get_")'
+  - name: no-delete
+    effect: deny
+    priority: 10
+    description: nobody deletes through the gateway
+    expression: 'tool.startsWith("delete_")'
+  - name: tenant
+    effect: deny
+    priority: -1
+    expression: 'claims["tenant"] != "acme"'
+`
+
+func newTestPolicySet(t *testing.T, algorithm cel.CombiningAlgorithm) *cel.PolicySet {
+	t.Helper()
+	ps, err := cel.DecodePolicySet(newTestPolicyEngine(),
+		strings.NewReader(strings.Replace(testPolicyYAML, "%s", string(algorithm), 1)))
+	require.NoError(t, err)
+	return ps
+}
+
+func TestPolicySet_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	admin := map[string]any{claimGroups: []any{groupAdmins}, "tenant": "acme"}
+	user := map[string]any{claimGroups: []any{groupUsers}, "tenant": "acme"}
+	outsider := map[string]any{claimGroups: []any{groupAdmins}, "tenant": "other"}
+
+	tests := []struct {
+		name        string
+		algorithm   cel.CombiningAlgorithm
+		claims      map[string]any
+		tool        string
+		wantEffect  cel.Effect
+		wantRule    string
+		wantMatched []string
+		wantErrors  int
+	}{
+		{
+			name: "first match: higher priority deny wins", algorithm: cel.FirstMatch,
+			claims: admin, tool: "delete_repo",
+			wantEffect: cel.EffectDeny, wantRule: "no-delete", wantMatched: []string{"no-delete"},
+		},
+		{
+			name: "first match: earlier listed allow wins", algorithm: cel.FirstMatch,
+			claims: outsider, tool: "get_repo",
+			wantEffect: cel.EffectAllow, wantRule: "admins", wantMatched: []string{"admins"},
+		},
+		{
+			name: "first match: default deny", algorithm: cel.FirstMatch,
+			claims: user, tool: "create_issue",
+			wantEffect: cel.EffectDeny,
+		},
+		{
+			name: "deny overrides: lower priority deny beats allow", algorithm: cel.DenyOverrides,
+			claims: outsider, tool: "get_repo",
+			wantEffect: cel.EffectDeny, wantRule: "tenant", wantMatched: []string{"admins", "readers", "tenant"},
+		},
+		{
+			name: "deny overrides: allow", algorithm: cel.DenyOverrides,
+			claims: user, tool: "get_repo",
+			wantEffect: cel.EffectAllow, wantRule: "readers", wantMatched: []string{"readers"},
+		},
+		{
+			name: "deny overrides: failing deny rule fails closed", algorithm: cel.DenyOverrides,
+			claims: map[string]any{claimGroups: []any{groupAdmins}}, tool: "get_repo",
+			wantEffect: cel.EffectDeny, wantRule: "tenant", wantMatched: []string{"admins", "readers", "tenant"}, wantErrors: 1,
+		},
+		{
+			name: "failing allow rule does not match", algorithm: cel.FirstMatch,
+			claims: map[string]any{"tenant": "acme"}, tool: "create_issue",
+			wantEffect: cel.EffectDeny, wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ps := newTestPolicySet(t, tt.algorithm)
+			d := ps.Evaluate(map[string]any{claimsVar: tt.claims, "tool": tt.tool})
+
+			assert.Equal(t, tt.wantEffect, d.Effect)
+			assert.Equal(t, tt.wantEffect == cel.EffectAllow, d.Allowed())
+			assert.Equal(t, "tools", d.Policy)
+			assert.Equal(t, tt.wantRule, d.Rule)
+			assert.Equal(t, tt.wantMatched, d.Matched)
+			assert.Len(t, d.Errors, tt.wantErrors)
+			if tt.wantRule == "" {
+				assert.Equal(t, "no rule matched; default effect deny", d.Reason)
+			} else {
+				assert.Contains(t, d.Reason, tt.wantRule)
+				assert.NotEmpty(t, d.Expression)
+			}
+		})
+	}
+}
+
+func TestNewPolicySet_Errors(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestPolicyEngine()
+	rule := func(name string, effect cel.Effect, expr string) cel.RuleConfig {
+		return cel.RuleConfig{Name: name, Effect: effect, Expression: expr}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     cel.PolicySetConfig
+		wantErr []string
+	}{
+		{name: "no rules", cfg: cel.PolicySetConfig{}, wantErr: []string{"at least one rule"}},
+		{
+			name:    "unknown algorithm",
+			cfg:     cel.PolicySetConfig{Algorithm: "permit-overrides", Rules: []cel.RuleConfig{rule("a", cel.EffectAllow, "true")}},
+			wantErr: []string{"permit-overrides"},
+		},
+		{
+			name:    "unknown default",
+			cfg:     cel.PolicySetConfig{DefaultEffect: "maybe", Rules: []cel.RuleConfig{rule("a", cel.EffectAllow, "true")}},
+			wantErr: []string{"maybe"},
+		},
+		{
+			name: "every invalid rule reported",
+			cfg: cel.PolicySetConfig{Rules: []cel.RuleConfig{
+				rule("", cel.EffectAllow, "true"),
+				rule("a", "permit", "true"),
+				rule("b", cel.EffectAllow, " "),
+				rule("c", cel.EffectAllow, `tool ==`),
+				rule("c", cel.EffectAllow, "true"),
+				rule("d", cel.EffectDeny, `tool + "x"`),
+			}},
+			wantErr: []string{
+				"rules[0]: rule name is required",
+				`rules[1]: rule "a": unknown effect "permit"`,
+				`rules[2]: rule "b": expression is required`,
+				`rules[3]: rule "c"`,
+				`rules[4]: rule "c": duplicate`,
+				`rules[5]: rule "d"`, "not bool",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := cel.NewPolicySet(engine, tt.cfg)
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+
+	_, err := cel.NewPolicySet(engine, cel.PolicySetConfig{Rules: []cel.RuleConfig{rule("a", cel.EffectAllow, `undefined == 1`)}})
+	var checkErr *cel.CheckError
+	assert.True(t, errors.As(err, &checkErr), "expected CheckError, got %T", err)
+}
+
+func TestLoadPolicySet(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(testPolicyYAML, "%s", "first-match", 1)), 0o600))
+
+	ps, err := cel.LoadPolicySet(newTestPolicyEngine(), path)
+	require.NoError(t, err)
+	assert.Equal(t, "tools", ps.Name())
+
+	require.NoError(t, os.WriteFile(path, []byte("rules: []\nunknown: true\n"), 0o600))
+	_, err = cel.LoadPolicySet(newTestPolicyEngine(), path)
+	assert.ErrorContains(t, err, "unknown", "decoding is strict")
+}
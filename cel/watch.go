@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stacklok/toolhive-core/config"
+)
+
+// defaultWatchPollInterval is how often a Watcher checks its file for changes
+// when WatchConfig.PollInterval is zero.
+const defaultWatchPollInterval = 30 * time.Second
+
+// WatchConfig configures a Watcher.
+type WatchConfig struct {
+	// Path is the YAML file the expressions are read from.
+	Path string
+
+	// PollInterval is how often Path is re-read. Zero uses 30s; negative is
+	// an error. A change is detected from file content, not modification
+	// times, so an update that preserves mtime (a ConfigMap, say) is still
+	// seen.
+	PollInterval time.Duration
+
+	// Logger receives a warning when a reload fails. Nil uses slog.Default().
+	Logger *slog.Logger
+}
+
+// ExpressionsConfig is the file format WatchExpressions reads: named
+// expressions, for a caller that evaluates them itself.
+//
+//	expressions:
+//	  is-admin: '"admins" in claims["groups"]'
+//	  read-only: 'tool.startsWith("get_")'
+type ExpressionsConfig struct {
+	Expressions map[string]string `yaml:"expressions" json:"expressions"`
+}
+
+// Watcher holds a value compiled from a file and recompiles it when the file
+// changes, without fsnotify. The new value replaces the old one only if
+// everything in the file compiles: a reload that fails — a half-written file,
+// a YAML error, one invalid expression among many — is logged and the
+// previous value stays in service, so an invalid edit never takes a running
+// policy down. Only construction requires a clean load.
+//
+// It is safe for concurrent use.
+type Watcher[T any] struct {
+	path   string
+	logger *slog.Logger
+	load   func([]byte) (T, error)
+
+	// current is the last successfully compiled value, swapped whole on
+	// reload so Current never takes a lock on the request path.
+	current atomic.Pointer[T]
+
+	// reloadMu serializes reloads (the poll loop and Reload) and guards
+	// digest, the hash of the file content current was compiled from.
+	reloadMu sync.Mutex
+	digest   [sha256.Size]byte
+
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// WatchExpressions compiles the expressions in the ExpressionsConfig file at
+// cfg.Path with engine, keyed by name, and recompiles them when it changes.
+//
+// ctx governs the lifetime of the polling goroutine: it stops when ctx is
+// canceled or Close is called.
+func WatchExpressions(
+	ctx context.Context, engine *Engine, cfg WatchConfig,
+) (*Watcher[map[string]*CompiledExpression], error) {
+	return newWatcher(ctx, cfg, func(data []byte) (map[string]*CompiledExpression, error) {
+		var ec ExpressionsConfig
+		if err := config.Decode(bytes.NewReader(data), &ec); err != nil {
+			return nil, err
+		}
+		return compileExpressions(engine, ec.Expressions)
+	})
+}
+
+// WatchPolicySet builds a PolicySet from the PolicySetConfig file at
+// cfg.Path with engine, as LoadPolicySet does, and rebuilds it when it
+// changes.
+//
+// ctx governs the lifetime of the polling goroutine: it stops when ctx is
+// canceled or Close is called.
+func WatchPolicySet(ctx context.Context, engine *Engine, cfg WatchConfig) (*Watcher[*PolicySet], error) {
+	return newWatcher(ctx, cfg, func(data []byte) (*PolicySet, error) {
+		return DecodePolicySet(engine, bytes.NewReader(data))
+	})
+}
+
+func newWatcher[T any](ctx context.Context, cfg WatchConfig, load func([]byte) (T, error)) (*Watcher[T], error) {
+	if cfg.Path == "" {
+		return nil, errors.New("watch path is required")
+	}
+	if cfg.PollInterval < 0 {
+		return nil, fmt.Errorf("watch poll interval must not be negative: %s", cfg.PollInterval)
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultWatchPollInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	w := &Watcher[T]{path: cfg.Path, logger: cfg.Logger, load: load}
+	if err := w.reload(); err != nil {
+		return nil, err
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	go w.poll(ctx, cfg.PollInterval)
+	return w, nil
+}
+
+// Current returns the value from the last successful load.
+func (w *Watcher[T]) Current() T {
+	return *w.current.Load()
+}
+
+// Reload re-reads the file immediately instead of waiting for the next poll,
+// for a caller that knows it just changed (a SIGHUP handler, say). On failure
+// the previous value stays in service and the error is returned.
+func (w *Watcher[T]) Reload() error {
+	return w.reload()
+}
+
+// Close stops polling. It is idempotent; Current keeps serving the last
+// loaded value afterwards.
+func (w *Watcher[T]) Close() {
+	w.closeOnce.Do(w.cancel)
+}
+
+// poll reloads the file every interval until ctx is done.
+func (w *Watcher[T]) poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.reload(); err != nil {
+				w.logger.WarnContext(ctx, "expression file reload failed; keeping previous expressions",
+					slog.String("path", w.path), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// reload reads the file and, when its content changed since the last good
+// load, compiles and installs the new value.
+//
+// The digest is recorded only after a successful compile, so content that
+// failed is retried on the next poll rather than remembered as current.
+func (w *Watcher[T]) reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	data, err := os.ReadFile(w.path) //#nosec G304 -- path is caller-provided config location, not user input
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", w.path, err)
+	}
+	digest := sha256.Sum256(data)
+	if w.current.Load() != nil && digest == w.digest {
+		return nil
+	}
+	v, err := w.load(data)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", w.path, err)
+	}
+	w.current.Store(&v)
+	w.digest = digest
+	return nil
+}
+
+// compileExpressions compiles every expression, reporting all that fail
+// rather than the first, in name order.
+func compileExpressions(engine *Engine, exprs map[string]string) (map[string]*CompiledExpression, error) {
+	compiled := make(map[string]*CompiledExpression, len(exprs))
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(exprs)) {
+		if strings.TrimSpace(exprs[name]) == "" {
+			errs = append(errs, fmt.Errorf("expression %q: expression is required", name))
+			continue
+		}
+		expr, err := engine.Compile(exprs[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expression %q: %w", name, err))
+			continue
+		}
+		compiled[name] = expr
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cel_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	celgo "cel.dev/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/cel"
+)
+
+func writeWatchFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestWatchExpressions(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "expressions.yaml")
+	writeWatchFile(t, path, "expressions:\n  small: 'x < 10'\n")
+
+	engine := cel.NewEngine(celgo.Variable("x", celgo.IntType))
+	w, err := cel.WatchExpressions(context.Background(), engine, cel.WatchConfig{
+		Path:         path,
+		PollInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(w.Close)
+
+	eval := func(name string, x int) bool {
+		expr, ok := w.Current()[name]
+		require.True(t, ok, "expression %q", name)
+		got, err := expr.EvaluateBool(map[string]any{"x": x})
+		require.NoError(t, err)
+		return got
+	}
+	assert.True(t, eval("small", 5))
+
+	// A change is picked up by the poll loop.
+	writeWatchFile(t, path, "expressions:\n  small: 'x < 3'\n  big: 'x > 100'\n")
+	require.Eventually(t, func() bool { return len(w.Current()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, eval("small", 5))
+	assert.True(t, eval("big", 101))
+
+	// One invalid expression keeps every previous expression in service.
+	writeWatchFile(t, path, "expressions:\n  small: 'x < 1'\n  big: 'x >'\n  empty: ' '\n")
+	err = w.Reload()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `expression "big"`)
+	assert.ErrorContains(t, err, `expression "empty": expression is required`)
+	assert.Len(t, w.Current(), 2)
+	assert.True(t, eval("small", 2), "x < 3 still in service")
+
+	// So does an unreadable file.
+	writeWatchFile(t, path, "expressions: [\n")
+	require.Error(t, w.Reload())
+	require.NoError(t, os.Remove(path))
+	require.Error(t, w.Reload())
+	assert.Len(t, w.Current(), 2)
+
+	// Fixing the file recovers.
+	writeWatchFile(t, path, "expressions:\n  small: 'x < 1'\n")
+	require.NoError(t, w.Reload())
+	assert.Len(t, w.Current(), 1)
+
+	w.Close()
+	w.Close()
+	assert.Len(t, w.Current(), 1, "Current keeps serving after Close")
+}
+
+func TestWatchPolicySet(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writeWatchFile(t, path, strings.Replace(testPolicyYAML, "%s", "first-match", 1))
+
+	w, err := cel.WatchPolicySet(context.Background(), newTestPolicyEngine(), cel.WatchConfig{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(w.Close)
+
+	ctx := map[string]any{claimsVar: map[string]any{claimGroups: []any{groupUsers}, "tenant": "acme"}, "tool": "get_repo"}
+	d := w.Current().Evaluate(ctx)
+	assert.True(t, d.Allowed())
+
+	// An invalid edit is rejected whole.
+	writeWatchFile(t, path, "rules:\n  - name: all\n    effect: allow\n    expression: 'true'\n  - name: bad\n    effect: deny\n    expression: 'tool =='\n")
+	require.Error(t, w.Reload())
+	assert.Equal(t, "tools", w.Current().Name())
+
+	writeWatchFile(t, path, "name: locked\nrules:\n  - name: nothing\n    effect: deny\n    expression: 'true'\n")
+	require.NoError(t, w.Reload())
+	assert.Equal(t, "locked", w.Current().Name())
+	d = w.Current().Evaluate(ctx)
+	assert.False(t, d.Allowed())
+}
+
+func TestWatch_ConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	engine := newTestPolicyEngine()
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	writeWatchFile(t, invalid, "expressions:\n  a: 'tool =='\n")
+
+	tests := []struct {
+		name    string
+		cfg     cel.WatchConfig
+		wantErr string
+	}{
+		{name: "no path", cfg: cel.WatchConfig{}, wantErr: "path is required"},
+		{name: "negative interval", cfg: cel.WatchConfig{Path: invalid, PollInterval: -time.Second}, wantErr: "negative"},
+		{name: "missing file", cfg: cel.WatchConfig{Path: filepath.Join(dir, "missing.yaml")}, wantErr: "missing.yaml"},
+		{name: "invalid expression", cfg: cel.WatchConfig{Path: invalid}, wantErr: `expression "a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := cel.WatchExpressions(context.Background(), engine, tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
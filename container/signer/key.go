@@ -65,6 +65,19 @@ func resolveKeyPath(path string) (string, error) {
 // decrypted with the COSIGN_PASSWORD environment variable, matching the
 // cosign CLI's behavior.
 func loadKeypair(path string) (sign.Keypair, error) {
+	signerKey, err := LoadPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileKeypair{priv: signerKey}, nil
+}
+
+// LoadPrivateKey reads the cosign PEM private key at path — the file
+// Options.Key names — and returns it as a crypto.Signer. It accepts the same
+// formats as signing does, decrypting with COSIGN_PASSWORD, so other signing
+// uses (audit-log checkpoints, for one) can share the key an operator already
+// manages for artifacts rather than provisioning another.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
 	resolved, err := resolveKeyPath(path)
 	if err != nil {
 		return nil, err
@@ -81,7 +94,7 @@ func loadKeypair(path string) (sign.Keypair, error) {
 	if !ok {
 		return nil, fmt.Errorf("signing key type %T cannot sign", priv)
 	}
-	return &fileKeypair{priv: signerKey}, nil
+	return signerKey, nil
 }
 
 // decodePrivateKeyPEM decodes a signing key, accepting both the format the
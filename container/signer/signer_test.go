@@ -311,6 +311,21 @@ func TestFileKeypairMetadata(t *testing.T) {
 	assert.NotNil(t, kp.GetPublicKey())
 }
 
+func TestLoadPrivateKey(t *testing.T) {
+	t.Parallel()
+	keyPath, pubPEM := writeTestKey(t)
+	key, err := LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+	gotPEM, err := cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	require.NoError(t, err)
+	assert.Equal(t, pubPEM, gotPEM)
+
+	_, err = LoadPrivateKey("")
+	assert.ErrorIs(t, err, ErrKeyRequired)
+	_, err = LoadPrivateKey(writeGarbageKey(t))
+	assert.Error(t, err)
+}
+
 func TestResolveKeyPath(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	gosdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/stacklok/toolhive-core/audit"
+	mcp "github.com/stacklok/toolhive-core/mcpcompat/mcp"
+)
+
+// Transport names recorded under audit.MetadataExtraKeyTransport.
+const (
+	transportStdio          = "stdio"
+	transportStreamableHTTP = "streamable-http"
+	transportSSE            = "sse"
+)
+
+// AuditConfig configures WithAudit.
+type AuditConfig struct {
+	// Hook receives the event for every audited method. It runs
+	// synchronously on the dispatch path, after the method is handled and
+	// before its response is sent, so it should hand the event off — to an
+	// audit.Dispatcher, say — rather than do slow work itself. Required:
+	// WithAudit with a nil Hook audits nothing. authn.AuditToLogger fits.
+	Hook func(ctx context.Context, event *audit.AuditEvent)
+
+	// Component is the event's Component. Empty uses the server name.
+	Component string
+
+	// IncludeMethods, when non-empty, limits auditing to the JSON-RPC methods
+	// it matches; ExcludeMethods then removes methods from what is left.
+	// Entries are path.Match patterns, so "notifications/*" matches every
+	// notification; a malformed pattern matches nothing. A typical exclusion
+	// is {"ping", "notifications/*"}.
+	IncludeMethods []string
+	ExcludeMethods []string
+
+	// Identity returns the caller's Subjects and DelegationChain from the
+	// request context. authn.AuditIdentity reads them from the authn
+	// Principal. Nil records only the client name and version.
+	Identity func(ctx context.Context) (map[string]string, *audit.DelegationChain)
+
+	// IncludeRequestData records the method's params (tool arguments, for
+	// tools/call) as the event's Data. Params routinely carry secrets: pair
+	// it with an audit.Redactor in Hook.
+	IncludeRequestData bool
+}
+
+// WithAudit emits an audit event for every JSON-RPC method the server
+// handles, on every transport. Each event carries:
+//
+//   - Type: the audit.EventTypeMCP* type for the method, or
+//     audit.EventTypeMCPRequest for one without its own.
+//   - Outcome: audit.OutcomeSuccess; audit.OutcomeFailure for a JSON-RPC
+//     error response (unknown tool, invalid params) or a tool result with
+//     isError set; audit.OutcomeError for any other error.
+//   - Subjects: AuditConfig.Identity's, with the client name and version the
+//     session sent in initialize.
+//   - DelegationChain: AuditConfig.Identity's.
+//   - Source: the remote address for the HTTP transports, with the
+//     User-Agent and session ID; "stdio" for stdio.
+//   - Target: the method, and the tool or prompt name or resource URI it
+//     acted on.
+//   - Metadata.Extra: the negotiated protocol version, the transport, the
+//     handling time and the size of the JSON result.
+//
+// Requests refused before dispatch, by a CallGate or for an unknown session,
+// never reach the server and are not audited here.
+func WithAudit(cfg AuditConfig) ServerOption {
+	return func(s *MCPServer) {
+		if cfg.Hook == nil {
+			s.audit = nil
+			return
+		}
+		if cfg.Component == "" {
+			cfg.Component = s.name
+		}
+		s.audit = &cfg
+	}
+}
+
+// audited reports whether cfg covers method.
+func (cfg *AuditConfig) audited(method string) bool {
+	if len(cfg.IncludeMethods) > 0 && !matchMethod(cfg.IncludeMethods, method) {
+		return false
+	}
+	return !matchMethod(cfg.ExcludeMethods, method)
+}
+
+// matchMethod reports whether any of patterns matches method.
+func matchMethod(patterns []string, method string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, method); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// transportInfo records how a request reached the server, for audit
+// events. The transports put it on the context they dispatch with; for
+// stateful HTTP sessions the per-POST copy, bridged by
+// sessionDispatchMiddleware, takes precedence over the initialize-time one.
+type transportInfo struct {
+	name       string
+	remoteAddr string
+}
+
+type transportInfoKey struct{}
+
+// transportContext returns ctx recording that requests arrive over the named
+// transport from remoteAddr (host:port, or empty), when the server audits.
+func (s *MCPServer) transportContext(ctx context.Context, name, remoteAddr string) context.Context {
+	if s.audit == nil {
+		return ctx
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return context.WithValue(ctx, transportInfoKey{}, transportInfo{name: name, remoteAddr: host})
+}
+
+// withTransportInfo is transportContext for an HTTP transport's request.
+func (s *MCPServer) withTransportInfo(r *http.Request, name string) *http.Request {
+	if s.audit == nil {
+		return r
+	}
+	return r.WithContext(s.transportContext(r.Context(), name, r.RemoteAddr))
+}
+
+// auditMethods maps JSON-RPC methods to their audit event types. Other
+// notifications are audit.EventTypeMCPNotification, and other requests
+// audit.EventTypeMCPRequest.
+var auditMethods = map[string]string{
+	string(mcp.MethodInitialize):             audit.EventTypeMCPInitialize,
+	string(mcp.MethodPing):                   audit.EventTypeMCPPing,
+	string(mcp.MethodToolsList):              audit.EventTypeMCPToolsList,
+	string(mcp.MethodToolsCall):              audit.EventTypeMCPToolCall,
+	string(mcp.MethodResourcesList):          audit.EventTypeMCPResourcesList,
+	string(mcp.MethodResourcesTemplatesList): audit.EventTypeMCPResourcesList,
+	string(mcp.MethodResourcesRead):          audit.EventTypeMCPResourceRead,
+	string(mcp.MethodPromptsList):            audit.EventTypeMCPPromptsList,
+	string(mcp.MethodPromptsGet):             audit.EventTypeMCPPromptGet,
+	string(mcp.MethodSetLogLevel):            audit.EventTypeMCPLogging,
+	string(mcp.MethodComplete):               audit.EventTypeMCPCompletion,
+	"notifications/roots/list_changed":       audit.EventTypeMCPRootsListChanged,
+}
+
+// auditEventType returns the audit event type for method.
+func auditEventType(method string) string {
+	if t, ok := auditMethods[method]; ok {
+		return t
+	}
+	if strings.HasPrefix(method, "notifications/") {
+		return audit.EventTypeMCPNotification
+	}
+	return audit.EventTypeMCPRequest
+}
+
+// auditDispatch emits the audit event for one handled method. It runs in
+// sessionDispatchMiddleware after the handler has returned res and err.
+func (s *MCPServer) auditDispatch(
+	ctx context.Context, method string, req gosdk.Request, res gosdk.Result, err error, elapsed time.Duration,
+) {
+	cfg := s.audit
+	if cfg == nil || !cfg.audited(method) {
+		return
+	}
+
+	subjects := map[string]string{}
+	var chain *audit.DelegationChain
+	if cfg.Identity != nil {
+		var ids map[string]string
+		ids, chain = cfg.Identity(ctx)
+		maps.Copy(subjects, ids)
+	}
+
+	// The session's initialize params carry the client and protocol version;
+	// for initialize itself they are only recorded once the handler has run,
+	// which it has.
+	var initParams *gosdk.InitializeParams
+	ss, _ := req.GetSession().(*gosdk.ServerSession)
+	if ss != nil {
+		initParams = ss.InitializeParams()
+	}
+	if p, ok := req.GetParams().(*gosdk.InitializeParams); ok && p != nil {
+		initParams = p
+	}
+	protocolVersion := ""
+	if initParams != nil {
+		if initParams.ClientInfo != nil {
+			subjects[audit.SubjectKeyClientName] = initParams.ClientInfo.Name
+			subjects[audit.SubjectKeyClientVersion] = initParams.ClientInfo.Version
+		}
+		protocolVersion = initParams.ProtocolVersion
+	}
+	if r, ok := res.(*gosdk.InitializeResult); ok && r != nil {
+		protocolVersion = r.ProtocolVersion
+	}
+
+	info, _ := ctx.Value(transportInfoKey{}).(transportInfo)
+	if re := req.GetExtra(); re != nil && re.Header != nil {
+		if v := re.Header.Get(mcpProtocolVersionHeader); v != "" {
+			protocolVersion = v
+		}
+	}
+
+	event := audit.NewAuditEvent(
+		auditEventType(method), auditSource(info, req, ss), auditOutcome(res, err), subjects, cfg.Component,
+	).
+		WithTarget(auditTarget(method, req)).
+		WithDelegationChain(chain)
+
+	event.Metadata.Extra = map[string]any{
+		audit.MetadataExtraKeyDuration: elapsed.Milliseconds(),
+	}
+	if info.name != "" {
+		event.Metadata.Extra[audit.MetadataExtraKeyTransport] = info.name
+	}
+	if protocolVersion != "" {
+		event.Metadata.Extra[audit.MetadataExtraKeyMCPVersion] = protocolVersion
+	}
+	if size, ok := responseSize(res); ok {
+		event.Metadata.Extra[audit.MetadataExtraKeyResponseSize] = size
+	}
+
+	if cfg.IncludeRequestData {
+		if params := req.GetParams(); params != nil {
+			if data, err := json.Marshal(params); err == nil {
+				raw := json.RawMessage(data)
+				event.WithData(&raw)
+			}
+		}
+	}
+
+	cfg.Hook(ctx, event)
+}
+
+// responseSize returns the length of res's JSON encoding. It encodes res into
+// a byteCounter, so unlike json.Marshal it does not return a copy of a large
+// tool result only to take its length.
+func responseSize(res gosdk.Result) (int, bool) {
+	if res == nil {
+		return 0, false
+	}
+	var n byteCounter
+	if err := json.NewEncoder(&n).Encode(res); err != nil {
+		return 0, false
+	}
+	// Encode terminates the value with a newline that is not part of it.
+	return int(n) - 1, true
+}
+
+// byteCounter is an io.Writer that counts the bytes written to it.
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// auditSource returns the event's Source: the remote address for a network
+// transport and the transport's name for a local one, with the User-Agent
+// and session ID when there are any.
+func auditSource(info transportInfo, req gosdk.Request, ss *gosdk.ServerSession) audit.EventSource {
+	source := audit.EventSource{Type: audit.SourceTypeLocal, Value: cmp.Or(info.name, "unknown")}
+	if info.remoteAddr != "" {
+		source = audit.EventSource{Type: audit.SourceTypeNetwork, Value: info.remoteAddr}
+	}
+	extra := map[string]any{}
+	if re := req.GetExtra(); re != nil && re.Header != nil {
+		if ua := re.Header.Get("User-Agent"); ua != "" {
+			extra[audit.SourceExtraKeyUserAgent] = ua
+		}
+	}
+	if ss != nil && ss.ID() != "" {
+		extra[audit.SourceExtraKeySessionID] = ss.ID()
+	}
+	if len(extra) > 0 {
+		source.Extra = extra
+	}
+	return source
+}
+
+// auditOutcome classifies a handled method's result.
+func auditOutcome(res gosdk.Result, err error) string {
+	var jerr *jsonrpc.Error
+	switch {
+	case errors.As(err, &jerr):
+		return audit.OutcomeFailure
+	case err != nil:
+		return audit.OutcomeError
+	}
+	if r, ok := res.(*gosdk.CallToolResult); ok && r != nil && r.IsError {
+		return audit.OutcomeFailure
+	}
+	return audit.OutcomeSuccess
+}
+
+// auditTarget returns the event's Target: the method, and what it acted on.
+func auditTarget(method string, req gosdk.Request) map[string]string {
+	target := map[string]string{audit.TargetKeyMethod: method}
+	switch p := req.GetParams().(type) {
+	case *gosdk.CallToolParamsRaw:
+		if p != nil {
+			target[audit.TargetKeyType] = audit.TargetTypeTool
+			target[audit.TargetKeyName] = p.Name
+		}
+	case *gosdk.GetPromptParams:
+		if p != nil {
+			target[audit.TargetKeyType] = audit.TargetTypePrompt
+			target[audit.TargetKeyName] = p.Name
+		}
+	case *gosdk.ReadResourceParams:
+		if p != nil {
+			target[audit.TargetKeyType] = audit.TargetTypeResource
+			target[audit.TargetKeyURI] = p.URI
+		}
+	default:
+		target[audit.TargetKeyType] = audit.TargetTypeServer
+	}
+	return target
+}
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: Copyright 2026 Stacklok, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stacklok/toolhive-core/audit"
+	"github.com/stacklok/toolhive-core/authn"
+	"github.com/stacklok/toolhive-core/mcpcompat/client"
+	mcp "github.com/stacklok/toolhive-core/mcpcompat/mcp"
+	"github.com/stacklok/toolhive-core/mcpcompat/server"
+)
+
+// auditEvents collects the events WithAudit emits.
+type auditEvents struct {
+	mu     sync.Mutex
+	events []*audit.AuditEvent
+}
+
+func (a *auditEvents) hook(_ context.Context, e *audit.AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, e)
+}
+
+func (a *auditEvents) get() []*audit.AuditEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*audit.AuditEvent(nil), a.events...)
+}
+
+// startAuditedServer serves an audited server with a succeeding "greet" tool
+// and a failing "fail" tool, every request authenticated as alice acting
+// through a gateway, and returns an initialized client.
+func startAuditedServer(t *testing.T, cfg server.AuditConfig) *client.Client {
+	t.Helper()
+	ctx := context.Background()
+
+	srv := server.NewMCPServer("audited-server", "1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithAudit(cfg),
+	)
+	srv.AddTool(mcp.NewTool("greet", mcp.WithString("name")),
+		func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("hello " + req.GetString("name", "world")), nil
+		})
+	srv.AddTool(mcp.NewTool("fail"),
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError("boom"), nil
+		})
+
+	principal := authn.Principal{
+		Issuer:  "https://issuer.example.com",
+		Subject: "alice",
+		Claims:  map[string]any{"act": map[string]any{"iss": "https://issuer.example.com", "sub": "gateway"}},
+	}
+	ts := httptest.NewServer(server.NewStreamableHTTPServer(srv,
+		server.WithHTTPContextFunc(func(ctx context.Context, _ *http.Request) context.Context {
+			return authn.ContextWithPrincipal(ctx, principal)
+		}),
+	))
+	t.Cleanup(ts.Close)
+
+	c, err := client.NewStreamableHttpClient(ts.URL)
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	t.Cleanup(func() { _ = c.Close() })
+	_, err = c.Initialize(ctx, mcp.InitializeRequest{
+		Params: mcp.InitializeParams{
+			ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+			ClientInfo:      mcp.Implementation{Name: testClientName, Version: testClientVersion},
+		},
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestWithAudit_EndToEnd(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var rec auditEvents
+	c := startAuditedServer(t, server.AuditConfig{
+		Hook:           rec.hook,
+		Identity:       authn.AuditIdentity,
+		ExcludeMethods: []string{"notifications/*", "server/discover"},
+	})
+
+	_, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	_, err = c.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "greet", Arguments: map[string]any{"name": "ada"}},
+	})
+	require.NoError(t, err)
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "fail"}})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	_, err = c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "missing"}})
+	require.Error(t, err)
+
+	events := rec.get()
+	require.Len(t, events, 5)
+
+	type summary struct{ typ, outcome, target string }
+	var got []summary
+	for _, e := range events {
+		got = append(got, summary{e.Type, e.Outcome, e.Target[audit.TargetKeyName]})
+	}
+	assert.Equal(t, []summary{
+		{audit.EventTypeMCPInitialize, audit.OutcomeSuccess, ""},
+		{audit.EventTypeMCPToolsList, audit.OutcomeSuccess, ""},
+		{audit.EventTypeMCPToolCall, audit.OutcomeSuccess, "greet"},
+		{audit.EventTypeMCPToolCall, audit.OutcomeFailure, "fail"},
+		{audit.EventTypeMCPToolCall, audit.OutcomeFailure, "missing"},
+	}, got)
+
+	call := events[2]
+	assert.Equal(t, "audited-server", call.Component)
+	assert.Equal(t, map[string]string{
+		audit.TargetKeyMethod: string(mcp.MethodToolsCall),
+		audit.TargetKeyType:   audit.TargetTypeTool,
+		audit.TargetKeyName:   "greet",
+	}, call.Target)
+	assert.Equal(t, map[string]string{
+		audit.SubjectKeyUserID:        "alice",
+		"issuer":                      "https://issuer.example.com",
+		audit.SubjectKeyClientName:    testClientName,
+		audit.SubjectKeyClientVersion: testClientVersion,
+	}, call.Subjects)
+	require.NotNil(t, call.DelegationChain)
+	assert.Equal(t, "gateway", call.DelegationChain.Chain[0].Subject)
+
+	assert.Equal(t, audit.SourceTypeNetwork, call.Source.Type)
+	assert.Equal(t, "127.0.0.1", call.Source.Value)
+	assert.NotEmpty(t, call.Source.Extra[audit.SourceExtraKeySessionID])
+	assert.NotEmpty(t, call.Source.Extra[audit.SourceExtraKeyUserAgent])
+
+	assert.Equal(t, "streamable-http", call.Metadata.Extra[audit.MetadataExtraKeyTransport])
+	assert.Equal(t, mcp.LATEST_PROTOCOL_VERSION, call.Metadata.Extra[audit.MetadataExtraKeyMCPVersion])
+	assert.Contains(t, call.Metadata.Extra, audit.MetadataExtraKeyDuration)
+	assert.Positive(t, call.Metadata.Extra[audit.MetadataExtraKeyResponseSize])
+	assert.Nil(t, call.Data, "params are not recorded by default")
+}
+
+func TestWithAudit_MethodFilters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		wantTypes []string
+	}{
+		{
+			name:      "include",
+			include:   []string{"tools/*"},
+			wantTypes: []string{audit.EventTypeMCPToolsList, audit.EventTypeMCPToolCall},
+		},
+		{
+			name:      "include and exclude",
+			include:   []string{"tools/*"},
+			exclude:   []string{"tools/list"},
+			wantTypes: []string{audit.EventTypeMCPToolCall},
+		},
+		{
+			name:      "exclude",
+			exclude:   []string{"notifications/*", "server/discover", "initialize", "tools/call"},
+			wantTypes: []string{audit.EventTypeMCPToolsList},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			var rec auditEvents
+			c := startAuditedServer(t, server.AuditConfig{
+				Hook:           rec.hook,
+				IncludeMethods: tt.include,
+				ExcludeMethods: tt.exclude,
+			})
+			_, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+			require.NoError(t, err)
+			_, err = c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "greet"}})
+			require.NoError(t, err)
+
+			var types []string
+			for _, e := range rec.get() {
+				types = append(types, e.Type)
+			}
+			assert.Equal(t, tt.wantTypes, types)
+		})
+	}
+}
+
+func TestWithAudit_RequestData(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var rec auditEvents
+	c := startAuditedServer(t, server.AuditConfig{
+		Hook:               rec.hook,
+		Component:          "gateway",
+		IncludeMethods:     []string{"tools/call"},
+		IncludeRequestData: true,
+	})
+	_, err := c.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "greet", Arguments: map[string]any{"name": "ada"}},
+	})
+	require.NoError(t, err)
+
+	events := rec.get()
+	require.Len(t, events, 1)
+	assert.Equal(t, "gateway", events[0].Component)
+	assert.Equal(t, map[string]string{
+		audit.SubjectKeyClientName:    testClientName,
+		audit.SubjectKeyClientVersion: testClientVersion,
+	}, events[0].Subjects, "without Identity only the client is recorded")
+	require.NotNil(t, events[0].Data)
+	var params struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	require.NoError(t, json.Unmarshal(*events[0].Data, &params))
+	assert.Equal(t, "greet", params.Name)
+	assert.Equal(t, map[string]any{"name": "ada"}, params.Arguments)
+}
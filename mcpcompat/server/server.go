@@ -43,6 +43,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 	gosdk "github.com/modelcontextprotocol/go-sdk/mcp"
@@ -147,6 +148,10 @@ type MCPServer struct {
 	logger  *slog.Logger
 	hooks   *Hooks
 
+	// audit, when set via WithAudit, receives an audit event for every
+	// dispatched method (see auditDispatch in audit.go).
+	audit *AuditConfig
+
 	// Capability flags set via WithToolCapabilities/WithResourceCapabilities/
 	// WithPromptCapabilities. These are wired into the go-sdk ServerOptions in
 	// buildServer (see ServerOptions.Capabilities): go-sdk otherwise infers
@@ -845,7 +850,9 @@ func (s *MCPServer) sessionDispatchMiddleware(srv *gosdk.Server, stateless bool)
 			// hook's request object is populated from req.GetParams(); see
 			// fireBeforeHooks for the extraction and fallback behavior.
 			s.fireBeforeHooks(ctx, method, req)
+			start := time.Now()
 			res, err := next(ctx, method, req)
+			elapsed := time.Since(start)
 			// Narrow go-sdk's default in-body cacheScope:"public" hint to
 			// "private" on results this shim serves per-identity — which is
 			// every serving mode, stateful included. See stripPublicCacheScope
@@ -857,6 +864,7 @@ func (s *MCPServer) sessionDispatchMiddleware(srv *gosdk.Server, stateless bool)
 			if err != nil && method == string(mcp.MethodToolsCall) {
 				err = translateUnknownToolError(err, req)
 			}
+			s.auditDispatch(ctx, method, req, res, err, elapsed)
 			// registerAndSync is the OnRegisterSession path (and, transitively,
 			// registers the session in the shared s.sessions registry — see
 			// NOTE(stateless-projection) on bindSessionForDispatch). Skip it
@@ -64,7 +64,8 @@ func ServeStdio(server *MCPServer, _ ...StdioOption) error {
 	if err != nil {
 		return err
 	}
-	return srv.Run(context.Background(), &gosdk.StdioTransport{})
+	ctx := server.transportContext(context.Background(), transportStdio, "")
+	return srv.Run(ctx, &gosdk.StdioTransport{})
 }
 
 // --- Streamable HTTP -------------------------------------------------------
@@ -351,6 +352,7 @@ func (s *StreamableHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	if s.contextFunc != nil {
 		r = r.WithContext(s.contextFunc(r.Context(), r))
 	}
+	r = s.mcp.withTransportInfo(r, transportStreamableHTTP)
 	// Pre-dispatch denial gate. Placement is deliberate:
 	//   (a) AFTER contextFunc, so a gate that reads context injected via the
 	//       shim option (identity, parsed request) sees it;
@@ -743,6 +745,7 @@ func (s *SSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ensureAcceptMediaTypes(r)
+	r = s.mcp.withTransportInfo(r, transportSSE)
 	// mcp-go advertised a distinct message endpoint for client POSTs, whereas
 	// go-sdk derives the endpoint it advertises in the SSE "endpoint" event from
 	// the SSE (GET) request's own path plus a sessionid query. Rewrite the GET